@@ -4,6 +4,8 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/unixpickle/model3d/model3d"
 
@@ -12,7 +14,7 @@ import (
 
 func main() {
 	inPath := flag.String("in", "", "Input .scad-like file")
-	outPath := flag.String("out", "out.stl", "Output STL path")
+	outPath := flag.String("out", "out.stl", "Output STL or 3MF path")
 	delta := flag.Float64("delta", 0.02, "DC resolution (smaller = finer)")
 	flag.Parse()
 
@@ -21,35 +23,80 @@ func main() {
 		os.Exit(2)
 	}
 
-	srcBytes, err := os.ReadFile(*inPath)
-	if err != nil {
-		fmt.Fprintln(os.Stderr, "read:", err)
+	if err := run(*inPath, *outPath, *delta); err != nil {
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
+	fmt.Println("wrote:", *outPath)
+}
+
+// run converts the script at inPath into an STL or 3MF file.
+//
+// STL files contain the union of every shape, while 3MF files keep shapes
+// with different color(), material() or part() tags as separate objects.
+func run(inPath, outPath string, delta float64) error {
+	srcBytes, err := os.ReadFile(inPath)
+	if err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
 
 	prog, err := scad.Parse(string(srcBytes))
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "parse:", err)
-		os.Exit(1)
+		return fmt.Errorf("parse: %w", err)
 	}
 
-	shape, err := scad.Eval(prog, scad.Hooks{})
+	hooks := scad.Hooks{
+		ReadFile: func(path string) ([]byte, error) {
+			// Resolve imports relative to the input script.
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(filepath.Dir(inPath), path)
+			}
+			return os.ReadFile(path)
+		},
+	}
+
+	if strings.ToLower(filepath.Ext(outPath)) == ".3mf" {
+		objects, err := scad.EvalObjects(prog, hooks)
+		if err != nil {
+			return fmt.Errorf("eval: %w", err)
+		}
+		meshes := make([]*model3d.Mesh, len(objects))
+		tags := make([]scad.ShapeTags, len(objects))
+		for i, shape := range objects {
+			mesh, err := shapeToMesh(shape, delta)
+			if err != nil {
+				return fmt.Errorf("eval: %w", err)
+			}
+			meshes[i] = mesh
+			tags[i] = shape.Tags
+		}
+		if err := save3MF(outPath, meshes, tags); err != nil {
+			return fmt.Errorf("save 3mf: %w", err)
+		}
+		return nil
+	}
+
+	// Tagged objects may overlap, so STL output meshes their union.
+	shape, err := scad.Eval(prog, hooks)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "eval:", err)
-		os.Exit(1)
+		return fmt.Errorf("eval: %w", err)
+	}
+	mesh, err := shapeToMesh(shape, delta)
+	if err != nil {
+		return fmt.Errorf("eval: %w", err)
+	}
+	if err := mesh.SaveGroupedSTL(outPath); err != nil {
+		return fmt.Errorf("save stl: %w", err)
 	}
+	return nil
+}
 
+func shapeToMesh(shape scad.ShapeRep, delta float64) (*model3d.Mesh, error) {
 	if shape.Kind == scad.ShapeMesh3D {
-		if err := shape.M3.SaveGroupedSTL(*outPath); err != nil {
-			fmt.Fprintln(os.Stderr, "save stl:", err)
-			os.Exit(1)
-		}
-		fmt.Println("wrote:", *outPath)
-		return
+		return shape.M3, nil
 	}
 	if shape.Kind == scad.ShapeSolid2D || shape.Kind == scad.ShapeMesh2D || shape.Kind == scad.ShapeSDF2D {
-		fmt.Fprintln(os.Stderr, "eval:", "2D outputs are not supported for STL export")
-		os.Exit(1)
+		return nil, fmt.Errorf("2D outputs are not supported for STL export")
 	}
 
 	solid := shape.S3
@@ -57,15 +104,9 @@ func main() {
 		solid = model3d.SDFToSolid(shape.SDF3, 0)
 	}
 	if solid == nil {
-		fmt.Fprintln(os.Stderr, "eval:", "output is not a 3D shape")
-		os.Exit(1)
+		return nil, fmt.Errorf("output is not a 3D shape")
 	}
 
-	mesh := model3d.DualContour(solid, *delta, true, false)
-	mesh = mesh.EliminateCoplanar(1e-8)
-	if err := mesh.SaveGroupedSTL(*outPath); err != nil {
-		fmt.Fprintln(os.Stderr, "save stl:", err)
-		os.Exit(1)
-	}
-	fmt.Println("wrote:", *outPath)
+	mesh := model3d.DualContour(solid, delta, true, false)
+	return mesh.EliminateCoplanar(1e-8), nil
 }
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"archive/zip"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/model3d/model3d"
+)
+
+func TestRunSTLUnionsTaggedObjects(t *testing.T) {
+	dir := t.TempDir()
+	inPath := filepath.Join(dir, "in.scad")
+	outPath := filepath.Join(dir, "out.stl")
+	src := `color("red") cube(2); color("blue") translate([1, 0, 0]) cube(2);`
+	if err := os.WriteFile(inPath, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := run(inPath, outPath, 0.05); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	tris, err := model3d.ReadSTL(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mesh := model3d.NewMeshTriangles(tris)
+	if n := mesh.SelfIntersections(); n != 0 {
+		t.Fatalf("expected no self-intersections, got %d", n)
+	}
+	if v := mesh.Volume(); math.Abs(v-12) > 0.1 {
+		t.Fatalf("expected volume 12, got %f", v)
+	}
+}
+
+func TestRun3MFResizeKeepsMaterials(t *testing.T) {
+	dir := t.TempDir()
+	inPath := filepath.Join(dir, "in.scad")
+	outPath := filepath.Join(dir, "out.3mf")
+	src := `resize([4, 2, 2]) { material("PLA") cube(1); material("PETG") translate([1, 0, 0]) cube(1); }`
+	if err := os.WriteFile(inPath, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := run(inPath, outPath, 0.05); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.OpenReader(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	f, err := zr.Open("3D/3dmodel.model")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := string(data)
+	for _, name := range []string{`name="PLA"`, `name="PETG"`} {
+		if !strings.Contains(model, name) {
+			t.Fatalf("expected material %s in model", name)
+		}
+	}
+	if n := strings.Count(model, "<object "); n != 2 {
+		t.Fatalf("expected 2 objects, got %d", n)
+	}
+	if !strings.Contains(model, `pindex="1"`) {
+		t.Fatal("expected an object to use the second material")
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/unixpickle/model3d/model3d"
+
+	"github.com/unixpickle/m3dscad/scad"
+)
+
+const (
+	threeMFContentTypes = `<?xml version="1.0" encoding="UTF-8"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+  <Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+  <Default Extension="model" ContentType="application/vnd.ms-package.3dmanufacturing-3dmodel+xml"/>
+</Types>
+`
+	threeMFRels = `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Target="/3D/3dmodel.model" Id="rel0" Type="http://schemas.microsoft.com/3dmanufacturing/2013/01/3dmodel"/>
+</Relationships>
+`
+)
+
+// save3MF writes one 3MF object per mesh, with a base material for each
+// distinct color or material tag.
+func save3MF(path string, meshes []*model3d.Mesh, tags []scad.ShapeTags) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for _, file := range []struct {
+		Name string
+		Data string
+	}{
+		{"[Content_Types].xml", threeMFContentTypes},
+		{"_rels/.rels", threeMFRels},
+	} {
+		w, err := zw.Create(file.Name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, file.Data); err != nil {
+			return err
+		}
+	}
+	w, err := zw.Create("3D/3dmodel.model")
+	if err != nil {
+		return err
+	}
+	if err := write3MFModel(w, meshes, tags); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func write3MFModel(w io.Writer, meshes []*model3d.Mesh, tags []scad.ShapeTags) error {
+	var materials []scad.ShapeTags
+	materialIndex := map[scad.ShapeTags]int{}
+	for _, t := range tags {
+		key := scad.ShapeTags{HasColor: t.HasColor, Color: t.Color, Material: t.Material}
+		if _, ok := materialIndex[key]; !ok && (t.HasColor || t.Material != "") {
+			materialIndex[key] = len(materials)
+			materials = append(materials, key)
+		}
+	}
+
+	fmt.Fprintln(w, `<?xml version="1.0" encoding="UTF-8"?>`)
+	fmt.Fprintln(w, `<model unit="millimeter" xml:lang="en-US" xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02">`)
+	fmt.Fprintln(w, `<resources>`)
+	const materialsID = 1
+	if len(materials) > 0 {
+		fmt.Fprintf(w, "<basematerials id=\"%d\">\n", materialsID)
+		for _, m := range materials {
+			name := m.Material
+			if name == "" {
+				name = threeMFColor(m.Color)
+			}
+			color := [4]float64{0.8, 0.8, 0.8, 1}
+			if m.HasColor {
+				color = m.Color
+			}
+			fmt.Fprintf(w, "<base name=\"%s\" displaycolor=\"%s\"/>\n", xmlEscape(name), threeMFColor(color))
+		}
+		fmt.Fprintln(w, `</basematerials>`)
+	}
+	for i, mesh := range meshes {
+		t := tags[i]
+		objectID := i + materialsID + 1
+		fmt.Fprintf(w, "<object id=\"%d\" type=\"model\"", objectID)
+		if t.Part != "" {
+			fmt.Fprintf(w, " name=\"%s\"", xmlEscape(t.Part))
+		}
+		key := scad.ShapeTags{HasColor: t.HasColor, Color: t.Color, Material: t.Material}
+		if idx, ok := materialIndex[key]; ok {
+			fmt.Fprintf(w, " pid=\"%d\" pindex=\"%d\"", materialsID, idx)
+		}
+		fmt.Fprintln(w, "><mesh><vertices>")
+		vertexIDs := map[model3d.Coord3D]int{}
+		for _, v := range mesh.VertexSlice() {
+			vertexIDs[v] = len(vertexIDs)
+			fmt.Fprintf(w, "<vertex x=\"%g\" y=\"%g\" z=\"%g\"/>\n", v.X, v.Y, v.Z)
+		}
+		fmt.Fprintln(w, "</vertices><triangles>")
+		for _, tri := range mesh.TriangleSlice() {
+			fmt.Fprintf(
+				w,
+				"<triangle v1=\"%d\" v2=\"%d\" v3=\"%d\"/>\n",
+				vertexIDs[tri[0]],
+				vertexIDs[tri[1]],
+				vertexIDs[tri[2]],
+			)
+		}
+		fmt.Fprintln(w, "</triangles></mesh></object>")
+	}
+	fmt.Fprintln(w, `</resources>`)
+	fmt.Fprintln(w, `<build>`)
+	for i := range meshes {
+		fmt.Fprintf(w, "<item objectid=\"%d\"/>\n", i+materialsID+1)
+	}
+	fmt.Fprintln(w, `</build>`)
+	_, err := fmt.Fprintln(w, `</model>`)
+	return err
+}
+
+func threeMFColor(c [4]float64) string {
+	res := "#"
+	for _, x := range c {
+		res += fmt.Sprintf("%02X", int(x*255+0.5))
+	}
+	return res
+}
+
+func xmlEscape(s string) string {
+	var buf strings.Builder
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
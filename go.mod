@@ -8,14 +8,16 @@ require github.com/unixpickle/path2d v0.2.0
 
 require github.com/unixpickle/textcurve v0.1.0
 
-require github.com/unixpickle/webgpu-meshes v0.1.0
+require (
+	github.com/go-text/typesetting v0.3.4
+	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0
+	github.com/unixpickle/webgpu-meshes v0.1.0
+	golang.org/x/image v0.42.0
+)
 
 require (
-	github.com/go-text/typesetting v0.3.4 // indirect
-	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/unixpickle/essentials v1.3.0 // indirect
 	github.com/unixpickle/splaytree v1.2.0 // indirect
-	golang.org/x/image v0.42.0 // indirect
 	golang.org/x/text v0.38.0 // indirect
 )
@@ -213,6 +213,16 @@ type FuncLitExpr struct {
 func (*FuncLitExpr) exprNode()  {}
 func (e *FuncLitExpr) pos() Pos { return e.P }
 
+// ShapeLitExpr is a `shape { ... }` expression, which evaluates its body
+// to a shape value instead of adding it to the output.
+type ShapeLitExpr struct {
+	Body []Stmt
+	P    Pos
+}
+
+func (*ShapeLitExpr) exprNode()  {}
+func (e *ShapeLitExpr) pos() Pos { return e.P }
+
 type IndexExpr struct {
 	X     Expr
 	Index Expr
@@ -5,6 +5,7 @@ import (
 	"log"
 	"math"
 	"math/rand"
+	"os"
 	"sort"
 	"strconv"
 	"strings"
@@ -36,12 +37,18 @@ type scope struct {
 type env struct {
 	scopes []*scope
 	hooks  Hooks
+
+	// handlers maps builtin module names to their implementations.
+	// It is stored here rather than referenced directly, since the
+	// handlers themselves evaluate statements.
+	handlers map[string]callHandler
 }
 
 func (e *env) WithScopes(s []*scope) *env {
 	return &env{
-		scopes: s,
-		hooks:  e.hooks,
+		scopes:   s,
+		hooks:    e.hooks,
+		handlers: e.handlers,
 	}
 }
 
@@ -67,6 +74,17 @@ type Hooks struct {
 
 	// MarchingSquares is used to create a mesh from a 23D solid.
 	MarchingSquares func(obj ShapeRep, delta float64, iters int) (*model2d.Mesh, error)
+
+	// ReadFile is used to load external files, such as those passed to
+	// import() and surface().
+	// If it is nil, os.ReadFile is used.
+	ReadFile func(path string) ([]byte, error)
+
+	// Fonts contains TTF or OTF files with TrueType outlines that text() can
+	// select by family and style, e.g. font="Noto Sans:style=Bold".
+	// The embedded Liberation Sans is always available, and registered fonts
+	// are also used as fallbacks for glyphs missing from the selected font.
+	Fonts [][]byte
 }
 
 // An EchoHandler is called when a script executes the built-in echo()
@@ -101,11 +119,15 @@ func newEnv(hooks Hooks) *env {
 			return model2d.MarchingSquaresSearch(obj.S2, delta, iters), nil
 		}
 	}
+	if hooks.ReadFile == nil {
+		hooks.ReadFile = os.ReadFile
+	}
 	root := newScope()
 	root.vars["PI"] = Num(math.Pi)
 	return &env{
-		scopes: []*scope{root},
-		hooks:  hooks,
+		scopes:   []*scope{root},
+		hooks:    hooks,
+		handlers: builtinHandlers,
 	}
 }
 
@@ -202,16 +224,62 @@ func Parse(src string) (*Program, error) {
 }
 
 func Eval(p *Program, hooks Hooks) (ShapeRep, error) {
+	_, merged, err := evalProgram(p, hooks)
+	return merged, err
+}
+
+// EvalObjects is like Eval, but it keeps shapes with different tags
+// from color(), material() and part() as separate objects.
+//
+// Shapes with the same tags are merged into one object, and objects are
+// returned in the order their tags first appear.
+func EvalObjects(p *Program, hooks Hooks) ([]ShapeRep, error) {
+	_, objects, err := EvalUnionAndObjects(p, hooks)
+	return objects, err
+}
+
+// EvalUnionAndObjects returns the results of both Eval and EvalObjects,
+// while only evaluating the program once.
+//
+// Objects may overlap, so the union should be used for outputs which
+// cannot keep objects separate, such as STL files.
+func EvalUnionAndObjects(p *Program, hooks Hooks) (ShapeRep, []ShapeRep, error) {
+	e, merged, err := evalProgram(p, hooks)
+	if err != nil {
+		return ShapeRep{}, nil, err
+	}
+	if len(merged.Parts) == 0 {
+		return merged, []ShapeRep{merged}, nil
+	}
+	var tags []ShapeTags
+	groups := map[ShapeTags][]ShapeRep{}
+	for _, part := range merged.Parts {
+		if _, ok := groups[part.Tags]; !ok {
+			tags = append(tags, part.Tags)
+		}
+		groups[part.Tags] = append(groups[part.Tags], part)
+	}
+	objects := make([]ShapeRep, len(tags))
+	for i, t := range tags {
+		objects[i], err = unionAll(e.hooks.Numerics, groups[t])
+		if err != nil {
+			return ShapeRep{}, nil, err
+		}
+	}
+	return merged, objects, nil
+}
+
+func evalProgram(p *Program, hooks Hooks) (*env, ShapeRep, error) {
 	e := newEnv(hooks)
 	solids, err := evalStmts(e, p.Stmts)
 	if err != nil {
-		return ShapeRep{}, err
+		return nil, ShapeRep{}, err
 	}
 	merged, err := unionAll(e.hooks.Numerics, solids)
 	if err != nil {
-		return ShapeRep{}, err
+		return nil, ShapeRep{}, err
 	}
-	return merged, nil
+	return e, merged, nil
 }
 
 func evalStmts(e *env, ss []Stmt) ([]ShapeRep, error) {
@@ -355,7 +423,7 @@ func evalCallStmt(e *env, st *CallStmt) (*ShapeRep, error) {
 		return nil, nil
 	}
 
-	if handler, ok := builtinHandlers[name]; ok {
+	if handler, ok := e.handlers[name]; ok {
 		if len(st.Children) == 0 && handler.RequireChildren {
 			return nil, fmt.Errorf("%s() requires children", name)
 		}
@@ -386,10 +454,17 @@ func evalCallStmt(e *env, st *CallStmt) (*ShapeRep, error) {
 				return nil, err
 			}
 		}
-		res, err := handler.Eval(e, st, children, childUnion)
+		var res ShapeRep
+		var err error
+		if handler.PerPart && childUnion != nil && len(childUnion.Parts) > 0 {
+			res, err = evalPerPart(e, st, handler, childUnion.Parts)
+		} else {
+			res, err = handler.Eval(e, st, children, childUnion)
+		}
 		if err != nil {
 			return nil, err
 		}
+		inheritTags(&res, children)
 		return &res, nil
 	}
 
@@ -424,6 +499,35 @@ func evalCallStmt(e *env, st *CallStmt) (*ShapeRep, error) {
 	return nil, fmt.Errorf("unknown module/primitive %q", name)
 }
 
+// evalPerPart applies a builtin separately to each tagged part of its
+// children, so that the parts survive the operation.
+func evalPerPart(e *env, st *CallStmt, handler callHandler, parts []ShapeRep) (ShapeRep, error) {
+	return mapTaggedParts(e.hooks.Numerics, ShapeRep{Parts: parts}, func(part ShapeRep) (ShapeRep, error) {
+		return handler.Eval(e, st, []ShapeRep{part}, &part)
+	})
+}
+
+// mapTaggedParts applies f to each tagged part of s and unions the
+// results, so that the parts survive the operation.
+//
+// This is for builtins which derive their parameters from the bounds of
+// the whole child, and can then map each part with the same parameters.
+func mapTaggedParts(n shapekernel.Numerics, s ShapeRep, f func(ShapeRep) (ShapeRep, error)) (ShapeRep, error) {
+	if len(s.Parts) == 0 {
+		return f(s)
+	}
+	results := make([]ShapeRep, len(s.Parts))
+	for i, part := range s.Parts {
+		res, err := f(part)
+		if err != nil {
+			return ShapeRep{}, err
+		}
+		inheritTags(&res, []ShapeRep{part})
+		results[i] = res
+	}
+	return unionAll(n, results)
+}
+
 func evalStmtsAsOne(e *env, ss []Stmt) (*ShapeRep, error) {
 	solids, err := evalStmts(e, ss)
 	if err != nil {
@@ -556,6 +660,8 @@ func evalExpr(e *env, ex Expr) (Value, error) {
 			Body:     x.Body,
 			Captured: e.captureScopes(),
 		}), nil
+	case *ShapeLitExpr:
+		return evalShapeLitExpr(e, x)
 	case *InvokeExpr:
 		fnV, err := evalExpr(e, x.Fn)
 		if err != nil {
@@ -844,6 +950,12 @@ func evalFuncCall(e *env, c Call) (Value, error) {
 			return Value{}, err
 		}
 		return Bool(arg0.Kind == ValFunc && arg0.Func != nil), nil
+	case "is_shape":
+		arg0, err := evalUnaryFuncArg(e, c)
+		if err != nil {
+			return Value{}, err
+		}
+		return Bool(arg0.Kind == ValShape && arg0.Shape != nil), nil
 	case "sin":
 		x, err := evalUnaryNumericFuncArg(e, c)
 		if err != nil {
@@ -1075,6 +1187,32 @@ func evalFuncCall(e *env, c Call) (Value, error) {
 			out = append(out, Num(minX+rng.Float64()*span))
 		}
 		return List(out), nil
+	case "gear_pitch_radius", "gear_center_distance":
+		return evalGearFunc(e, c)
+	case "textmetrics":
+		return evalTextMetrics(e, c)
+	case "fontmetrics":
+		return evalFontMetrics(e, c)
+	case "bezier_points":
+		return evalBezierPoints(e, c)
+	case "catmull_rom":
+		return evalCatmullRom(e, c)
+	case "arc_points":
+		return evalArcPoints(e, c)
+	case "path_points":
+		return evalPathPoints(e, c)
+	case "resample_path":
+		return evalResamplePath(e, c)
+	case "bounds":
+		return evalShapeBounds(e, c)
+	case "volume":
+		return evalShapeVolume(e, c)
+	case "area":
+		return evalShapeArea(e, c)
+	case "centroid":
+		return evalShapeCentroid(e, c)
+	case "contains":
+		return evalShapeContains(e, c)
 	case "lookup":
 		if len(c.Args) != 2 {
 			return Value{}, PosErrorf(c.P, "lookup() needs exactly 2 arguments")
@@ -1304,6 +1442,11 @@ func valueString(v Value) string {
 			}
 		}
 		return "function(" + strings.Join(paramStrs, ", ") + ") " + formatExpr(v.Func.Body)
+	case ValShape:
+		if v.Shape == nil {
+			return "shape {}"
+		}
+		return "shape { " + strconv.Itoa(v.Shape.Kind.Dimension()) + "D }"
 	default:
 		return "undef"
 	}
@@ -1410,6 +1553,8 @@ func formatExpr(ex Expr) string {
 			}
 		}
 		return "function(" + strings.Join(paramStrs, ", ") + ") " + formatExpr(x.Body)
+	case *ShapeLitExpr:
+		return "shape {...}"
 	default:
 		return "?"
 	}
@@ -0,0 +1,248 @@
+package scad
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/unixpickle/model3d/model2d"
+	"github.com/unixpickle/model3d/model3d"
+)
+
+// boundsBisectIters is the number of bisection steps used to refine each
+// face of a sampled bounding box.
+const boundsBisectIters = 20
+
+// shapeBounds returns the declared bounding box of a shape.
+// For 2D shapes, the z bounds are zero.
+func shapeBounds(s ShapeRep) (model3d.Coord3D, model3d.Coord3D, error) {
+	var min, max model3d.Coord3D
+	switch s.Kind {
+	case ShapeSolid2D:
+		min, max = xyToCoord3D(s.S2.Min()), xyToCoord3D(s.S2.Max())
+	case ShapeSolid3D:
+		min, max = s.S3.Min(), s.S3.Max()
+	case ShapeMesh2D:
+		if s.M2.NumSegments() == 0 {
+			return min, max, fmt.Errorf("empty mesh has no bounds")
+		}
+		min, max = xyToCoord3D(s.M2.Min()), xyToCoord3D(s.M2.Max())
+	case ShapeMesh3D:
+		if s.M3.NumTriangles() == 0 {
+			return min, max, fmt.Errorf("empty mesh has no bounds")
+		}
+		min, max = s.M3.Min(), s.M3.Max()
+	case ShapeSDF2D:
+		min, max = xyToCoord3D(s.SDF2.Min()), xyToCoord3D(s.SDF2.Max())
+	case ShapeSDF3D:
+		min, max = s.SDF3.Min(), s.SDF3.Max()
+	case ShapeMetaball2D:
+		if s.MB2 == nil || len(s.MB2.Balls) == 0 {
+			return min, max, fmt.Errorf("empty metaball has no bounds")
+		}
+		min2, max2 := model2d.BoundsUnion(s.MB2.Balls)
+		min, max = xyToCoord3D(min2), xyToCoord3D(max2)
+	case ShapeMetaball3D:
+		if s.MB3 == nil || len(s.MB3.Balls) == 0 {
+			return min, max, fmt.Errorf("empty metaball has no bounds")
+		}
+		min, max = model3d.BoundsUnion(s.MB3.Balls)
+	case ShapeHull2D:
+		if s.H2 == nil || len(s.H2.Circles) == 0 {
+			return min, max, fmt.Errorf("empty hull has no bounds")
+		}
+		min2, max2 := s.H2.Circles[0].Min(), s.H2.Circles[0].Max()
+		for _, c := range s.H2.Circles[1:] {
+			min2 = min2.Min(c.Min())
+			max2 = max2.Max(c.Max())
+		}
+		min, max = xyToCoord3D(min2), xyToCoord3D(max2)
+	default:
+		return min, max, fmt.Errorf("unsupported shape kind")
+	}
+	if !finiteCoord3D(min) || !finiteCoord3D(max) {
+		return min, max, fmt.Errorf("shape has unbounded extent")
+	}
+	return min, max, nil
+}
+
+// sampledShapeBounds tightens the declared bounds of a solid or SDF by
+// sampling it on a grid with the given number of cells along each axis,
+// and then bisecting towards each face of the box.
+//
+// Meshes, hulls, and metaballs already have exact bounds, so their
+// declared bounds are returned directly.
+func sampledShapeBounds(s ShapeRep, samples int) (model3d.Coord3D, model3d.Coord3D, error) {
+	min, max, err := shapeBounds(s)
+	if err != nil {
+		return min, max, err
+	}
+	var contains func(c model3d.Coord3D) bool
+	switch s.Kind {
+	case ShapeSolid2D:
+		contains = func(c model3d.Coord3D) bool { return s.S2.Contains(c.XY()) }
+	case ShapeSolid3D:
+		contains = s.S3.Contains
+	case ShapeSDF2D:
+		contains = func(c model3d.Coord3D) bool { return s.SDF2.SDF(c.XY()) > 0 }
+	case ShapeSDF3D:
+		contains = func(c model3d.Coord3D) bool { return s.SDF3.SDF(c) > 0 }
+	default:
+		return min, max, nil
+	}
+
+	dim := s.Kind.Dimension()
+	counts := [3]int{samples + 1, samples + 1, 1}
+	if dim == 3 {
+		counts[2] = samples + 1
+	}
+	size := max.Sub(min).Array()
+	var step [3]float64
+	for i := 0; i < dim; i++ {
+		step[i] = size[i] / float64(samples)
+	}
+	point := func(idx [3]int) [3]float64 {
+		var res [3]float64
+		for i, x := range min.Array() {
+			res[i] = x + float64(idx[i])*step[i]
+		}
+		return res
+	}
+
+	occupied := make([]bool, counts[0]*counts[1]*counts[2])
+	flatIndex := func(idx [3]int) int {
+		return idx[0] + counts[0]*(idx[1]+counts[1]*idx[2])
+	}
+	lo := counts
+	hi := [3]int{-1, -1, -1}
+	var idx [3]int
+	for idx[2] = 0; idx[2] < counts[2]; idx[2]++ {
+		for idx[1] = 0; idx[1] < counts[1]; idx[1]++ {
+			for idx[0] = 0; idx[0] < counts[0]; idx[0]++ {
+				if !contains(model3d.NewCoord3DArray(point(idx))) {
+					continue
+				}
+				occupied[flatIndex(idx)] = true
+				for i := 0; i < 3; i++ {
+					if idx[i] < lo[i] {
+						lo[i] = idx[i]
+					}
+					if idx[i] > hi[i] {
+						hi[i] = idx[i]
+					}
+				}
+			}
+		}
+	}
+	if hi[0] < 0 {
+		return min, max, fmt.Errorf("shape appears to be empty")
+	}
+
+	outMin, outMax := min.Array(), max.Array()
+	for axis := 0; axis < dim; axis++ {
+		for _, toMin := range []bool{true, false} {
+			slice, neighbor := hi[axis], 1
+			if toMin {
+				slice, neighbor = lo[axis], -1
+			}
+			if slice+neighbor < 0 || slice+neighbor >= counts[axis] {
+				// The shape touches the declared bounds.
+				continue
+			}
+			best := math.Inf(1)
+			if !toMin {
+				best = math.Inf(-1)
+			}
+			var idx [3]int
+			for idx[2] = 0; idx[2] < counts[2]; idx[2]++ {
+				for idx[1] = 0; idx[1] < counts[1]; idx[1]++ {
+					for idx[0] = 0; idx[0] < counts[0]; idx[0]++ {
+						if idx[axis] != slice || !occupied[flatIndex(idx)] {
+							continue
+						}
+						p := point(idx)
+						inside, outside := p[axis], p[axis]+float64(neighbor)*step[axis]
+						for i := 0; i < boundsBisectIters; i++ {
+							p[axis] = (inside + outside) / 2
+							if contains(model3d.NewCoord3DArray(p)) {
+								inside = p[axis]
+							} else {
+								outside = p[axis]
+							}
+						}
+						if toMin {
+							best = math.Min(best, inside)
+						} else {
+							best = math.Max(best, inside)
+						}
+					}
+				}
+			}
+			if toMin {
+				outMin[axis] = best
+			} else {
+				outMax[axis] = best
+			}
+		}
+	}
+	return model3d.NewCoord3DArray(outMin), model3d.NewCoord3DArray(outMax), nil
+}
+
+func xyToCoord3D(c model2d.Coord) model3d.Coord3D {
+	return model3d.XYZ(c.X, c.Y, 0)
+}
+
+func finiteCoord3D(c model3d.Coord3D) bool {
+	for _, x := range c.Array() {
+		if math.IsInf(x, 0) || math.IsNaN(x) {
+			return false
+		}
+	}
+	return true
+}
+
+func handleBoundsOf(e *env, st *CallStmt, _ []ShapeRep, childUnion *ShapeRep) (ShapeRep, error) {
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "samples", Pos: 0, Default: Num(64)},
+	})
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	min, max, err := parseBoundsSamples(args, "bounds_of", childUnion)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	n := e.hooks.Numerics
+	if childUnion.Kind.Dimension() == 2 {
+		rect := &model2d.Rect{MinVal: min.XY(), MaxVal: max.XY()}
+		return shapeSolid2D(rect, rect2DSolidKernel(n, rect)), nil
+	}
+	rect := &model3d.Rect{MinVal: min, MaxVal: max}
+	return shapeSolid3D(rect, rect3DSolidKernel(n, rect)), nil
+}
+
+// parseBoundsSamples reads the "samples" argument and computes the
+// bounds of a shape, using declared bounds if samples is zero.
+func parseBoundsSamples(
+	args map[string]Value,
+	opName string,
+	shape *ShapeRep,
+) (model3d.Coord3D, model3d.Coord3D, error) {
+	samples, err := argNum(args, "samples")
+	if err != nil {
+		return model3d.Coord3D{}, model3d.Coord3D{}, err
+	}
+	if samples < 0 || samples != math.Floor(samples) {
+		return model3d.Coord3D{}, model3d.Coord3D{},
+			fmt.Errorf("%s(): samples must be a non-negative integer", opName)
+	}
+	var min, max model3d.Coord3D
+	if samples == 0 {
+		min, max, err = shapeBounds(*shape)
+	} else {
+		min, max, err = sampledShapeBounds(*shape, int(samples))
+	}
+	if err != nil {
+		return min, max, fmt.Errorf("%s(): %w", opName, err)
+	}
+	return min, max, nil
+}
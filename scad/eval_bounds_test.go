@@ -0,0 +1,113 @@
+package scad
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/model3d/model2d"
+	"github.com/unixpickle/model3d/model3d"
+)
+
+func TestResize(t *testing.T) {
+	tests := []struct {
+		src string
+		min model3d.Coord3D
+		max model3d.Coord3D
+	}{
+		{`resize([4, 6, 8]) cube([2, 2, 2]);`, model3d.XYZ(0, 0, 0), model3d.XYZ(4, 6, 8)},
+		{`resize([4, 0, 0]) cube([2, 1, 1]);`, model3d.XYZ(0, 0, 0), model3d.XYZ(4, 1, 1)},
+		{`resize([4, 0, 0], auto=true) cube([2, 1, 1]);`, model3d.XYZ(0, 0, 0), model3d.XYZ(4, 2, 2)},
+		{`resize([4, 0, 0], auto=[false, true]) cube([2, 1, 1]);`, model3d.XYZ(0, 0, 0), model3d.XYZ(4, 2, 1)},
+		{`resize([0, 1, 3], auto=true) cube([2, 2, 2]);`, model3d.XYZ(0, 0, 0), model3d.XYZ(3, 1, 3)},
+		// Auto axes follow the axis with the largest new size, not the
+		// largest scale.
+		{`resize([0, 2, 8], auto=true) cube([1, 0.5, 4]);`, model3d.XYZ(0, 0, 0), model3d.XYZ(2, 2, 8)},
+		{`resize([5, 5, 5]) cube_sdf(size=[2, 2, 2], center=true);`, model3d.XYZ(-2.5, -2.5, -2.5), model3d.XYZ(2.5, 2.5, 2.5)},
+		{`resize(10) marching_cubes(delta=0.1) sphere(r=1);`, model3d.XYZ(-5, -5, -5), model3d.XYZ(5, 5, 5)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.src, func(t *testing.T) {
+			shape := mustEvalShape(t, tc.src)
+			min, max, err := shapeBounds(shape)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if min.Dist(tc.min) > 1e-8 || max.Dist(tc.max) > 1e-8 {
+				t.Fatalf("expected bounds %v-%v, got %v-%v", tc.min, tc.max, min, max)
+			}
+		})
+	}
+
+	shape := mustEvalShape(t, `resize([10, 0], auto=true) square([2, 1]);`)
+	if shape.Kind != ShapeSolid2D {
+		t.Fatalf("expected ShapeSolid2D, got %v", shape.Kind)
+	}
+	if max := shape.S2.Max(); max.Dist(model2d.XY(10, 5)) > 1e-8 {
+		t.Fatalf("unexpected max %v", max)
+	}
+	if shape.Kernel == nil {
+		t.Fatal("expected kernel to be preserved")
+	}
+}
+
+func TestResizeSampledBounds(t *testing.T) {
+	// The declared bounds of a difference come from the first child.
+	src := `
+		resize([2, 0, 0], samples=32)
+			difference() {
+				cube([10, 4, 4]);
+				translate([5, -1, -1]) cube([10, 6, 6]);
+			}
+	`
+	solid := mustEvalSolid(t, src)
+	assertContains(t, solid, model3d.XYZ(1.9, 2, 2), true)
+	assertContains(t, solid, model3d.XYZ(2.1, 2, 2), false)
+
+	shape := mustEvalShape(t, `
+		bounds_of()
+			difference() {
+				circle(r=3);
+				translate([1, -5]) square([5, 10]);
+			}
+	`)
+	if shape.Kind != ShapeSolid2D || shape.Kernel == nil {
+		t.Fatalf("expected ShapeSolid2D with kernel, got %v", shape.Kind)
+	}
+	min, max := shape.S2.Min(), shape.S2.Max()
+	if min.Dist(model2d.XY(-3, -3)) > 1e-3 || max.Dist(model2d.XY(1, 3)) > 1e-3 {
+		t.Fatalf("unexpected sampled bounds %v-%v", min, max)
+	}
+
+	shape = mustEvalShape(t, `bounds_of() sphere_sdf(r=2);`)
+	if shape.Kind != ShapeSolid3D {
+		t.Fatalf("expected ShapeSolid3D, got %v", shape.Kind)
+	}
+	if s := shape.S3.Max().Sub(shape.S3.Min()); math.Abs(s.X-4) > 1e-3 || math.Abs(s.Z-4) > 1e-3 {
+		t.Fatalf("unexpected sampled size %v", s)
+	}
+}
+
+func TestResizeErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`resize([1, 1, 1]) square(1);`, "z size not supported"},
+		{`resize([1, -1, 1]) cube(1);`, "non-negative"},
+		{`resize([1, 1, 1]) cube([1, 1, 0]);`, "zero extent"},
+		{`resize([1, 2, 3]) sphere_sdf(r=1);`, "non-uniform scaling not supported"},
+		{`resize([1, 1, 1], auto="yes") cube(1);`, "auto must be"},
+		{`resize([1, 1, 1], samples=-1) cube(1);`, "samples must be"},
+	}
+	for _, tc := range tests {
+		prog, err := Parse(tc.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = Eval(prog, Hooks{})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", tc.src, tc.want, err)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package scad
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/model3d/model3d"
+)
+
+func mustEvalObjects(t *testing.T, src string) []ShapeRep {
+	t.Helper()
+	prog, err := Parse(src)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	objects, err := EvalObjects(prog, Hooks{})
+	if err != nil {
+		t.Fatalf("eval failed: %v", err)
+	}
+	return objects
+}
+
+func TestColorParsing(t *testing.T) {
+	tests := []struct {
+		src  string
+		want [4]float64
+	}{
+		{`color("red") cube(1);`, [4]float64{1, 0, 0, 1}},
+		{`color("Red", 0.5) cube(1);`, [4]float64{1, 0, 0, 0.5}},
+		{`color("#0f08") cube(1);`, [4]float64{0, 1, 0, 0x88 / 255.0}},
+		{`color("#336699") cube(1);`, [4]float64{0x33 / 255.0, 0x66 / 255.0, 0x99 / 255.0, 1}},
+		{`color([0.1, 0.2, 0.3]) cube(1);`, [4]float64{0.1, 0.2, 0.3, 1}},
+		{`color([0.1, 0.2, 0.3, 0.4]) cube(1);`, [4]float64{0.1, 0.2, 0.3, 0.4}},
+		{`color(c=[0.1, 0.2, 0.3, 0.4], alpha=1) cube(1);`, [4]float64{0.1, 0.2, 0.3, 1}},
+	}
+	for _, tc := range tests {
+		shape := mustEvalShape(t, tc.src)
+		if !shape.Tags.HasColor {
+			t.Fatalf("%s: expected a color", tc.src)
+		}
+		for i, x := range shape.Tags.Color {
+			if math.Abs(x-tc.want[i]) > 1e-8 {
+				t.Fatalf("%s: expected %v, got %v", tc.src, tc.want, shape.Tags.Color)
+			}
+		}
+	}
+
+	for _, src := range []string{
+		`color("notacolor") cube(1);`,
+		`color("#12345") cube(1);`,
+		`color([1, 2]) cube(1);`,
+		`color([2, 0, 0]) cube(1);`,
+		`material("") cube(1);`,
+	} {
+		prog, err := Parse(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Eval(prog, Hooks{}); err == nil {
+			t.Fatalf("%s: expected error", src)
+		}
+	}
+}
+
+func TestEvalObjects(t *testing.T) {
+	objects := mustEvalObjects(t, `
+		module assembly() {
+			color("red") part("base") cube(1);
+			translate([2, 0, 0]) color("blue") sphere(r=0.5);
+			color("red") part("base") translate([0, 2, 0]) cube(1);
+		}
+		translate([0, 0, 5]) material("PLA") assembly();
+	`)
+	if len(objects) != 2 {
+		t.Fatalf("expected 2 objects, got %d", len(objects))
+	}
+	base, ball := objects[0], objects[1]
+	if base.Tags.Part != "base" || base.Tags.Color != [4]float64{1, 0, 0, 1} || base.Tags.Material != "PLA" {
+		t.Fatalf("unexpected base tags %+v", base.Tags)
+	}
+	if ball.Tags.Part != "" || ball.Tags.Color != [4]float64{0, 0, 1, 1} || ball.Tags.Material != "PLA" {
+		t.Fatalf("unexpected ball tags %+v", ball.Tags)
+	}
+	assertContains(t, base.S3, model3d.XYZ(0.5, 2.5, 5.5), true)
+	assertContains(t, base.S3, model3d.XYZ(2, 0.5, 5.5), false)
+	assertContains(t, ball.S3, model3d.XYZ(2, 0.5, 5.5), false)
+	assertContains(t, ball.S3, model3d.XYZ(2, 0, 5), true)
+
+	// Eval still returns the merged shape.
+	merged := mustEvalShape(t, `color("red") cube(1); color("blue") translate([2, 0, 0]) cube(1);`)
+	assertContains(t, merged.S3, model3d.XYZ(0.5, 0.5, 0.5), true)
+	assertContains(t, merged.S3, model3d.XYZ(2.5, 0.5, 0.5), true)
+	if len(merged.Parts) != 2 {
+		t.Fatalf("expected 2 parts, got %d", len(merged.Parts))
+	}
+}
+
+func TestTagInheritance(t *testing.T) {
+	// Inner colors take precedence over outer ones.
+	objects := mustEvalObjects(t, `color("blue") { color("red") cube(1); sphere(1); }`)
+	if len(objects) != 2 || objects[0].Tags.Color[0] != 1 || objects[1].Tags.Color[2] != 1 {
+		t.Fatalf("unexpected objects %+v", objects)
+	}
+
+	// CSG operations take the tags of their first child.
+	objects = mustEvalObjects(t, `
+		difference() {
+			color("green") cube(2);
+			color("red") cube(1);
+		}
+	`)
+	if len(objects) != 1 || objects[0].Tags.Color != [4]float64{0, 128.0 / 255, 0, 1} {
+		t.Fatalf("unexpected objects %+v", objects)
+	}
+
+	// Transforms and deformations keep the parts of their children, and
+	// resize() scales every part by the bounds of the whole child.
+	for _, src := range []string{
+		`resize([8, 4, 2]) { color("red") cube(1); color("blue") translate([1, 0, 0]) cube(1); }`,
+		`twist(10) { color("red") cube(1); color("blue") translate([1, 0, 0]) cube(1); }`,
+		`taper(scale_to=0.5) { color("red") cube(1); color("blue") translate([1, 0, 0]) cube(1); }`,
+		`clip(max_z=0.5) { color("red") cube(1); color("blue") translate([1, 0, 0]) cube(1); }`,
+		`transform([-1, -1, -1], [3, 2, 2], function(p) p) { color("red") cube(1); color("blue") translate([1, 0, 0]) cube(1); }`,
+	} {
+		objects = mustEvalObjects(t, src)
+		if len(objects) != 2 || objects[0].Tags.Color[0] != 1 || objects[1].Tags.Color[2] != 1 {
+			t.Fatalf("%s: unexpected objects %+v", src, objects)
+		}
+	}
+	objects = mustEvalObjects(t, `
+		resize([8, 4, 2]) { color("red") cube(1); color("blue") translate([1, 0, 0]) cube(1); }
+	`)
+	assertContains(t, objects[0].S3, model3d.XYZ(3.9, 3.9, 1.9), true)
+	assertContains(t, objects[0].S3, model3d.XYZ(4.1, 2, 1), false)
+	assertContains(t, objects[1].S3, model3d.XYZ(4.1, 3.9, 1.9), true)
+	assertContains(t, objects[1].S3, model3d.XYZ(7.9, 0.1, 0.1), true)
+
+	// Blended unions cannot split their fillets between parts.
+	for _, src := range []string{
+		`union(fillet=0.2) { color("red") cube(1); color("blue") translate([1, 0, 0]) cube(1); }`,
+		`smooth_union(0.2) { color("red") sphere_sdf(1); color("blue") translate([1, 0, 0]) sphere_sdf(1); }`,
+	} {
+		prog, err := Parse(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := EvalObjects(prog, Hooks{}); err == nil || !strings.Contains(err.Error(), "apply them after blending") {
+			t.Fatalf("%s: unexpected error %v", src, err)
+		}
+	}
+
+	// Untagged scripts produce a single object.
+	objects = mustEvalObjects(t, `cube(1); sphere(1);`)
+	if len(objects) != 1 || objects[0].Tags != (ShapeTags{}) {
+		t.Fatalf("unexpected objects %+v", objects)
+	}
+}
+
+func TestPartMeshes(t *testing.T) {
+	objects := mustEvalObjects(t, `
+		part("left") marching_cubes(delta=0.1) cube(1);
+		part("right") translate([3, 0, 0]) marching_cubes(delta=0.1) cube(1);
+	`)
+	if len(objects) != 2 {
+		t.Fatalf("expected 2 objects, got %d", len(objects))
+	}
+	for _, obj := range objects {
+		if obj.Kind != ShapeMesh3D || math.Abs(obj.M3.Volume()-1) > 0.05 {
+			t.Fatalf("unexpected object %s", obj.Tags.Part)
+		}
+	}
+	if objects[1].Tags.Part != "right" || objects[1].M3.Min().X < 2.9 {
+		t.Fatalf("unexpected right object: %+v", objects[1].Tags)
+	}
+}
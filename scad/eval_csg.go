@@ -8,10 +8,19 @@ import (
 	shapekernel "github.com/unixpickle/webgpu-meshes/shapekernel"
 )
 
-func handleUnion(e *env, st *CallStmt, _ []ShapeRep, childUnion *ShapeRep) (ShapeRep, error) {
-	if _, err := bindArgs(e, st.Call, []ArgSpec{}); err != nil {
+func handleUnion(e *env, st *CallStmt, children []ShapeRep, childUnion *ShapeRep) (ShapeRep, error) {
+	args, err := bindArgs(e, st.Call, csgBlendArgs)
+	if err != nil {
 		return ShapeRep{}, err
 	}
+	if blend, ok, err := parseCSGBlend("union", args); err != nil {
+		return ShapeRep{}, err
+	} else if ok {
+		if err := checkBlendTags("union", children); err != nil {
+			return ShapeRep{}, err
+		}
+		return blendedCSG(e, smoothUnionOp.Named("union"), blend, args, children)
+	}
 	if childUnion == nil {
 		return ShapeRep{}, fmt.Errorf("union() requires children")
 	}
@@ -19,9 +28,17 @@ func handleUnion(e *env, st *CallStmt, _ []ShapeRep, childUnion *ShapeRep) (Shap
 }
 
 func handleDifference(e *env, st *CallStmt, children []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
-	if _, err := bindArgs(e, st.Call, []ArgSpec{}); err != nil {
+	args, err := bindArgs(e, st.Call, csgBlendArgs)
+	if err != nil {
 		return ShapeRep{}, err
 	}
+	if len(children) > 0 {
+		if blend, ok, err := parseCSGBlend("difference", args); err != nil {
+			return ShapeRep{}, err
+		} else if ok {
+			return blendedCSG(e, smoothDifferenceOp.Named("difference"), blend, args, children)
+		}
+	}
 	if len(children) == 0 {
 		return ShapeRep{}, fmt.Errorf("difference() had no solids")
 	}
@@ -72,16 +89,24 @@ func handleDifference(e *env, st *CallStmt, children []ShapeRep, _ *ShapeRep) (S
 			MB3:  children[0].MB3.Join(subUnion.MB3.Scale(-1)),
 		}, nil
 	case ShapeMesh2D, ShapeMesh3D:
-		return ShapeRep{}, fmt.Errorf("difference() not supported for meshes")
+		return meshBooleanAll("difference", children, meshDifference)
 	default:
 		return ShapeRep{}, fmt.Errorf("difference(): unknown shape kind")
 	}
 }
 
 func handleIntersection(e *env, st *CallStmt, children []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
-	if _, err := bindArgs(e, st.Call, []ArgSpec{}); err != nil {
+	args, err := bindArgs(e, st.Call, csgBlendArgs)
+	if err != nil {
 		return ShapeRep{}, err
 	}
+	if len(children) > 0 {
+		if blend, ok, err := parseCSGBlend("intersection", args); err != nil {
+			return ShapeRep{}, err
+		} else if ok {
+			return blendedCSG(e, smoothIntersectionOp.Named("intersection"), blend, args, children)
+		}
+	}
 	if len(children) == 0 {
 		return ShapeRep{}, fmt.Errorf("intersection() had no solids")
 	}
@@ -127,7 +152,7 @@ func handleIntersection(e *env, st *CallStmt, children []ShapeRep, _ *ShapeRep)
 		}
 		return shapeSDF2D(sdfIntersect2D(children), k), nil
 	case ShapeMesh2D, ShapeMesh3D:
-		return ShapeRep{}, fmt.Errorf("intersection() not supported for meshes")
+		return meshBooleanAll("intersection", children, meshIntersection)
 	default:
 		return ShapeRep{}, fmt.Errorf("intersection(): unknown shape kind")
 	}
@@ -0,0 +1,310 @@
+package scad
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unixpickle/model3d/model2d"
+	"github.com/unixpickle/model3d/model3d"
+)
+
+func boxMeshSource(min, max [3]float64) string {
+	return fmt.Sprintf(
+		"translate([%g, %g, %g]) linear_extrude(height=%g) polygon_mesh(points=[[0,0],[%g,0],[%g,%g],[0,%g]]);",
+		min[0], min[1], min[2], max[2]-min[2],
+		max[0]-min[0], max[0]-min[0], max[1]-min[1], max[1]-min[1],
+	)
+}
+
+func checkClosedMesh(t *testing.T, m *model3d.Mesh) {
+	t.Helper()
+	if m.NeedsRepair() {
+		t.Fatal("mesh is not watertight")
+	}
+	if n := len(m.InconsistentEdges()); n != 0 {
+		t.Fatalf("mesh has %d inconsistent edges", n)
+	}
+}
+
+func TestMeshBooleans(t *testing.T) {
+	a := boxMeshSource([3]float64{0, 0, 0}, [3]float64{2, 2, 2})
+	tests := []struct {
+		name   string
+		b      string
+		union  float64
+		inter  float64
+		differ float64
+	}{
+		// Edges of b pass exactly through the diagonals of a's faces.
+		{"corner", boxMeshSource([3]float64{1, 1, 1}, [3]float64{3, 3, 3}), 15, 1, 7},
+		{"shared face", boxMeshSource([3]float64{2, 0, 0}, [3]float64{3, 2, 2}), 12, 0, 8},
+		{"coplanar overlap", boxMeshSource([3]float64{1, 0, 0}, [3]float64{3, 2, 2}), 12, 4, 4},
+		{"inside", boxMeshSource([3]float64{0.5, 0.5, 0.5}, [3]float64{1.5, 1.5, 1.5}), 8, 1, 7},
+		{"disjoint", boxMeshSource([3]float64{5, 5, 5}, [3]float64{6, 6, 6}), 9, 0, 8},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, op := range []struct {
+				name   string
+				volume float64
+			}{
+				{"union", tc.union},
+				{"intersection", tc.inter},
+				{"difference", tc.differ},
+			} {
+				shape := mustEvalShape(t, op.name+"() { "+a+" "+tc.b+" }")
+				if shape.Kind != ShapeMesh3D {
+					t.Fatalf("%s: expected ShapeMesh3D, got %v", op.name, shape.Kind)
+				}
+				checkClosedMesh(t, shape.M3)
+				if v := shape.M3.Volume(); math.Abs(v-op.volume) > 1e-8 {
+					t.Fatalf("%s: expected volume %f, got %f", op.name, op.volume, v)
+				}
+			}
+		})
+	}
+}
+
+func TestMeshBooleanCurved(t *testing.T) {
+	a := `marching_cubes(delta=0.1) sphere(r=1);`
+	b := `translate([0.7, 0.3, 0.2]) marching_cubes(delta=0.13) sphere(r=0.8);`
+	meshA := mustEvalShape(t, a).M3
+	meshB := mustEvalShape(t, b).M3
+	solidA := model3d.NewColliderSolid(model3d.MeshToCollider(meshA))
+	solidB := model3d.NewColliderSolid(model3d.MeshToCollider(meshB))
+
+	for _, op := range []struct {
+		name     string
+		contains func(c model3d.Coord3D) bool
+	}{
+		{"union", func(c model3d.Coord3D) bool { return solidA.Contains(c) || solidB.Contains(c) }},
+		{"intersection", func(c model3d.Coord3D) bool { return solidA.Contains(c) && solidB.Contains(c) }},
+		{"difference", func(c model3d.Coord3D) bool { return solidA.Contains(c) && !solidB.Contains(c) }},
+	} {
+		shape := mustEvalShape(t, op.name+"() { "+a+" "+b+" }")
+		checkClosedMesh(t, shape.M3)
+		result := model3d.NewColliderSolid(model3d.MeshToCollider(shape.M3))
+		mismatches := 0
+		for x := -1.05; x < 1.6; x += 0.1 {
+			for y := -1.05; y < 1.2; y += 0.1 {
+				for z := -1.05; z < 1.1; z += 0.1 {
+					c := model3d.XYZ(x, y, z)
+					if result.Contains(c) != op.contains(c) {
+						mismatches++
+					}
+				}
+			}
+		}
+		if mismatches != 0 {
+			t.Fatalf("%s: %d points disagree with the input solids", op.name, mismatches)
+		}
+	}
+}
+
+func TestMeshBooleanNearDegenerate(t *testing.T) {
+	// Many sphere vertices land within rounding error of the box faces,
+	// so intersection points nearly coincide with each other.
+	a := boxMeshSource([3]float64{-1, -1, -1}, [3]float64{1, 1, 1})
+	b := `translate([0.3, 0, 0]) marching_cubes(delta=0.05) sphere(r=1.2);`
+	for _, op := range []string{"union", "intersection", "difference"} {
+		shape := mustEvalShape(t, op+"() { "+a+" "+b+" }")
+		checkClosedMesh(t, shape.M3)
+	}
+}
+
+func TestMeshBooleanSelfIntersections(t *testing.T) {
+	// Intersection points which nearly coincide with input vertices
+	// create slivers, which fold over their neighbors once rounded.
+	for _, pair := range [][2]string{
+		{
+			`marching_cubes(delta=0.1) sphere(r=1);`,
+			`translate([0.3, 0.2, 0.1]) marching_cubes(delta=0.1) sphere(r=1);`,
+		},
+		{
+			`marching_cubes(delta=0.05) sphere(r=1);`,
+			`translate([0.5, 0, 0]) marching_cubes(delta=0.05) sphere(r=1);`,
+		},
+		{
+			`marching_cubes(delta=0.03) sphere(r=1);`,
+			`translate([0.7, 0.3, 0.2]) marching_cubes(delta=0.04) sphere(r=0.8);`,
+		},
+	} {
+		for _, op := range []string{"union", "intersection", "difference"} {
+			shape := mustEvalShape(t, op+"() { "+pair[0]+" "+pair[1]+" }")
+			checkClosedMesh(t, shape.M3)
+			if n := shape.M3.SelfIntersections(); n != 0 {
+				t.Fatalf("%s of %s and %s: %d self-intersections", op, pair[0], pair[1], n)
+			}
+		}
+	}
+}
+
+func TestMeshBooleanCoplanarCaps(t *testing.T) {
+	// Bars rotated about a common axis share their cap planes, so every
+	// cap triangle is split along many crossing intersection segments.
+	for n := 3; n <= 5; n++ {
+		start := time.Now()
+		shape := mustEvalShape(t, fmt.Sprintf(
+			"for (i = [0:%d]) rotate([0, 0, i*180/%d]) linear_extrude(5) polygon_mesh(points=[[-10,-1],[10,-1],[10,1],[-10,1]]);",
+			n-1, n,
+		))
+		if elapsed := time.Since(start); elapsed > 20*time.Second {
+			t.Fatalf("union of %d bars took %v", n, elapsed)
+		}
+		checkClosedMesh(t, shape.M3)
+		if count := shape.M3.SelfIntersections(); count != 0 {
+			t.Fatalf("union of %d bars: %d self-intersections", n, count)
+		}
+	}
+}
+
+func TestMeshBooleans2D(t *testing.T) {
+	a := `polygon_mesh(points=[[0,0],[2,0],[2,2],[0,2]]);`
+	b := `translate([1, 1]) polygon_mesh(points=[[0,0],[2,0],[2,2],[0,2]]);`
+	for _, tc := range []struct {
+		op   string
+		area float64
+	}{
+		{"union", 7},
+		{"intersection", 1},
+		{"difference", 3},
+	} {
+		shape := mustEvalShape(t, tc.op+"() { "+a+" "+b+" }")
+		if shape.Kind != ShapeMesh2D {
+			t.Fatalf("%s: expected ShapeMesh2D, got %v", tc.op, shape.Kind)
+		}
+		if area := shape.M2.Area(); math.Abs(area-tc.area) > 1e-8 {
+			t.Fatalf("%s: expected area %f, got %f", tc.op, tc.area, area)
+		}
+	}
+}
+
+func TestMeshBooleanOpenMeshes(t *testing.T) {
+	closed := mustEvalShape(t, boxMeshSource([3]float64{0, 0, 0}, [3]float64{2, 2, 2}))
+	open := model3d.NewMesh()
+	open.Add(&model3d.Triangle{model3d.XYZ(1, 1, 1), model3d.XYZ(3, 1, 1), model3d.XYZ(1, 3, 1)})
+	children := []ShapeRep{closed, shapeMesh3D(open)}
+
+	_, err := meshBooleanAll("difference", children, meshDifference)
+	if err == nil || !strings.Contains(err.Error(), "must be closed") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Unions of open meshes keep every triangle.
+	union, err := meshBooleanAll("union", children, meshUnion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(union.M3.TriangleSlice()); n != len(closed.M3.TriangleSlice())+1 {
+		t.Fatalf("unexpected triangle count %d", n)
+	}
+}
+
+func TestCSGFilletChamfer(t *testing.T) {
+	// The concave corner of an L shape is filled with an arc centered at
+	// [3, 3].
+	shape := mustEvalShape(t, `
+		union(fillet=1) { square_sdf([2, 4]); square_sdf([4, 2]); }
+	`)
+	if shape.Kind != ShapeSDF2D || shape.Kernel == nil {
+		t.Fatalf("expected SDF2D with kernel, got %v", shape.Kind)
+	}
+	if d := shape.SDF2.SDF(model2d.XY(2.2, 2.2)); math.Abs(d-(math.Hypot(0.8, 0.8)-1)) > 1e-8 {
+		t.Fatalf("unexpected SDF %f in the fillet", d)
+	}
+	if d := shape.SDF2.SDF(model2d.XY(2.8, 2.8)); d >= 0 {
+		t.Fatalf("expected the fillet to be concave, got %f", d)
+	}
+	if max := shape.SDF2.Max(); max.Dist(model2d.XY(5, 5)) > 1e-8 {
+		t.Fatalf("unexpected bounds max %v", max)
+	}
+
+	// Corners between the two children are beveled, but corners of a
+	// single child are not.
+	shape = mustEvalShape(t, `
+		intersection(chamfer=1) { square_sdf(4); translate([1, 1]) square_sdf(4); }
+	`)
+	if d := shape.SDF2.SDF(model2d.XY(3.9, 1.1)); d >= 0 {
+		t.Fatalf("expected the corner to be beveled, got %f", d)
+	}
+	if d := shape.SDF2.SDF(model2d.XY(1.1, 1.1)); math.Abs(d-0.1) > 1e-8 {
+		t.Fatalf("expected a sharp corner, got %f", d)
+	}
+
+	// Solids go through an approximate SDF and come back as solids.
+	shape = mustEvalShape(t, `
+		difference(fillet=0.5) { cube(4); translate([2, 2, 2]) cube(4); }
+	`)
+	if shape.Kind != ShapeSolid3D || shape.Kernel == nil {
+		t.Fatalf("expected Solid3D with kernel, got %v", shape.Kind)
+	}
+	if shape.S3.Contains(model3d.XYZ(1.95, 3, 3.95)) {
+		t.Fatal("expected the edge of the cut to be rounded")
+	}
+	if !shape.S3.Contains(model3d.XYZ(1.5, 3, 3.5)) || !shape.S3.Contains(model3d.XYZ(3, 3, 1.9)) {
+		t.Fatal("expected the rest of the cut to be unchanged")
+	}
+}
+
+func TestCSGFilletDefaultDelta(t *testing.T) {
+	var deltas []float64
+	hooks := Hooks{
+		MarchingCubes: func(obj ShapeRep, delta float64, iters int) (*model3d.Mesh, error) {
+			deltas = append(deltas, delta)
+			return model3d.NewMeshRect(obj.S3.Min(), obj.S3.Max()), nil
+		},
+	}
+	for _, tc := range []struct {
+		src  string
+		want float64
+	}{
+		// Small solids are meshed at a quarter of the fillet size.
+		{`union(fillet=1) { cube(4); translate([2, 2, 2]) cube(4); }`, 0.25},
+		// Larger solids are meshed with a limited number of cells.
+		{`union(fillet=1.5) { cube(64); translate([32, 32, 32]) cube(64); }`, 0.5},
+		{`union(fillet=1, delta=0.1) { cube(64); translate([32, 32, 32]) cube(64); }`, 0.1},
+	} {
+		prog, err := Parse(tc.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		deltas = nil
+		if _, err := Eval(prog, hooks); err != nil {
+			t.Fatalf("%s: %v", tc.src, err)
+		}
+		for _, delta := range deltas {
+			if math.Abs(delta-tc.want) > 1e-8 {
+				t.Fatalf("%s: expected delta %f, got %f", tc.src, tc.want, delta)
+			}
+		}
+		if len(deltas) != 2 {
+			t.Fatalf("%s: expected 2 meshed children, got %d", tc.src, len(deltas))
+		}
+	}
+}
+
+func TestCSGFilletChamferErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`union(fillet=1, chamfer=1) { circle_sdf(r=1); square_sdf(1); }`, "cannot provide both"},
+		{`difference(chamfer=-1) { circle_sdf(r=1); square_sdf(1); }`, "must not be negative"},
+		{`intersection(fillet=1) { ` + boxMeshSource([3]float64{0, 0, 0}, [3]float64{1, 1, 1}) + ` }`, "require SDF or solid children"},
+		{`union(fillet=1) { circle_sdf(r=1); square(1); }`, "mixed shape kinds"},
+		{`union(fillet=0.1) { cube(200); translate([100, 100, 100]) cube(200); }`, "pass delta explicitly"},
+	}
+	for _, tc := range tests {
+		prog, err := Parse(tc.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = Eval(prog, Hooks{})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", tc.src, tc.want, err)
+		}
+	}
+}
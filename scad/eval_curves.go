@@ -0,0 +1,292 @@
+package scad
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/unixpickle/model3d/model3d"
+	"github.com/unixpickle/path2d"
+)
+
+// evalBezierPoints implements bezier_points(), which samples a Bezier
+// curve of any degree at n+1 evenly spaced parameters.
+func evalBezierPoints(e *env, c Call) (Value, error) {
+	args, err := bindArgs(e, c, []ArgSpec{
+		{Name: "ctrl", Pos: 0, Required: true},
+		{Name: "n", Pos: 1, Default: Num(16)},
+	})
+	if err != nil {
+		return Value{}, err
+	}
+	ctrl, dim, err := parseCurvePoints("bezier_points", "ctrl", args["ctrl"])
+	if err != nil {
+		return Value{}, err
+	}
+	if len(ctrl) < 2 {
+		return Value{}, fmt.Errorf("bezier_points(): ctrl needs at least 2 points")
+	}
+	n, err := argCurveSegments(args, "bezier_points", "n")
+	if err != nil {
+		return Value{}, err
+	}
+	res := make([]model3d.Coord3D, n+1)
+	scratch := make([]model3d.Coord3D, len(ctrl))
+	for i := range res {
+		// De Casteljau's algorithm.
+		t := float64(i) / float64(n)
+		copy(scratch, ctrl)
+		for k := len(scratch) - 1; k > 0; k-- {
+			for j := 0; j < k; j++ {
+				scratch[j] = scratch[j].Scale(1 - t).Add(scratch[j+1].Scale(t))
+			}
+		}
+		res[i] = scratch[0]
+	}
+	return curvePointsValue(res, dim), nil
+}
+
+// evalCatmullRom implements catmull_rom(), which interpolates a spline
+// through the points like sweep(spline=true).
+func evalCatmullRom(e *env, c Call) (Value, error) {
+	args, err := bindArgs(e, c, []ArgSpec{
+		{Name: "points", Pos: 0, Required: true},
+		{Name: "n", Pos: 1, Default: Num(8)},
+		{Name: "closed", Pos: 2, Default: Bool(false)},
+	})
+	if err != nil {
+		return Value{}, err
+	}
+	points, dim, err := parseCurvePoints("catmull_rom", "points", args["points"])
+	if err != nil {
+		return Value{}, err
+	}
+	if len(points) < 2 {
+		return Value{}, fmt.Errorf("catmull_rom(): points needs at least 2 points")
+	}
+	n, err := argCurveSegments(args, "catmull_rom", "n")
+	if err != nil {
+		return Value{}, err
+	}
+	closed, err := argBool(args, "closed")
+	if err != nil {
+		return Value{}, err
+	}
+	return curvePointsValue(catmullRomPoints(points, closed, n), dim), nil
+}
+
+// evalArcPoints implements arc_points(), which samples a circular arc
+// from angle a0 to a1, in degrees, counter-clockwise when a1 > a0.
+//
+// A full turn omits the last point, which would repeat the first.
+func evalArcPoints(e *env, c Call) (Value, error) {
+	args, err := bindArgs(e, c, []ArgSpec{
+		{Name: "center", Pos: 0, Default: List([]Value{Num(0), Num(0)})},
+		{Name: "r", Pos: 1, Default: Num(1)},
+		{Name: "a0", Pos: 2, Default: Num(0)},
+		{Name: "a1", Pos: 3, Default: Num(360)},
+		{Name: "n", Pos: 4, Default: Num(32)},
+	})
+	if err != nil {
+		return Value{}, err
+	}
+	center, dim, err := parseCurvePoint("arc_points", "center", args["center"])
+	if err != nil {
+		return Value{}, err
+	}
+	r, err := argNum(args, "r")
+	if err != nil {
+		return Value{}, err
+	}
+	if r <= 0 {
+		return Value{}, fmt.Errorf("arc_points(): r must be positive")
+	}
+	a0, err := argNum(args, "a0")
+	if err != nil {
+		return Value{}, err
+	}
+	a1, err := argNum(args, "a1")
+	if err != nil {
+		return Value{}, err
+	}
+	n, err := argCurveSegments(args, "arc_points", "n")
+	if err != nil {
+		return Value{}, err
+	}
+	count := n + 1
+	if math.Abs(a1-a0) >= 360 && math.Mod(math.Abs(a1-a0), 360) == 0 {
+		count = n
+	}
+	res := make([]model3d.Coord3D, count)
+	for i := range res {
+		theta := (a0 + (a1-a0)*float64(i)/float64(n)) * math.Pi / 180
+		res[i] = center.Add(model3d.XY(math.Cos(theta), math.Sin(theta)).Scale(r))
+	}
+	return curvePointsValue(res, dim), nil
+}
+
+// evalPathPoints implements path_points(), which samples one subpath of
+// an SVG path the same way as path().
+//
+// Straight lines are kept as single segments, and closed subpaths do not
+// repeat their first point.
+func evalPathPoints(e *env, c Call) (Value, error) {
+	args, err := bindArgs(e, c, []ArgSpec{
+		{Name: "path", Pos: 0, Required: true},
+		{Name: "segments", Pos: 1, Default: Num(32)},
+		{Name: "subpath", Pos: 2, Default: Num(0)},
+	})
+	if err != nil {
+		return Value{}, err
+	}
+	path, err := argString(args, "path")
+	if err != nil {
+		return Value{}, err
+	}
+	segments, err := argCurveSegments(args, "path_points", "segments")
+	if err != nil {
+		return Value{}, err
+	}
+	subpath, err := argNum(args, "subpath")
+	if err != nil {
+		return Value{}, err
+	}
+	curves, err := path2d.ParseSVGPath(path)
+	if err != nil {
+		return Value{}, fmt.Errorf("path_points(): %w", err)
+	}
+	if subpath != math.Floor(subpath) || subpath < 0 || int(subpath) >= len(curves) {
+		return Value{}, fmt.Errorf("path_points(): subpath must be an index below %d", len(curves))
+	}
+	points2D := svgCurvePoints(curves[int(subpath)], segments)
+	if len(points2D) > 2 && points2D[0] == points2D[len(points2D)-1] {
+		points2D = points2D[:len(points2D)-1]
+	}
+	points := make([]model3d.Coord3D, len(points2D))
+	for i, p := range points2D {
+		points[i] = model3d.XY(p.X, p.Y)
+	}
+	return curvePointsValue(points, 2), nil
+}
+
+// evalResamplePath implements resample_path(), which places points at
+// equal distances along a polyline, keeping its endpoints. The spacing is
+// adjusted slightly so that the path divides evenly.
+//
+// Closed paths include the segment back to the first point, which is not
+// repeated at the end.
+func evalResamplePath(e *env, c Call) (Value, error) {
+	args, err := bindArgs(e, c, []ArgSpec{
+		{Name: "points", Pos: 0, Required: true},
+		{Name: "spacing", Pos: 1, Required: true},
+		{Name: "closed", Pos: 2, Default: Bool(false)},
+	})
+	if err != nil {
+		return Value{}, err
+	}
+	points, dim, err := parseCurvePoints("resample_path", "points", args["points"])
+	if err != nil {
+		return Value{}, err
+	}
+	spacing, err := argNum(args, "spacing")
+	if err != nil {
+		return Value{}, err
+	}
+	if spacing <= 0 {
+		return Value{}, fmt.Errorf("resample_path(): spacing must be positive")
+	}
+	closed, err := argBool(args, "closed")
+	if err != nil {
+		return Value{}, err
+	}
+	if closed && len(points) > 0 {
+		points = append(points, points[0])
+	}
+	lengths := make([]float64, len(points))
+	for i := 1; i < len(points); i++ {
+		lengths[i] = lengths[i-1] + points[i].Dist(points[i-1])
+	}
+	if len(points) < 2 || lengths[len(lengths)-1] == 0 {
+		return Value{}, fmt.Errorf("resample_path(): points must have a non-zero length")
+	}
+	total := lengths[len(lengths)-1]
+	n := int(math.Max(1, math.Round(total/spacing)))
+
+	res := make([]model3d.Coord3D, 0, n+1)
+	seg := 1
+	for i := 0; i <= n; i++ {
+		if i == n {
+			if !closed {
+				res = append(res, points[len(points)-1])
+			}
+			break
+		}
+		s := total * float64(i) / float64(n)
+		for seg < len(points)-1 && lengths[seg] < s {
+			seg++
+		}
+		segLen := lengths[seg] - lengths[seg-1]
+		if segLen == 0 {
+			res = append(res, points[seg])
+			continue
+		}
+		t := (s - lengths[seg-1]) / segLen
+		res = append(res, points[seg-1].Scale(1-t).Add(points[seg].Scale(t)))
+	}
+	return curvePointsValue(res, dim), nil
+}
+
+// parseCurvePoints parses a list of points which are either all 2D or
+// all 3D, returning the dimension along with the points.
+func parseCurvePoints(opName, argName string, val Value) ([]model3d.Coord3D, int, error) {
+	if val.Kind != ValList {
+		return nil, 0, fmt.Errorf("%s(): %s must be a list of points", opName, argName)
+	}
+	var dim int
+	points := make([]model3d.Coord3D, 0, len(val.List))
+	for _, v := range val.List {
+		p, pDim, err := parseCurvePoint(opName, argName, v)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s(): %s must be a list of [x, y] or [x, y, z] points", opName, argName)
+		}
+		if dim != 0 && pDim != dim {
+			return nil, 0, fmt.Errorf("%s(): %s must not mix 2D and 3D points", opName, argName)
+		}
+		dim = pDim
+		points = append(points, p)
+	}
+	return points, dim, nil
+}
+
+func parseCurvePoint(opName, argName string, val Value) (model3d.Coord3D, int, error) {
+	if val.Kind != ValList || (len(val.List) != 2 && len(val.List) != 3) {
+		return model3d.Coord3D{}, 0, fmt.Errorf("%s(): %s must be an [x, y] or [x, y, z] point", opName, argName)
+	}
+	xyz, err := val.AsVec3()
+	if err != nil {
+		return model3d.Coord3D{}, 0, fmt.Errorf("%s(): %s: %w", opName, argName, err)
+	}
+	return model3d.NewCoord3DArray(xyz), len(val.List), nil
+}
+
+func curvePointsValue(points []model3d.Coord3D, dim int) Value {
+	res := make([]Value, len(points))
+	for i, p := range points {
+		if dim == 2 {
+			res[i] = List([]Value{Num(p.X), Num(p.Y)})
+		} else {
+			res[i] = List([]Value{Num(p.X), Num(p.Y), Num(p.Z)})
+		}
+	}
+	return List(res)
+}
+
+func argCurveSegments(args map[string]Value, opName, argName string) (int, error) {
+	n, err := argNum(args, argName)
+	if err != nil {
+		return 0, err
+	}
+	if n < 1 || n != math.Floor(n) {
+		return 0, fmt.Errorf("%s(): %s must be a positive integer", opName, argName)
+	}
+	return int(n), nil
+}
@@ -0,0 +1,139 @@
+package scad
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/model3d/model2d"
+	"github.com/unixpickle/model3d/model3d"
+)
+
+func TestCurveFunctions(t *testing.T) {
+	evalPoints := func(src string) []model3d.Coord3D {
+		prog, err := Parse("out = " + src + ";")
+		if err != nil {
+			t.Fatalf("parse failed: %v", err)
+		}
+		e := newEnv(Hooks{})
+		if _, err := evalStmts(e, prog.Stmts); err != nil {
+			t.Fatalf("%s: eval failed: %v", src, err)
+		}
+		out, _ := e.get("out")
+		var res []model3d.Coord3D
+		for _, v := range out.List {
+			xyz, err := v.AsVec3()
+			if err != nil {
+				t.Fatal(err)
+			}
+			res = append(res, model3d.NewCoord3DArray(xyz))
+		}
+		return res
+	}
+	assertPoints := func(src string, expected []model3d.Coord3D) {
+		actual := evalPoints(src)
+		if len(actual) != len(expected) {
+			t.Fatalf("%s: expected %d points, got %d", src, len(expected), len(actual))
+		}
+		for i, p := range expected {
+			if p.Dist(actual[i]) > 1e-8 {
+				t.Fatalf("%s: point %d: expected %v, got %v", src, i, p, actual[i])
+			}
+		}
+	}
+
+	// A quadratic Bezier curve matches model2d's implementation.
+	curve := model2d.BezierCurve{model2d.XY(0, 0), model2d.XY(5, 10), model2d.XY(10, 0)}
+	var expected []model3d.Coord3D
+	for i := 0; i <= 4; i++ {
+		p := curve.Eval(float64(i) / 4)
+		expected = append(expected, model3d.XY(p.X, p.Y))
+	}
+	assertPoints(`bezier_points([[0, 0], [5, 10], [10, 0]], 4)`, expected)
+	assertPoints(`bezier_points([[0, 0, 0], [1, 2, 3]], n=2)`, []model3d.Coord3D{
+		model3d.XYZ(0, 0, 0), model3d.XYZ(0.5, 1, 1.5), model3d.XYZ(1, 2, 3),
+	})
+
+	// Catmull-Rom splines pass through the points.
+	spline := evalPoints(`catmull_rom([[0, 0], [10, 0], [10, 10]], 4)`)
+	if len(spline) != 9 || spline[4].Dist(model3d.XY(10, 0)) > 1e-8 ||
+		spline[8].Dist(model3d.XY(10, 10)) > 1e-8 {
+		t.Fatalf("unexpected spline %v", spline)
+	}
+	if closed := evalPoints(`catmull_rom([[0, 0], [10, 0], [10, 10]], 4, closed=true)`); len(closed) != 12 {
+		t.Fatalf("expected 12 points for a closed spline, got %d", len(closed))
+	}
+
+	assertPoints(`arc_points([1, 2], 2, 0, 180, 2)`, []model3d.Coord3D{
+		model3d.XY(3, 2), model3d.XY(1, 4), model3d.XY(-1, 2),
+	})
+	assertPoints(`arc_points(r=1, n=4)`, []model3d.Coord3D{
+		model3d.XY(1, 0), model3d.XY(0, 1), model3d.XY(-1, 0), model3d.XY(0, -1),
+	})
+	assertPoints(`arc_points([0, 0, 5], 1, 90, 0, 1)`, []model3d.Coord3D{
+		model3d.XYZ(0, 1, 5), model3d.XYZ(1, 0, 5),
+	})
+
+	// Straight lines are not subdivided, and closed subpaths do not repeat
+	// their first point.
+	assertPoints(`path_points("M0 0 L4 0 L4 4 Z M10 10 Q 15 20 20 10", segments=2, subpath=0)`, []model3d.Coord3D{
+		model3d.XY(0, 0), model3d.XY(4, 0), model3d.XY(4, 4),
+	})
+	assertPoints(`path_points("M0 0 L4 0 L4 4 Z M10 10 Q 15 20 20 10", 2, 1)`, []model3d.Coord3D{
+		model3d.XY(10, 10), model3d.XY(15, 15), model3d.XY(20, 10),
+	})
+
+	assertPoints(`resample_path([[0, 0], [3, 0], [3, 1]], 1)`, []model3d.Coord3D{
+		model3d.XY(0, 0), model3d.XY(1, 0), model3d.XY(2, 0), model3d.XY(3, 0), model3d.XY(3, 1),
+	})
+	assertPoints(`resample_path([[0, 0, 0], [0, 0, 10]], 3)`, []model3d.Coord3D{
+		model3d.XYZ(0, 0, 0), model3d.XYZ(0, 0, 10.0/3), model3d.XYZ(0, 0, 20.0/3), model3d.XYZ(0, 0, 10),
+	})
+	assertPoints(`resample_path([[0, 0], [2, 0], [2, 2], [0, 2]], 2, closed=true)`, []model3d.Coord3D{
+		model3d.XY(0, 0), model3d.XY(2, 0), model3d.XY(2, 2), model3d.XY(0, 2),
+	})
+
+	// The points work directly as polygon outlines and line_join() paths,
+	// and 3D points work as sweep() paths.
+	shape := mustEvalShape(t, `polygon(arc_points(r=10, n=64));`)
+	if !shape.S2.Contains(model2d.XY(9.5, 0)) || shape.S2.Contains(model2d.XY(10.5, 0)) {
+		t.Fatal("unexpected polygon from arc_points()")
+	}
+	tube := mustEvalShape(t, `line_join(path_points("M0 0 Q 5 10 10 0", 8), r=0.5);`)
+	if !tube.S3.Contains(model3d.XYZ(5, 5, 0)) || tube.S3.Contains(model3d.XYZ(5, 5, 1)) {
+		t.Fatal("unexpected line_join() of path_points()")
+	}
+	swept := mustEvalShape(t, `sweep(arc_points([0, 0, 0], 10, a1=90, n=16)) square(1, center=true);`)
+	mid := 10 * math.Sqrt2 / 2
+	if !swept.S3.Contains(model3d.XYZ(mid, mid, 0)) || swept.S3.Contains(model3d.XYZ(mid, mid, 1)) {
+		t.Fatal("unexpected sweep() along arc_points()")
+	}
+}
+
+func TestCurveFunctionErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`x = bezier_points([[0, 0]]);`, "ctrl needs at least 2 points"},
+		{`x = bezier_points([[0, 0], [1, 1, 1]]);`, "must not mix 2D and 3D points"},
+		{`x = bezier_points([[0, 0], [1, 1]], 0);`, "n must be a positive integer"},
+		{`x = catmull_rom(5);`, "points must be a list of points"},
+		{`x = arc_points(r=0);`, "r must be positive"},
+		{`x = arc_points(5);`, "center must be an [x, y] or [x, y, z] point"},
+		{`x = path_points("M0 0 L1 1", subpath=1);`, "subpath must be an index below 1"},
+		{`x = path_points("L1 1");`, "path_points(): "},
+		{`x = resample_path([[0, 0], [1, 0]], 0);`, "spacing must be positive"},
+		{`x = resample_path([[1, 1], [1, 1]], 1);`, "must have a non-zero length"},
+	}
+	for _, tc := range tests {
+		prog, err := Parse(tc.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = Eval(prog, Hooks{})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", tc.src, tc.want, err)
+		}
+	}
+}
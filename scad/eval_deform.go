@@ -0,0 +1,325 @@
+package scad
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/unixpickle/model3d/model3d"
+	shapekernel "github.com/unixpickle/webgpu-meshes/shapekernel"
+)
+
+// A deformAxes names the axis a deformer acts along (U) and the two
+// axes perpendicular to it (V and W), in right-handed order.
+type deformAxes struct {
+	U int
+	V int
+	W int
+}
+
+func parseDeformAxis(opName string, args map[string]Value) (deformAxes, error) {
+	name, err := argString(args, "axis")
+	if err != nil {
+		return deformAxes{}, fmt.Errorf("%s(): axis must be \"x\", \"y\" or \"z\"", opName)
+	}
+	u := strings.Index("xyz", strings.ToLower(name))
+	if len(name) != 1 || u == -1 {
+		return deformAxes{}, fmt.Errorf("%s(): axis must be \"x\", \"y\" or \"z\"", opName)
+	}
+	return deformAxes{U: u, V: (u + 1) % 3, W: (u + 2) % 3}, nil
+}
+
+// KernelArgs returns the swizzle names of the axes for kernel code.
+func (d deformAxes) KernelArgs() []any {
+	return []any{"U", string("xyz"[d.U]), "V", string("xyz"[d.V]), "W", string("xyz"[d.W])}
+}
+
+// perpRadius computes the largest distance from the axis to a corner of
+// a bounding box.
+func (d deformAxes) perpRadius(min, max [3]float64) float64 {
+	var r float64
+	for _, v := range []float64{min[d.V], max[d.V]} {
+		for _, w := range []float64{min[d.W], max[d.W]} {
+			r = math.Max(r, math.Hypot(v, w))
+		}
+	}
+	return r
+}
+
+// shearLipschitz bounds the stretch of a map whose Jacobian is diagonal
+// with entries of at most diag, plus a single column of norm shear.
+func shearLipschitz(diag, shear float64) float64 {
+	return math.Max(1, diag) + shear
+}
+
+func handleTwist(e *env, st *CallStmt, _ []ShapeRep, childUnion *ShapeRep) (ShapeRep, error) {
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "angle_per_unit", Pos: 0, Required: true},
+		{Name: "axis", Pos: 1, Default: String("z")},
+	})
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	rate, err := argNum(args, "angle_per_unit")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	axes, err := parseDeformAxis("twist", args)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	rate *= math.Pi / 180
+
+	d := &domainMap{
+		OpName: "twist",
+		Map: func(c [3]float64) [3]float64 {
+			theta := -rate * c[axes.U]
+			cos, sin := math.Cos(theta), math.Sin(theta)
+			v, w := c[axes.V], c[axes.W]
+			c[axes.V] = cos*v - sin*w
+			c[axes.W] = sin*v + cos*w
+			return c
+		},
+		Bounds: func(min, max [3]float64) ([3]float64, [3]float64) {
+			r := axes.perpRadius(min, max)
+			min[axes.V], min[axes.W] = -r, -r
+			max[axes.V], max[axes.W] = r, r
+			return min, max
+		},
+		KernelCode: `
+			let theta = -{{.Rate}} * p.{{.U}};
+			let c = cos(theta);
+			let s = sin(theta);
+			var q = p;
+			q.{{.V}} = c * p.{{.V}} - s * p.{{.W}};
+			q.{{.W}} = s * p.{{.V}} + c * p.{{.W}};
+		`,
+		KernelArgs: append(axes.KernelArgs(), "Rate", float32(rate)),
+	}
+	return applyDeform(e.hooks.Numerics, childUnion, d, func(min, max [3]float64) float64 {
+		return shearLipschitz(1, math.Abs(rate)*axes.perpRadius(min, max))
+	})
+}
+
+func handleBend(e *env, st *CallStmt, _ []ShapeRep, childUnion *ShapeRep) (ShapeRep, error) {
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "radius", Pos: 0, Required: true},
+		{Name: "axis", Pos: 1, Default: String("z")},
+	})
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	radius, err := argNum(args, "radius")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	if radius == 0 {
+		return ShapeRep{}, fmt.Errorf("bend(): radius must be non-zero")
+	}
+	axes, err := parseDeformAxis("bend", args)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	if childUnion.Kind != ShapeSolid3D && childUnion.Kind != ShapeSDF3D {
+		return ShapeRep{}, fmt.Errorf("bend(): requires a 3D solid or SDF")
+	}
+
+	// The child runs along U and is wrapped around a center at radius
+	// along V. The bend is computed for a positive radius, flipping the
+	// direction toward the center for a negative one.
+	sign := 1.0
+	if radius < 0 {
+		sign = -1
+	}
+	r := math.Abs(radius)
+
+	var childMin, childMax [3]float64
+	if childUnion.Kind == ShapeSolid3D {
+		childMin, childMax = childUnion.S3.Min().Array(), childUnion.S3.Max().Array()
+	} else {
+		childMin, childMax = childUnion.SDF3.Min().Array(), childUnion.SDF3.Max().Array()
+	}
+	inner := r - math.Max(sign*childMin[axes.V], sign*childMax[axes.V])
+	if inner <= 0 {
+		return ShapeRep{}, fmt.Errorf("bend(): child extends past the center of the bend")
+	}
+
+	d := &domainMap{
+		OpName: "bend",
+		Map: func(c [3]float64) [3]float64 {
+			dx := c[axes.U]
+			dy := r - sign*c[axes.V]
+			c[axes.U] = r * math.Atan2(dx, dy)
+			c[axes.V] = sign * (r - math.Hypot(dx, dy))
+			return c
+		},
+		Bounds: func(min, max [3]float64) ([3]float64, [3]float64) {
+			return bendBounds(axes, sign, r, min, max)
+		},
+		KernelCode: `
+			let dx = p.{{.U}};
+			let dy = {{.Radius}} - {{.Sign}} * p.{{.V}};
+			var q = p;
+			q.{{.U}} = {{.Radius}} * atan2(dx, dy);
+			q.{{.V}} = {{.Sign}} * ({{.Radius}} - length(vec2f(dx, dy)));
+		`,
+		KernelArgs: append(axes.KernelArgs(), "Radius", float32(r), "Sign", float32(sign)),
+	}
+	return applyDeform(e.hooks.Numerics, childUnion, d, func(min, max [3]float64) float64 {
+		// Arcs closer to the center than the radius are compressed, so
+		// the inverse map stretches them by the ratio of the radii.
+		return r / inner
+	})
+}
+
+// bendBounds computes the bounds of the annular sector that a bent box
+// occupies.
+func bendBounds(axes deformAxes, sign, r float64, min, max [3]float64) ([3]float64, [3]float64) {
+	theta0 := math.Max(-math.Pi, min[axes.U]/r)
+	theta1 := math.Min(math.Pi, max[axes.U]/r)
+	thetas := []float64{theta0, theta1}
+	for k := -2.0; k <= 2; k++ {
+		if theta := k * math.Pi / 2; theta > theta0 && theta < theta1 {
+			thetas = append(thetas, theta)
+		}
+	}
+	radii := []float64{r - sign*min[axes.V], r - sign*max[axes.V]}
+
+	newMin, newMax := min, max
+	for i, theta := range thetas {
+		for j, rad := range radii {
+			u := rad * math.Sin(theta)
+			v := sign * (r - rad*math.Cos(theta))
+			if i == 0 && j == 0 {
+				newMin[axes.U], newMax[axes.U] = u, u
+				newMin[axes.V], newMax[axes.V] = v, v
+			} else {
+				newMin[axes.U], newMax[axes.U] = math.Min(newMin[axes.U], u), math.Max(newMax[axes.U], u)
+				newMin[axes.V], newMax[axes.V] = math.Min(newMin[axes.V], v), math.Max(newMax[axes.V], v)
+			}
+		}
+	}
+	return newMin, newMax
+}
+
+func handleTaper(e *env, st *CallStmt, _ []ShapeRep, childUnion *ShapeRep) (ShapeRep, error) {
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "scale_from", Pos: 0, Default: Num(1)},
+		{Name: "scale_to", Pos: 1, Required: true},
+		{Name: "axis", Pos: 2, Default: String("z")},
+	})
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	from, err := argNum(args, "scale_from")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	to, err := argNum(args, "scale_to")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	if from <= 0 || to <= 0 {
+		return ShapeRep{}, fmt.Errorf("taper(): scales must be positive")
+	}
+	axes, err := parseDeformAxis("taper", args)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	if childUnion.Kind != ShapeSolid3D && childUnion.Kind != ShapeSDF3D {
+		return ShapeRep{}, fmt.Errorf("taper(): requires a 3D solid or SDF")
+	}
+
+	// The scale changes linearly from the bottom to the top of the
+	// child's bounding box along the axis.
+	var t0, t1 float64
+	if childUnion.Kind == ShapeSolid3D {
+		t0, t1 = childUnion.S3.Min().Array()[axes.U], childUnion.S3.Max().Array()[axes.U]
+	} else {
+		t0, t1 = childUnion.SDF3.Min().Array()[axes.U], childUnion.SDF3.Max().Array()[axes.U]
+	}
+	var slope float64
+	if t1 > t0 {
+		slope = (to - from) / (t1 - t0)
+	}
+	scaleAt := func(t float64) float64 {
+		return from + slope*(math.Max(t0, math.Min(t1, t))-t0)
+	}
+	minScale := math.Min(from, to)
+
+	d := &domainMap{
+		OpName: "taper",
+		Map: func(c [3]float64) [3]float64 {
+			s := scaleAt(c[axes.U])
+			c[axes.V] /= s
+			c[axes.W] /= s
+			return c
+		},
+		Bounds: func(min, max [3]float64) ([3]float64, [3]float64) {
+			for _, axis := range []int{axes.V, axes.W} {
+				lo, hi := min[axis], max[axis]
+				min[axis] = math.Min(lo*from, lo*to)
+				max[axis] = math.Max(hi*from, hi*to)
+			}
+			return min, max
+		},
+		KernelCode: `
+			let s = {{.From}} + {{.Slope}} * (clamp(p.{{.U}}, {{.T0}}, {{.T1}}) - {{.T0}});
+			var q = p / s;
+			q.{{.U}} = p.{{.U}};
+		`,
+		KernelArgs: append(
+			axes.KernelArgs(),
+			"From", float32(from),
+			"Slope", float32(slope),
+			"T0", float32(t0),
+			"T1", float32(t1),
+		),
+	}
+	return applyDeform(e.hooks.Numerics, childUnion, d, func(min, max [3]float64) float64 {
+		shear := axes.perpRadius(min, max) * math.Abs(slope) / (minScale * minScale)
+		return shearLipschitz(1/minScale, shear)
+	})
+}
+
+// applyDeform applies a domain map to a 3D solid or SDF, computing the
+// map's Lipschitz bound from the bounds of the result.
+//
+// Tagged parts of the child are deformed separately with the same map, so
+// that they survive the operation.
+func applyDeform(
+	n shapekernel.Numerics,
+	childUnion *ShapeRep,
+	d *domainMap,
+	lipschitz func(min, max [3]float64) float64,
+) (ShapeRep, error) {
+	if len(childUnion.Parts) > 0 {
+		return mapTaggedParts(n, *childUnion, func(part ShapeRep) (ShapeRep, error) {
+			partMap := *d
+			return applyDeform(n, &part, &partMap, lipschitz)
+		})
+	}
+	switch childUnion.Kind {
+	case ShapeSolid3D:
+		child := childUnion.S3
+		min, max := d.Bounds(child.Min().Array(), child.Max().Array())
+		solid := model3d.CheckedFuncSolid(
+			model3d.NewCoord3DArray(min),
+			model3d.NewCoord3DArray(max),
+			func(c model3d.Coord3D) bool {
+				return child.Contains(model3d.NewCoord3DArray(d.Map(c.Array())))
+			},
+		)
+		var k *shapekernel.ShapeKernel
+		if childUnion.Kernel != nil {
+			k = asPtr(domainMapKernel(n, *childUnion.Kernel, d))
+		}
+		return shapeSolid3D(solid, k), nil
+	case ShapeSDF3D:
+		min, max := d.Bounds(childUnion.SDF3.Min().Array(), childUnion.SDF3.Max().Array())
+		d.Lipschitz = lipschitz(min, max)
+		return applyDomainMap(n, childUnion, d)
+	default:
+		return ShapeRep{}, fmt.Errorf("%s(): requires a 3D solid or SDF", d.OpName)
+	}
+}
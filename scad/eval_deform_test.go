@@ -0,0 +1,113 @@
+package scad
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/model3d/model3d"
+)
+
+func TestTwist(t *testing.T) {
+	shape := mustEvalShape(t, `twist(90) translate([0, 0, 1]) cube([4, 1, 2], center=true);`)
+	if shape.Kind != ShapeSolid3D || shape.Kernel == nil {
+		t.Fatalf("expected Solid3D with kernel, got %v", shape.Kind)
+	}
+	if !strings.Contains(shape.Kernel.Code, "twist") {
+		t.Fatal("expected twist in kernel code")
+	}
+	// At z=1 the bar has turned by 90 degrees onto the Y axis.
+	if !shape.S3.Contains(model3d.XYZ(0, 1.8, 1)) || shape.S3.Contains(model3d.XYZ(1.8, 0, 1)) {
+		t.Fatal("unexpected twisted cross-section at z=1")
+	}
+	if !shape.S3.Contains(model3d.XYZ(1.8, 0, 0.01)) {
+		t.Fatal("expected untwisted cross-section near z=0")
+	}
+	r := math.Hypot(2, 0.5)
+	if max := shape.S3.Max(); max.Dist(model3d.XYZ(r, r, 2)) > 1e-8 {
+		t.Fatalf("unexpected bounds max %v", max)
+	}
+
+	shape = mustEvalShape(t, `twist(45, axis="x") sphere_sdf(r=1);`)
+	if shape.Kind != ShapeSDF3D {
+		t.Fatalf("expected SDF3D, got %v", shape.Kind)
+	}
+	// The SDF is scaled down to stay a lower bound on the true distance.
+	if d := shape.SDF3.SDF(model3d.XYZ(0, 0, 0)); d <= 0 || d > 1 {
+		t.Fatalf("unexpected center SDF %f", d)
+	}
+}
+
+func TestBend(t *testing.T) {
+	shape := mustEvalShape(t, `bend(10, axis="x") cube([10*PI, 1, 1], center=true);`)
+	// A bar with length equal to half the circumference becomes a half ring
+	// centered at [0, 10].
+	for _, c := range []model3d.Coord3D{
+		model3d.XYZ(0, 0, 0),
+		model3d.XYZ(10*math.Sin(1.5), 10-10*math.Cos(1.5), 0),
+		model3d.XYZ(-10*math.Sin(1.5), 10-10*math.Cos(1.5), 0),
+		model3d.XYZ(10*math.Sin(0.5), 10-10*math.Cos(0.5), 0),
+	} {
+		if !shape.S3.Contains(c) {
+			t.Fatalf("expected %v to be inside", c)
+		}
+	}
+	if shape.S3.Contains(model3d.XYZ(0, 10, 0)) || shape.S3.Contains(model3d.XYZ(0, 20, 0)) {
+		t.Fatal("expected the bend center and far side to be empty")
+	}
+	min, max := shape.S3.Min(), shape.S3.Max()
+	if min.Dist(model3d.XYZ(-10.5, -0.5, -0.5)) > 1e-8 || max.Dist(model3d.XYZ(10.5, 10, 0.5)) > 1e-8 {
+		t.Fatalf("unexpected bounds %v %v", min, max)
+	}
+
+	shape = mustEvalShape(t, `bend(-5) sphere_sdf(r=1);`)
+	if d := shape.SDF3.SDF(model3d.XYZ(0, 0, 0)); math.Abs(d-1/(5.0/4)) > 1e-8 {
+		t.Fatalf("unexpected center SDF %f", d)
+	}
+}
+
+func TestTaper(t *testing.T) {
+	shape := mustEvalShape(t, `taper(1, 0.5) cube([2, 2, 2], center=true);`)
+	if !shape.S3.Contains(model3d.XYZ(0.9, 0, -0.99)) || shape.S3.Contains(model3d.XYZ(0.6, 0, 0.99)) {
+		t.Fatal("unexpected tapered cross-sections")
+	}
+	if !shape.S3.Contains(model3d.XYZ(0.7, 0.7, 0)) || shape.S3.Contains(model3d.XYZ(0.8, 0, 0)) {
+		t.Fatal("unexpected tapered cross-section at z=0")
+	}
+	min, max := shape.S3.Min(), shape.S3.Max()
+	if min.Dist(model3d.XYZ(-1, -1, -1)) > 1e-8 || max.Dist(model3d.XYZ(1, 1, 1)) > 1e-8 {
+		t.Fatalf("unexpected bounds %v %v", min, max)
+	}
+
+	shape = mustEvalShape(t, `taper(scale_from=1, scale_to=3, axis="y") sphere_sdf(r=1);`)
+	max = shape.SDF3.Max()
+	if max.Dist(model3d.XYZ(3, 1, 3)) > 1e-8 {
+		t.Fatalf("unexpected bounds max %v", max)
+	}
+	if d := shape.SDF3.SDF(model3d.XYZ(0, 0, 0)); d <= 0 || d > 1 {
+		t.Fatalf("unexpected center SDF %f", d)
+	}
+}
+
+func TestDeformErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`twist(10) square(1);`, "requires a 3D solid or SDF"},
+		{`twist(10, axis="q") cube(1);`, "axis must be"},
+		{`bend(0) cube(1);`, "non-zero"},
+		{`bend(1) translate([0, 2, 0]) cube(1);`, "past the center"},
+		{`taper(1, 0) cube(1);`, "must be positive"},
+	}
+	for _, tc := range tests {
+		prog, err := Parse(tc.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = Eval(prog, Hooks{})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", tc.src, tc.want, err)
+		}
+	}
+}
@@ -0,0 +1,375 @@
+package scad
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/unixpickle/model3d/model2d"
+	"github.com/unixpickle/model3d/model3d"
+	shapekernel "github.com/unixpickle/webgpu-meshes/shapekernel"
+)
+
+// A domainMap is an SDF modifier which maps each query point into the
+// coordinate space of the child, so the child is evaluated only once
+// per query no matter how many copies of it appear.
+type domainMap struct {
+	OpName string
+
+	// Map transforms a point, using the components up to the dimension
+	// of the shape.
+	Map func(c [3]float64) [3]float64
+
+	// Bounds maps the child's bounding box to the result's.
+	Bounds func(min, max [3]float64) ([3]float64, [3]float64)
+
+	// KernelCode computes a vector q from a float vector p, and
+	// KernelArgs are the template arguments it uses.
+	KernelCode string
+	KernelArgs []any
+
+	// Lipschitz, if greater than one, bounds how much Map can stretch
+	// distances. Distances from the child are divided by it so that the
+	// result is still a conservative SDF.
+	Lipschitz float64
+}
+
+// SDFScale returns the factor that child distances are divided by.
+func (d *domainMap) SDFScale() float64 {
+	return math.Max(1, d.Lipschitz)
+}
+
+func handleRepeatSDF(e *env, st *CallStmt, _ []ShapeRep, childUnion *ShapeRep) (ShapeRep, error) {
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "spacing", Pos: 0, Required: true},
+		{Name: "count", Pos: 1, Required: true},
+	})
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	spacing, err := argVec3(args, "spacing")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	count, err := parseRepeatCount(args["count"])
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	dim := childUnion.Kind.Dimension()
+	var maxIdx [3]float64
+	for i := 0; i < 3; i++ {
+		if i >= dim || count[i] == 1 {
+			// The spacing is irrelevant for a single copy, but it must be
+			// non-zero to avoid dividing by zero.
+			spacing[i] = 1
+			continue
+		}
+		if spacing[i] <= 0 {
+			return ShapeRep{}, fmt.Errorf("repeat_sdf(): spacing must be positive along repeated axes")
+		}
+		maxIdx[i] = float64(count[i] - 1)
+	}
+	return applyDomainMap(e.hooks.Numerics, childUnion, &domainMap{
+		OpName: "repeat_sdf",
+		Map: func(c [3]float64) [3]float64 {
+			for i := 0; i < 3; i++ {
+				idx := math.Max(0, math.Min(maxIdx[i], math.Round(c[i]/spacing[i])))
+				c[i] -= idx * spacing[i]
+			}
+			return c
+		},
+		Bounds: func(min, max [3]float64) ([3]float64, [3]float64) {
+			for i := 0; i < 3; i++ {
+				max[i] += maxIdx[i] * spacing[i]
+			}
+			return min, max
+		},
+		KernelCode: `
+			let s = {{.Spacing}};
+			let q = p - s * clamp(round(p / s), {{.Zero}}, {{.MaxIdx}});
+		`,
+		KernelArgs: []any{
+			"Spacing", domainVecLiteral(dim, spacing),
+			"Zero", domainVecLiteral(dim, [3]float64{}),
+			"MaxIdx", domainVecLiteral(dim, maxIdx),
+		},
+	})
+}
+
+// parseRepeatCount reads a count per axis, treating axes which are
+// missing from a vector as a single copy.
+func parseRepeatCount(v Value) ([3]int, error) {
+	res := [3]int{1, 1, 1}
+	var values []Value
+	switch v.Kind {
+	case ValNum:
+		values = []Value{v, v, v}
+	case ValList:
+		values = v.List
+	default:
+		return res, fmt.Errorf("repeat_sdf(): count must be a number or vector")
+	}
+	for i := 0; i < 3 && i < len(values); i++ {
+		x, err := values[i].AsNum()
+		if err != nil || x < 1 || x != math.Floor(x) {
+			return res, fmt.Errorf("repeat_sdf(): count must contain positive integers")
+		}
+		res[i] = int(x)
+	}
+	return res, nil
+}
+
+func handlePolarRepeatSDF(e *env, st *CallStmt, _ []ShapeRep, childUnion *ShapeRep) (ShapeRep, error) {
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "n", Pos: 0, Required: true},
+	})
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	count, err := argNum(args, "n")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	if count < 1 || count != math.Floor(count) {
+		return ShapeRep{}, fmt.Errorf("polar_repeat_sdf(): n must be a positive integer")
+	}
+	sector := 2 * math.Pi / count
+	return applyDomainMap(e.hooks.Numerics, childUnion, &domainMap{
+		OpName: "polar_repeat_sdf",
+		Map: func(c [3]float64) [3]float64 {
+			theta := -math.Round(math.Atan2(c[1], c[0])/sector) * sector
+			cos, sin := math.Cos(theta), math.Sin(theta)
+			return [3]float64{cos*c[0] - sin*c[1], sin*c[0] + cos*c[1], c[2]}
+		},
+		Bounds: func(min, max [3]float64) ([3]float64, [3]float64) {
+			var r float64
+			for _, x := range []float64{min[0], max[0]} {
+				for _, y := range []float64{min[1], max[1]} {
+					r = math.Max(r, math.Hypot(x, y))
+				}
+			}
+			return [3]float64{-r, -r, min[2]}, [3]float64{r, r, max[2]}
+		},
+		KernelCode: `
+			let theta = -round(atan2(p.y, p.x) / {{.Sector}}) * {{.Sector}};
+			let c = cos(theta);
+			let s = sin(theta);
+			var q = p;
+			q.x = c * p.x - s * p.y;
+			q.y = s * p.x + c * p.y;
+		`,
+		KernelArgs: []any{"Sector", float32(sector)},
+	})
+}
+
+func handleSymmetrySDF(e *env, st *CallStmt, _ []ShapeRep, childUnion *ShapeRep) (ShapeRep, error) {
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "axes", Pos: 0, Required: true},
+	})
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	dim := childUnion.Kind.Dimension()
+	axes, err := parseSymmetryAxes(args["axes"], dim)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	var mask [3]float64
+	for i, a := range axes {
+		if a {
+			mask[i] = 1
+		}
+	}
+	return applyDomainMap(e.hooks.Numerics, childUnion, &domainMap{
+		OpName: "symmetry_sdf",
+		Map: func(c [3]float64) [3]float64 {
+			for i, a := range axes {
+				if a {
+					c[i] = math.Abs(c[i])
+				}
+			}
+			return c
+		},
+		Bounds: func(min, max [3]float64) ([3]float64, [3]float64) {
+			// Only the positive half of the child is kept and mirrored.
+			for i, a := range axes {
+				if a {
+					max[i] = math.Max(max[i], 0)
+					min[i] = -max[i]
+				}
+			}
+			return min, max
+		},
+		KernelCode: `
+			let q = mix(p, abs(p), {{.Mask}});
+		`,
+		KernelArgs: []any{"Mask", domainVecLiteral(dim, mask)},
+	})
+}
+
+// parseSymmetryAxes reads either a string of axis names, like "xz", or
+// a vector of flags, like [1, 0, 1].
+func parseSymmetryAxes(v Value, dim int) ([3]bool, error) {
+	var res [3]bool
+	switch v.Kind {
+	case ValString:
+		for _, ch := range strings.ToLower(v.Str) {
+			idx := strings.IndexRune("xyz", ch)
+			if idx == -1 || idx >= dim {
+				return res, fmt.Errorf("symmetry_sdf(): unknown axis %q", ch)
+			}
+			res[idx] = true
+		}
+	case ValList:
+		for i, x := range v.List {
+			if i >= 3 {
+				break
+			}
+			var flag bool
+			if x.Kind == ValBool {
+				flag = x.Bool
+			} else if n, err := x.AsNum(); err == nil {
+				flag = n != 0
+			} else {
+				return res, fmt.Errorf("symmetry_sdf(): axes must contain booleans or numbers")
+			}
+			if flag && i >= dim {
+				return res, fmt.Errorf("symmetry_sdf(): axis %d not supported for 2D shapes", i)
+			}
+			res[i] = flag
+		}
+	default:
+		return res, fmt.Errorf("symmetry_sdf(): axes must be a string or vector")
+	}
+	return res, nil
+}
+
+func handleElongateSDF(e *env, st *CallStmt, _ []ShapeRep, childUnion *ShapeRep) (ShapeRep, error) {
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "h", Pos: 0, Required: true},
+	})
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	h, err := argVec3(args, "h")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	dim := childUnion.Kind.Dimension()
+	for i := 0; i < 3; i++ {
+		if i >= dim {
+			h[i] = 0
+		} else if h[i] < 0 {
+			return ShapeRep{}, fmt.Errorf("elongate_sdf(): h must be non-negative")
+		}
+	}
+	return applyDomainMap(e.hooks.Numerics, childUnion, &domainMap{
+		OpName: "elongate_sdf",
+		Map: func(c [3]float64) [3]float64 {
+			for i := 0; i < 3; i++ {
+				c[i] -= math.Max(-h[i], math.Min(h[i], c[i]))
+			}
+			return c
+		},
+		Bounds: func(min, max [3]float64) ([3]float64, [3]float64) {
+			// Each half of the child moves away from the origin, and the
+			// cross section at zero is stretched between them.
+			for i := 0; i < 3; i++ {
+				if min[i] <= 0 {
+					min[i] -= h[i]
+				} else {
+					min[i] += h[i]
+				}
+				if max[i] >= 0 {
+					max[i] += h[i]
+				} else {
+					max[i] -= h[i]
+				}
+			}
+			return min, max
+		},
+		KernelCode: `
+			let h = {{.H}};
+			let q = p - clamp(p, -h, h);
+		`,
+		KernelArgs: []any{"H", domainVecLiteral(dim, h)},
+	})
+}
+
+func applyDomainMap(n shapekernel.Numerics, childUnion *ShapeRep, d *domainMap) (ShapeRep, error) {
+	var k *shapekernel.ShapeKernel
+	if childUnion.Kernel != nil && (childUnion.Kind == ShapeSDF2D || childUnion.Kind == ShapeSDF3D) {
+		k = asPtr(domainMapKernel(n, *childUnion.Kernel, d))
+	}
+	switch childUnion.Kind {
+	case ShapeSDF2D:
+		child := childUnion.SDF2
+		childMin, childMax := child.Min(), child.Max()
+		min, max := d.Bounds(
+			[3]float64{childMin.X, childMin.Y},
+			[3]float64{childMax.X, childMax.Y},
+		)
+		sdf := model2d.FuncSDF(model2d.XY(min[0], min[1]), model2d.XY(max[0], max[1]), func(c model2d.Coord) float64 {
+			q := d.Map([3]float64{c.X, c.Y})
+			return child.SDF(model2d.XY(q[0], q[1])) / d.SDFScale()
+		})
+		return shapeSDF2D(sdf, k), nil
+	case ShapeSDF3D:
+		child := childUnion.SDF3
+		min, max := d.Bounds(child.Min().Array(), child.Max().Array())
+		sdf := model3d.FuncSDF(model3d.NewCoord3DArray(min), model3d.NewCoord3DArray(max), func(c model3d.Coord3D) float64 {
+			return child.SDF(model3d.NewCoord3DArray(d.Map(c.Array()))) / d.SDFScale()
+		})
+		return shapeSDF3D(sdf, k), nil
+	default:
+		return ShapeRep{}, fmt.Errorf("%s(): requires an SDF", d.OpName)
+	}
+}
+
+// domainMapKernel wraps a kernel so that it is evaluated at the mapped
+// point, scaling SDF values by the map's Lipschitz bound. The mapping is
+// computed in floating point.
+func domainMapKernel(n shapekernel.Numerics, k shapekernel.ShapeKernel, d *domainMap) shapekernel.ShapeKernel {
+	asFloat, mapped := n.Symbols.AsFloat2, n.Symbols.Make2+"(%[1]s(q.x), %[1]s(q.y))"
+	if k.Kind.Dim() == 3 {
+		asFloat, mapped = n.Symbols.AsFloat3, n.Symbols.Make3+"(%[1]s(q.x), %[1]s(q.y), %[1]s(q.z))"
+	}
+	fnName := kernelFnID(&k.IDs, d.OpName)
+	args := []any{
+		"N", n.Symbols,
+		"Entrypoint", fnName,
+		"ArgType", k.Kind.ArgType(n),
+		"ReturnType", k.Kind.ReturnType(n),
+		"AsFloat", asFloat,
+		"Mapped", fmt.Sprintf(mapped, n.Symbols.FromFloat),
+		"Inner", k.EntrypointName,
+	}
+	result := "{{.Inner}}({{.Mapped}})"
+	if scale := d.SDFScale(); scale > 1 && (k.Kind == shapekernel.SDF2D || k.Kind == shapekernel.SDF3D) {
+		result = "{{.N.FromFloat}}({{.N.AsFloat}}(" + result + ") / {{.Scale}})"
+		args = append(args, "Scale", float32(scale))
+	}
+	body := strings.Split(strings.TrimSpace(shapekernel.Dedent(d.KernelCode)), "\n")
+	shapekernel.AppendWGSL(
+		&k,
+		`
+			fn {{.Entrypoint}}(p_raw: {{.ArgType}}) -> {{.ReturnType}} {
+				let p = {{.AsFloat}}(p_raw);
+				`+strings.Join(body, "\n\t\t\t\t")+`
+				return `+result+`;
+			}
+		`,
+		append(args, d.KernelArgs...)...,
+	)
+	k.EntrypointName = fnName
+	return k
+}
+
+// domainVecLiteral formats a WGSL float vector with the given dimension.
+func domainVecLiteral(dim int, v [3]float64) string {
+	parts := make([]string, dim)
+	for i := range parts {
+		parts[i] = fmt.Sprintf("%v", float32(v[i]))
+	}
+	return fmt.Sprintf("vec%df(%s)", dim, strings.Join(parts, ", "))
+}
@@ -0,0 +1,138 @@
+package scad
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/model3d/model2d"
+	"github.com/unixpickle/model3d/model3d"
+)
+
+func TestRepeatSDF(t *testing.T) {
+	shape := mustEvalShape(t, `repeat_sdf(spacing=3, count=[4, 2]) sphere_sdf(r=1);`)
+	if shape.Kind != ShapeSDF3D || shape.Kernel == nil {
+		t.Fatalf("expected SDF3D with kernel, got %v", shape.Kind)
+	}
+	if !strings.Contains(shape.Kernel.Code, "repeat_sdf") {
+		t.Fatal("expected repeat_sdf in kernel code")
+	}
+	for _, c := range []struct {
+		P    model3d.Coord3D
+		Dist float64
+	}{
+		{model3d.XYZ(0, 0, 0), 1},
+		{model3d.XYZ(9, 3, 0), 1},
+		{model3d.XYZ(1.5, 0, 0), -0.5},
+		// Past the last copy, the distance grows instead of wrapping.
+		{model3d.XYZ(12, 0, 0), -2},
+		{model3d.XYZ(0, -2, 0), -1},
+		// The missing Z count means a single copy along Z.
+		{model3d.XYZ(0, 0, 3), -2},
+	} {
+		if d := shape.SDF3.SDF(c.P); math.Abs(d-c.Dist) > 1e-8 {
+			t.Fatalf("point %v: expected %f, got %f", c.P, c.Dist, d)
+		}
+	}
+	min, max := shape.SDF3.Min(), shape.SDF3.Max()
+	if min.Dist(model3d.XYZ(-1, -1, -1)) > 1e-8 || max.Dist(model3d.XYZ(10, 4, 1)) > 1e-8 {
+		t.Fatalf("unexpected bounds %v %v", min, max)
+	}
+
+	shape = mustEvalShape(t, `repeat_sdf([2, 0], [3, 1]) circle_sdf(r=0.5);`)
+	if d := shape.SDF2.SDF(model2d.XY(4, 0)); math.Abs(d-0.5) > 1e-8 {
+		t.Fatalf("unexpected 2D SDF %f", d)
+	}
+}
+
+func TestPolarRepeatSDF(t *testing.T) {
+	shape := mustEvalShape(t, `polar_repeat_sdf(6) translate([3, 0, 0]) sphere_sdf(r=0.5);`)
+	for i := 0; i < 6; i++ {
+		theta := float64(i) * math.Pi / 3
+		c := model3d.XYZ(3*math.Cos(theta), 3*math.Sin(theta), 0)
+		if d := shape.SDF3.SDF(c); math.Abs(d-0.5) > 1e-8 {
+			t.Fatalf("copy %d: expected 0.5, got %f", i, d)
+		}
+	}
+	if d := shape.SDF3.SDF(model3d.XYZ(0, 0, 0)); math.Abs(d+2.5) > 1e-8 {
+		t.Fatalf("unexpected center SDF %f", d)
+	}
+	// The bounds cover every rotation of the child's bounding box.
+	r := math.Hypot(3.5, 0.5)
+	if max := shape.SDF3.Max(); max.Dist(model3d.XYZ(r, r, 0.5)) > 1e-8 {
+		t.Fatalf("unexpected bounds max %v", max)
+	}
+
+	shape = mustEvalShape(t, `polar_repeat_sdf(n=4) translate([2, 0]) circle_sdf(r=0.5);`)
+	if d := shape.SDF2.SDF(model2d.XY(0, -2)); math.Abs(d-0.5) > 1e-8 {
+		t.Fatalf("unexpected 2D SDF %f", d)
+	}
+}
+
+func TestSymmetrySDF(t *testing.T) {
+	shape := mustEvalShape(t, `symmetry_sdf("xy") translate([2, 1, 0]) sphere_sdf(r=0.5);`)
+	for _, c := range []model3d.Coord3D{
+		model3d.XYZ(2, 1, 0),
+		model3d.XYZ(-2, 1, 0),
+		model3d.XYZ(2, -1, 0),
+		model3d.XYZ(-2, -1, 0),
+	} {
+		if d := shape.SDF3.SDF(c); math.Abs(d-0.5) > 1e-8 {
+			t.Fatalf("point %v: expected 0.5, got %f", c, d)
+		}
+	}
+	min, max := shape.SDF3.Min(), shape.SDF3.Max()
+	if min.Dist(model3d.XYZ(-2.5, -1.5, -0.5)) > 1e-8 || max.Dist(model3d.XYZ(2.5, 1.5, 0.5)) > 1e-8 {
+		t.Fatalf("unexpected bounds %v %v", min, max)
+	}
+
+	shape = mustEvalShape(t, `symmetry_sdf([1, 0]) translate([2, 0]) circle_sdf(r=0.5);`)
+	if d := shape.SDF2.SDF(model2d.XY(-2, 0)); math.Abs(d-0.5) > 1e-8 {
+		t.Fatalf("unexpected 2D SDF %f", d)
+	}
+}
+
+func TestElongateSDF(t *testing.T) {
+	shape := mustEvalShape(t, `elongate_sdf([2, 0, 0]) sphere_sdf(r=1);`)
+	for _, c := range []struct {
+		P    model3d.Coord3D
+		Dist float64
+	}{
+		{model3d.XYZ(0, 0, 0), 1},
+		{model3d.XYZ(2.5, 0, 0), 0.5},
+		{model3d.XYZ(-3.5, 0, 0), -0.5},
+		{model3d.XYZ(1, 2, 0), -1},
+	} {
+		if d := shape.SDF3.SDF(c.P); math.Abs(d-c.Dist) > 1e-8 {
+			t.Fatalf("point %v: expected %f, got %f", c.P, c.Dist, d)
+		}
+	}
+	min, max := shape.SDF3.Min(), shape.SDF3.Max()
+	if min.Dist(model3d.XYZ(-3, -1, -1)) > 1e-8 || max.Dist(model3d.XYZ(3, 1, 1)) > 1e-8 {
+		t.Fatalf("unexpected bounds %v %v", min, max)
+	}
+}
+
+func TestDomainSDFErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`repeat_sdf(2, 3) cube(1);`, "requires an SDF"},
+		{`repeat_sdf(0, 3) sphere_sdf(r=1);`, "spacing must be positive"},
+		{`repeat_sdf(2, 1.5) sphere_sdf(r=1);`, "positive integers"},
+		{`polar_repeat_sdf(0) sphere_sdf(r=1);`, "positive integer"},
+		{`symmetry_sdf("xz") circle_sdf(r=1);`, "unknown axis"},
+		{`elongate_sdf(-1) sphere_sdf(r=1);`, "non-negative"},
+	}
+	for _, tc := range tests {
+		prog, err := Parse(tc.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = Eval(prog, Hooks{})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", tc.src, tc.want, err)
+		}
+	}
+}
@@ -19,6 +19,7 @@ func handleLinearExtrude(e *env, st *CallStmt, _ []ShapeRep, childUnion *ShapeRe
 		{Name: "center", Pos: 1, Default: Bool(false)},
 		{Name: "twist", Pos: 2, Default: Num(0.0)},
 		{Name: "scale", Pos: 3, Default: Num(1.0)},
+		{Name: "slices", Pos: 4},
 	})
 	if err != nil {
 		return ShapeRep{}, err
@@ -46,6 +47,10 @@ func handleLinearExtrude(e *env, st *CallStmt, _ []ShapeRep, childUnion *ShapeRe
 	if height < 0 {
 		height = -height
 	}
+	slices, err := linearExtrudeSlices(args, twist)
+	if err != nil {
+		return ShapeRep{}, err
+	}
 	z0, z1 := linearExtrudeZBounds(height, center)
 	switch childUnion.Kind {
 	case ShapeSolid2D:
@@ -61,26 +66,37 @@ func handleLinearExtrude(e *env, st *CallStmt, _ []ShapeRep, childUnion *ShapeRe
 		}
 		return shapeSolid3D(linearExtrude(childUnion.S2, height, center, twist, scale), k), nil
 	case ShapeMesh2D:
-		if err := checkExtrudeTransform("Mesh", twist, scale); err != nil {
+		if err := checkExtrudeScale("Mesh", scale, true); err != nil {
 			return ShapeRep{}, err
 		}
-		if !childUnion.M2.Manifold() {
-			return ShapeRep{}, fmt.Errorf("linear_extrude(): Mesh must be manifold")
+		rep, err := repairedProfileMesh("linear_extrude", childUnion.M2)
+		if err != nil {
+			return ShapeRep{}, err
 		}
-		if n := len(childUnion.M2.InconsistentVertices()); n != 0 {
-			return ShapeRep{}, fmt.Errorf("linear_extrude(): Mesh has %d inconsistent vertices", n)
+		if isIdentityExtrude(twist, scale) {
+			return shapeMesh3D(model3d.ProfileMesh(rep, z0, z1)), nil
 		}
-		rep, _ := childUnion.M2.RepairNormals(1e-9)
-		return shapeMesh3D(model3d.ProfileMesh(rep, z0, z1)), nil
+		return shapeMesh3D(linearExtrudeMesh(rep, z0, z1, twist, scale, slices)), nil
 	case ShapeSDF2D:
-		if err := checkExtrudeTransform("SDF", twist, scale); err != nil {
+		if err := checkExtrudeScale("SDF", scale, false); err != nil {
 			return ShapeRep{}, err
 		}
 		var k *shapekernel.ShapeKernel
 		if childUnion.Kernel != nil {
 			k = asPtr(shapekernel.LinearExtrudeSDF(e.hooks.Numerics, *childUnion.Kernel, height, center))
 		}
-		return shapeSDF3D(model3d.ProfileSDF(childUnion.SDF2, z0, z1), k), nil
+		profile := shapeSDF3D(model3d.ProfileSDF(childUnion.SDF2, z0, z1), k)
+		if isIdentityExtrude(twist, scale) {
+			return profile, nil
+		}
+		return applyDomainMap(e.hooks.Numerics, &profile, linearExtrudeDomainMap(
+			childUnion.SDF2.Min(),
+			childUnion.SDF2.Max(),
+			z0,
+			z1,
+			twist,
+			scale,
+		))
 	default:
 		return ShapeRep{}, fmt.Errorf("linear_extrude(): unknown 2D kind")
 	}
@@ -189,17 +205,159 @@ func linearExtrudeZBounds(height float64, center bool) (float64, float64) {
 	return z0, z1
 }
 
-func checkExtrudeTransform(kind string, twist float64, scale [2]float64) error {
+// linearExtrudeSlices parses the slices argument, which defaults to one
+// slice per 5 degrees of twist.
+func linearExtrudeSlices(args map[string]Value, twist float64) (int, error) {
+	if v := args["slices"]; v.Kind != ValNull {
+		slices, err := argNum(args, "slices")
+		if err != nil {
+			return 0, err
+		}
+		if slices < 1 || slices != math.Floor(slices) {
+			return 0, fmt.Errorf("linear_extrude(): slices must be a positive integer")
+		}
+		return int(slices), nil
+	}
+	return int(math.Max(1, math.Ceil(math.Abs(twist)/5))), nil
+}
+
+func isIdentityExtrude(twist float64, scale [2]float64) bool {
 	const eps = 1e-9
-	if math.Abs(twist) > eps {
-		return fmt.Errorf("linear_extrude(): twist != 0 is unsupported for %s", kind)
+	return math.Abs(twist) <= eps && math.Abs(scale[0]-1) <= eps && math.Abs(scale[1]-1) <= eps
+}
+
+// checkExtrudeScale makes sure that a scale can be applied exactly (for
+// meshes) or with a bounded distance error (for SDFs). Meshes may taper
+// to a single point, while SDFs need a positive scale everywhere.
+func checkExtrudeScale(kind string, scale [2]float64, allowPoint bool) error {
+	if allowPoint && scale[0] == 0 && scale[1] == 0 {
+		return nil
 	}
-	if math.Abs(scale[0]-1) > eps || math.Abs(scale[1]-1) > eps {
-		return fmt.Errorf("linear_extrude(): scale != 1 is unsupported for %s", kind)
+	if scale[0] <= 0 || scale[1] <= 0 {
+		return fmt.Errorf("linear_extrude(): scale must be positive for %s", kind)
 	}
 	return nil
 }
 
+// linearExtrudeMesh lofts a 2D mesh through a twisted and scaled
+// extrusion, using the given number of slices along the sides.
+func linearExtrudeMesh(
+	m *model2d.Mesh,
+	z0, z1 float64,
+	twist float64,
+	scale [2]float64,
+	slices int,
+) *model3d.Mesh {
+	xform := func(c model2d.Coord, i int) model3d.Coord3D {
+		t := float64(i) / float64(slices)
+		sx := 1 + t*(scale[0]-1)
+		sy := 1 + t*(scale[1]-1)
+		angle := twist * math.Pi / 180 * t
+		cosA := math.Cos(angle)
+		sinA := math.Sin(angle)
+		x, y := c.X*sx, c.Y*sy
+		// Adding zero turns -0 into 0, so that points collapsed by a zero
+		// scale are bitwise identical.
+		return model3d.XYZ(x*cosA+y*sinA+0, y*cosA-x*sinA+0, z0+t*(z1-z0))
+	}
+	res := model3d.NewMesh()
+	addTri := func(t *model3d.Triangle) {
+		// Sides and caps collapse when the scale reaches zero.
+		if t[0] != t[1] && t[1] != t[2] && t[2] != t[0] {
+			res.Add(t)
+		}
+	}
+
+	tris := model2d.TriangulateMesh(m)
+	edges := map[[2]model2d.Coord]bool{}
+	for _, t := range tris {
+		addTri(&model3d.Triangle{xform(t[0], 0), xform(t[1], 0), xform(t[2], 0)})
+		addTri(&model3d.Triangle{xform(t[1], slices), xform(t[0], slices), xform(t[2], slices)})
+		for i := 0; i < 3; i++ {
+			edges[[2]model2d.Coord{t[i], t[(i+1)%3]}] = true
+		}
+	}
+
+	// Boundary edges of the triangulation are connected from the bottom
+	// cap to the top cap.
+	for edge := range edges {
+		if edges[[2]model2d.Coord{edge[1], edge[0]}] {
+			continue
+		}
+		a, b := edge[1], edge[0]
+		for i := 0; i < slices; i++ {
+			addTri(&model3d.Triangle{xform(a, i), xform(b, i), xform(a, i+1)})
+			addTri(&model3d.Triangle{xform(b, i), xform(b, i+1), xform(a, i+1)})
+		}
+	}
+	return res
+}
+
+// linearExtrudeDomainMap creates a map from a twisted and scaled
+// extrusion back to the straight extrusion of a 2D SDF, with a Lipschitz
+// bound for the resulting distance field.
+func linearExtrudeDomainMap(
+	min2, max2 model2d.Coord,
+	z0, z1 float64,
+	twist float64,
+	scale [2]float64,
+) *domainMap {
+	height := z1 - z0
+	invHeight := 0.0
+	if height > 0 {
+		invHeight = 1 / height
+	}
+	twistRad := twist * math.Pi / 180
+	extrudeT := func(z float64) float64 {
+		return math.Max(0, math.Min(1, (z-z0)*invHeight))
+	}
+	d := &domainMap{
+		OpName: "linear_extrude",
+		Map: func(c [3]float64) [3]float64 {
+			x, y, _ := inverseExtrudeTransform(c[0], c[1], extrudeT(c[2]), twist, scale)
+			return [3]float64{x, y, c[2]}
+		},
+		Bounds: func(min, max [3]float64) ([3]float64, [3]float64) {
+			if twist == 0 {
+				for axis := 0; axis < 2; axis++ {
+					min[axis] = math.Min(min[axis], min[axis]*scale[axis])
+					max[axis] = math.Max(max[axis], max[axis]*scale[axis])
+				}
+				return min, max
+			}
+			r := maxCornerRadius(model2d.XY(min[0], min[1]), model2d.XY(max[0], max[1])) * maxAbsScale(scale)
+			return [3]float64{-r, -r, min[2]}, [3]float64{r, r, max[2]}
+		},
+		KernelCode: `
+			let t = clamp((p.z - {{.Z0}}) * {{.InvHeight}}, 0.0, 1.0);
+			let sx = 1.0 + t * {{.ScaleX}};
+			let sy = 1.0 + t * {{.ScaleY}};
+			let angle = {{.Twist}} * t;
+			let c = cos(angle);
+			let s = sin(angle);
+			var q = p;
+			q.x = (c * p.x - s * p.y) / sx;
+			q.y = (s * p.x + c * p.y) / sy;
+		`,
+		KernelArgs: []any{
+			"Z0", float32(z0),
+			"InvHeight", float32(invHeight),
+			"ScaleX", float32(scale[0] - 1),
+			"ScaleY", float32(scale[1] - 1),
+			"Twist", float32(twistRad),
+		},
+	}
+
+	// The map divides by the scale and rotates around Z, so the shear
+	// along Z grows with the distance from the axis.
+	minScale := math.Min(1, math.Min(scale[0], scale[1]))
+	scaleRate := math.Max(math.Abs(scale[0]-1), math.Abs(scale[1]-1)) * invHeight
+	radius := maxCornerRadius(min2, max2) * maxAbsScale(scale)
+	shear := radius * (math.Abs(twistRad)*invHeight/minScale + scaleRate/(minScale*minScale))
+	d.Lipschitz = shearLipschitz(1/minScale, shear)
+	return d
+}
+
 func insetExtrudeFunc(bottom, top float64, bottomFn, topFn string) (toolbox3d.InsetFunc, error) {
 	bottomInset, err := insetExtrudeSideFunc("bottom_fn", bottomFn, bottom, true)
 	if err != nil {
@@ -240,12 +398,13 @@ func insetExtrudeSideFunc(argName, kind string, radius float64, bottom bool) (to
 }
 
 func handleRotateExtrude(e *env, st *CallStmt, _ []ShapeRep, childUnion *ShapeRep) (ShapeRep, error) {
-	if childUnion.Kind != ShapeSolid2D && childUnion.Kind != ShapeSDF2D {
+	if childUnion.Kind != ShapeSolid2D && childUnion.Kind != ShapeSDF2D && childUnion.Kind != ShapeMesh2D {
 		return ShapeRep{}, fmt.Errorf("rotate_extrude() requires 2D children")
 	}
 	args, err := bindArgs(e, st.Call, []ArgSpec{
 		{Name: "angle", Pos: 0, Default: Num(360.0)},
 		{Name: "start", Pos: 1, Default: Num(0.0)},
+		{Name: "segments", Pos: 2, Default: Num(64)},
 	})
 	if err != nil {
 		return ShapeRep{}, err
@@ -259,15 +418,41 @@ func handleRotateExtrude(e *env, st *CallStmt, _ []ShapeRep, childUnion *ShapeRe
 		return ShapeRep{}, err
 	}
 	full := math.Abs(angle) >= 360-1e-9
-	if childUnion.Kind == ShapeSDF2D {
-		if !full {
-			return ShapeRep{}, fmt.Errorf("rotate_extrude(): SDF input requires full 360 angle")
-		}
+	switch childUnion.Kind {
+	case ShapeSDF2D:
 		var k *shapekernel.ShapeKernel
+		if full {
+			if childUnion.Kernel != nil {
+				k = asPtr(shapekernel.RevolveSDF(e.hooks.Numerics, *childUnion.Kernel))
+			}
+			return shapeSDF3D(model3d.RevolveSDF(childUnion.SDF2), k), nil
+		}
+		lo, width := revolveRange(angle*math.Pi/180, start*math.Pi/180)
 		if childUnion.Kernel != nil {
-			k = asPtr(shapekernel.RevolveSDF(e.hooks.Numerics, *childUnion.Kernel))
+			k = asPtr(revolveSDFRangeKernel(e.hooks.Numerics, *childUnion.Kernel, lo, width))
+		}
+		return shapeSDF3D(revolveSDFRange(childUnion.SDF2, lo, width), k), nil
+	case ShapeMesh2D:
+		segments, err := argNum(args, "segments")
+		if err != nil {
+			return ShapeRep{}, err
+		}
+		if segments < 3 || segments != math.Floor(segments) {
+			return ShapeRep{}, fmt.Errorf("rotate_extrude(): segments must be an integer >= 3")
+		}
+		mesh, err := repairedProfileMesh("rotate_extrude", childUnion.M2)
+		if err != nil {
+			return ShapeRep{}, err
+		}
+		lo, width := revolveRange(angle*math.Pi/180, start*math.Pi/180)
+		if full {
+			width = 2 * math.Pi
 		}
-		return shapeSDF3D(model3d.RevolveSDF(childUnion.SDF2), k), nil
+		mesh, err = revolveProfileSide(mesh)
+		if err != nil {
+			return ShapeRep{}, err
+		}
+		return shapeMesh3D(revolveMesh(mesh, lo, width, int(segments))), nil
 	}
 	solid, err := model3d.RevolveSolidRange(childUnion.S2, angle*math.Pi/180, start*math.Pi/180)
 	if err != nil {
@@ -284,6 +469,198 @@ func handleRotateExtrude(e *env, st *CallStmt, _ []ShapeRep, childUnion *ShapeRe
 	return shapeSolid3D(solid, k), nil
 }
 
+// repairedProfileMesh checks that a 2D mesh bounds a region, and makes
+// its segments consistently oriented.
+func repairedProfileMesh(opName string, m *model2d.Mesh) (*model2d.Mesh, error) {
+	if !m.Manifold() {
+		return nil, fmt.Errorf("%s(): Mesh must be manifold", opName)
+	}
+	if n := len(m.InconsistentVertices()); n != 0 {
+		return nil, fmt.Errorf("%s(): Mesh has %d inconsistent vertices", opName, n)
+	}
+	rep, _ := m.RepairNormals(1e-9)
+	return rep, nil
+}
+
+// revolveRange converts a signed angle and a start angle into a
+// counter-clockwise range starting at lo, with lo in [0, 2*pi).
+func revolveRange(angle, start float64) (lo, width float64) {
+	lo = start
+	if angle < 0 {
+		lo += angle
+	}
+	lo = math.Mod(lo, 2*math.Pi)
+	if lo < 0 {
+		lo += 2 * math.Pi
+	}
+	return lo, math.Abs(angle)
+}
+
+// revolveSDFRange revolves a 2D SDF around the Z axis through a range of
+// angles, capping the ends with the profile itself.
+//
+// The profile is folded onto the positive X axis, as in
+// model3d.RevolveSDF. Points in the range are closest to the surface in
+// their own half-plane or to a cap, while points outside the range are
+// closest to one of the caps. The distance is exact unless both caps
+// are more than 90 degrees away, in which case the distance through the
+// axis gives a lower bound.
+func revolveSDFRange(s model2d.SDF, lo, width float64) model3d.SDF {
+	min2 := s.Min()
+	max2 := s.Max()
+	rMax := math.Max(math.Abs(min2.X), math.Abs(max2.X))
+	fold := func(r, z float64) float64 {
+		return math.Max(s.SDF(model2d.XY(r, z)), s.SDF(model2d.XY(-r, z)))
+	}
+	capDist := func(r, delta, z float64) float64 {
+		// Distance to the profile placed in a half-plane that is delta
+		// radians away from the point.
+		w, u := r, 0.0
+		if delta < math.Pi/2 {
+			w, u = r*math.Sin(delta), r*math.Cos(delta)
+		}
+		return math.Hypot(w, math.Max(0, -fold(u, z)))
+	}
+	return model3d.FuncSDF(
+		model3d.XYZ(-rMax, -rMax, min2.Y),
+		model3d.XYZ(rMax, rMax, max2.Y),
+		func(c model3d.Coord3D) float64 {
+			r := math.Hypot(c.X, c.Y)
+			offset := math.Mod(math.Atan2(c.Y, c.X)-lo+4*math.Pi, 2*math.Pi)
+			d1 := math.Min(offset, 2*math.Pi-offset)
+			d2 := math.Abs(offset - width)
+			d2 = math.Min(d2, 2*math.Pi-d2)
+			caps := math.Min(capDist(r, d1, c.Z), capDist(r, d2, c.Z))
+			if offset > width {
+				return -caps
+			}
+			d := fold(r, c.Z)
+			if d <= 0 {
+				return d
+			}
+			return math.Min(d, caps)
+		},
+	)
+}
+
+// revolveSDFRangeKernel creates a kernel matching revolveSDFRange.
+func revolveSDFRangeKernel(n shapekernel.Numerics, k shapekernel.ShapeKernel, lo, width float64) shapekernel.ShapeKernel {
+	if k.Kind != shapekernel.SDF2D {
+		panic("expected 2D SDF kernel")
+	}
+	foldName := kernelFnID(&k.IDs, "revolve_fold")
+	capName := kernelFnID(&k.IDs, "revolve_cap")
+	fnName := kernelFnID(&k.IDs, "revolve_range_sdf")
+	shapekernel.AppendWGSL(
+		&k,
+		`
+			fn {{.Fold}}(r: f32, z: f32) -> f32 {
+				let zn = {{.N.FromFloat}}(z);
+				let dPos = {{.N.AsFloat}}({{.Inner}}({{.N.Make2}}({{.N.FromFloat}}(r), zn)));
+				let dNeg = {{.N.AsFloat}}({{.Inner}}({{.N.Make2}}({{.N.FromFloat}}(-r), zn)));
+				return max(dPos, dNeg);
+			}
+
+			fn {{.Cap}}(r: f32, delta: f32, z: f32) -> f32 {
+				var w = r;
+				var u = 0.0;
+				if (delta < 1.5707964) {
+					w = r * sin(delta);
+					u = r * cos(delta);
+				}
+				return length(vec2f(w, max(0.0, -{{.Fold}}(u, z))));
+			}
+
+			fn {{.Entrypoint}}(p_raw: {{.N.Dtype3}}) -> {{.N.Dtype}} {
+				let p = {{.N.AsFloat3}}(p_raw);
+				let r = length(p.xy);
+				let tau = 6.2831855;
+				let offset = (atan2(p.y, p.x) - {{.Lo}} + 2.0 * tau) % tau;
+				let d1 = min(offset, tau - offset);
+				var d2 = abs(offset - {{.Width}});
+				d2 = min(d2, tau - d2);
+				let caps = min({{.Cap}}(r, d1, p.z), {{.Cap}}(r, d2, p.z));
+				if (offset > {{.Width}}) {
+					return {{.N.FromFloat}}(-caps);
+				}
+				let d = {{.Fold}}(r, p.z);
+				if (d <= 0.0) {
+					return {{.N.FromFloat}}(d);
+				}
+				return {{.N.FromFloat}}(min(d, caps));
+			}
+		`,
+		"N", n.Symbols,
+		"Entrypoint", fnName,
+		"Fold", foldName,
+		"Cap", capName,
+		"Inner", k.EntrypointName,
+		"Lo", float32(lo),
+		"Width", float32(width),
+	)
+	k.Kind = shapekernel.SDF3D
+	k.EntrypointName = fnName
+	return k
+}
+
+// revolveProfileSide makes sure that a profile lies on one side of the
+// Z axis, mirroring it onto the positive side if necessary.
+func revolveProfileSide(m *model2d.Mesh) (*model2d.Mesh, error) {
+	min, max := m.Min(), m.Max()
+	if min.X >= 0 {
+		return m, nil
+	} else if max.X > 0 {
+		return nil, fmt.Errorf("rotate_extrude(): Mesh must not cross the Z axis")
+	}
+	res := model2d.NewMesh()
+	m.Iterate(func(s *model2d.Segment) {
+		res.Add(&model2d.Segment{
+			model2d.XY(-s[1].X, s[1].Y),
+			model2d.XY(-s[0].X, s[0].Y),
+		})
+	})
+	return res, nil
+}
+
+// revolveMesh revolves a profile on the positive X side of the Z axis
+// through a range of angles, adding caps for partial revolutions.
+//
+// The segments argument is the number of segments in a full circle.
+func revolveMesh(m *model2d.Mesh, lo, width float64, segments int) *model3d.Mesh {
+	full := width >= 2*math.Pi-1e-9
+	steps := int(math.Max(1, math.Ceil(float64(segments)*width/(2*math.Pi)-1e-9)))
+	xform := func(c model2d.Coord, i int) model3d.Coord3D {
+		if full && i == steps {
+			i = 0
+		}
+		theta := lo + width*float64(i)/float64(steps)
+		// Adding zero turns -0 into 0, so that points on the axis are
+		// bitwise identical.
+		return model3d.XYZ(c.X*math.Cos(theta)+0, c.X*math.Sin(theta)+0, c.Y)
+	}
+	res := model3d.NewMesh()
+	addTri := func(t *model3d.Triangle) {
+		// Sides collapse for profile points on the axis.
+		if t[0] != t[1] && t[1] != t[2] && t[2] != t[0] {
+			res.Add(t)
+		}
+	}
+	m.Iterate(func(s *model2d.Segment) {
+		a, b := s[0], s[1]
+		for i := 0; i < steps; i++ {
+			addTri(&model3d.Triangle{xform(a, i), xform(b, i), xform(a, i+1)})
+			addTri(&model3d.Triangle{xform(b, i), xform(b, i+1), xform(a, i+1)})
+		}
+	})
+	if !full {
+		for _, t := range model2d.TriangulateMesh(m) {
+			addTri(&model3d.Triangle{xform(t[1], 0), xform(t[0], 0), xform(t[2], 0)})
+			addTri(&model3d.Triangle{xform(t[0], steps), xform(t[1], steps), xform(t[2], steps)})
+		}
+	}
+	return res
+}
+
 func inverseExtrudeTransform(x, y, t, twist float64, scale [2]float64) (float64, float64, bool) {
 	sx := 1 + t*(scale[0]-1)
 	sy := 1 + t*(scale[1]-1)
@@ -0,0 +1,632 @@
+package scad
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/unixpickle/model3d/model2d"
+	"github.com/unixpickle/model3d/model3d"
+	shapekernel "github.com/unixpickle/webgpu-meshes/shapekernel"
+)
+
+const (
+	gearFlankSegments = 16
+	gearArcSegments   = 4
+	gearBoreSegments  = 64
+)
+
+// A gearSpec describes an involute gear with standard proportions: an
+// addendum of one module and a dedendum of 1.25 modules.
+//
+// For helical gears, Module and PressureAngle are measured normal to the
+// teeth, and the profile is the transverse section.
+type gearSpec struct {
+	Teeth         int
+	Module        float64
+	PressureAngle float64
+	HelixAngle    float64
+}
+
+func (g *gearSpec) PitchRadius() float64 {
+	return g.Module / math.Cos(g.HelixAngle) * float64(g.Teeth) / 2
+}
+
+func (g *gearSpec) OuterRadius() float64 {
+	return g.PitchRadius() + g.Module
+}
+
+func (g *gearSpec) RootRadius() float64 {
+	return g.PitchRadius() - 1.25*g.Module
+}
+
+func (g *gearSpec) BaseRadius() float64 {
+	return g.PitchRadius() * math.Cos(g.transversePressureAngle())
+}
+
+func (g *gearSpec) transversePressureAngle() float64 {
+	return math.Atan(math.Tan(g.PressureAngle) / math.Cos(g.HelixAngle))
+}
+
+// halfToothAngle computes half of the angle subtended by a tooth at a
+// radius at or above the base circle.
+func (g *gearSpec) halfToothAngle(r float64) float64 {
+	involute := func(a float64) float64 {
+		return math.Tan(a) - a
+	}
+	pressure := math.Acos(math.Min(1, g.BaseRadius()/r))
+	return math.Pi/(2*float64(g.Teeth)) + involute(g.transversePressureAngle()) - involute(pressure)
+}
+
+// Profile computes the outline of the gear counter-clockwise, with the
+// first tooth centered on the +X axis. Teeth are not undercut.
+func (g *gearSpec) Profile() []model2d.Coord {
+	rootR, baseR, outerR := g.RootRadius(), g.BaseRadius(), g.OuterRadius()
+	startR := math.Max(rootR, baseR)
+	polar := func(r, theta float64) model2d.Coord {
+		return model2d.XY(r*math.Cos(theta), r*math.Sin(theta))
+	}
+
+	var res []model2d.Coord
+	toothAngle := 2 * math.Pi / float64(g.Teeth)
+	for i := 0; i < g.Teeth; i++ {
+		center := toothAngle * float64(i)
+		if rootR < baseR {
+			res = append(res, polar(rootR, center-g.halfToothAngle(baseR)))
+		}
+		for j := 0; j <= gearFlankSegments; j++ {
+			r := startR + (outerR-startR)*float64(j)/gearFlankSegments
+			res = append(res, polar(r, center-g.halfToothAngle(r)))
+		}
+		tipAngle := g.halfToothAngle(outerR)
+		for j := 1; j < gearArcSegments; j++ {
+			frac := float64(j) / gearArcSegments
+			res = append(res, polar(outerR, center-tipAngle+2*tipAngle*frac))
+		}
+		for j := gearFlankSegments; j >= 0; j-- {
+			r := startR + (outerR-startR)*float64(j)/gearFlankSegments
+			res = append(res, polar(r, center+g.halfToothAngle(r)))
+		}
+		if rootR < baseR {
+			res = append(res, polar(rootR, center+g.halfToothAngle(baseR)))
+		}
+		gapStart := center + g.halfToothAngle(startR)
+		gapEnd := center + toothAngle - g.halfToothAngle(startR)
+		for j := 1; j < gearArcSegments; j++ {
+			frac := float64(j) / gearArcSegments
+			res = append(res, polar(rootR, gapStart+(gapEnd-gapStart)*frac))
+		}
+	}
+	return res
+}
+
+// Mesh creates the outline of the gear, with an optional round bore.
+func (g *gearSpec) Mesh(opName string, bore float64) (*model2d.Mesh, error) {
+	loops := [][]model2d.Coord{g.Profile()}
+	if bore > 0 {
+		hole := make([]model2d.Coord, gearBoreSegments)
+		for i := range hole {
+			theta := 2 * math.Pi * float64(i) / gearBoreSegments
+			hole[i] = model2d.XY(bore/2*math.Cos(theta), bore/2*math.Sin(theta))
+		}
+		loops = append(loops, hole)
+	}
+	return loopsMesh(opName, loops...)
+}
+
+// TwistAngle computes the rotation of the gear's profile over the given
+// height, following a right-handed helix for positive helix angles.
+func (g *gearSpec) TwistAngle(height float64) float64 {
+	return height * math.Tan(g.HelixAngle) / g.PitchRadius()
+}
+
+// loopsMesh creates a consistently oriented mesh from closed loops.
+func loopsMesh(opName string, loops ...[]model2d.Coord) (*model2d.Mesh, error) {
+	mesh := model2d.NewMesh()
+	for _, loop := range loops {
+		m, err := polygonPathMesh(loop, defaultPolygonPath(len(loop)))
+		if err != nil {
+			return nil, err
+		}
+		mesh.AddMesh(m)
+	}
+	return repairedProfileMesh(opName, mesh)
+}
+
+func parseGearSpec(opName string, args map[string]Value) (*gearSpec, error) {
+	teeth, err := argNum(args, "teeth")
+	if err != nil {
+		return nil, err
+	}
+	module, err := argNum(args, "module")
+	if err != nil {
+		return nil, err
+	}
+	pressureAngle, err := argNum(args, "pressure_angle")
+	if err != nil {
+		return nil, err
+	}
+	var helixAngle float64
+	if _, ok := args["helix_angle"]; ok {
+		helixAngle, err = argNum(args, "helix_angle")
+		if err != nil {
+			return nil, err
+		}
+	}
+	if teeth < 3 || teeth != math.Floor(teeth) {
+		return nil, fmt.Errorf("%s(): teeth must be an integer >= 3", opName)
+	}
+	if module <= 0 {
+		return nil, fmt.Errorf("%s(): module must be positive", opName)
+	}
+	if pressureAngle <= 0 || pressureAngle >= 45 {
+		return nil, fmt.Errorf("%s(): pressure_angle must be between 0 and 45 degrees", opName)
+	}
+	if math.Abs(helixAngle) >= 75 {
+		return nil, fmt.Errorf("%s(): helix_angle must be between -75 and 75 degrees", opName)
+	}
+	g := &gearSpec{
+		Teeth:         int(teeth),
+		Module:        module,
+		PressureAngle: pressureAngle * math.Pi / 180,
+		HelixAngle:    helixAngle * math.Pi / 180,
+	}
+	if g.halfToothAngle(g.OuterRadius()) <= 0 {
+		return nil, fmt.Errorf("%s(): teeth are too pointed for this pressure_angle", opName)
+	}
+	if g.halfToothAngle(math.Max(g.RootRadius(), g.BaseRadius())) >= math.Pi/float64(g.Teeth) {
+		return nil, fmt.Errorf("%s(): teeth are too wide for this pressure_angle", opName)
+	}
+	return g, nil
+}
+
+func parseGearBore(opName string, g *gearSpec, args map[string]Value) (float64, error) {
+	bore, err := argNum(args, "bore")
+	if err != nil {
+		return 0, err
+	}
+	if bore < 0 {
+		return 0, fmt.Errorf("%s(): bore must be non-negative", opName)
+	}
+	if bore/2 >= g.RootRadius() {
+		return 0, fmt.Errorf("%s(): bore must be smaller than the root diameter", opName)
+	}
+	return bore, nil
+}
+
+func gearArgSpecs(helical bool) []ArgSpec {
+	specs := []ArgSpec{
+		{Name: "teeth", Pos: 0, Required: true},
+		{Name: "module", Pos: 1, Default: Num(1)},
+		{Name: "pressure_angle", Pos: 2, Default: Num(20)},
+		{Name: "thickness", Aliases: []string{"h"}, Pos: 3, Default: Num(5)},
+		{Name: "bore", Pos: 4, Default: Num(0)},
+		{Name: "center", Pos: -1, Default: Bool(false)},
+	}
+	if helical {
+		specs = append(specs, ArgSpec{Name: "helix_angle", Pos: 5, Default: Num(15)})
+	}
+	return specs
+}
+
+// A gearKind selects how the profile of a gear turns along its axis.
+type gearKind int
+
+const (
+	gearSpur gearKind = iota
+	gearHelical
+	gearHerringbone
+)
+
+// A parsedGear is a gear profile along with its extrusion.
+type parsedGear struct {
+	OpName  string
+	Spec    *gearSpec
+	Kind    gearKind
+	Profile *model2d.Mesh
+	Center  bool
+	Z0      float64
+	Z1      float64
+}
+
+// angle computes the counter-clockwise rotation of the profile at a
+// fraction t of the thickness.
+func (p *parsedGear) angle(t float64) float64 {
+	switch p.Kind {
+	case gearHelical:
+		return p.Spec.TwistAngle(p.Z1-p.Z0) * t
+	case gearHerringbone:
+		return p.Spec.TwistAngle((p.Z1-p.Z0)/2) * (1 - math.Abs(2*t-1))
+	default:
+		return 0
+	}
+}
+
+func parseGear(e *env, st *CallStmt, kind gearKind) (*parsedGear, error) {
+	opName := st.Call.Name
+	args, err := bindArgs(e, st.Call, gearArgSpecs(kind != gearSpur))
+	if err != nil {
+		return nil, err
+	}
+	g, err := parseGearSpec(opName, args)
+	if err != nil {
+		return nil, err
+	}
+	thickness, err := argNum(args, "thickness")
+	if err != nil {
+		return nil, err
+	}
+	if thickness <= 0 {
+		return nil, fmt.Errorf("%s(): thickness must be positive", opName)
+	}
+	bore, err := parseGearBore(opName, g, args)
+	if err != nil {
+		return nil, err
+	}
+	center, err := argBool(args, "center")
+	if err != nil {
+		return nil, err
+	}
+	profile, err := g.Mesh(opName, bore)
+	if err != nil {
+		return nil, err
+	}
+	z0, z1 := linearExtrudeZBounds(thickness, center)
+	return &parsedGear{
+		OpName:  opName,
+		Spec:    g,
+		Kind:    kind,
+		Profile: profile,
+		Center:  center,
+		Z0:      z0,
+		Z1:      z1,
+	}, nil
+}
+
+// Mesh extrudes the profile, sampling twisted gears once per degree of
+// rotation.
+func (p *parsedGear) Mesh() *model3d.Mesh {
+	if p.Kind == gearSpur {
+		return model3d.ProfileMesh(p.Profile, p.Z0, p.Z1)
+	}
+	twist := math.Abs(p.Spec.TwistAngle(p.Z1 - p.Z0))
+	slices := int(math.Max(2, math.Ceil(twist*180/math.Pi)))
+	if p.Kind == gearHerringbone && slices%2 == 1 {
+		slices++
+	}
+	mesh := linearExtrudeMesh(p.Profile, p.Z0, p.Z1, 0, [2]float64{1, 1}, slices)
+	return mesh.MapCoords(func(c model3d.Coord3D) model3d.Coord3D {
+		angle := p.angle((c.Z - p.Z0) / (p.Z1 - p.Z0))
+		return model3d.Rotation(model3d.Z(1), angle).Apply(c)
+	})
+}
+
+func (p *parsedGear) SDF(n shapekernel.Numerics) (ShapeRep, error) {
+	sdf2 := model2d.MeshToSDF(p.Profile)
+	k := shapekernel.LinearExtrudeSDF(n, *meshSDFKernel2D(n, p.Profile), p.Z1-p.Z0, p.Center)
+	profile := shapeSDF3D(model3d.ProfileSDF(sdf2, p.Z0, p.Z1), &k)
+	if p.Kind == gearSpur {
+		return profile, nil
+	}
+	return applyDomainMap(n, &profile, p.domainMap(sdf2.Min(), sdf2.Max()))
+}
+
+// domainMap maps a twisted gear back to the straight extrusion of its
+// profile.
+func (p *parsedGear) domainMap(min2, max2 model2d.Coord) *domainMap {
+	height := p.Z1 - p.Z0
+	total := p.angle(1)
+	kernelAngle := "t"
+	if p.Kind == gearHerringbone {
+		total = p.angle(0.5)
+		kernelAngle = "(1.0 - abs(2.0 * t - 1.0))"
+	}
+	radius := maxCornerRadius(min2, max2)
+	d := &domainMap{
+		OpName: p.OpName,
+		Map: func(c [3]float64) [3]float64 {
+			t := math.Max(0, math.Min(1, (c[2]-p.Z0)/height))
+			angle := -p.angle(t)
+			cosA, sinA := math.Cos(angle), math.Sin(angle)
+			return [3]float64{c[0]*cosA - c[1]*sinA, c[0]*sinA + c[1]*cosA, c[2]}
+		},
+		Bounds: func(min, max [3]float64) ([3]float64, [3]float64) {
+			return [3]float64{-radius, -radius, min[2]}, [3]float64{radius, radius, max[2]}
+		},
+		KernelCode: `
+			let t = clamp((p.z - {{.Z0}}) / {{.Height}}, 0.0, 1.0);
+			let angle = {{.Inverse}} * ` + kernelAngle + `;
+			let c = cos(angle);
+			let s = sin(angle);
+			var q = p;
+			q.x = c * p.x - s * p.y;
+			q.y = s * p.x + c * p.y;
+		`,
+		KernelArgs: []any{
+			"Z0", float32(p.Z0),
+			"Height", float32(height),
+			"Inverse", float32(-total),
+		},
+	}
+
+	// The rotation changes fastest with height on the herringbone's
+	// halves, shearing points in proportion to their radius.
+	rate := math.Abs(total) / height
+	if p.Kind == gearHerringbone {
+		rate *= 2
+	}
+	d.Lipschitz = shearLipschitz(1, radius*rate)
+	return d
+}
+
+func handleSpurGear(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	return gearMesh(e, st, gearSpur)
+}
+
+func handleSpurGearSDF(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	return gearSDF(e, st, gearSpur)
+}
+
+func handleHelicalGear(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	return gearMesh(e, st, gearHelical)
+}
+
+func handleHelicalGearSDF(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	return gearSDF(e, st, gearHelical)
+}
+
+func handleHerringboneGear(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	return gearMesh(e, st, gearHerringbone)
+}
+
+func handleHerringboneGearSDF(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	return gearSDF(e, st, gearHerringbone)
+}
+
+func gearMesh(e *env, st *CallStmt, kind gearKind) (ShapeRep, error) {
+	gear, err := parseGear(e, st, kind)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return shapeMesh3D(gear.Mesh()), nil
+}
+
+func gearSDF(e *env, st *CallStmt, kind gearKind) (ShapeRep, error) {
+	gear, err := parseGear(e, st, kind)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return gear.SDF(e.hooks.Numerics)
+}
+
+func handleGearProfile(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	opName := st.Call.Name
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "teeth", Pos: 0, Required: true},
+		{Name: "module", Pos: 1, Default: Num(1)},
+		{Name: "pressure_angle", Pos: 2, Default: Num(20)},
+		{Name: "bore", Pos: 3, Default: Num(0)},
+		{Name: "helix_angle", Pos: 4, Default: Num(0)},
+	})
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	g, err := parseGearSpec(opName, args)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	bore, err := parseGearBore(opName, g, args)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	mesh, err := g.Mesh(opName, bore)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return shapeMesh2D(mesh), nil
+}
+
+// A rackSpec describes a straight rack with teeth along the X axis,
+// pointing toward +Y, with the pitch line on the X axis.
+type rackSpec struct {
+	Teeth         int
+	Module        float64
+	PressureAngle float64
+	Height        float64
+}
+
+// Profile computes the outline of the rack counter-clockwise, starting
+// from the back of the rack at the origin.
+func (r *rackSpec) Profile() []model2d.Coord {
+	pitch := math.Pi * r.Module
+	length := pitch * float64(r.Teeth)
+	dedendum := 1.25 * r.Module
+	slope := math.Tan(r.PressureAngle)
+	res := []model2d.Coord{
+		model2d.XY(0, -r.Height),
+		model2d.XY(length, -r.Height),
+		model2d.XY(length, -dedendum),
+	}
+	for i := r.Teeth - 1; i >= 0; i-- {
+		center := (float64(i) + 0.5) * pitch
+		res = append(
+			res,
+			model2d.XY(center+pitch/4+dedendum*slope, -dedendum),
+			model2d.XY(center+pitch/4-r.Module*slope, r.Module),
+			model2d.XY(center-pitch/4+r.Module*slope, r.Module),
+			model2d.XY(center-pitch/4-dedendum*slope, -dedendum),
+		)
+	}
+	return append(res, model2d.XY(0, -dedendum))
+}
+
+func parseRack(e *env, st *CallStmt, extruded bool) (*rackSpec, float64, bool, error) {
+	opName := st.Call.Name
+	specs := []ArgSpec{
+		{Name: "teeth", Pos: 0, Required: true},
+		{Name: "module", Pos: 1, Default: Num(1)},
+		{Name: "pressure_angle", Pos: 2, Default: Num(20)},
+		{Name: "height", Pos: 3},
+	}
+	if extruded {
+		specs = append(
+			specs,
+			ArgSpec{Name: "thickness", Aliases: []string{"h"}, Pos: 4, Default: Num(5)},
+			ArgSpec{Name: "center", Pos: -1, Default: Bool(false)},
+		)
+	}
+	args, err := bindArgs(e, st.Call, specs)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	teeth, err := argNum(args, "teeth")
+	if err != nil {
+		return nil, 0, false, err
+	}
+	module, err := argNum(args, "module")
+	if err != nil {
+		return nil, 0, false, err
+	}
+	pressureAngle, err := argNum(args, "pressure_angle")
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if teeth < 1 || teeth != math.Floor(teeth) {
+		return nil, 0, false, fmt.Errorf("%s(): teeth must be a positive integer", opName)
+	}
+	if module <= 0 {
+		return nil, 0, false, fmt.Errorf("%s(): module must be positive", opName)
+	}
+	if pressureAngle <= 0 || pressureAngle >= 45 {
+		return nil, 0, false, fmt.Errorf("%s(): pressure_angle must be between 0 and 45 degrees", opName)
+	}
+	rack := &rackSpec{
+		Teeth:         int(teeth),
+		Module:        module,
+		PressureAngle: pressureAngle * math.Pi / 180,
+		Height:        3 * module,
+	}
+	if args["height"].Kind != ValNull {
+		rack.Height, err = argNum(args, "height")
+		if err != nil {
+			return nil, 0, false, err
+		}
+		if rack.Height <= 1.25*module {
+			return nil, 0, false, fmt.Errorf("%s(): height must be greater than the dedendum (1.25 * module)", opName)
+		}
+	}
+	if math.Pi/4 <= 1.25*math.Tan(rack.PressureAngle) {
+		return nil, 0, false, fmt.Errorf("%s(): teeth are too pointed for this pressure_angle", opName)
+	}
+	if !extruded {
+		return rack, 0, false, nil
+	}
+	thickness, err := argNum(args, "thickness")
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if thickness <= 0 {
+		return nil, 0, false, fmt.Errorf("%s(): thickness must be positive", opName)
+	}
+	center, err := argBool(args, "center")
+	if err != nil {
+		return nil, 0, false, err
+	}
+	return rack, thickness, center, nil
+}
+
+func handleRack(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	rack, thickness, center, err := parseRack(e, st, true)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	mesh, err := loopsMesh(st.Call.Name, rack.Profile())
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	z0, z1 := linearExtrudeZBounds(thickness, center)
+	return shapeMesh3D(model3d.ProfileMesh(mesh, z0, z1)), nil
+}
+
+func handleRackSDF(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	rack, thickness, center, err := parseRack(e, st, true)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	mesh, err := loopsMesh(st.Call.Name, rack.Profile())
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	n := e.hooks.Numerics
+	z0, z1 := linearExtrudeZBounds(thickness, center)
+	k := shapekernel.LinearExtrudeSDF(n, *meshSDFKernel2D(n, mesh), thickness, center)
+	return shapeSDF3D(model3d.ProfileSDF(model2d.MeshToSDF(mesh), z0, z1), &k), nil
+}
+
+func handleRackProfile(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	rack, _, _, err := parseRack(e, st, false)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	mesh, err := loopsMesh(st.Call.Name, rack.Profile())
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return shapeMesh2D(mesh), nil
+}
+
+// evalGearFunc implements the gear layout functions, gear_pitch_radius()
+// and gear_center_distance().
+func evalGearFunc(e *env, c Call) (Value, error) {
+	specs := []ArgSpec{
+		{Name: "teeth", Pos: 0, Required: true},
+		{Name: "module", Pos: 1, Default: Num(1)},
+		{Name: "helix_angle", Pos: 2, Default: Num(0)},
+	}
+	if c.Name == "gear_center_distance" {
+		specs = []ArgSpec{
+			{Name: "teeth1", Pos: 0, Required: true},
+			{Name: "teeth2", Pos: 1, Required: true},
+			{Name: "module", Pos: 2, Default: Num(1)},
+			{Name: "helix_angle", Pos: 3, Default: Num(0)},
+		}
+	}
+	args, err := bindArgs(e, c, specs)
+	if err != nil {
+		return Value{}, err
+	}
+	radius := func(teethName string) (float64, error) {
+		teeth, err := argNum(args, teethName)
+		if err != nil {
+			return 0, err
+		}
+		module, err := argNum(args, "module")
+		if err != nil {
+			return 0, err
+		}
+		helixAngle, err := argNum(args, "helix_angle")
+		if err != nil {
+			return 0, err
+		}
+		if teeth <= 0 || module <= 0 {
+			return 0, fmt.Errorf("%s(): %s and module must be positive", c.Name, teethName)
+		}
+		g := gearSpec{Teeth: 1, Module: module, HelixAngle: helixAngle * math.Pi / 180}
+		return g.PitchRadius() * teeth, nil
+	}
+	if c.Name == "gear_pitch_radius" {
+		r, err := radius("teeth")
+		if err != nil {
+			return Value{}, err
+		}
+		return Num(r), nil
+	}
+	r1, err := radius("teeth1")
+	if err != nil {
+		return Value{}, err
+	}
+	r2, err := radius("teeth2")
+	if err != nil {
+		return Value{}, err
+	}
+	return Num(r1 + r2), nil
+}
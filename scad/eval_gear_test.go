@@ -0,0 +1,213 @@
+package scad
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/model3d/model2d"
+	"github.com/unixpickle/model3d/model3d"
+)
+
+func TestGearFunctions(t *testing.T) {
+	prog, err := Parse(`
+		out = [
+			gear_pitch_radius(20, 2),
+			gear_pitch_radius(teeth=10, helix_angle=60),
+			gear_center_distance(12, 24, 2),
+			gear_center_distance(10, 30, module=1.5, helix_angle=60),
+		];
+	`)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	e := newEnv(Hooks{})
+	if _, err := evalStmts(e, prog.Stmts); err != nil {
+		t.Fatalf("eval failed: %v", err)
+	}
+	got, _ := e.get("out")
+	want := []float64{20, 10, 36, 60}
+	for i, wantNum := range want {
+		gotNum, err := got.List[i].AsNum()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(gotNum-wantNum) > 1e-9 {
+			t.Fatalf("result %d: got %f want %f", i, gotNum, wantNum)
+		}
+	}
+}
+
+func TestGearProfile(t *testing.T) {
+	shape := mustEvalShape(t, `gear_profile(12, 2, bore=5);`)
+	if shape.Kind != ShapeMesh2D {
+		t.Fatalf("expected Mesh2D, got %v", shape.Kind)
+	}
+	mesh := shape.M2
+	if !mesh.Manifold() {
+		t.Fatal("expected a manifold profile")
+	}
+	if r := mesh.Max().X; math.Abs(r-14) > 1e-8 {
+		t.Fatalf("expected the first tooth to reach the outer radius, got %f", r)
+	}
+	solid := mesh.Solid()
+	polar := func(r, theta float64) model2d.Coord {
+		return model2d.XY(r*math.Cos(theta), r*math.Sin(theta))
+	}
+	for i := 0; i < 12; i++ {
+		center := 2 * math.Pi * float64(i) / 12
+		// The tooth and gap are each half of the circular pitch.
+		halfTooth := math.Pi / 24
+		for _, c := range []struct {
+			Point model2d.Coord
+			Want  bool
+		}{
+			{polar(12, center+halfTooth*0.95), true},
+			{polar(12, center-halfTooth*0.95), true},
+			{polar(12, center+halfTooth*1.05), false},
+			{polar(12, center-halfTooth*1.05), false},
+			{polar(13.9, center), true},
+			{polar(14.1, center), false},
+			{polar(9.4, center+math.Pi/12), true},
+			{polar(9.6, center+math.Pi/12), false},
+			{polar(2.4, center), false},
+			{polar(2.6, center), true},
+		} {
+			if solid.Contains(c.Point) != c.Want {
+				t.Fatalf("tooth %d: expected containment %v at %v", i, c.Want, c.Point)
+			}
+		}
+	}
+
+	rack := mustEvalShape(t, `rack_profile(5, 2, height=4);`)
+	if rack.Kind != ShapeMesh2D {
+		t.Fatalf("expected Mesh2D, got %v", rack.Kind)
+	}
+	if min, max := rack.M2.Min(), rack.M2.Max(); min.Dist(model2d.XY(0, -4)) > 1e-8 ||
+		max.Dist(model2d.XY(10*math.Pi, 2)) > 1e-8 {
+		t.Fatalf("unexpected rack bounds %v %v", min, max)
+	}
+	rackSolid := rack.M2.Solid()
+	for i := 0; i < 5; i++ {
+		center := (float64(i) + 0.5) * 2 * math.Pi
+		if !rackSolid.Contains(model2d.XY(center+math.Pi/2*0.95, 0)) {
+			t.Fatalf("tooth %d: expected half the pitch to be solid", i)
+		}
+		if rackSolid.Contains(model2d.XY(center+math.Pi/2*1.05, 0)) {
+			t.Fatalf("tooth %d: expected half the pitch to be empty", i)
+		}
+	}
+}
+
+func TestGears(t *testing.T) {
+	for _, name := range []string{"spur_gear", "helical_gear", "herringbone_gear"} {
+		src := name + `(16, 1.5, thickness=6, bore=4, center=true);`
+		shape := mustEvalShape(t, src)
+		if shape.Kind != ShapeMesh3D {
+			t.Fatalf("%s: expected Mesh3D, got %v", name, shape.Kind)
+		}
+		mesh := shape.M3
+		if mesh.NeedsRepair() || len(mesh.SingularVertices()) != 0 {
+			t.Fatalf("%s: mesh is not manifold", name)
+		}
+		if _, n := mesh.RepairNormals(1e-8); n != 0 {
+			t.Fatalf("%s: mesh has %d inconsistent normals", name, n)
+		}
+		if min, max := mesh.Min(), mesh.Max(); math.Abs(min.Z+3) > 1e-8 || math.Abs(max.Z-3) > 1e-8 {
+			t.Fatalf("%s: unexpected bounds %v %v", name, min, max)
+		}
+
+		sdfShape := mustEvalShape(t, name+`_sdf(16, 1.5, thickness=6, bore=4, center=true);`)
+		if sdfShape.Kind != ShapeSDF3D || sdfShape.Kernel == nil {
+			t.Fatalf("%s: expected SDF3D with kernel, got %v", name, sdfShape.Kind)
+		}
+		sdf := sdfShape.SDF3
+		meshSolid := model3d.NewColliderSolid(model3d.MeshToCollider(mesh))
+		rng := rand.New(rand.NewSource(0))
+		for i := 0; i < 2000; i++ {
+			c := model3d.NewCoord3DRandBounds(sdf.Min(), sdf.Max(), rng)
+			d := sdf.SDF(c)
+			if math.Abs(d) < 0.05 {
+				continue
+			}
+			if meshSolid.Contains(c) != (d > 0) {
+				t.Fatalf("%s: mesh and SDF disagree at %v (SDF %f)", name, c, d)
+			}
+			c2 := c.Add(model3d.NewCoord3DRandNorm(rng).Scale(0.05))
+			if math.Abs(sdf.SDF(c2)-d) > c.Dist(c2)+1e-8 {
+				t.Fatalf("%s: SDF is not 1-Lipschitz at %v", name, c)
+			}
+		}
+	}
+
+	// A right-handed helical gear turns counter-clockwise going up, while a
+	// herringbone gear turns back to where it started.
+	gearAt := func(src string, angle, z float64) float64 {
+		shape := mustEvalShape(t, src)
+		return shape.SDF3.SDF(model3d.XYZ(10.5*math.Cos(angle), 10.5*math.Sin(angle), z))
+	}
+	helical := `helical_gear_sdf(20, 1, thickness=10, helix_angle=30);`
+	twist := 10 * math.Tan(math.Pi/6) / gearPitchRadiusForTest(20, 1, 30)
+	if d := gearAt(helical, twist*0.99, 9.9); d <= 0 {
+		t.Fatalf("expected tooth to follow the helix, got %f", d)
+	}
+	if d := gearAt(helical, twist*0.99+math.Pi/20, 9.9); d >= 0 {
+		t.Fatalf("expected gap to follow the helix, got %f", d)
+	}
+	herringbone := `herringbone_gear_sdf(20, 1, thickness=10, helix_angle=30);`
+	if d := gearAt(herringbone, 0, 9.9); d <= 0 {
+		t.Fatalf("expected herringbone tooth at the top, got %f", d)
+	}
+	if d := gearAt(herringbone, twist/2, 5); d <= 0 {
+		t.Fatalf("expected herringbone tooth to turn at the middle, got %f", d)
+	}
+
+	rack := mustEvalShape(t, `rack(4, thickness=3);`)
+	if rack.Kind != ShapeMesh3D || rack.M3.NeedsRepair() {
+		t.Fatalf("expected a manifold mesh, got %v", rack.Kind)
+	}
+	rackSDF := mustEvalShape(t, `rack_sdf(4, thickness=3);`)
+	if d := rackSDF.SDF3.SDF(model3d.XYZ(math.Pi/2, 0, 1.5)); d <= 0 {
+		t.Fatalf("expected rack tooth to be solid, got %f", d)
+	}
+	if d := rackSDF.SDF3.SDF(model3d.XYZ(math.Pi, 0.5, 1.5)); d >= 0 {
+		t.Fatalf("expected rack gap to be empty, got %f", d)
+	}
+}
+
+func gearPitchRadiusForTest(teeth int, module, helixAngle float64) float64 {
+	g := gearSpec{Teeth: teeth, Module: module, HelixAngle: helixAngle * math.Pi / 180}
+	return g.PitchRadius()
+}
+
+func TestGearErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`spur_gear(2);`, "teeth must be an integer >= 3"},
+		{`spur_gear(10.5);`, "teeth must be an integer >= 3"},
+		{`helical_gear_sdf(10, module=0);`, "module must be positive"},
+		{`spur_gear(10, pressure_angle=50);`, "pressure_angle must be between"},
+		{`herringbone_gear(10, helix_angle=80);`, "helix_angle must be between"},
+		{`spur_gear(10, thickness=0);`, "thickness must be positive"},
+		{`spur_gear(10, bore=8);`, "bore must be smaller than the root diameter"},
+		{`spur_gear(10, bore=-1);`, "bore must be non-negative"},
+		{`rack(0);`, "teeth must be a positive integer"},
+		{`rack(3, height=1);`, "height must be greater than the dedendum"},
+		{`rack_profile(3, pressure_angle=40);`, "teeth are too pointed"},
+		{`x = gear_pitch_radius(0);`, "teeth and module must be positive"},
+		{`x = gear_center_distance(10);`, "missing parameter \"teeth2\""},
+	}
+	for _, tc := range tests {
+		prog, err := Parse(tc.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = Eval(prog, Hooks{})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", tc.src, tc.want, err)
+		}
+	}
+}
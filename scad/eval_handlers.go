@@ -4,7 +4,12 @@ type callHandler struct {
 	AllowChildren   bool
 	RequireChildren bool
 	NeedsChildUnion bool
-	Eval            func(e *env, st *CallStmt, children []ShapeRep, childUnion *ShapeRep) (ShapeRep, error)
+
+	// PerPart causes the handler to be applied to each tagged part of
+	// the child union separately, preserving the parts.
+	PerPart bool
+
+	Eval func(e *env, st *CallStmt, children []ShapeRep, childUnion *ShapeRep) (ShapeRep, error)
 }
 
 var builtinHandlers = map[string]callHandler{
@@ -24,40 +29,112 @@ var builtinHandlers = map[string]callHandler{
 		RequireChildren: true,
 		Eval:            handleIntersection,
 	},
+	"smooth_union": {
+		AllowChildren:   true,
+		RequireChildren: true,
+		Eval:            handleSmoothUnion,
+	},
+	"smooth_difference": {
+		AllowChildren:   true,
+		RequireChildren: true,
+		Eval:            handleSmoothDifference,
+	},
+	"smooth_intersection": {
+		AllowChildren:   true,
+		RequireChildren: true,
+		Eval:            handleSmoothIntersection,
+	},
 	"translate": {
 		AllowChildren:   true,
 		RequireChildren: true,
 		NeedsChildUnion: true,
+		PerPart:         true,
 		Eval:            handleTranslate,
 	},
 	"scale": {
 		AllowChildren:   true,
 		RequireChildren: true,
 		NeedsChildUnion: true,
+		PerPart:         true,
 		Eval:            handleScale,
 	},
+	"resize": {
+		AllowChildren:   true,
+		RequireChildren: true,
+		NeedsChildUnion: true,
+		Eval:            handleResize,
+	},
+	"instance": {
+		Eval: handleInstance,
+	},
+	"bounds_of": {
+		AllowChildren:   true,
+		RequireChildren: true,
+		NeedsChildUnion: true,
+		Eval:            handleBoundsOf,
+	},
 	"rotate": {
 		AllowChildren:   true,
 		RequireChildren: true,
 		NeedsChildUnion: true,
+		PerPart:         true,
 		Eval:            handleRotate,
 	},
 	"mirror": {
 		AllowChildren:   true,
 		RequireChildren: true,
 		NeedsChildUnion: true,
+		PerPart:         true,
 		Eval:            handleMirror,
 	},
 	"transform": {
 		AllowChildren:   true,
 		RequireChildren: true,
 		NeedsChildUnion: true,
+		PerPart:         true,
 		Eval:            handleTransform,
 	},
+	"twist": {
+		AllowChildren:   true,
+		RequireChildren: true,
+		NeedsChildUnion: true,
+		Eval:            handleTwist,
+	},
+	"bend": {
+		AllowChildren:   true,
+		RequireChildren: true,
+		NeedsChildUnion: true,
+		Eval:            handleBend,
+	},
+	"taper": {
+		AllowChildren:   true,
+		RequireChildren: true,
+		NeedsChildUnion: true,
+		Eval:            handleTaper,
+	},
+	"color": {
+		AllowChildren:   true,
+		RequireChildren: true,
+		NeedsChildUnion: true,
+		Eval:            handleColor,
+	},
+	"material": {
+		AllowChildren:   true,
+		RequireChildren: true,
+		NeedsChildUnion: true,
+		Eval:            handleMaterial,
+	},
+	"part": {
+		AllowChildren:   true,
+		RequireChildren: true,
+		NeedsChildUnion: true,
+		Eval:            handlePart,
+	},
 	"clip": {
 		AllowChildren:   true,
 		RequireChildren: true,
 		NeedsChildUnion: true,
+		PerPart:         true,
 		Eval:            handleClip,
 	},
 	"linear_extrude": {
@@ -78,6 +155,23 @@ var builtinHandlers = map[string]callHandler{
 		NeedsChildUnion: true,
 		Eval:            handleRotateExtrude,
 	},
+	"loft": {
+		AllowChildren:   true,
+		RequireChildren: true,
+		Eval:            handleLoft,
+	},
+	"sweep": {
+		AllowChildren:   true,
+		RequireChildren: true,
+		NeedsChildUnion: true,
+		Eval:            handleSweep,
+	},
+	"path_extrude": {
+		AllowChildren:   true,
+		RequireChildren: true,
+		NeedsChildUnion: true,
+		Eval:            handleSweep,
+	},
 	"marching_squares": {
 		AllowChildren:   true,
 		RequireChildren: true,
@@ -108,6 +202,24 @@ var builtinHandlers = map[string]callHandler{
 		NeedsChildUnion: true,
 		Eval:            handleMeshToHull,
 	},
+	"shell": {
+		AllowChildren:   true,
+		RequireChildren: true,
+		NeedsChildUnion: true,
+		Eval:            handleShell,
+	},
+	"hollow": {
+		AllowChildren:   true,
+		RequireChildren: true,
+		NeedsChildUnion: true,
+		Eval:            handleShell,
+	},
+	"lattice_fill": {
+		AllowChildren:   true,
+		RequireChildren: true,
+		NeedsChildUnion: true,
+		Eval:            handleLatticeFill,
+	},
 	"inset_sdf": {
 		AllowChildren:   true,
 		RequireChildren: true,
@@ -120,6 +232,30 @@ var builtinHandlers = map[string]callHandler{
 		NeedsChildUnion: true,
 		Eval:            handleOutsetSDF,
 	},
+	"repeat_sdf": {
+		AllowChildren:   true,
+		RequireChildren: true,
+		NeedsChildUnion: true,
+		Eval:            handleRepeatSDF,
+	},
+	"polar_repeat_sdf": {
+		AllowChildren:   true,
+		RequireChildren: true,
+		NeedsChildUnion: true,
+		Eval:            handlePolarRepeatSDF,
+	},
+	"symmetry_sdf": {
+		AllowChildren:   true,
+		RequireChildren: true,
+		NeedsChildUnion: true,
+		Eval:            handleSymmetrySDF,
+	},
+	"elongate_sdf": {
+		AllowChildren:   true,
+		RequireChildren: true,
+		NeedsChildUnion: true,
+		Eval:            handleElongateSDF,
+	},
 	"solid": {
 		AllowChildren:   true,
 		RequireChildren: true,
@@ -172,6 +308,15 @@ var builtinHandlers = map[string]callHandler{
 	"cube_sdf": {
 		Eval: handleCubeSDF,
 	},
+	"gyroid_sdf": {
+		Eval: handleGyroidSDF,
+	},
+	"schwarz_p_sdf": {
+		Eval: handleSchwarzPSDF,
+	},
+	"diamond_sdf": {
+		Eval: handleDiamondSDF,
+	},
 	"cylinder": {
 		Eval: handleCylinder,
 	},
@@ -190,6 +335,111 @@ var builtinHandlers = map[string]callHandler{
 	"capsule_sdf": {
 		Eval: handleCapsuleSDF,
 	},
+	"torus": {
+		Eval: handleTorus,
+	},
+	"torus_metaball": {
+		Eval: handleTorusMetaball,
+	},
+	"torus_sdf": {
+		Eval: handleTorusSDF,
+	},
+	"cone": {
+		Eval: handleCone,
+	},
+	"cone_metaball": {
+		Eval: handleConeMetaball,
+	},
+	"cone_sdf": {
+		Eval: handleConeSDF,
+	},
+	"ellipsoid": {
+		Eval: handleEllipsoid,
+	},
+	"ellipsoid_metaball": {
+		Eval: handleEllipsoidMetaball,
+	},
+	"ellipsoid_sdf": {
+		Eval: handleEllipsoidSDF,
+	},
+	"wedge": {
+		Eval: handleWedge,
+	},
+	"wedge_metaball": {
+		Eval: handleWedgeMetaball,
+	},
+	"wedge_sdf": {
+		Eval: handleWedgeSDF,
+	},
+	"prism": {
+		Eval: handlePrism,
+	},
+	"prism_metaball": {
+		Eval: handlePrismMetaball,
+	},
+	"prism_sdf": {
+		Eval: handlePrismSDF,
+	},
+	"rounded_cube": {
+		Eval: handleRoundedCube,
+	},
+	"rounded_cube_sdf": {
+		Eval: handleRoundedCubeSDF,
+	},
+	"rounded_cylinder": {
+		Eval: handleRoundedCylinder,
+	},
+	"rounded_cylinder_sdf": {
+		Eval: handleRoundedCylinderSDF,
+	},
+	"thread": {
+		Eval: handleThread,
+	},
+	"thread_sdf": {
+		Eval: handleThreadSDF,
+	},
+	"bolt": {
+		Eval: handleBolt,
+	},
+	"bolt_sdf": {
+		Eval: handleBoltSDF,
+	},
+	"nut": {
+		Eval: handleNut,
+	},
+	"nut_sdf": {
+		Eval: handleNutSDF,
+	},
+	"tapped_hole": {
+		Eval: handleTappedHole,
+	},
+	"tapped_hole_sdf": {
+		Eval: handleTappedHoleSDF,
+	},
+	"spur_gear": {
+		Eval: handleSpurGear,
+	},
+	"spur_gear_sdf": {
+		Eval: handleSpurGearSDF,
+	},
+	"helical_gear": {
+		Eval: handleHelicalGear,
+	},
+	"helical_gear_sdf": {
+		Eval: handleHelicalGearSDF,
+	},
+	"herringbone_gear": {
+		Eval: handleHerringboneGear,
+	},
+	"herringbone_gear_sdf": {
+		Eval: handleHerringboneGearSDF,
+	},
+	"rack": {
+		Eval: handleRack,
+	},
+	"rack_sdf": {
+		Eval: handleRackSDF,
+	},
 	"line_join": {
 		Eval: handleLineJoin,
 	},
@@ -220,6 +470,30 @@ var builtinHandlers = map[string]callHandler{
 	"square_sdf": {
 		Eval: handleSquareSDF,
 	},
+	"regular_polygon": {
+		Eval: handleRegularPolygon,
+	},
+	"regular_polygon_metaball": {
+		Eval: handleRegularPolygonMetaball,
+	},
+	"regular_polygon_sdf": {
+		Eval: handleRegularPolygonSDF,
+	},
+	"star": {
+		Eval: handleStar,
+	},
+	"star_metaball": {
+		Eval: handleStarMetaball,
+	},
+	"star_sdf": {
+		Eval: handleStarSDF,
+	},
+	"rounded_square": {
+		Eval: handleRoundedSquare,
+	},
+	"rounded_square_sdf": {
+		Eval: handleRoundedSquareSDF,
+	},
 	"fn_solid": {
 		Eval: handleFnSolid,
 	},
@@ -241,6 +515,12 @@ var builtinHandlers = map[string]callHandler{
 	"path_sdf": {
 		Eval: handlePathSDF,
 	},
+	"gear_profile": {
+		Eval: handleGearProfile,
+	},
+	"rack_profile": {
+		Eval: handleRackProfile,
+	},
 	"path_mesh": {
 		Eval: handlePathMesh,
 	},
@@ -253,4 +533,25 @@ var builtinHandlers = map[string]callHandler{
 	"text_sdf": {
 		Eval: handleTextSDF,
 	},
+	"text_on_path": {
+		Eval: handleTextOnPath,
+	},
+	"text_on_cylinder": {
+		Eval: handleTextOnCylinder,
+	},
+	"text_on_cylinder_sdf": {
+		Eval: handleTextOnCylinderSDF,
+	},
+	"import": {
+		Eval: handleImport,
+	},
+	"svg_document": {
+		Eval: handleSVGDocument,
+	},
+	"surface": {
+		Eval: handleSurface,
+	},
+	"surface_mesh": {
+		Eval: handleSurfaceMesh,
+	},
 }
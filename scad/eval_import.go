@@ -0,0 +1,166 @@
+package scad
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/unixpickle/model3d/model2d"
+	"github.com/unixpickle/model3d/model3d"
+)
+
+func handleImport(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "file", Pos: 0, Required: true},
+		{Name: "convexity", Pos: 1, Default: Num(1)},
+		{Name: "center", Pos: -1, Default: Bool(false)},
+		{Name: "layer", Pos: -1, Default: String("")},
+		{Name: "id", Pos: -1, Default: String("")},
+		{Name: "dpi", Pos: -1, Default: Num(96)},
+		{Name: "fill_rule", Pos: -1},
+		{Name: "segments", Pos: -1, Default: Num(32)},
+	})
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	file, err := argString(args, "file")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	center, err := argBool(args, "center")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	opts, err := parseImportOptions2D("import", args)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+
+	ext := strings.ToLower(filepath.Ext(file))
+	var read3D func([]byte) (*model3d.Mesh, error)
+	var read2D func([]byte, importOptions2D) (*model2d.Mesh, error)
+	switch ext {
+	case ".stl":
+		read3D = readSTLMesh
+	case ".off":
+		read3D = readOFFMesh
+	case ".obj":
+		read3D = readOBJMesh
+	case ".3mf":
+		read3D = read3MFMesh
+	case ".svg":
+		read2D = readSVGMesh
+	case ".dxf":
+		read2D = readDXFMesh
+	default:
+		return ShapeRep{}, fmt.Errorf("import(): unsupported file format %q", ext)
+	}
+
+	data, err := e.hooks.ReadFile(file)
+	if err != nil {
+		return ShapeRep{}, fmt.Errorf("import(): %w", err)
+	}
+	if read3D != nil {
+		mesh, err := read3D(data)
+		if err != nil {
+			return ShapeRep{}, fmt.Errorf("import(): %s: %w", file, err)
+		}
+		if mesh.NumTriangles() == 0 {
+			return ShapeRep{}, fmt.Errorf("import(): %s: no triangles found", file)
+		}
+		if center {
+			mesh = mesh.Translate(mesh.Min().Mid(mesh.Max()).Scale(-1))
+		}
+		return shapeMesh3D(mesh), nil
+	}
+	mesh, err := read2D(data, opts)
+	if err != nil {
+		return ShapeRep{}, fmt.Errorf("import(): %s: %w", file, err)
+	}
+	if mesh.NumSegments() == 0 {
+		return ShapeRep{}, fmt.Errorf("import(): %s: no filled outlines found", file)
+	}
+	if center {
+		mesh = mesh.Translate(mesh.Min().Mid(mesh.Max()).Scale(-1))
+	}
+	return shapeMesh2D(mesh), nil
+}
+
+func handleSVGDocument(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "file", Pos: 0, Required: true},
+		{Name: "id", Pos: -1, Default: String("")},
+		{Name: "layer", Pos: -1, Default: String("")},
+		{Name: "center", Pos: -1, Default: Bool(false)},
+		{Name: "dpi", Pos: -1, Default: Num(96)},
+		{Name: "fill_rule", Pos: -1},
+		{Name: "segments", Pos: -1, Default: Num(32)},
+	})
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	file, err := argString(args, "file")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	center, err := argBool(args, "center")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	opts, err := parseImportOptions2D("svg_document", args)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	data, err := e.hooks.ReadFile(file)
+	if err != nil {
+		return ShapeRep{}, fmt.Errorf("svg_document(): %w", err)
+	}
+	mesh, err := readSVGMesh(data, opts)
+	if err != nil {
+		return ShapeRep{}, fmt.Errorf("svg_document(): %s: %w", file, err)
+	}
+	if mesh.NumSegments() == 0 {
+		return ShapeRep{}, fmt.Errorf("svg_document(): %s: no filled shapes found", file)
+	}
+	if center {
+		mesh = mesh.Translate(mesh.Min().Mid(mesh.Max()).Scale(-1))
+	}
+	return shapeMesh2D(mesh), nil
+}
+
+func parseImportOptions2D(opName string, args map[string]Value) (importOptions2D, error) {
+	var opts importOptions2D
+	var err error
+	if opts.Layer, err = argString(args, "layer"); err != nil {
+		return opts, err
+	}
+	if opts.ID, err = argString(args, "id"); err != nil {
+		return opts, err
+	}
+	if opts.DPI, err = argNum(args, "dpi"); err != nil {
+		return opts, err
+	}
+	if opts.DPI <= 0 {
+		return opts, fmt.Errorf("%s(): dpi must be positive", opName)
+	}
+	segments, err := argNum(args, "segments")
+	if err != nil {
+		return opts, err
+	}
+	if float64(int(segments)) != segments || segments < 1 {
+		return opts, fmt.Errorf("%s(): segments must be a positive integer", opName)
+	}
+	opts.Segments = int(segments)
+	if v := args["fill_rule"]; v.Kind != ValNull {
+		s, err := v.AsString()
+		if err != nil {
+			return opts, fmt.Errorf("%s(): fill_rule: %w", opName, err)
+		}
+		rule, err := parseFillRule(opName, s)
+		if err != nil {
+			return opts, err
+		}
+		opts.FillRule = &rule
+	}
+	return opts, nil
+}
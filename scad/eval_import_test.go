@@ -0,0 +1,459 @@
+package scad
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/model3d/model2d"
+	"github.com/unixpickle/model3d/model3d"
+)
+
+func mustEvalShapeWithFiles(t *testing.T, src string, files map[string][]byte) ShapeRep {
+	t.Helper()
+	shape, err := evalWithFiles(src, files)
+	if err != nil {
+		t.Fatalf("eval failed: %v", err)
+	}
+	return shape
+}
+
+func evalWithFiles(src string, files map[string][]byte) (ShapeRep, error) {
+	prog, err := Parse(src)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return Eval(prog, Hooks{
+		ReadFile: func(path string) ([]byte, error) {
+			if data, ok := files[path]; ok {
+				return data, nil
+			}
+			return nil, fmt.Errorf("file not found: %s", path)
+		},
+	})
+}
+
+// signedArea2D is positive for meshes following the model2d orientation.
+func signedArea2D(m *model2d.Mesh) float64 {
+	var result float64
+	m.Iterate(func(s *model2d.Segment) {
+		result += (s[1].X*s[0].Y - s[0].X*s[1].Y) / 2
+	})
+	return result
+}
+
+func TestImportMeshFormats(t *testing.T) {
+	cube := model3d.NewMeshRect(model3d.XYZ(0, 0, 0), model3d.XYZ(2, 3, 4))
+
+	vertices := cube.VertexSlice()
+	var obj bytes.Buffer
+	vertexIDs := map[model3d.Coord3D]int{}
+	for _, v := range vertices {
+		vertexIDs[v] = len(vertexIDs) + 1
+		fmt.Fprintf(&obj, "v %f %f %f\n", v.X, v.Y, v.Z)
+	}
+	for _, tri := range cube.TriangleSlice() {
+		fmt.Fprintf(&obj, "f %d/1 %d/2 %d/3\n", vertexIDs[tri[0]], vertexIDs[tri[1]], vertexIDs[tri[2]])
+	}
+
+	var off bytes.Buffer
+	fmt.Fprintf(&off, "OFF\n%d %d 0\n", len(vertexIDs), cube.NumTriangles())
+	for _, v := range vertices {
+		fmt.Fprintf(&off, "%f %f %f\n", v.X, v.Y, v.Z)
+	}
+	for _, tri := range cube.TriangleSlice() {
+		fmt.Fprintf(&off, "3 %d %d %d\n", vertexIDs[tri[0]]-1, vertexIDs[tri[1]]-1, vertexIDs[tri[2]]-1)
+	}
+
+	files := map[string][]byte{
+		"part.stl": cube.EncodeSTL(),
+		"part.obj": obj.Bytes(),
+		"part.off": off.Bytes(),
+		"part.3mf": encodeTest3MF(t, cube),
+	}
+	for name := range files {
+		t.Run(name, func(t *testing.T) {
+			shape := mustEvalShapeWithFiles(t, fmt.Sprintf("import(%q);", name), files)
+			if shape.Kind != ShapeMesh3D {
+				t.Fatalf("expected ShapeMesh3D, got %v", shape.Kind)
+			}
+			if got := shape.M3.NumTriangles(); got != cube.NumTriangles() {
+				t.Fatalf("expected %d triangles, got %d", cube.NumTriangles(), got)
+			}
+			if v := shape.M3.Volume(); math.Abs(v-24) > 1e-4 {
+				t.Fatalf("expected volume 24, got %f", v)
+			}
+
+			centered := mustEvalShapeWithFiles(t, fmt.Sprintf("import(%q, center=true);", name), files)
+			if min := centered.M3.Min(); min.Dist(model3d.XYZ(-1, -1.5, -2)) > 1e-4 {
+				t.Fatalf("unexpected centered min: %v", min)
+			}
+		})
+	}
+}
+
+func encodeTest3MF(t *testing.T, mesh *model3d.Mesh) []byte {
+	var model strings.Builder
+	model.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<model unit="centimeter" xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02">
+<resources><object id="1" type="model"><mesh><vertices>`)
+	ids := map[model3d.Coord3D]int{}
+	for _, v := range mesh.VertexSlice() {
+		ids[v] = len(ids)
+		// Stored in centimeters, shifted by a build transform.
+		fmt.Fprintf(&model, `<vertex x="%f" y="%f" z="%f"/>`, v.X/10, v.Y/10, v.Z/10-1)
+	}
+	model.WriteString("</vertices><triangles>")
+	for _, tri := range mesh.TriangleSlice() {
+		fmt.Fprintf(&model, `<triangle v1="%d" v2="%d" v3="%d"/>`, ids[tri[0]], ids[tri[1]], ids[tri[2]])
+	}
+	model.WriteString(`</triangles></mesh></object></resources>
+<build><item objectid="1" transform="1 0 0 0 1 0 0 0 1 0 0 1"/></build></model>`)
+
+	return zipTestFiles(t, map[string]string{"3D/3dmodel.model": model.String()})
+}
+
+func zipTestFiles(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, contents := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(contents)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestImport3MFComponents(t *testing.T) {
+	cube := model3d.NewMeshRect(model3d.XYZ(0, 0, 0), model3d.XYZ(1, 1, 1))
+	var part strings.Builder
+	part.WriteString(`<model unit="millimeter"><resources><object id="1"><mesh><vertices>`)
+	ids := map[model3d.Coord3D]int{}
+	for _, v := range cube.VertexSlice() {
+		ids[v] = len(ids)
+		fmt.Fprintf(&part, `<vertex x="%f" y="%f" z="%f"/>`, v.X, v.Y, v.Z)
+	}
+	part.WriteString("</vertices><triangles>")
+	for _, tri := range cube.TriangleSlice() {
+		fmt.Fprintf(&part, `<triangle v1="%d" v2="%d" v3="%d"/>`, ids[tri[0]], ids[tri[1]], ids[tri[2]])
+	}
+	part.WriteString(`</triangles></mesh></object></resources><build/></model>`)
+
+	// The cube lives in its own part, which the root model places twice.
+	// Unreferenced models and objects are ignored.
+	files := map[string][]byte{
+		"parts.3mf": zipTestFiles(t, map[string]string{
+			"_rels/.rels": `<Relationships><Relationship Target="/3D/root.model" ` +
+				`Type="http://schemas.microsoft.com/3dmanufacturing/2013/01/3dmodel"/></Relationships>`,
+			"3D/root.model": `<model unit="millimeter" xmlns:p="http://schemas.microsoft.com/3dmanufacturing/production/2015/06">
+<resources>
+<object id="5"><components>
+<component p:path="/3D/Objects/cube.model" objectid="1"/>
+<component p:path="/3D/Objects/cube.model" objectid="1" transform="1 0 0 0 1 0 0 0 1 3 0 0"/>
+</components></object>
+<object id="6"><components><component p:path="/3D/Objects/cube.model" objectid="1"/></components></object>
+</resources>
+<build><item objectid="5"/></build></model>`,
+			"3D/Objects/cube.model":   part.String(),
+			"3D/Objects/unused.model": part.String(),
+		}),
+	}
+	shape := mustEvalShapeWithFiles(t, `import("parts.3mf");`, files)
+	if got := shape.M3.NumTriangles(); got != 2*cube.NumTriangles() {
+		t.Fatalf("expected %d triangles, got %d", 2*cube.NumTriangles(), got)
+	}
+	if max := shape.M3.Max(); max.Dist(model3d.XYZ(4, 1, 1)) > 1e-8 {
+		t.Fatalf("unexpected max %v", max)
+	}
+
+	// Each object references the next one ten times, so the fanout grows
+	// exponentially even though the nesting is shallow.
+	var bomb strings.Builder
+	bomb.WriteString(`<model><resources>` + strings.TrimSuffix(strings.TrimPrefix(
+		part.String(), `<model unit="millimeter"><resources>`), `</resources><build/></model>`))
+	for i := 2; i < 10; i++ {
+		fmt.Fprintf(&bomb, `<object id="%d"><components>`, i)
+		for j := 0; j < 10; j++ {
+			fmt.Fprintf(&bomb, `<component objectid="%d"/>`, i-1)
+		}
+		bomb.WriteString(`</components></object>`)
+	}
+	bomb.WriteString(`</resources><build><item objectid="9"/></build></model>`)
+	files["bomb.3mf"] = zipTestFiles(t, map[string]string{"3D/3dmodel.model": bomb.String()})
+	if _, err := evalWithFiles(`import("bomb.3mf");`, files); err == nil ||
+		!strings.Contains(err.Error(), "components") {
+		t.Fatalf("expected component limit error, got %v", err)
+	}
+}
+
+func TestImportMalformedMeshes(t *testing.T) {
+	cube := model3d.NewMeshRect(model3d.XYZ(0, 0, 0), model3d.XYZ(1, 1, 1))
+	stl := cube.EncodeSTL()
+	hugeSTL := append([]byte{}, stl...)
+	hugeSTL[80], hugeSTL[81], hugeSTL[82], hugeSTL[83] = 0xff, 0xff, 0xff, 0x7f
+	// An ASCII file with a non-ASCII byte is read as binary.
+	corruptASCII := []byte("solid x\n" + strings.Repeat("facet normal 0 0 0\n", 10) + "\xff\n")
+
+	files := map[string][]byte{
+		"truncated.stl": stl[:len(stl)-20],
+		"header.stl":    stl[:40],
+		"huge.stl":      hugeSTL,
+		"corrupt.stl":   corruptASCII,
+		"face.off":      []byte("OFF\n3 1 0\n0 0 0\n1 0 0\n0 1 0\n2 0 1\n"),
+		"counts.off":    []byte("OFF\n1000000000000 1 0\n0 0 0\n"),
+		"negative.off":  []byte("OFF -3 1 0\n0 0 0\n"),
+		"short.off":     []byte("OFF\n3 1 0\n0 0 0\n1 0 0\n"),
+	}
+	for name := range files {
+		_, err := evalWithFiles(fmt.Sprintf("import(%q);", name), files)
+		if err == nil || !strings.Contains(err.Error(), "import(): "+name) {
+			t.Fatalf("%s: expected import error, got %v", name, err)
+		}
+	}
+}
+
+func TestImportSVGFillRules(t *testing.T) {
+	// Two nested squares with the same orientation.
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg" xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape"
+		width="40mm" height="40mm">
+		<g inkscape:groupmode="layer" inkscape:label="Outline">
+			<path d="M0 0 H40 V40 H0 Z M10 10 H30 V30 H10 Z"/>
+		</g>
+		<g inkscape:groupmode="layer" inkscape:label="Holes">
+			<path style="fill-rule:evenodd" d="M0 0 H40 V40 H0 Z M10 10 H30 V30 H10 Z"/>
+		</g>
+		<g id="Grouped" inkscape:label="Grouped"><path d="M0 0 H1 V1 H0 Z"/></g>
+	</svg>`)
+	files := map[string][]byte{"art.svg": svg}
+
+	tests := []struct {
+		src  string
+		area float64
+	}{
+		{`import("art.svg", dpi=25.4, layer="Outline");`, 1600},
+		{`import("art.svg", dpi=25.4, layer="Holes");`, 1200},
+		{`import("art.svg", dpi=25.4, layer="Outline", fill_rule="evenodd");`, 1200},
+		{`import("art.svg", dpi=25.4, layer="Holes", fill_rule="nonzero");`, 1600},
+		{`import("art.svg", dpi=25.4);`, 1600},
+	}
+	for _, tc := range tests {
+		t.Run(tc.src, func(t *testing.T) {
+			shape := mustEvalShapeWithFiles(t, tc.src, files)
+			if shape.Kind != ShapeMesh2D {
+				t.Fatalf("expected ShapeMesh2D, got %v", shape.Kind)
+			}
+			if !shape.M2.Manifold() {
+				t.Fatal("expected manifold mesh")
+			}
+			if a := signedArea2D(shape.M2); math.Abs(a-tc.area) > 1e-6 {
+				t.Fatalf("expected area %f, got %f", tc.area, a)
+			}
+		})
+	}
+
+	shape := mustEvalShapeWithFiles(t, `import("art.svg", dpi=25.4, layer="Holes");`, files)
+	solid := shape.M2.Solid()
+	if !solid.Contains(model2d.XY(5, 5)) || solid.Contains(model2d.XY(20, 20)) {
+		t.Fatal("unexpected containment for evenodd import")
+	}
+
+	// Only Inkscape layers can be selected by layer name.
+	if _, err := evalWithFiles(`import("art.svg", layer="Grouped");`, files); err == nil ||
+		!strings.Contains(err.Error(), "no layer named") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestImportSVGUnits(t *testing.T) {
+	files := map[string][]byte{
+		"px.svg": []byte(`<svg width="96" height="96"><path id="sq" d="M0 0 h96 v48 h-96 z"/></svg>`),
+	}
+	shape := mustEvalShapeWithFiles(t, `import("px.svg");`, files)
+	min, max := shape.M2.Min(), shape.M2.Max()
+	if min.Dist(model2d.XY(0, 12.7)) > 1e-6 || max.Dist(model2d.XY(25.4, 25.4)) > 1e-6 {
+		t.Fatalf("unexpected bounds %v-%v", min, max)
+	}
+	shape = mustEvalShapeWithFiles(t, `import("px.svg", dpi=25.4, id="sq");`, files)
+	if max := shape.M2.Max(); max.Dist(model2d.XY(96, 96)) > 1e-6 {
+		t.Fatalf("unexpected max %v", max)
+	}
+}
+
+func TestImportDXF(t *testing.T) {
+	dxf := strings.Join([]string{
+		"0", "SECTION", "2", "HEADER",
+		"9", "$INSUNITS", "70", "5",
+		"0", "ENDSEC",
+		"0", "SECTION", "2", "ENTITIES",
+		"0", "LWPOLYLINE", "8", "Plate", "90", "4", "70", "1",
+		"10", "0", "20", "0",
+		"10", "4", "20", "0",
+		"10", "4", "20", "2",
+		"10", "0", "20", "2",
+		"0", "CIRCLE", "8", "Plate", "10", "2", "20", "1", "40", "0.5",
+		"0", "LINE", "8", "Tab", "10", "5", "20", "0", "11", "6", "21", "0",
+		"0", "ARC", "8", "Tab", "10", "5.5", "20", "0", "40", "0.5", "50", "0", "51", "180",
+		"0", "ENDSEC",
+		"0", "EOF",
+	}, "\n")
+	files := map[string][]byte{"plate.dxf": []byte(dxf)}
+
+	shape := mustEvalShapeWithFiles(t, `import("plate.dxf", layer="Plate", segments=256);`, files)
+	if shape.Kind != ShapeMesh2D {
+		t.Fatalf("expected ShapeMesh2D, got %v", shape.Kind)
+	}
+	// Centimeters are converted to millimeters, and the circle is a hole.
+	expected := 40*20 - math.Pi*5*5
+	if a := signedArea2D(shape.M2); math.Abs(a-expected)/expected > 1e-3 {
+		t.Fatalf("expected area %f, got %f", expected, a)
+	}
+
+	shape = mustEvalShapeWithFiles(t, `import("plate.dxf", layer="Tab", segments=256);`, files)
+	expected = math.Pi * 5 * 5 / 2
+	if a := signedArea2D(shape.M2); math.Abs(a-expected)/expected > 1e-3 {
+		t.Fatalf("expected area %f, got %f", expected, a)
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	files := map[string][]byte{
+		"a.svg": []byte(`<svg><path d="M0 0 L1 0 L1 1 Z"/></svg>`),
+	}
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`import("missing.stl");`, "file not found"},
+		{`import("a.png");`, "unsupported file format"},
+		{`import("a.svg", layer="nope");`, "no layer named"},
+		{`import("a.svg", id="nope");`, "no element with id"},
+		{`import("a.svg", fill_rule="winding");`, "fill_rule must be"},
+	}
+	for _, tc := range tests {
+		_, err := evalWithFiles(tc.src, files)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", tc.src, tc.want, err)
+		}
+	}
+}
+
+func TestFillMesh2DOverlaps(t *testing.T) {
+	mesh := model2d.NewMeshRect(model2d.XY(0, 0), model2d.XY(2, 2))
+	mesh.AddMesh(model2d.NewMeshRect(model2d.XY(1, 1), model2d.XY(3, 3)))
+	mesh.AddMesh(model2d.NewMeshRect(model2d.XY(2, 0), model2d.XY(3, 1)))
+
+	union := fillMesh2D(mesh, fillRuleNonZero)
+	if !union.Manifold() {
+		t.Fatal("expected manifold union")
+	}
+	if a := signedArea2D(union); math.Abs(a-8) > 1e-8 {
+		t.Fatalf("expected union area 8, got %f", a)
+	}
+
+	xor := fillMesh2D(mesh, fillRuleEvenOdd)
+	if a := signedArea2D(xor); math.Abs(a-7) > 1e-8 {
+		t.Fatalf("expected evenodd area 7, got %f", a)
+	}
+}
+
+func TestSVGDocument(t *testing.T) {
+	// The viewBox maps user units to half millimeters, with y facing up.
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="50mm" height="50mm" viewBox="0 0 100 100">
+		<g id="shapes" transform="translate(10, 10)">
+			<rect id="box" width="20" height="10"/>
+			<rect id="rounded" x="30" width="20" height="20" rx="5"/>
+			<circle id="dot" cx="10" cy="40" r="5" transform="scale(2)"/>
+			<ellipse id="oval" cx="60" cy="60" rx="10" ry="5"/>
+			<polygon id="tri" points="0,60 20,60 0,80"/>
+		</g>
+		<g id="hidden" style="display:none"><rect width="100" height="100"/></g>
+		<rect id="outline" width="100" height="100" fill="none" stroke="black"/>
+		<g id="unfilled" style="fill:none"><rect id="refilled" x="90" y="90" width="5" height="5" fill="red"/></g>
+		<path id="turned" transform="rotate(90, 90, 90)" d="M80 80 h10 v20 h-10 z"/>
+	</svg>`)
+	files := map[string][]byte{"shapes.svg": svg}
+
+	tests := []struct {
+		id   string
+		area float64
+		min  model2d.Coord
+		max  model2d.Coord
+	}{
+		{"box", 50, model2d.XY(5, 40), model2d.XY(15, 45)},
+		{"rounded", 100 - (4-math.Pi)*6.25, model2d.XY(20, 35), model2d.XY(30, 45)},
+		{"dot", math.Pi * 25, model2d.XY(10, 0), model2d.XY(20, 10)},
+		{"oval", math.Pi * 12.5, model2d.XY(30, 12.5), model2d.XY(40, 17.5)},
+		{"tri", 50, model2d.XY(5, 5), model2d.XY(15, 15)},
+		{"turned", 50, model2d.XY(40, 5), model2d.XY(50, 10)},
+	}
+	for _, tc := range tests {
+		src := fmt.Sprintf(`svg_document("shapes.svg", id=%q, segments=256);`, tc.id)
+		shape := mustEvalShapeWithFiles(t, src, files)
+		if shape.Kind != ShapeMesh2D {
+			t.Fatalf("%s: expected Mesh2D, got %v", tc.id, shape.Kind)
+		}
+		mesh, err := readSVGMesh(svg, importOptions2D{ID: tc.id, DPI: 96, Segments: 256})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a := signedArea2D(mesh); math.Abs(a-tc.area)/tc.area > 1e-3 {
+			t.Fatalf("%s: expected area %f, got %f", tc.id, tc.area, a)
+		}
+		min, max := shape.M2.Min(), shape.M2.Max()
+		if min.Dist(tc.min) > 1e-6 || max.Dist(tc.max) > 1e-6 {
+			t.Fatalf("%s: unexpected bounds %v-%v", tc.id, min, max)
+		}
+	}
+
+	// Hidden groups and unfilled elements are skipped, but children of an
+	// unfilled group can set their own fill.
+	shape := mustEvalShapeWithFiles(t, `svg_document("shapes.svg", center=true);`, files)
+	if size := shape.M2.Max().Sub(shape.M2.Min()); size.Dist(model2d.XY(45, 45)) > 1e-6 {
+		t.Fatalf("unexpected size %v", size)
+	}
+	refilled := mustEvalShapeWithFiles(t, `svg_document("shapes.svg", id="unfilled");`, files)
+	if a := signedArea2D(refilled.M2); math.Abs(a-6.25) > 1e-6 {
+		t.Fatalf("expected area 6.25, got %f", a)
+	}
+	if _, err := evalWithFiles(`svg_document("shapes.svg", id="outline");`, files); err == nil ||
+		!strings.Contains(err.Error(), "no filled shapes found") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The same file is read by import().
+	imported := mustEvalShapeWithFiles(t, `import("shapes.svg", center=true);`, files)
+	if imported.Kind != ShapeMesh2D || imported.M2.NumSegments() != shape.M2.NumSegments() {
+		t.Fatalf("expected import() to match svg_document()")
+	}
+	if _, err := evalWithFiles(`svg_document("shapes.svg", layer="nope");`, files); err == nil ||
+		!strings.Contains(err.Error(), "svg_document(): shapes.svg: no layer named") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestParseSVGTransform(t *testing.T) {
+	tr, err := parseSVGTransform("translate(1 2), scale(2,3) rotate(90) skewX(45)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// skewX maps (1, 1) to (2, 1), rotate to (-1, 2), scale to (-2, 6)
+	// and translate to (-1, 8).
+	if c := tr.Apply(model2d.XY(1, 1)); c.Dist(model2d.XY(-1, 8)) > 1e-8 {
+		t.Fatalf("unexpected result %v", c)
+	}
+	for _, bad := range []string{"translate(1", "spin(3)", "scale()", "matrix(1 2 3)"} {
+		if _, err := parseSVGTransform(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
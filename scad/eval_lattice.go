@@ -0,0 +1,256 @@
+package scad
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/unixpickle/model3d/model3d"
+	shapekernel "github.com/unixpickle/webgpu-meshes/shapekernel"
+)
+
+// A tpmsKind is a triply periodic minimal surface, given as an implicit
+// function of coordinates scaled to a period of 2*pi.
+//
+// The gradient of each function has a norm of at most sqrt(3).
+type tpmsKind struct {
+	Name string
+	Func func(x, y, z float64) float64
+
+	// WGSL computes the function of a vec3f named q.
+	WGSL string
+}
+
+var tpmsKinds = map[string]tpmsKind{
+	"gyroid": {
+		Name: "gyroid",
+		Func: func(x, y, z float64) float64 {
+			return math.Sin(x)*math.Cos(y) + math.Sin(y)*math.Cos(z) + math.Sin(z)*math.Cos(x)
+		},
+		WGSL: "dot(sin(q), cos(q.yzx))",
+	},
+	"schwarz_p": {
+		Name: "schwarz_p",
+		Func: func(x, y, z float64) float64 {
+			return math.Cos(x) + math.Cos(y) + math.Cos(z)
+		},
+		WGSL: "dot(cos(q), vec3f(1.0))",
+	},
+	"diamond": {
+		Name: "diamond",
+		Func: func(x, y, z float64) float64 {
+			sx, sy, sz := math.Sin(x), math.Sin(y), math.Sin(z)
+			cx, cy, cz := math.Cos(x), math.Cos(y), math.Cos(z)
+			return sx*sy*sz + sx*cy*cz + cx*sy*cz + cx*cy*sz
+		},
+		WGSL: "s.x * s.y * s.z + s.x * c.y * c.z + c.x * s.y * c.z + c.x * c.y * s.z",
+	},
+}
+
+// A tpmsSheet is a wall around a minimal surface.
+//
+// The wall is at least Thickness thick everywhere, and thicker where
+// the gradient of the implicit function is small.
+type tpmsSheet struct {
+	Kind      tpmsKind
+	Period    float64
+	Thickness float64
+}
+
+func (t *tpmsSheet) freq() float64 {
+	return 2 * math.Pi / t.Period
+}
+
+// SDF computes an unbounded lower bound on the distance to the sheet.
+func (t *tpmsSheet) SDF(c model3d.Coord3D) float64 {
+	f := t.freq()
+	g := t.Kind.Func(c.X*f, c.Y*f, c.Z*f)
+	return t.Thickness/2 - math.Abs(g)/(math.Sqrt(3)*f)
+}
+
+// Kernel creates an unbounded SDF kernel matching SDF.
+func (t *tpmsSheet) Kernel(n shapekernel.Numerics) shapekernel.ShapeKernel {
+	k := shapekernel.ShapeKernel{Kind: shapekernel.SDF3D}
+	fnName := kernelFnID(&k.IDs, t.Kind.Name+"_sdf")
+	shapekernel.AppendWGSL(
+		&k,
+		`
+			fn {{.Entrypoint}}(p: {{.N.Dtype3}}) -> {{.N.Dtype}} {
+				let q = {{.N.AsFloat3}}(p) * {{.Freq}};
+				let s = sin(q);
+				let c = cos(q);
+				let g = {{.Expr}};
+				return {{.N.FromFloat}}({{.HalfThickness}} - abs(g) / ({{.Freq}} * 1.7320508));
+			}
+		`,
+		"N", n.Symbols,
+		"Entrypoint", fnName,
+		"Expr", t.Kind.WGSL,
+		"Freq", float32(t.freq()),
+		"HalfThickness", float32(t.Thickness/2),
+	)
+	k.EntrypointName = fnName
+	return k
+}
+
+func parseTPMSSheet(opName, kindName string, args map[string]Value) (*tpmsSheet, error) {
+	kind, ok := tpmsKinds[kindName]
+	if !ok {
+		return nil, fmt.Errorf("%s(): unknown lattice %q (expected \"gyroid\", \"schwarz_p\" or \"diamond\")",
+			opName, kindName)
+	}
+	period, err := argNum(args, "period")
+	if err != nil {
+		return nil, err
+	}
+	thickness, err := argNum(args, "thickness")
+	if err != nil {
+		return nil, err
+	}
+	if period <= 0 {
+		return nil, fmt.Errorf("%s(): period must be positive", opName)
+	}
+	if thickness <= 0 {
+		return nil, fmt.Errorf("%s(): thickness must be positive", opName)
+	}
+	return &tpmsSheet{Kind: kind, Period: period, Thickness: thickness}, nil
+}
+
+func handleGyroidSDF(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	return tpmsPrimitive(e, st, "gyroid")
+}
+
+func handleSchwarzPSDF(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	return tpmsPrimitive(e, st, "schwarz_p")
+}
+
+func handleDiamondSDF(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	return tpmsPrimitive(e, st, "diamond")
+}
+
+// tpmsPrimitive creates a lattice sheet clipped to a box.
+func tpmsPrimitive(e *env, st *CallStmt, kindName string) (ShapeRep, error) {
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "period", Pos: 0, Default: Num(10)},
+		{Name: "thickness", Pos: 1, Default: Num(1)},
+		{Name: "size", Pos: 2},
+		{Name: "center", Pos: 3, Default: Bool(false)},
+	})
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	sheet, err := parseTPMSSheet(st.Call.Name, kindName, args)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+
+	// The lattice fills a single period by default.
+	size := [3]float64{sheet.Period, sheet.Period, sheet.Period}
+	if args["size"].Kind != ValNull {
+		size, err = args["size"].AsVec3()
+		if err != nil {
+			return ShapeRep{}, err
+		}
+	}
+	if size[0] <= 0 || size[1] <= 0 || size[2] <= 0 {
+		return ShapeRep{}, fmt.Errorf("%s(): size must be positive", st.Call.Name)
+	}
+	center, err := argBool(args, "center")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	max := model3d.NewCoord3DArray(size)
+	min := model3d.Coord3D{}
+	if center {
+		min, max = max.Scale(-0.5), max.Scale(0.5)
+	}
+	rect := model3d.NewRect(min, max)
+
+	sdf := model3d.FuncSDF(min, max, func(c model3d.Coord3D) float64 {
+		return math.Min(sheet.SDF(c), rect.SDF(c))
+	})
+	k := shapekernel.IntersectSDFs(e.hooks.Numerics, []shapekernel.ShapeKernel{
+		sheet.Kernel(e.hooks.Numerics),
+		*rect3DSDFKernel(e.hooks.Numerics, rect),
+	})
+	return shapeSDF3D(sdf, &k), nil
+}
+
+func handleLatticeFill(e *env, st *CallStmt, _ []ShapeRep, childUnion *ShapeRep) (ShapeRep, error) {
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "thickness", Pos: 0, Required: true},
+		{Name: "lattice", Pos: 1, Default: String("gyroid")},
+		{Name: "period", Pos: 2, Default: Num(10)},
+		{Name: "wall", Pos: 3, Default: Num(1)},
+		{Name: "delta", Pos: -1},
+	})
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	skin, err := argNum(args, "thickness")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	if skin < 0 {
+		return ShapeRep{}, fmt.Errorf("lattice_fill(): thickness must not be negative")
+	}
+	kindName, err := argString(args, "lattice")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	// The lattice's own thickness is passed as "wall", so that the skin
+	// can use the same name as shell().
+	args["thickness"] = args["wall"]
+	sheet, err := parseTPMSSheet("lattice_fill", kindName, args)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	// childSDF3D coarsens this spacing as needed for large solids.
+	defaultDelta := sheet.Thickness / 4
+	if skin > 0 {
+		defaultDelta = math.Min(defaultDelta, skin/4)
+	}
+	sdf, k, err := childSDF3D(e, "lattice_fill", childUnion, args, defaultDelta)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+
+	// The skin and the clipped lattice are combined as
+	// max(min(d, skin - d), min(d, lattice)).
+	result := model3d.FuncSDF(sdf.Min(), sdf.Max(), func(c model3d.Coord3D) float64 {
+		d := sdf.SDF(c)
+		return math.Min(d, math.Max(skin-d, sheet.SDF(c)))
+	})
+	if k != nil {
+		k = asPtr(latticeFillKernel(e.hooks.Numerics, *k, sheet.Kernel(e.hooks.Numerics), skin))
+	}
+	return shapeSDF3D(result, k), nil
+}
+
+// latticeFillKernel creates a kernel matching lattice_fill() from the
+// kernels of the child and the lattice.
+func latticeFillKernel(n shapekernel.Numerics, child, lattice shapekernel.ShapeKernel, skin float64) shapekernel.ShapeKernel {
+	k := child
+	nextK := shapekernel.ShiftIDs(lattice, k.IDs)
+	k.IDs = nextK.IDs
+	k.Buffers = append(append([]shapekernel.Buffer{}, k.Buffers...), nextK.Buffers...)
+	k.Code += "\n" + nextK.Code
+
+	fnName := kernelFnID(&k.IDs, "lattice_fill_sdf")
+	shapekernel.AppendWGSL(
+		&k,
+		`
+			fn {{.Entrypoint}}(p: {{.N.Dtype3}}) -> {{.N.Dtype}} {
+				let d = {{.N.AsFloat}}({{.Child}}(p));
+				let lattice = {{.N.AsFloat}}({{.Lattice}}(p));
+				return {{.N.FromFloat}}(min(d, max({{.Skin}} - d, lattice)));
+			}
+		`,
+		"N", n.Symbols,
+		"Entrypoint", fnName,
+		"Child", k.EntrypointName,
+		"Lattice", nextK.EntrypointName,
+		"Skin", float32(skin),
+	)
+	k.EntrypointName = fnName
+	return k
+}
@@ -0,0 +1,146 @@
+package scad
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/model3d/model3d"
+)
+
+func TestTPMSSDF(t *testing.T) {
+	for _, name := range []string{"gyroid", "schwarz_p", "diamond"} {
+		shape := mustEvalShape(t, name+`_sdf(period=4, thickness=0.5, size=[8, 8, 4], center=true);`)
+		if shape.Kind != ShapeSDF3D || shape.Kernel == nil {
+			t.Fatalf("%s: expected SDF3D with kernel, got %v", name, shape.Kind)
+		}
+		if !strings.Contains(shape.Kernel.Code, name+"_sdf") {
+			t.Fatalf("%s: expected lattice in kernel code", name)
+		}
+		sdf := shape.SDF3
+		if sdf.Min().Dist(model3d.XYZ(-4, -4, -2)) > 1e-8 || sdf.Max().Dist(model3d.XYZ(4, 4, 2)) > 1e-8 {
+			t.Fatalf("%s: unexpected bounds %v %v", name, sdf.Min(), sdf.Max())
+		}
+		if d := sdf.SDF(model3d.XYZ(5, 0, 0)); d >= 0 {
+			t.Fatalf("%s: expected point outside the box to be outside, got %f", name, d)
+		}
+
+		// The SDF should never change faster than the distance between
+		// points, and the lattice should fill part of the box.
+		rng := rand.New(rand.NewSource(0))
+		var inside int
+		for i := 0; i < 2000; i++ {
+			c1 := model3d.NewCoord3DRandBounds(sdf.Min(), sdf.Max(), rng)
+			c2 := c1.Add(model3d.NewCoord3DRandNorm(rng).Scale(0.1))
+			d1, d2 := sdf.SDF(c1), sdf.SDF(c2)
+			if math.Abs(d1-d2) > c1.Dist(c2)+1e-8 {
+				t.Fatalf("%s: SDF is not 1-Lipschitz between %v and %v", name, c1, c2)
+			}
+			if d1 > 0 {
+				inside++
+			}
+		}
+		if inside < 100 || inside > 1900 {
+			t.Fatalf("%s: unexpected lattice fraction %d/2000", name, inside)
+		}
+	}
+
+	// The gyroid passes through the origin, and the wall is at least the
+	// requested thickness.
+	shape := mustEvalShape(t, `gyroid_sdf(10, 1, center=true);`)
+	if d := shape.SDF3.SDF(model3d.XYZ(0, 0, 0)); math.Abs(d-0.5) > 1e-8 {
+		t.Fatalf("expected half the thickness at the surface, got %f", d)
+	}
+	if max := shape.SDF3.Max(); max.Dist(model3d.XYZ(5, 5, 5)) > 1e-8 {
+		t.Fatalf("expected one period by default, got %v", max)
+	}
+}
+
+func TestLatticeFill(t *testing.T) {
+	for _, src := range []string{
+		`lattice_fill(1, "schwarz_p", period=5, wall=0.5) cube_sdf(20, center=true);`,
+		`lattice_fill(1, "schwarz_p", period=5, wall=0.5, delta=0.2) cube(20, center=true);`,
+	} {
+		shape := mustEvalShape(t, src)
+		if shape.Kind != ShapeSDF3D {
+			t.Fatalf("%s: expected SDF3D, got %v", src, shape.Kind)
+		}
+		if shape.Kernel == nil || !strings.Contains(shape.Kernel.Code, "lattice_fill_sdf") {
+			t.Fatalf("%s: expected lattice_fill kernel", src)
+		}
+		sdf := shape.SDF3
+		if sdf.Max().X < 10 || sdf.Max().X > 11 {
+			t.Fatalf("%s: unexpected bounds %v", src, sdf.Max())
+		}
+		// The skin is solid.
+		if d := sdf.SDF(model3d.XYZ(9.5, 1.2, 1.3)); d <= 0 {
+			t.Fatalf("%s: expected the skin to be solid, got %f", src, d)
+		}
+		// The Schwarz P surface is far from the center of each cell.
+		if d := sdf.SDF(model3d.XYZ(0, 0, 0)); d >= 0 {
+			t.Fatalf("%s: expected the center of a cell to be empty, got %f", src, d)
+		}
+		if d := sdf.SDF(model3d.XYZ(1.25, 1.25, 1.25)); d <= 0 {
+			t.Fatalf("%s: expected the lattice to be solid, got %f", src, d)
+		}
+		if d := sdf.SDF(model3d.XYZ(12, 0, 0)); d >= 0 {
+			t.Fatalf("%s: expected the outside to be empty, got %f", src, d)
+		}
+	}
+}
+
+func TestLatticeFillDefaultDelta(t *testing.T) {
+	var delta float64
+	hooks := Hooks{
+		MarchingCubes: func(obj ShapeRep, d float64, iters int) (*model3d.Mesh, error) {
+			delta = d
+			return model3d.NewMeshRect(obj.S3.Min(), obj.S3.Max()), nil
+		},
+	}
+	for _, tc := range []struct {
+		src  string
+		want float64
+	}{
+		// The thinner of the skin and the wall sets the spacing for small
+		// solids, and the number of cells limits it for large ones.
+		{`lattice_fill(1, wall=0.5) cube(10);`, 0.125},
+		{`lattice_fill(0.2, wall=0.5) cube(256);`, 2},
+		{`lattice_fill(0.2, wall=0.5, delta=0.5) cube(256);`, 0.5},
+	} {
+		prog, err := Parse(tc.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Eval(prog, hooks); err != nil {
+			t.Fatalf("%s: %v", tc.src, err)
+		}
+		if math.Abs(delta-tc.want) > 1e-8 {
+			t.Fatalf("%s: expected delta %f, got %f", tc.src, tc.want, delta)
+		}
+	}
+}
+
+func TestLatticeErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`gyroid_sdf(period=0);`, "period must be positive"},
+		{`diamond_sdf(10, thickness=-1);`, "thickness must be positive"},
+		{`schwarz_p_sdf(10, 1, size=[1, 0, 1]);`, "size must be positive"},
+		{`lattice_fill(-1) sphere_sdf(r=1);`, "must not be negative"},
+		{`lattice_fill(1, "honeycomb") sphere_sdf(r=1);`, "unknown lattice"},
+		{`lattice_fill(1) circle_sdf(r=1);`, "requires a 3D SDF, solid, or mesh"},
+	}
+	for _, tc := range tests {
+		prog, err := Parse(tc.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = Eval(prog, Hooks{})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", tc.src, tc.want, err)
+		}
+	}
+}
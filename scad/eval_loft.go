@@ -0,0 +1,347 @@
+package scad
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/unixpickle/model3d/model2d"
+	"github.com/unixpickle/model3d/model3d"
+	shapekernel "github.com/unixpickle/webgpu-meshes/shapekernel"
+)
+
+func handleLoft(e *env, st *CallStmt, children []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "heights", Pos: 0},
+		{Name: "height", Pos: -1, Default: Num(1)},
+	})
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	if len(children) < 2 {
+		return ShapeRep{}, fmt.Errorf("loft(): requires at least two profiles")
+	}
+	kind, err := ensureSameKind(children)
+	if err != nil {
+		return ShapeRep{}, fmt.Errorf("loft(): %w", err)
+	}
+	heights, err := loftHeights(args, len(children))
+	if err != nil {
+		return ShapeRep{}, err
+	}
+
+	switch kind {
+	case ShapeMesh2D:
+		loops := make([][]model2d.Coord, len(children))
+		for i, ch := range children {
+			loops[i], err = loftProfileLoop(ch.M2)
+			if err != nil {
+				return ShapeRep{}, err
+			}
+		}
+		return shapeMesh3D(loftMesh(matchLoftLoops(loops), heights)), nil
+	case ShapeSDF2D:
+		sdfs := make([]model2d.SDF, len(children))
+		for i, ch := range children {
+			sdfs[i] = ch.SDF2
+		}
+		sdf := newLoftSDF(sdfs, heights)
+		var k *shapekernel.ShapeKernel
+		if kernels, ok := concatKernels(children); ok {
+			k = asPtr(loftSDFKernel(e.hooks.Numerics, kernels, heights, sdf.Lipschitz))
+		}
+		return shapeSDF3D(sdf, k), nil
+	default:
+		return ShapeRep{}, fmt.Errorf("loft(): requires 2D SDF or mesh children")
+	}
+}
+
+// loftHeights parses the height of each profile, which may either be
+// given explicitly or spaced evenly up to a total height.
+func loftHeights(args map[string]Value, numProfiles int) ([]float64, error) {
+	heights := make([]float64, numProfiles)
+	if v := args["heights"]; v.Kind != ValNull {
+		if v.Kind != ValList {
+			return nil, fmt.Errorf("loft(): heights must be a list of numbers")
+		}
+		if len(v.List) != numProfiles {
+			return nil, fmt.Errorf("loft(): expected %d heights for %d profiles, got %d",
+				numProfiles, numProfiles, len(v.List))
+		}
+		for i, h := range v.List {
+			if h.Kind != ValNum {
+				return nil, fmt.Errorf("loft(): heights must be a list of numbers")
+			}
+			heights[i] = h.Num
+		}
+	} else {
+		height, err := argNum(args, "height")
+		if err != nil {
+			return nil, err
+		}
+		for i := range heights {
+			heights[i] = height * float64(i) / float64(numProfiles-1)
+		}
+	}
+	for i := 1; i < len(heights); i++ {
+		if heights[i] <= heights[i-1] {
+			return nil, fmt.Errorf("loft(): heights must be strictly increasing")
+		}
+	}
+	return heights, nil
+}
+
+// loftProfileLoop extracts the single closed loop of a profile mesh, in
+// counter-clockwise order.
+func loftProfileLoop(m *model2d.Mesh) ([]model2d.Coord, error) {
+	segs := m.SegmentSlice()
+	if len(segs) < 3 {
+		return nil, fmt.Errorf("loft(): mesh profiles must each be a single closed loop")
+	}
+	m, err := repairedProfileMesh("loft", m)
+	if err != nil {
+		return nil, err
+	}
+	segs = m.SegmentSlice()
+	next := make(map[model2d.Coord]*model2d.Segment, len(segs))
+	for _, seg := range segs {
+		if _, ok := next[seg[0]]; ok {
+			return nil, fmt.Errorf("loft(): mesh profiles must each be a single closed loop")
+		}
+		next[seg[0]] = seg
+	}
+	loop := make([]model2d.Coord, 0, len(segs))
+	for seg := segs[0]; len(loop) == 0 || seg != segs[0]; seg = next[seg[1]] {
+		loop = append(loop, seg[0])
+	}
+	if len(loop) != len(segs) {
+		return nil, fmt.Errorf("loft(): mesh profiles must each be a single closed loop")
+	}
+
+	var area float64
+	for i, c := range loop {
+		area += c.X*loop[(i+1)%len(loop)].Y - c.Y*loop[(i+1)%len(loop)].X
+	}
+	if area < 0 {
+		for i, j := 0, len(loop)-1; i < j; i, j = i+1, j-1 {
+			loop[i], loop[j] = loop[j], loop[i]
+		}
+	}
+	return loop, nil
+}
+
+// matchLoftLoops gives every loop the same number of vertices, and
+// rotates each loop so that its vertices line up with the previous one.
+//
+// Loops with fewer vertices are refined by splitting their longest
+// edges, which keeps their shape unchanged.
+func matchLoftLoops(loops [][]model2d.Coord) [][]model2d.Coord {
+	var count int
+	for _, loop := range loops {
+		count = max(count, len(loop))
+	}
+	res := make([][]model2d.Coord, len(loops))
+	for i, loop := range loops {
+		for len(loop) < count {
+			var longest int
+			var longestLen float64
+			for j, c := range loop {
+				if l := c.Dist(loop[(j+1)%len(loop)]); l > longestLen {
+					longest, longestLen = j, l
+				}
+			}
+			mid := loop[longest].Mid(loop[(longest+1)%len(loop)])
+			loop = append(append(append([]model2d.Coord{}, loop[:longest+1]...), mid), loop[longest+1:]...)
+		}
+		if i > 0 {
+			prev := res[i-1]
+			bestShift, bestCost := 0, math.Inf(1)
+			for shift := 0; shift < count; shift++ {
+				var cost float64
+				for j, c := range prev {
+					cost += c.SquaredDist(loop[(j+shift)%count])
+				}
+				if cost < bestCost {
+					bestShift, bestCost = shift, cost
+				}
+			}
+			loop = append(append([]model2d.Coord{}, loop[bestShift:]...), loop[:bestShift]...)
+		}
+		res[i] = loop
+	}
+	return res
+}
+
+// loftMesh connects matched counter-clockwise loops at the given
+// heights, capping the bottom and top.
+func loftMesh(loops [][]model2d.Coord, heights []float64) *model3d.Mesh {
+	res := model3d.NewMesh()
+	at := func(level, i int) model3d.Coord3D {
+		loop := loops[level]
+		return model3d.XYZ(loop[i%len(loop)].X, loop[i%len(loop)].Y, heights[level])
+	}
+	for level := 0; level+1 < len(loops); level++ {
+		for i := range loops[level] {
+			res.Add(&model3d.Triangle{at(level, i), at(level, i+1), at(level+1, i+1)})
+			res.Add(&model3d.Triangle{at(level, i), at(level+1, i+1), at(level+1, i)})
+		}
+	}
+	for _, level := range []int{0, len(loops) - 1} {
+		capMesh := model2d.NewMesh()
+		loop := loops[level]
+		for i, c := range loop {
+			// Triangulation expects clockwise loops.
+			capMesh.Add(&model2d.Segment{loop[(i+1)%len(loop)], c})
+		}
+		for _, t := range model2d.TriangulateMesh(capMesh) {
+			tri := &model3d.Triangle{
+				model3d.XYZ(t[0].X, t[0].Y, heights[level]),
+				model3d.XYZ(t[1].X, t[1].Y, heights[level]),
+				model3d.XYZ(t[2].X, t[2].Y, heights[level]),
+			}
+			if (tri.Normal().Z > 0) != (level > 0) {
+				tri[0], tri[1] = tri[1], tri[0]
+			}
+			res.Add(tri)
+		}
+	}
+	return res
+}
+
+// A loftSDF linearly interpolates between profile SDFs at a sequence of
+// heights, and caps the result at the first and last heights.
+//
+// Interpolation stretches distances along Z by the change between
+// neighboring profiles, so the interpolated value is divided by a
+// Lipschitz bound to keep it a lower bound on the true distance.
+type loftSDF struct {
+	Profiles  []model2d.SDF
+	Heights   []float64
+	Lipschitz float64
+
+	min model3d.Coord3D
+	max model3d.Coord3D
+}
+
+func newLoftSDF(profiles []model2d.SDF, heights []float64) *loftSDF {
+	res := &loftSDF{Profiles: profiles, Heights: heights, Lipschitz: 1}
+	min2, max2 := profiles[0].Min(), profiles[0].Max()
+	for i, p := range profiles[1:] {
+		min2, max2 = min2.Min(p.Min()), max2.Max(p.Max())
+		diff := maxLoftProfileDiff(profiles[i], p)
+		slope := diff / (heights[i+1] - heights[i])
+		res.Lipschitz = math.Max(res.Lipschitz, math.Sqrt(1+slope*slope))
+	}
+	res.min = model3d.XYZ(min2.X, min2.Y, heights[0])
+	res.max = model3d.XYZ(max2.X, max2.Y, heights[len(heights)-1])
+	return res
+}
+
+// maxLoftProfileDiff bounds the largest difference between two profile
+// SDFs anywhere in the plane.
+//
+// The difference is at most the Hausdorff distance between the shapes
+// outside of both bounding boxes, and this is attained inside of the
+// boxes. Inside the boxes, the difference is sampled on a grid, and
+// grows by at most twice the distance to the nearest sample.
+func maxLoftProfileDiff(p1, p2 model2d.SDF) float64 {
+	const gridSize = 32
+	min, max := p1.Min().Min(p2.Min()), p1.Max().Max(p2.Max())
+	step := max.Sub(min).Scale(1.0 / (gridSize - 1))
+	var res float64
+	for i := 0; i < gridSize; i++ {
+		for j := 0; j < gridSize; j++ {
+			c := min.Add(model2d.XY(float64(i)*step.X, float64(j)*step.Y))
+			res = math.Max(res, math.Abs(p1.SDF(c)-p2.SDF(c)))
+		}
+	}
+	return res + step.Norm()
+}
+
+func (l *loftSDF) Min() model3d.Coord3D {
+	return l.min
+}
+
+func (l *loftSDF) Max() model3d.Coord3D {
+	return l.max
+}
+
+func (l *loftSDF) SDF(c model3d.Coord3D) float64 {
+	h := l.Heights
+	z := math.Max(h[0], math.Min(h[len(h)-1], c.Z))
+	i := 0
+	for i+2 < len(h) && z > h[i+1] {
+		i++
+	}
+	t := (z - h[i]) / (h[i+1] - h[i])
+	p := c.XY()
+	d := ((1-t)*l.Profiles[i].SDF(p) + t*l.Profiles[i+1].SDF(p)) / l.Lipschitz
+	return math.Min(d, math.Min(c.Z-h[0], h[len(h)-1]-c.Z))
+}
+
+// loftSDFKernel creates a kernel matching a loftSDF from the kernels of
+// its profiles.
+func loftSDFKernel(
+	n shapekernel.Numerics,
+	kernels []shapekernel.ShapeKernel,
+	heights []float64,
+	lipschitz float64,
+) shapekernel.ShapeKernel {
+	k := kernels[0]
+	k.Buffers = append([]shapekernel.Buffer{}, k.Buffers...)
+	names := []string{k.EntrypointName}
+	for _, next := range kernels[1:] {
+		next = shapekernel.ShiftIDs(next, k.IDs)
+		k.IDs = next.IDs
+		k.Buffers = append(k.Buffers, next.Buffers...)
+		k.Code += "\n" + next.Code
+		names = append(names, next.EntrypointName)
+	}
+
+	// Profiles are interpolated in a chain of branches, one per pair of
+	// neighboring heights.
+	var branches string
+	for i := 0; i+1 < len(heights); i++ {
+		cond := "else if (z <= {{.H1}})"
+		if i == 0 {
+			cond = "if (z <= {{.H1}})"
+		} else if i+2 == len(heights) {
+			cond = "else"
+		}
+		branches += shapekernel.WGSL(
+			cond+` {
+				let t = (z - {{.H0}}) / ({{.H1}} - {{.H0}});
+				d = mix({{.N.AsFloat}}({{.F0}}(q)), {{.N.AsFloat}}({{.F1}}(q)), t);
+			} `,
+			"N", n.Symbols,
+			"F0", names[i],
+			"F1", names[i+1],
+			"H0", float32(heights[i]),
+			"H1", float32(heights[i+1]),
+		)
+	}
+
+	fnName := kernelFnID(&k.IDs, "loft_sdf")
+	shapekernel.AppendWGSL(
+		&k,
+		`
+			fn {{.Entrypoint}}(p_raw: {{.N.Dtype3}}) -> {{.N.Dtype}} {
+				let p = {{.N.AsFloat3}}(p_raw);
+				let q = {{.N.Make2}}({{.N.FromFloat}}(p.x), {{.N.FromFloat}}(p.y));
+				let z = clamp(p.z, {{.ZMin}}, {{.ZMax}});
+				var d = 0.0;
+				{{.Branches}}
+				d = d / {{.Lipschitz}};
+				return {{.N.FromFloat}}(min(d, min(p.z - {{.ZMin}}, {{.ZMax}} - p.z)));
+			}
+		`,
+		"N", n.Symbols,
+		"Entrypoint", fnName,
+		"Branches", branches,
+		"ZMin", float32(heights[0]),
+		"ZMax", float32(heights[len(heights)-1]),
+		"Lipschitz", float32(lipschitz),
+	)
+	k.Kind = shapekernel.SDF3D
+	k.EntrypointName = fnName
+	return k
+}
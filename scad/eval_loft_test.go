@@ -0,0 +1,131 @@
+package scad
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/model3d/model3d"
+)
+
+func TestLoftMesh(t *testing.T) {
+	// Square profiles make a frustum.
+	shape := mustEvalShape(t, `
+		loft([0, 2]) {
+			path_mesh("M-1,-1 L1,-1 L1,1 L-1,1 Z");
+			path_mesh("M-0.5,-0.5 L0.5,-0.5 L0.5,0.5 L-0.5,0.5 Z");
+		}
+	`)
+	if shape.Kind != ShapeMesh3D {
+		t.Fatalf("expected Mesh3D, got %v", shape.Kind)
+	}
+	if shape.M3.NeedsRepair() {
+		t.Fatal("mesh needs repair")
+	}
+	if v := shape.M3.Volume(); math.Abs(v-14.0/3) > 1e-8 {
+		t.Fatalf("expected volume %f, got %f", 14.0/3, v)
+	}
+
+	// Profiles with different vertex counts and orientations are matched
+	// up, and the middle profile is placed evenly.
+	shape = mustEvalShape(t, `
+		loft(height=4) {
+			polygon_mesh([for (i = [0:31]) 2 * [cos(i * 360 / 32), sin(i * 360 / 32)]]);
+			polygon_mesh([[-1, -1], [-1, 1], [1, 1], [1, -1]]);
+			translate([1, 0]) polygon_mesh([[-1, -1], [1, -1], [1, 1], [-1, 1]]);
+		}
+	`)
+	if shape.M3.NeedsRepair() {
+		t.Fatal("mesh needs repair")
+	}
+	if n := len(shape.M3.SingularVertices()); n != 0 {
+		t.Fatalf("unexpected %d singular vertices", n)
+	}
+	min, max := shape.M3.Min(), shape.M3.Max()
+	if min.Dist(model3d.XYZ(-2, -2, 0)) > 1e-8 || max.Dist(model3d.XYZ(2, 2, 4)) > 1e-8 {
+		t.Fatalf("unexpected bounds %v %v", min, max)
+	}
+	// The bottom half is between a prism of the square and one of the
+	// circle, and the top half is skewed but keeps the area of the square.
+	if v := shape.M3.Volume(); v < 4*2+4 || v > 4*math.Pi*2+4*2 {
+		t.Fatalf("unexpected volume %f", v)
+	}
+}
+
+func TestLoftSDF(t *testing.T) {
+	shape := mustEvalShape(t, `
+		loft([0, 1, 3]) {
+			circle_sdf(r=2);
+			circle_sdf(r=1);
+			translate([1, 0]) square_sdf(size=2, center=true);
+		}
+	`)
+	if shape.Kind != ShapeSDF3D || shape.Kernel == nil {
+		t.Fatalf("expected SDF3D with kernel, got %v", shape.Kind)
+	}
+	sdf := shape.SDF3
+	if d := sdf.SDF(model3d.XYZ(1.4, 0, 0.5)); d <= 0 {
+		t.Fatalf("expected point between the circles to be inside, got %f", d)
+	}
+	if d := sdf.SDF(model3d.XYZ(1.6, 0, 0.5)); d >= 0 {
+		t.Fatalf("expected point outside the circles to be outside, got %f", d)
+	}
+	if d := sdf.SDF(model3d.XYZ(1.9, 0.9, 2.99)); d <= 0 {
+		t.Fatalf("expected point in the top square to be inside, got %f", d)
+	}
+	if d := sdf.SDF(model3d.XYZ(0, 0, 3.5)); math.Abs(d+0.5) > 1e-8 {
+		t.Fatalf("expected distance to the top cap, got %f", d)
+	}
+
+	// Circles interpolate to cones, which can be compared to a mesh to
+	// check that the SDF is a lower bound on the true distance.
+	sdf = mustEvalShape(t, `
+		loft([0, 1, 3]) { circle_sdf(r=2); circle_sdf(r=1); circle_sdf(r=1.5); }
+	`).SDF3
+	mesh := mustEvalShape(t, `
+		function ring(r) = [for (i = [0:255]) r * [cos(i * 360 / 256), sin(i * 360 / 256)]];
+		loft([0, 1, 3]) {
+			polygon_mesh(ring(2));
+			polygon_mesh(ring(1));
+			polygon_mesh(ring(1.5));
+		}
+	`)
+	meshSDF := model3d.MeshToSDF(mesh.M3)
+	rng := rand.New(rand.NewSource(0))
+	for i := 0; i < 2000; i++ {
+		c := model3d.NewCoord3DRandBounds(model3d.XYZ(-4, -4, -1), model3d.XYZ(4, 4, 4), rng)
+		if d, expected := sdf.SDF(c), meshSDF.SDF(c); (d > 0) != (expected > 0) || math.Abs(d) > math.Abs(expected)+1e-3 {
+			t.Fatalf("point %v: SDF %f exceeds mesh distance %f", c, d, expected)
+		}
+	}
+}
+
+func TestLoftErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`loft() circle_sdf(r=1);`, "at least two profiles"},
+		{`loft() { circle_sdf(r=1); polygon_mesh([[0, 0], [1, 0], [0, 1]]); }`, "mixed shape kinds"},
+		{`loft() { circle(r=1); circle(r=2); }`, "requires 2D SDF or mesh children"},
+		{`loft([0, 1, 2]) { circle_sdf(r=1); circle_sdf(r=2); }`, "expected 2 heights"},
+		{`loft([1, 0]) { circle_sdf(r=1); circle_sdf(r=2); }`, "strictly increasing"},
+		{`
+			loft() {
+				polygon_mesh([[0, 0], [1, 0], [0, 1]]);
+				path_mesh("M0,0 L1,0 L0,1 Z M2,0 L3,0 L2,1 Z");
+			}
+		`, "single closed loop"},
+	}
+	for _, tc := range tests {
+		prog, err := Parse(tc.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = Eval(prog, Hooks{})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", tc.src, tc.want, err)
+		}
+	}
+}
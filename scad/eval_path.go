@@ -31,10 +31,14 @@ func handlePathMesh(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep,
 	return shapeMesh2D(mesh), nil
 }
 
+// parsePathMesh samples an SVG path. Without a fill rule or stroke width,
+// the sampled curves are used as-is.
 func parsePathMesh(e *env, st *CallStmt) (*model2d.Mesh, error) {
 	args, err := bindArgs(e, st.Call, []ArgSpec{
 		{Name: "path", Pos: 0, Required: true},
 		{Name: "segments", Pos: 1, Default: Num(1000)},
+		{Name: "fill_rule", Pos: -1},
+		{Name: "stroke_width", Pos: -1, Default: Num(0)},
 	})
 	if err != nil {
 		return nil, err
@@ -53,15 +57,57 @@ func parsePathMesh(e *env, st *CallStmt) (*model2d.Mesh, error) {
 	if segments < 1 {
 		return nil, fmt.Errorf("path(): segments must be >= 1")
 	}
+	strokeWidth, err := argNum(args, "stroke_width")
+	if err != nil {
+		return nil, err
+	}
+	if strokeWidth < 0 {
+		return nil, fmt.Errorf("path(): stroke_width must be non-negative")
+	}
+	var rule *fillRule
+	if v := args["fill_rule"]; v.Kind != ValNull {
+		if strokeWidth > 0 {
+			return nil, fmt.Errorf("path(): fill_rule cannot be combined with stroke_width")
+		}
+		s, err := v.AsString()
+		if err != nil {
+			return nil, fmt.Errorf("path(): fill_rule: %w", err)
+		}
+		r, err := parseFillRule("path", s)
+		if err != nil {
+			return nil, err
+		}
+		rule = &r
+	}
 	curves, err := path2d.ParseSVGPath(path)
 	if err != nil {
 		return nil, fmt.Errorf("path(): %w", err)
 	}
-	mesh := model2d.NewMesh()
-	for _, curve := range curves {
-		mesh.AddMesh(model2d.CurveMesh(curve, int(segments)))
+
+	var mesh *model2d.Mesh
+	if strokeWidth > 0 {
+		var polylines [][]model2d.Coord
+		for _, curve := range curves {
+			polylines = append(polylines, svgCurvePoints(curve, int(segments)))
+		}
+		mesh = strokeMesh2D(polylines, strokeWidth)
+	} else {
+		mesh = model2d.NewMesh()
+		for _, curve := range curves {
+			mesh.AddMesh(model2d.CurveMesh(curve, int(segments)))
+			if rule != nil {
+				// Fill rules need closed loops, so open subpaths are
+				// closed with a straight line like in SVG.
+				if start, end := curve.Eval(0), curve.Eval(1); start != end {
+					mesh.Add(&model2d.Segment{end, start})
+				}
+			}
+		}
+		if rule != nil {
+			mesh = fillMesh2D(mesh, *rule)
+		}
 	}
-	if mesh == nil || mesh.NumSegments() == 0 {
+	if mesh.NumSegments() == 0 {
 		return nil, fmt.Errorf("path(): no segments produced")
 	}
 	return mesh, nil
@@ -3,8 +3,10 @@ package scad
 import (
 	"math"
 	"math/rand"
+	"strings"
 	"testing"
 
+	"github.com/unixpickle/model3d/model2d"
 	"github.com/unixpickle/model3d/model3d"
 )
 
@@ -143,3 +145,55 @@ func TestPathMatchesPrimitive(t *testing.T) {
 		})
 	}
 }
+
+func TestPathFillRuleAndStroke(t *testing.T) {
+	polygonArea := func(n int) float64 {
+		return float64(n) / 2 * math.Sin(2*math.Pi/float64(n))
+	}
+	tests := []struct {
+		src  string
+		area float64
+	}{
+		{`path_mesh("M0 0 H4 V4 H0 Z M1 1 H3 V3 H1 Z", fill_rule="nonzero");`, 16},
+		{`path_mesh("M0 0 H4 V4 H0 Z M1 1 H3 V3 H1 Z", fill_rule="evenodd");`, 12},
+
+		// Open subpaths are closed for filling.
+		{`path_mesh("M0 0 L4 0 L4 4", fill_rule="nonzero");`, 8},
+
+		// An open line has round caps, and a closed square has round
+		// outer corners.
+		{`path_mesh("M0 0 L10 0", stroke_width=2);`, 20 + polygonArea(strokeArcSegments)},
+		{`path_mesh("M0 0 H10 V10 H0 Z", stroke_width=2);`, 12*12 - 4 + polygonArea(strokeArcSegments) - 8*8},
+	}
+	for _, tc := range tests {
+		shape := mustEvalShape(t, tc.src)
+		if !shape.M2.Manifold() {
+			t.Fatalf("%s: expected manifold mesh", tc.src)
+		}
+		if a := signedArea2D(shape.M2); math.Abs(a-tc.area) > 1e-6 {
+			t.Fatalf("%s: expected area %f, got %f", tc.src, tc.area, a)
+		}
+	}
+
+	stroke := mustEvalShape(t, `path("M0 0 Q5 10 10 0", stroke_width=1, segments=50);`)
+	if stroke.Kind != ShapeSolid2D || stroke.Kernel == nil {
+		t.Fatalf("expected Solid2D with kernel, got %v", stroke.Kind)
+	}
+	if !stroke.S2.Contains(model2d.XY(5, 5)) || stroke.S2.Contains(model2d.XY(5, 3)) {
+		t.Fatal("unexpected containment for stroked curve")
+	}
+
+	for src, want := range map[string]string{
+		`path("M0 0 L1 0 L1 1 Z", fill_rule="winding");`:                 "fill_rule must be",
+		`path("M0 0 L1 0", stroke_width=-1);`:                            "stroke_width must be non-negative",
+		`path("M0 0 L1 0 L1 1 Z", stroke_width=1, fill_rule="nonzero");`: "cannot be combined",
+	} {
+		prog, err := Parse(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Eval(prog, Hooks{}); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%s: expected error containing %q, got %v", src, want, err)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package scad
 
 import (
 	"fmt"
+	"math"
 
 	"github.com/unixpickle/model3d/model2d"
 	"github.com/unixpickle/model3d/model3d"
@@ -248,6 +249,255 @@ func handleSquareSDF(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep,
 	return shapeSDF2D(rect, rect2DSDFKernel(e.hooks.Numerics, rect)), nil
 }
 
+func handleTorus(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	torus, err := parseTorus(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return primitiveShape3D(e, torus, ShapeSolid3D), nil
+}
+
+func handleTorusMetaball(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	torus, err := parseTorus(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return primitiveShape3D(e, torus, ShapeMetaball3D), nil
+}
+
+func handleTorusSDF(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	torus, err := parseTorus(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return primitiveShape3D(e, torus, ShapeSDF3D), nil
+}
+
+func handleCone(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	cone, err := parseCone(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return primitiveShape3D(e, cone, ShapeSolid3D), nil
+}
+
+func handleConeMetaball(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	cone, err := parseCone(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return primitiveShape3D(e, cone, ShapeMetaball3D), nil
+}
+
+func handleConeSDF(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	cone, err := parseCone(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return primitiveShape3D(e, cone, ShapeSDF3D), nil
+}
+
+func handleEllipsoid(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	ellipsoid, err := parseEllipsoid(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return primitiveShape3D(e, ellipsoid, ShapeSolid3D), nil
+}
+
+func handleEllipsoidMetaball(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	ellipsoid, err := parseEllipsoid(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return primitiveShape3D(e, ellipsoid, ShapeMetaball3D), nil
+}
+
+func handleEllipsoidSDF(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	ellipsoid, err := parseEllipsoid(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return primitiveShape3D(e, ellipsoid, ShapeSDF3D), nil
+}
+
+func handleWedge(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	wedge, err := parseWedge(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return primitiveShape3D(e, wedge, ShapeSolid3D), nil
+}
+
+func handleWedgeMetaball(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	wedge, err := parseWedge(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return primitiveShape3D(e, wedge, ShapeMetaball3D), nil
+}
+
+func handleWedgeSDF(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	wedge, err := parseWedge(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return primitiveShape3D(e, wedge, ShapeSDF3D), nil
+}
+
+func handlePrism(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	prism, err := parsePrism(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return primitiveShape3D(e, prism, ShapeSolid3D), nil
+}
+
+func handlePrismMetaball(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	prism, err := parsePrism(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return primitiveShape3D(e, prism, ShapeMetaball3D), nil
+}
+
+func handlePrismSDF(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	prism, err := parsePrism(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return primitiveShape3D(e, prism, ShapeSDF3D), nil
+}
+
+func handleRegularPolygon(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	polygon, err := parseRegularPolygon(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return primitiveShape2D(e, polygon, ShapeSolid2D), nil
+}
+
+func handleRegularPolygonMetaball(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	polygon, err := parseRegularPolygon(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return primitiveShape2D(e, polygon, ShapeMetaball2D), nil
+}
+
+func handleRegularPolygonSDF(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	polygon, err := parseRegularPolygon(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return primitiveShape2D(e, polygon, ShapeSDF2D), nil
+}
+
+func handleStar(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	star, err := parseStar(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return primitiveShape2D(e, star, ShapeSolid2D), nil
+}
+
+func handleStarMetaball(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	star, err := parseStar(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return primitiveShape2D(e, star, ShapeMetaball2D), nil
+}
+
+func handleStarSDF(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	star, err := parseStar(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return primitiveShape2D(e, star, ShapeSDF2D), nil
+}
+
+func handleRoundedCube(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	box, err := parseRoundedCube(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return primitiveShape3D(e, box, ShapeSolid3D), nil
+}
+
+func handleRoundedCubeSDF(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	box, err := parseRoundedCube(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return primitiveShape3D(e, box, ShapeSDF3D), nil
+}
+
+func handleRoundedCylinder(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	cyl, err := parseRoundedCylinder(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return primitiveShape3D(e, cyl, ShapeSolid3D), nil
+}
+
+func handleRoundedCylinderSDF(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	cyl, err := parseRoundedCylinder(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return primitiveShape3D(e, cyl, ShapeSDF3D), nil
+}
+
+func handleRoundedSquare(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	rect, err := parseRoundedSquare(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return primitiveShape2D(e, rect, ShapeSolid2D), nil
+}
+
+func handleRoundedSquareSDF(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	rect, err := parseRoundedSquare(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return primitiveShape2D(e, rect, ShapeSDF2D), nil
+}
+
+// primitiveShape3D wraps a primitive as a solid, metaball, or SDF
+// depending on kind.
+func primitiveShape3D(e *env, shape SolidSDF, kind ShapeKind) ShapeRep {
+	switch kind {
+	case ShapeSolid3D:
+		return shapeSolid3D(shape, primitiveSolidKernel3D(e.hooks.Numerics, shape))
+	case ShapeMetaball3D:
+		k := primitiveSDFKernel3D(e.hooks.Numerics, shape)
+		if k != nil {
+			k = asPtr(shapekernel.SDFToMetaball(e.hooks.Numerics, *k))
+		}
+		return shapeMetaball3D(shape, k)
+	default:
+		return shapeSDF3D(shape, primitiveSDFKernel3D(e.hooks.Numerics, shape))
+	}
+}
+
+// primitiveShape2D is like primitiveShape3D for 2D primitives.
+func primitiveShape2D(e *env, shape solidSDF2D, kind ShapeKind) ShapeRep {
+	switch kind {
+	case ShapeSolid2D:
+		return shapeSolid2D(shape, primitiveSolidKernel2D(e.hooks.Numerics, shape))
+	case ShapeMetaball2D:
+		k := primitiveSDFKernel2D(e.hooks.Numerics, shape)
+		if k != nil {
+			k = asPtr(shapekernel.SDFToMetaball(e.hooks.Numerics, *k))
+		}
+		return shapeMetaball2D(shape, k)
+	default:
+		return shapeSDF2D(shape, primitiveSDFKernel2D(e.hooks.Numerics, shape))
+	}
+}
+
 func handleLineJoin(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
 	args, err := bindArgs(e, st.Call, []ArgSpec{
 		{Name: "points", Pos: 0, Required: true},
@@ -631,7 +881,12 @@ func parseCylinder(e *env, st *CallStmt) (SolidSDF, error) {
 	if r1 < 0 || r2 < 0 {
 		return nil, fmt.Errorf("cylinder(): radii must be non-negative")
 	}
+	return cylinderPrimitive(h, r1, r2, center), nil
+}
 
+// cylinderPrimitive creates a cylinder, cone, or frustum along the Z
+// axis.
+func cylinderPrimitive(h, r1, r2 float64, center bool) SolidSDF {
 	z0 := 0.0
 	z1 := h
 	if center {
@@ -645,28 +900,28 @@ func parseCylinder(e *env, st *CallStmt) (SolidSDF, error) {
 			P1:     p1,
 			P2:     p2,
 			Radius: r1,
-		}, nil
+		}
 	}
 	if r1 == 0 {
 		return &model3d.Cone{
 			Tip:    p1,
 			Base:   p2,
 			Radius: r2,
-		}, nil
+		}
 	}
 	if r2 == 0 {
 		return &model3d.Cone{
 			Tip:    p2,
 			Base:   p1,
 			Radius: r1,
-		}, nil
+		}
 	}
 	return &model3d.ConeSlice{
 		P1: p1,
 		P2: p2,
 		R1: r1,
 		R2: r2,
-	}, nil
+	}
 }
 
 func parseCapsule(e *env, st *CallStmt) (*model3d.Capsule, error) {
@@ -769,6 +1024,324 @@ func parseSquare(e *env, st *CallStmt) (*model2d.Rect, error) {
 	), nil
 }
 
+func parseTorus(e *env, st *CallStmt) (*model3d.Torus, error) {
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "r_maj", Pos: 0, Default: Num(1)},
+		{Name: "r_min", Pos: 1, Default: Num(0.25)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	rMaj, err := argNum(args, "r_maj")
+	if err != nil {
+		return nil, err
+	}
+	rMin, err := argNum(args, "r_min")
+	if err != nil {
+		return nil, err
+	}
+	if rMaj < 0 {
+		return nil, fmt.Errorf("torus(): r_maj must be non-negative")
+	}
+	if rMin <= 0 {
+		return nil, fmt.Errorf("torus(): r_min must be positive")
+	}
+	return &model3d.Torus{
+		Axis:        model3d.Z(1),
+		OuterRadius: rMaj,
+		InnerRadius: rMin,
+	}, nil
+}
+
+func parseCone(e *env, st *CallStmt) (SolidSDF, error) {
+	bound, err := bindArgsDetailed(e, st.Call, []ArgSpec{
+		{Name: "h", Pos: 0, Default: Num(1)},
+		{Name: "r1", Pos: 1, Default: Num(1)},
+		{Name: "r2", Pos: 2, Default: Num(0)},
+		{Name: "center", Pos: 3, Default: Bool(false)},
+		{Name: "d1", Pos: -1, Default: Value{}},
+		{Name: "d2", Pos: -1, Default: Value{}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	h, err := argNum(bound.Values, "h")
+	if err != nil {
+		return nil, err
+	}
+	center, err := argBool(bound.Values, "center")
+	if err != nil {
+		return nil, err
+	}
+	var radii [2]float64
+	for i, name := range []string{"1", "2"} {
+		if bound.NamedProvided["d"+name] {
+			if bound.Provided["r"+name] {
+				return nil, fmt.Errorf("cone(): cannot provide both r%s and d%s", name, name)
+			}
+			d, err := argNum(bound.Values, "d"+name)
+			if err != nil {
+				return nil, err
+			}
+			radii[i] = d / 2
+		} else {
+			radii[i], err = argNum(bound.Values, "r"+name)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if h < 0 {
+		return nil, fmt.Errorf("cone(): h must be non-negative")
+	}
+	if radii[0] < 0 || radii[1] < 0 {
+		return nil, fmt.Errorf("cone(): radii must be non-negative")
+	}
+	return cylinderPrimitive(h, radii[0], radii[1], center), nil
+}
+
+func parseEllipsoid(e *env, st *CallStmt) (*ellipsoid, error) {
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "r", Pos: 0, Default: Num(1)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	r, err := argVec3(args, "r")
+	if err != nil {
+		return nil, err
+	}
+	if r[0] <= 0 || r[1] <= 0 || r[2] <= 0 {
+		return nil, fmt.Errorf("ellipsoid(): radii must be positive")
+	}
+	return &ellipsoid{Radii: model3d.NewCoord3DArray(r)}, nil
+}
+
+func parseWedge(e *env, st *CallStmt) (*prismPrimitive, error) {
+	rect, err := parseCube(e, st)
+	if err != nil {
+		return nil, err
+	}
+	min, max := rect.Min(), rect.Max()
+	if min.X >= max.X || min.Y >= max.Y || min.Z >= max.Z {
+		return nil, fmt.Errorf("wedge(): size must be positive")
+	}
+	// The slope rises from the front bottom edge to the back top edge.
+	return &prismPrimitive{
+		Profile: &triangle2D{
+			model2d.XY(min.Y, min.Z),
+			model2d.XY(max.Y, min.Z),
+			model2d.XY(max.Y, max.Z),
+		},
+		Axis:  0,
+		Start: min.X,
+		End:   max.X,
+	}, nil
+}
+
+func parsePrism(e *env, st *CallStmt) (*prismPrimitive, error) {
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "n", Pos: 0, Required: true},
+		{Name: "r", Pos: 1, Default: Num(1)},
+		{Name: "h", Pos: 2, Default: Num(1)},
+		{Name: "center", Pos: 3, Default: Bool(false)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	polygon, err := parseRegularPolygonArgs("prism", args)
+	if err != nil {
+		return nil, err
+	}
+	h, err := argNum(args, "h")
+	if err != nil {
+		return nil, err
+	}
+	if h < 0 {
+		return nil, fmt.Errorf("prism(): h must be non-negative")
+	}
+	center, err := argBool(args, "center")
+	if err != nil {
+		return nil, err
+	}
+	z0, z1 := 0.0, h
+	if center {
+		z0, z1 = -h/2, h/2
+	}
+	return &prismPrimitive{Profile: polygon, Axis: 2, Start: z0, End: z1}, nil
+}
+
+func parseRegularPolygon(e *env, st *CallStmt) (*starPolygon, error) {
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "n", Pos: 0, Required: true},
+		{Name: "r", Pos: 1, Default: Num(1)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseRegularPolygonArgs("regular_polygon", args)
+}
+
+func parseRegularPolygonArgs(opName string, args map[string]Value) (*starPolygon, error) {
+	n, err := argNum(args, "n")
+	if err != nil {
+		return nil, err
+	}
+	if n < 3 || n != math.Floor(n) {
+		return nil, fmt.Errorf("%s(): n must be an integer >= 3", opName)
+	}
+	r, err := argNum(args, "r")
+	if err != nil {
+		return nil, err
+	}
+	if r <= 0 {
+		return nil, fmt.Errorf("%s(): r must be positive", opName)
+	}
+	return newRegularPolygon(int(n), r), nil
+}
+
+func parseStar(e *env, st *CallStmt) (*starPolygon, error) {
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "n", Pos: 0, Required: true},
+		{Name: "r1", Pos: 1, Default: Num(1)},
+		{Name: "r2", Pos: 2},
+	})
+	if err != nil {
+		return nil, err
+	}
+	n, err := argNum(args, "n")
+	if err != nil {
+		return nil, err
+	}
+	if n < 2 || n != math.Floor(n) {
+		return nil, fmt.Errorf("star(): n must be an integer >= 2")
+	}
+	r1, err := argNum(args, "r1")
+	if err != nil {
+		return nil, err
+	}
+	r2 := r1 / 2
+	if args["r2"].Kind != ValNull {
+		r2, err = argNum(args, "r2")
+		if err != nil {
+			return nil, err
+		}
+	}
+	if r1 <= 0 || r2 <= 0 {
+		return nil, fmt.Errorf("star(): radii must be positive")
+	}
+	return &starPolygon{N: int(n), R1: r1, R2: r2}, nil
+}
+
+func parseRoundedCube(e *env, st *CallStmt) (*roundedBox3D, error) {
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "size", Pos: 0, Default: Num(1)},
+		{Name: "r", Pos: 1, Required: true},
+		{Name: "center", Pos: 2, Default: Bool(false)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	size, err := argVec3(args, "size")
+	if err != nil {
+		return nil, err
+	}
+	r, err := argNum(args, "r")
+	if err != nil {
+		return nil, err
+	}
+	if r < 0 {
+		return nil, fmt.Errorf("rounded_cube(): r must be non-negative")
+	}
+	if 2*r > size[0] || 2*r > size[1] || 2*r > size[2] {
+		return nil, fmt.Errorf("rounded_cube(): r must be at most half of each side")
+	}
+	center, err := argBool(args, "center")
+	if err != nil {
+		return nil, err
+	}
+	max := model3d.NewCoord3DArray(size)
+	min := model3d.Coord3D{}
+	if center {
+		min, max = max.Scale(-0.5), max.Scale(0.5)
+	}
+	return &roundedBox3D{MinVal: min, MaxVal: max, Radius: r}, nil
+}
+
+func parseRoundedCylinder(e *env, st *CallStmt) (*roundedCylinder, error) {
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "h", Pos: 0, Default: Num(1)},
+		{Name: "r", Pos: 1, Default: Num(1)},
+		{Name: "edge_r", Pos: 2, Required: true},
+		{Name: "center", Pos: 3, Default: Bool(false)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	h, err := argNum(args, "h")
+	if err != nil {
+		return nil, err
+	}
+	r, err := argNum(args, "r")
+	if err != nil {
+		return nil, err
+	}
+	edgeR, err := argNum(args, "edge_r")
+	if err != nil {
+		return nil, err
+	}
+	if edgeR < 0 {
+		return nil, fmt.Errorf("rounded_cylinder(): edge_r must be non-negative")
+	}
+	if edgeR > r || 2*edgeR > h {
+		return nil, fmt.Errorf("rounded_cylinder(): edge_r must be at most r and half of h")
+	}
+	center, err := argBool(args, "center")
+	if err != nil {
+		return nil, err
+	}
+	z0, z1 := 0.0, h
+	if center {
+		z0, z1 = -h/2, h/2
+	}
+	return &roundedCylinder{Z0: z0, Z1: z1, Radius: r, EdgeRadius: edgeR}, nil
+}
+
+func parseRoundedSquare(e *env, st *CallStmt) (*roundedBox2D, error) {
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "size", Pos: 0, Default: Num(1)},
+		{Name: "r", Pos: 1, Required: true},
+		{Name: "center", Pos: 2, Default: Bool(false)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	size, err := argVec2(args, "size")
+	if err != nil {
+		return nil, err
+	}
+	r, err := argNum(args, "r")
+	if err != nil {
+		return nil, err
+	}
+	if r < 0 {
+		return nil, fmt.Errorf("rounded_square(): r must be non-negative")
+	}
+	if 2*r > size[0] || 2*r > size[1] {
+		return nil, fmt.Errorf("rounded_square(): r must be at most half of each side")
+	}
+	center, err := argBool(args, "center")
+	if err != nil {
+		return nil, err
+	}
+	max := model2d.XY(size[0], size[1])
+	min := model2d.Coord{}
+	if center {
+		min, max = max.Scale(-0.5), max.Scale(0.5)
+	}
+	return &roundedBox2D{MinVal: min, MaxVal: max, Radius: r}, nil
+}
+
 func parsePolygonPoints(val Value) ([]model2d.Coord, error) {
 	if val.Kind != ValList {
 		return nil, fmt.Errorf("polygon(): points must be a list")
@@ -787,17 +1360,18 @@ func parsePolygonPoints(val Value) ([]model2d.Coord, error) {
 	return points, nil
 }
 
+// parseLineJoinPoints parses [x, y, z] points, or [x, y] points at z=0.
 func parseLineJoinPoints(val Value) ([]model3d.Coord3D, error) {
 	if val.Kind != ValList {
 		return nil, fmt.Errorf("line_join(): points must be a list")
 	}
 	points := make([]model3d.Coord3D, 0, len(val.List))
 	for _, v := range val.List {
-		if v.Kind != ValList || len(v.List) != 3 {
+		if v.Kind != ValList || (len(v.List) != 2 && len(v.List) != 3) {
 			return nil, fmt.Errorf("line_join(): points must be a list of [x, y, z] vectors")
 		}
 		xyz := [3]float64{}
-		for i := range xyz {
+		for i := range v.List {
 			n, err := v.List[i].AsNum()
 			if err != nil {
 				return nil, err
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"strings"
 	"testing"
 
 	"github.com/unixpickle/model3d/model2d"
@@ -707,3 +708,288 @@ func assertSolids3DEqual(t *testing.T, a, b model3d.Solid) {
 		}
 	}
 }
+
+func TestExtraPrimitiveVariants(t *testing.T) {
+	for _, tc := range []struct {
+		call string
+		dim  int
+	}{
+		{"torus(2, 0.5)", 3},
+		{"cone(h=2, r1=1, r2=0.5)", 3},
+		{"ellipsoid([1, 2, 3])", 3},
+		{"wedge([1, 2, 3])", 3},
+		{"prism(6, r=1, h=2)", 3},
+		{"regular_polygon(5, r=2)", 2},
+		{"star(5, 2, 1)", 2},
+	} {
+		name := tc.call[:strings.Index(tc.call, "(")]
+		args := tc.call[len(name):]
+		for suffix, kind := range map[string][2]ShapeKind{
+			"":          {ShapeSolid2D, ShapeSolid3D},
+			"_metaball": {ShapeMetaball2D, ShapeMetaball3D},
+			"_sdf":      {ShapeSDF2D, ShapeSDF3D},
+		} {
+			src := name + suffix + args + ";"
+			shape := mustEvalShape(t, src)
+			if shape.Kind != kind[tc.dim-2] {
+				t.Fatalf("%s: expected %v, got %v", src, kind[tc.dim-2], shape.Kind)
+			}
+			k := shape.Kernel
+			if shape.MB2 != nil {
+				k = shape.MB2.Kernels[0]
+			} else if shape.MB3 != nil {
+				k = shape.MB3.Kernels[0]
+			}
+			if k == nil {
+				t.Fatalf("%s: expected kernel", src)
+			}
+		}
+	}
+}
+
+func TestExtraPrimitiveSDFs2D(t *testing.T) {
+	for _, tc := range []struct {
+		sdf    string
+		points []model2d.Coord
+	}{
+		{
+			sdf: "regular_polygon_sdf(6, r=2);",
+			points: []model2d.Coord{
+				model2d.XY(2, 0),
+				model2d.XY(1, math.Sqrt(3)),
+				model2d.XY(-1, math.Sqrt(3)),
+				model2d.XY(-2, 0),
+				model2d.XY(-1, -math.Sqrt(3)),
+				model2d.XY(1, -math.Sqrt(3)),
+			},
+		},
+		{
+			sdf:    "star_sdf(3, 2, 0.5);",
+			points: (&starPolygon{N: 3, R1: 2, R2: 0.5}).Vertices(),
+		},
+	} {
+		sdf := mustEvalShape(t, tc.sdf).SDF2
+		mesh, err := polygonPathMesh(tc.points, defaultPolygonPath(len(tc.points)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := model2d.MeshToSDF(mesh)
+		if sdf.Min().Dist(expected.Min()) > 1e-8 || sdf.Max().Dist(expected.Max()) > 1e-8 {
+			t.Fatalf("%s: unexpected bounds %v %v", tc.sdf, sdf.Min(), sdf.Max())
+		}
+		rng := rand.New(rand.NewSource(0))
+		for i := 0; i < 1000; i++ {
+			c := model2d.NewCoordRandBounds(model2d.XY(-3, -3), model2d.XY(3, 3), rng)
+			if actual, exp := sdf.SDF(c), expected.SDF(c); math.Abs(actual-exp) > 1e-8 {
+				t.Fatalf("%s: point %v: expected SDF %f, got %f", tc.sdf, c, exp, actual)
+			}
+		}
+	}
+}
+
+func TestExtraPrimitiveSDFs3D(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+
+	// Prisms and wedges match extruded polygon meshes.
+	for _, tc := range [][2]string{
+		{
+			"prism_sdf(4, r=1, h=2, center=true);",
+			"linear_extrude(2, center=true) polygon_mesh([[1, 0], [0, 1], [-1, 0], [0, -1]]);",
+		},
+		{
+			"wedge_sdf([1, 2, 3]);",
+			"rotate([90, 0, 90]) linear_extrude(1) polygon_mesh([[0, 0], [2, 0], [2, 3]]);",
+		},
+	} {
+		sdf := mustEvalShape(t, tc[0]).SDF3
+		expected := model3d.MeshToSDF(mustEvalShape(t, tc[1]).M3)
+		if sdf.Min().Dist(expected.Min()) > 1e-8 || sdf.Max().Dist(expected.Max()) > 1e-8 {
+			t.Fatalf("%s: unexpected bounds %v %v", tc[0], sdf.Min(), sdf.Max())
+		}
+		for i := 0; i < 1000; i++ {
+			c := model3d.NewCoord3DRandBounds(model3d.XYZ(-2, -2, -2), model3d.XYZ(3, 3, 4), rng)
+			if actual, exp := sdf.SDF(c), expected.SDF(c); math.Abs(actual-exp) > 1e-8 {
+				t.Fatalf("%s: point %v: expected SDF %f, got %f", tc[0], c, exp, actual)
+			}
+		}
+	}
+
+	// Cones match the equivalent cylinder.
+	cone := mustEvalShape(t, "cone_sdf(h=2, d1=2);").SDF3
+	cyl := mustEvalShape(t, "cylinder_sdf(h=2, r1=1, r2=0);").SDF3
+	for i := 0; i < 100; i++ {
+		c := model3d.NewCoord3DRandBounds(model3d.XYZ(-2, -2, -1), model3d.XYZ(2, 2, 3), rng)
+		if cone.SDF(c) != cyl.SDF(c) {
+			t.Fatalf("point %v: cone SDF %f differs from cylinder SDF %f", c, cone.SDF(c), cyl.SDF(c))
+		}
+	}
+
+	torus := mustEvalShape(t, "torus_sdf(2, 0.5);").SDF3
+	if d := torus.SDF(model3d.XYZ(0, 2, 0)); math.Abs(d-0.5) > 1e-8 {
+		t.Fatalf("unexpected SDF in the tube: %f", d)
+	}
+	if d := torus.SDF(model3d.XYZ(0, 0, 1)); math.Abs(d-(0.5-math.Sqrt(5))) > 1e-8 {
+		t.Fatalf("unexpected SDF in the hole: %f", d)
+	}
+
+	// Ellipsoid distances match the nearest of many surface points.
+	radii := model3d.XYZ(1, 2, 3)
+	ellipsoid := mustEvalShape(t, "ellipsoid_sdf([1, 2, 3]);").SDF3
+	var surface []model3d.Coord3D
+	for i := 0; i <= 300; i++ {
+		theta := math.Pi * float64(i) / 300
+		for j := 0; j < 600; j++ {
+			phi := 2 * math.Pi * float64(j) / 600
+			dir := model3d.XYZ(math.Sin(theta)*math.Cos(phi), math.Sin(theta)*math.Sin(phi), math.Cos(theta))
+			surface = append(surface, dir.Mul(radii))
+		}
+	}
+	points := []model3d.Coord3D{
+		model3d.XYZ(0, 0, 0),
+		model3d.XYZ(0.5, 0, 0),
+		model3d.XYZ(0.2, 1.5, 0),
+		model3d.XYZ(0, 0, 2.5),
+		model3d.XYZ(3, 3, 3),
+	}
+	for i := 0; i < 50; i++ {
+		points = append(points, model3d.NewCoord3DRandBounds(radii.Scale(-1.5), radii.Scale(1.5), rng))
+	}
+	for _, c := range points {
+		expected := math.Inf(1)
+		for _, p := range surface {
+			expected = math.Min(expected, p.Dist(c))
+		}
+		actual := ellipsoid.SDF(c)
+		if math.Abs(math.Abs(actual)-expected) > 0.02 || actual > expected {
+			t.Fatalf("point %v: expected |SDF| %f, got %f", c, expected, actual)
+		}
+		if (actual > 0) != (c.Div(radii).Norm() < 1) {
+			t.Fatalf("point %v: unexpected sign of SDF %f", c, actual)
+		}
+	}
+}
+
+func TestExtraPrimitiveErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`torus(1, 0);`, "r_min must be positive"},
+		{`cone(h=1, r1=1, d1=2);`, "cannot provide both r1 and d1"},
+		{`cone_sdf(h=-1);`, "h must be non-negative"},
+		{`ellipsoid([1, 0, 1]);`, "radii must be positive"},
+		{`wedge_metaball([1, 0, 1]);`, "size must be positive"},
+		{`prism(2.5);`, "n must be an integer >= 3"},
+		{`regular_polygon_sdf(6, r=0);`, "r must be positive"},
+		{`star(1);`, "n must be an integer >= 2"},
+		{`star(5, 1, -1);`, "radii must be positive"},
+	}
+	for _, tc := range tests {
+		prog, err := Parse(tc.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = Eval(prog, Hooks{})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", tc.src, tc.want, err)
+		}
+	}
+}
+
+func TestRoundedPrimitives(t *testing.T) {
+	box := mustEvalShape(t, "rounded_cube_sdf([4, 6, 8], 1);")
+	if box.Kind != ShapeSDF3D || box.Kernel == nil {
+		t.Fatalf("expected SDF3D with kernel, got %v", box.Kind)
+	}
+	if box.SDF3.Min() != (model3d.Coord3D{}) || box.SDF3.Max() != model3d.XYZ(4, 6, 8) {
+		t.Fatalf("unexpected bounds %v %v", box.SDF3.Min(), box.SDF3.Max())
+	}
+	for _, tc := range []struct {
+		c model3d.Coord3D
+		d float64
+	}{
+		{model3d.XYZ(2, 3, 4), 2},
+		{model3d.XYZ(4, 3, 4), 0},
+		{model3d.XYZ(2, 3, 9), -1},
+		{model3d.XYZ(0, 0, 0), 1 - math.Sqrt(3)},
+		{model3d.XYZ(1, 1, 4), 1},
+	} {
+		if d := box.SDF3.SDF(tc.c); math.Abs(d-tc.d) > 1e-8 {
+			t.Fatalf("rounded_cube: point %v: expected SDF %f, got %f", tc.c, tc.d, d)
+		}
+	}
+
+	cyl := mustEvalShape(t, "rounded_cylinder_sdf(h=4, r=2, edge_r=0.5, center=true);")
+	if cyl.SDF3.Min() != model3d.XYZ(-2, -2, -2) || cyl.SDF3.Max() != model3d.XYZ(2, 2, 2) {
+		t.Fatalf("unexpected bounds %v %v", cyl.SDF3.Min(), cyl.SDF3.Max())
+	}
+	for _, tc := range []struct {
+		c model3d.Coord3D
+		d float64
+	}{
+		{model3d.XYZ(0, 0, 0), 2},
+		{model3d.XYZ(0, 2, 0), 0},
+		{model3d.XYZ(0, 0, -2), 0},
+		{model3d.XYZ(2, 0, 2), 0.5 - math.Sqrt(0.5)},
+		{model3d.XYZ(0, 0, 3), -1},
+	} {
+		if d := cyl.SDF3.SDF(tc.c); math.Abs(d-tc.d) > 1e-8 {
+			t.Fatalf("rounded_cylinder: point %v: expected SDF %f, got %f", tc.c, tc.d, d)
+		}
+	}
+
+	rect := mustEvalShape(t, "rounded_square_sdf([2, 4], 0.5, center=true);")
+	if rect.Kind != ShapeSDF2D || rect.Kernel == nil {
+		t.Fatalf("expected SDF2D with kernel, got %v", rect.Kind)
+	}
+	if rect.SDF2.Min() != model2d.XY(-1, -2) || rect.SDF2.Max() != model2d.XY(1, 2) {
+		t.Fatalf("unexpected bounds %v %v", rect.SDF2.Min(), rect.SDF2.Max())
+	}
+	if d := rect.SDF2.SDF(model2d.XY(1, 2)); math.Abs(d-(0.5-math.Sqrt(0.5))) > 1e-8 {
+		t.Fatalf("unexpected SDF at the corner: %f", d)
+	}
+
+	// The solid variants match the SDFs.
+	rng := rand.New(rand.NewSource(0))
+	for _, pair := range [][2]string{
+		{"rounded_cube([4, 6, 8], 1);", "rounded_cube_sdf([4, 6, 8], 1);"},
+		{"rounded_cylinder(4, 2, 0.5);", "rounded_cylinder_sdf(4, 2, 0.5);"},
+	} {
+		solid := mustEvalShape(t, pair[0])
+		if solid.Kind != ShapeSolid3D || solid.Kernel == nil {
+			t.Fatalf("%s: expected Solid3D with kernel, got %v", pair[0], solid.Kind)
+		}
+		sdf := mustEvalShape(t, pair[1]).SDF3
+		for i := 0; i < 1000; i++ {
+			c := model3d.NewCoord3DRandBounds(model3d.XYZ(-3, -3, -1), model3d.XYZ(5, 7, 9), rng)
+			if solid.S3.Contains(c) != (sdf.SDF(c) >= 0) {
+				t.Fatalf("%s: mismatch at %v", pair[0], c)
+			}
+		}
+	}
+	if shape := mustEvalShape(t, "rounded_square(2, 0.5);"); shape.Kind != ShapeSolid2D || shape.Kernel == nil {
+		t.Fatalf("expected Solid2D with kernel, got %v", shape.Kind)
+	}
+}
+
+func TestRoundedPrimitiveErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`rounded_cube(10);`, "missing"},
+		{`rounded_cube([10, 2, 10], 1.5);`, "at most half of each side"},
+		{`rounded_square_sdf(1, -1);`, "r must be non-negative"},
+		{`rounded_cylinder(h=1, r=2, edge_r=1);`, "at most r and half of h"},
+	}
+	for _, tc := range tests {
+		prog, err := Parse(tc.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = Eval(prog, Hooks{})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", tc.src, tc.want, err)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package scad
 
 import (
 	"fmt"
+	"math"
 
 	"github.com/unixpickle/model3d/model2d"
 	"github.com/unixpickle/model3d/model3d"
@@ -202,6 +203,19 @@ func (h *Hull2D) Join(other *Hull2D) *Hull2D {
 	return out
 }
 
+// ShapeTags is appearance and metadata attached to a shape by color(),
+// material() and part().
+type ShapeTags struct {
+	// HasColor is true if Color was set.
+	HasColor bool
+
+	// Color is an RGBA color with components in [0, 1].
+	Color [4]float64
+
+	Material string
+	Part     string
+}
+
 type ShapeRep struct {
 	Kind ShapeKind
 	S2   model2d.Solid
@@ -215,6 +229,23 @@ type ShapeRep struct {
 	H2   *Hull2D
 
 	Kernel *shapekernel.ShapeKernel
+
+	// Tags applies to the entire shape when Parts is empty.
+	Tags ShapeTags
+
+	// Parts holds differently tagged pieces of a union, which together
+	// make up the same geometry as the shape itself. Parts never have
+	// parts of their own.
+	Parts []ShapeRep
+}
+
+// TaggedParts returns the parts of the shape, or the shape itself if it
+// is not split into differently tagged parts.
+func (s ShapeRep) TaggedParts() []ShapeRep {
+	if len(s.Parts) > 0 {
+		return s.Parts
+	}
+	return []ShapeRep{s}
 }
 
 func shapeSolid2D(s model2d.Solid, k *shapekernel.ShapeKernel) ShapeRep {
@@ -294,6 +325,55 @@ func SDFToSolid(n shapekernel.Numerics, sdf ShapeRep) ShapeRep {
 	}
 }
 
+// solidToSDF approximates the distance field of a plain solid by
+// meshing it with the given grid spacing.
+func solidToSDF(e *env, opName string, solid ShapeRep, delta float64) (ShapeRep, error) {
+	switch solid.Kind {
+	case ShapeSolid2D:
+		mesh, err := e.hooks.MarchingSquares(solid, delta, 8)
+		if err != nil {
+			return ShapeRep{}, fmt.Errorf("%s(): %w", opName, err)
+		}
+		return shapeSDF2D(model2d.MeshToSDF(mesh), asPtr(shapekernel.Mesh2DSDF(e.hooks.Numerics, mesh))), nil
+	case ShapeSolid3D:
+		mesh, err := e.hooks.MarchingCubes(solid, delta, 8)
+		if err != nil {
+			return ShapeRep{}, fmt.Errorf("%s(): %w", opName, err)
+		}
+		return shapeSDF3D(model3d.MeshToSDF(mesh), asPtr(shapekernel.Mesh3DSDF(e.hooks.Numerics, mesh))), nil
+	default:
+		panic("expected solid argument")
+	}
+}
+
+// solidSDFMaxCells is the largest number of grid cells used to mesh a
+// solid in solidToSDF when no grid spacing is given.
+const solidSDFMaxCells = 1 << 21
+
+// solidSDFDelta picks a default grid spacing for solidToSDF.
+//
+// This is featureDelta unless meshing the bounds of the solid would
+// then take more than solidSDFMaxCells cells, in which case the
+// spacing is increased to stay within that limit.
+func solidSDFDelta(solid ShapeRep, featureDelta float64) float64 {
+	var size []float64
+	switch solid.Kind {
+	case ShapeSolid2D:
+		s := solid.S2.Max().Sub(solid.S2.Min())
+		size = []float64{s.X, s.Y}
+	case ShapeSolid3D:
+		s := solid.S3.Max().Sub(solid.S3.Min())
+		size = []float64{s.X, s.Y, s.Z}
+	default:
+		panic("expected solid argument")
+	}
+	volume := 1.0
+	for _, x := range size {
+		volume *= math.Max(x, featureDelta)
+	}
+	return math.Max(featureDelta, math.Pow(volume/solidSDFMaxCells, 1/float64(len(size))))
+}
+
 func handleSolid(e *env, st *CallStmt, _ []ShapeRep, childUnion *ShapeRep) (ShapeRep, error) {
 	if _, err := bindArgs(e, st.Call, []ArgSpec{}); err != nil {
 		return ShapeRep{}, err
@@ -319,6 +399,56 @@ func handleSolid(e *env, st *CallStmt, _ []ShapeRep, childUnion *ShapeRep) (Shap
 }
 
 func unionAll(n shapekernel.Numerics, children []ShapeRep) (ShapeRep, error) {
+	res, err := unionGeometry(n, children)
+	if err != nil || len(children) < 2 {
+		return res, err
+	}
+	res.Tags, res.Parts = joinTags(children)
+	return res, nil
+}
+
+// joinTags combines the tags of shapes that are being unioned.
+// If the shapes have different tags, the result is split into parts.
+func joinTags(shapes []ShapeRep) (ShapeTags, []ShapeRep) {
+	var parts []ShapeRep
+	for _, s := range shapes {
+		parts = append(parts, s.TaggedParts()...)
+	}
+	for _, p := range parts[1:] {
+		if p.Tags != parts[0].Tags {
+			return ShapeTags{}, parts
+		}
+	}
+	return parts[0].Tags, nil
+}
+
+// checkBlendTags reports an error if a blended union would merge
+// differently tagged children, since its fillets join the children into
+// a single surface which cannot be split back into parts.
+func checkBlendTags(opName string, children []ShapeRep) error {
+	if len(children) < 2 {
+		return nil
+	}
+	if _, parts := joinTags(children); len(parts) > 0 {
+		return fmt.Errorf(
+			"%s(): cannot blend children with different colors, materials or parts; apply them after blending",
+			opName,
+		)
+	}
+	return nil
+}
+
+// inheritTags gives the result of a builtin the tags of its children if
+// the builtin did not set any. As in OpenSCAD, an operation on several
+// differently tagged children takes the tags of the first one.
+func inheritTags(res *ShapeRep, children []ShapeRep) {
+	if res.Tags != (ShapeTags{}) || len(res.Parts) > 0 || len(children) == 0 {
+		return
+	}
+	res.Tags = children[0].TaggedParts()[0].Tags
+}
+
+func unionGeometry(n shapekernel.Numerics, children []ShapeRep) (ShapeRep, error) {
 	if len(children) == 0 {
 		return ShapeRep{}, fmt.Errorf("no shapes produced")
 	}
@@ -384,18 +514,8 @@ func unionAll(n shapekernel.Numerics, children []ShapeRep) (ShapeRep, error) {
 			out = out.Join(ch.H2)
 		}
 		return shapeHull2D(out), nil
-	case ShapeMesh3D:
-		out := model3d.NewMesh()
-		for _, ch := range children {
-			out.AddMesh(ch.M3)
-		}
-		return shapeMesh3D(out), nil
-	case ShapeMesh2D:
-		out := model2d.NewMesh()
-		for _, ch := range children {
-			out.AddMesh(ch.M2)
-		}
-		return shapeMesh2D(out), nil
+	case ShapeMesh3D, ShapeMesh2D:
+		return meshBooleanAll("union", children, meshUnion)
 	default:
 		return ShapeRep{}, fmt.Errorf("unknown shape kind")
 	}
@@ -447,12 +567,73 @@ func intersectAll(n shapekernel.Numerics, children []ShapeRep) (ShapeRep, error)
 		}
 		return shapeSDF2D(sdfIntersect2D(children), k), nil
 	case ShapeMesh2D, ShapeMesh3D:
-		return ShapeRep{}, fmt.Errorf("intersection() not supported for meshes")
+		return meshBooleanAll("intersection", children, meshIntersection)
 	default:
 		return ShapeRep{}, fmt.Errorf("intersection(): unknown shape kind")
 	}
 }
 
+// meshBooleanAll folds a boolean operation over mesh children from left
+// to right.
+//
+// 3D meshes must be closed, except that a union of open meshes falls
+// back to concatenating their triangles.
+func meshBooleanAll(opName string, children []ShapeRep, op meshBooleanOp) (ShapeRep, error) {
+	if children[0].Kind == ShapeMesh2D {
+		meshes := make([]*model2d.Mesh, len(children))
+		for i, ch := range children {
+			meshes[i] = ch.M2
+		}
+		return shapeMesh2D(clipMeshes2D(meshes, func(w []int) bool {
+			switch op {
+			case meshUnion:
+				for _, x := range w {
+					if x != 0 {
+						return true
+					}
+				}
+				return false
+			case meshIntersection:
+				for _, x := range w {
+					if x == 0 {
+						return false
+					}
+				}
+				return true
+			default:
+				for _, x := range w[1:] {
+					if x != 0 {
+						return false
+					}
+				}
+				return w[0] != 0
+			}
+		})), nil
+	}
+
+	for _, ch := range children {
+		if ch.M3.NeedsRepair() {
+			if op != meshUnion {
+				return ShapeRep{}, fmt.Errorf("%s(): meshes must be closed", opName)
+			}
+			out := model3d.NewMesh()
+			for _, ch := range children {
+				out.AddMesh(ch.M3)
+			}
+			return shapeMesh3D(out), nil
+		}
+	}
+	out := children[0].M3
+	for _, ch := range children[1:] {
+		var err error
+		out, err = meshBoolean3D(out, ch.M3, op)
+		if err != nil {
+			return ShapeRep{}, fmt.Errorf("%s(): %w", opName, err)
+		}
+	}
+	return shapeMesh3D(out), nil
+}
+
 func ensureSameKind(children []ShapeRep) (ShapeKind, error) {
 	if len(children) == 0 {
 		return ShapeSolid3D, fmt.Errorf("no shapes produced")
@@ -0,0 +1,327 @@
+package scad
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/unixpickle/model3d/model2d"
+	"github.com/unixpickle/model3d/model3d"
+)
+
+// evalShapeLitExpr evaluates a shape { ... } expression.
+func evalShapeLitExpr(e *env, x *ShapeLitExpr) (Value, error) {
+	e.push()
+	defer e.pop()
+	shape, err := evalStmtsAsOne(e, x.Body)
+	if err != nil {
+		return Value{}, err
+	}
+	if shape == nil {
+		return Value{}, PosErrorf(x.P, "shape literal produced no geometry")
+	}
+	return ShapeValue(*shape), nil
+}
+
+// handleInstance implements instance(), which adds a shape value captured
+// with a shape { ... } expression back to the output.
+func handleInstance(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "shape", Pos: 0, Required: true},
+	})
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return argShape(args, "instance", "shape")
+}
+
+// evalShapeBounds implements bounds(), which returns [min, max] corners of
+// a shape with the same sampling as bounds_of().
+func evalShapeBounds(e *env, c Call) (Value, error) {
+	args, err := bindArgs(e, c, []ArgSpec{
+		{Name: "shape", Pos: 0, Required: true},
+		{Name: "samples", Pos: 1, Default: Num(64)},
+	})
+	if err != nil {
+		return Value{}, err
+	}
+	shape, err := argQueryShape(args, "bounds")
+	if err != nil {
+		return Value{}, err
+	}
+	min, max, err := parseBoundsSamples(args, "bounds", &shape)
+	if err != nil {
+		return Value{}, err
+	}
+	dim := shape.Kind.Dimension()
+	return List([]Value{coordValue(min, dim), coordValue(max, dim)}), nil
+}
+
+// evalShapeVolume implements volume(), which measures a 3D shape.
+func evalShapeVolume(e *env, c Call) (Value, error) {
+	shape, samples, err := bindShapeMeasure(e, c, "volume")
+	if err != nil {
+		return Value{}, err
+	}
+	if shape.Kind.Dimension() != 3 {
+		return Value{}, fmt.Errorf("volume(): requires a 3D shape; use area() for 2D shapes")
+	}
+	mesh, err := shapeMeasureMesh3D(e, "volume", shape, samples)
+	if err != nil {
+		return Value{}, err
+	}
+	volume, _ := meshMoments3D(mesh)
+	return Num(math.Abs(volume)), nil
+}
+
+// evalShapeArea implements area(), which measures the area of a 2D shape
+// or the surface area of a 3D shape.
+func evalShapeArea(e *env, c Call) (Value, error) {
+	shape, samples, err := bindShapeMeasure(e, c, "area")
+	if err != nil {
+		return Value{}, err
+	}
+	if shape.Kind.Dimension() == 3 {
+		mesh, err := shapeMeasureMesh3D(e, "area", shape, samples)
+		if err != nil {
+			return Value{}, err
+		}
+		return Num(mesh.Area()), nil
+	}
+	mesh, err := shapeMeasureMesh2D(e, "area", shape, samples)
+	if err != nil {
+		return Value{}, err
+	}
+	area, _ := meshMoments2D(mesh)
+	return Num(math.Abs(area)), nil
+}
+
+// evalShapeCentroid implements centroid(), which returns the center of mass
+// of a shape, assuming uniform density.
+func evalShapeCentroid(e *env, c Call) (Value, error) {
+	shape, samples, err := bindShapeMeasure(e, c, "centroid")
+	if err != nil {
+		return Value{}, err
+	}
+	if shape.Kind.Dimension() == 3 {
+		mesh, err := shapeMeasureMesh3D(e, "centroid", shape, samples)
+		if err != nil {
+			return Value{}, err
+		}
+		volume, moment := meshMoments3D(mesh)
+		if volume == 0 {
+			return Value{}, fmt.Errorf("centroid(): shape has zero volume")
+		}
+		return coordValue(moment.Scale(1/volume), 3), nil
+	}
+	mesh, err := shapeMeasureMesh2D(e, "centroid", shape, samples)
+	if err != nil {
+		return Value{}, err
+	}
+	area, moment := meshMoments2D(mesh)
+	if area == 0 {
+		return Value{}, fmt.Errorf("centroid(): shape has zero area")
+	}
+	return coordValue(xyToCoord3D(moment.Scale(1/area)), 2), nil
+}
+
+// evalShapeContains implements contains(), which checks if a point is
+// inside of a shape.
+func evalShapeContains(e *env, c Call) (Value, error) {
+	args, err := bindArgs(e, c, []ArgSpec{
+		{Name: "shape", Pos: 0, Required: true},
+		{Name: "point", Pos: 1, Required: true},
+	})
+	if err != nil {
+		return Value{}, err
+	}
+	shape, err := argQueryShape(args, "contains")
+	if err != nil {
+		return Value{}, err
+	}
+	dim := shape.Kind.Dimension()
+	point := args["point"]
+	if point.Kind != ValList || len(point.List) != dim {
+		return Value{}, fmt.Errorf("contains(): point must be a %dD point to match the shape", dim)
+	}
+	xyz, err := point.AsVec3()
+	if err != nil {
+		return Value{}, fmt.Errorf("contains(): point: %w", err)
+	}
+	p := model3d.NewCoord3DArray(xyz)
+	switch shape.Kind {
+	case ShapeMesh2D:
+		return Bool(shape.M2.Solid().Contains(p.XY())), nil
+	case ShapeMesh3D:
+		return Bool(shape.M3.Solid().Contains(p)), nil
+	}
+	solid, err := shapeMeasureSolid(e, "contains", shape)
+	if err != nil {
+		return Value{}, err
+	}
+	if dim == 2 {
+		return Bool(solid.S2.Contains(p.XY())), nil
+	}
+	return Bool(solid.S3.Contains(p)), nil
+}
+
+func bindShapeMeasure(e *env, c Call, opName string) (ShapeRep, int, error) {
+	args, err := bindArgs(e, c, []ArgSpec{
+		{Name: "shape", Pos: 0, Required: true},
+		{Name: "samples", Pos: 1, Default: Num(64)},
+	})
+	if err != nil {
+		return ShapeRep{}, 0, err
+	}
+	shape, err := argQueryShape(args, opName)
+	if err != nil {
+		return ShapeRep{}, 0, err
+	}
+	samples, err := argNum(args, "samples")
+	if err != nil {
+		return ShapeRep{}, 0, err
+	}
+	if samples < 1 || samples != math.Floor(samples) {
+		return ShapeRep{}, 0, fmt.Errorf("%s(): samples must be a positive integer", opName)
+	}
+	return shape, int(samples), nil
+}
+
+func argShape(args map[string]Value, opName, name string) (ShapeRep, error) {
+	v, ok := args[name]
+	if !ok {
+		return ShapeRep{}, fmt.Errorf("missing parameter %q", name)
+	}
+	if v.Kind != ValShape || v.Shape == nil {
+		return ShapeRep{}, fmt.Errorf("%s(): %s must be a shape value", opName, name)
+	}
+	return *v.Shape, nil
+}
+
+// argQueryShape reads a shape which can be measured. Metaballs have no
+// surface until they are combined with metaball_solid().
+func argQueryShape(args map[string]Value, opName string) (ShapeRep, error) {
+	shape, err := argShape(args, opName, "shape")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	if shape.Kind == ShapeMetaball2D || shape.Kind == ShapeMetaball3D {
+		return ShapeRep{}, fmt.Errorf("%s(): metaballs must be wrapped in metaball_solid() first", opName)
+	}
+	return shape, nil
+}
+
+// shapeMeasureSolid converts a shape which is not a mesh into a solid.
+func shapeMeasureSolid(e *env, opName string, s ShapeRep) (ShapeRep, error) {
+	switch s.Kind {
+	case ShapeSolid2D, ShapeSolid3D:
+		return s, nil
+	case ShapeSDF2D, ShapeSDF3D:
+		return SDFToSolid(e.hooks.Numerics, s), nil
+	case ShapeHull2D:
+		return s.H2.Solid(e.hooks.Numerics), nil
+	default:
+		return ShapeRep{}, fmt.Errorf("%s(): unsupported shape kind", opName)
+	}
+}
+
+// shapeMeasureMesh2D returns a mesh to measure a 2D shape with.
+//
+// Meshes are measured exactly, while other shapes are meshed with
+// marching squares, using the given number of grid cells along the
+// largest side of their sampled bounds.
+func shapeMeasureMesh2D(e *env, opName string, s ShapeRep, samples int) (*model2d.Mesh, error) {
+	if s.Kind == ShapeMesh2D {
+		if s.M2.NumSegments() == 0 {
+			return nil, fmt.Errorf("%s(): empty mesh cannot be measured", opName)
+		}
+		return s.M2, nil
+	}
+	solid, delta, min, max, err := shapeMeasureGrid(e, opName, s, samples)
+	if err != nil {
+		return nil, err
+	}
+	bounded := model2d.ForceSolidBounds(solid.S2, min.XY(), max.XY())
+	mesh, err := e.hooks.MarchingSquares(shapeSolid2D(bounded, solid.Kernel), delta, 8)
+	if err != nil {
+		return nil, fmt.Errorf("%s(): %w", opName, err)
+	}
+	return mesh, nil
+}
+
+// shapeMeasureMesh3D is like shapeMeasureMesh2D, but for 3D shapes.
+func shapeMeasureMesh3D(e *env, opName string, s ShapeRep, samples int) (*model3d.Mesh, error) {
+	if s.Kind == ShapeMesh3D {
+		if s.M3.NumTriangles() == 0 {
+			return nil, fmt.Errorf("%s(): empty mesh cannot be measured", opName)
+		}
+		return s.M3, nil
+	}
+	solid, delta, min, max, err := shapeMeasureGrid(e, opName, s, samples)
+	if err != nil {
+		return nil, err
+	}
+	bounded := model3d.ForceSolidBounds(solid.S3, min, max)
+	mesh, err := e.hooks.MarchingCubes(shapeSolid3D(bounded, solid.Kernel), delta, 8)
+	if err != nil {
+		return nil, fmt.Errorf("%s(): %w", opName, err)
+	}
+	return mesh, nil
+}
+
+// shapeMeasureGrid converts a shape into a solid and chooses a grid for
+// meshing it, padding the sampled bounds by one cell so that the surface
+// is closed.
+func shapeMeasureGrid(
+	e *env,
+	opName string,
+	s ShapeRep,
+	samples int,
+) (ShapeRep, float64, model3d.Coord3D, model3d.Coord3D, error) {
+	var zero model3d.Coord3D
+	solid, err := shapeMeasureSolid(e, opName, s)
+	if err != nil {
+		return ShapeRep{}, 0, zero, zero, err
+	}
+	min, max, err := sampledShapeBounds(solid, samples)
+	if err != nil {
+		return ShapeRep{}, 0, zero, zero, fmt.Errorf("%s(): %w", opName, err)
+	}
+	delta := max.Sub(min).MaxCoord() / float64(samples)
+	if delta <= 0 {
+		return ShapeRep{}, 0, zero, zero, fmt.Errorf("%s(): shape appears to be empty", opName)
+	}
+	pad := model3d.XYZ(delta, delta, delta)
+	return solid, delta, min.Sub(pad), max.Add(pad), nil
+}
+
+// meshMoments3D computes the signed volume of a closed mesh, and the first
+// moment of the volume, which is the centroid scaled by the volume.
+func meshMoments3D(m *model3d.Mesh) (float64, model3d.Coord3D) {
+	var volume float64
+	var moment model3d.Coord3D
+	m.Iterate(func(t *model3d.Triangle) {
+		v := t[0].Dot(t[1].Cross(t[2])) / 6
+		volume += v
+		moment = moment.Add(t[0].Add(t[1]).Add(t[2]).Scale(v / 4))
+	})
+	return volume, moment
+}
+
+// meshMoments2D is like meshMoments3D, but for the area of a 2D mesh.
+func meshMoments2D(m *model2d.Mesh) (float64, model2d.Coord) {
+	var area float64
+	var moment model2d.Coord
+	m.Iterate(func(s *model2d.Segment) {
+		a := (s[0].X*s[1].Y - s[0].Y*s[1].X) / 2
+		area += a
+		moment = moment.Add(s[0].Add(s[1]).Scale(a / 3))
+	})
+	return area, moment
+}
+
+func coordValue(c model3d.Coord3D, dim int) Value {
+	if dim == 2 {
+		return List([]Value{Num(c.X), Num(c.Y)})
+	}
+	return List([]Value{Num(c.X), Num(c.Y), Num(c.Z)})
+}
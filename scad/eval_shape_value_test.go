@@ -0,0 +1,143 @@
+package scad
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/model3d/model3d"
+)
+
+func TestShapeValues(t *testing.T) {
+	var echoes []string
+	prog, err := Parse(`
+		part = shape { translate([10, 0, 0]) cube([4, 2, 6]); };
+		shape = 3;
+		echo(part, is_shape(part), is_shape(shape));
+		instance(part);
+		translate([0, 0, 10]) instance(part);
+	`)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	res, err := Eval(prog, Hooks{Echo: func(msg string) { echoes = append(echoes, msg) }})
+	if err != nil {
+		t.Fatalf("eval failed: %v", err)
+	}
+	if len(echoes) != 1 || echoes[0] != "shape { 3D }, true, false" {
+		t.Fatalf("unexpected echo output %q", echoes)
+	}
+	if res.Kind != ShapeSolid3D {
+		t.Fatalf("expected Solid3D, got %v", res.Kind)
+	}
+	for _, c := range []struct {
+		Point model3d.Coord3D
+		Want  bool
+	}{
+		{model3d.XYZ(12, 1, 3), true},
+		{model3d.XYZ(12, 1, 13), true},
+		{model3d.XYZ(12, 1, 8), false},
+		{model3d.XYZ(2, 1, 3), false},
+	} {
+		if res.S3.Contains(c.Point) != c.Want {
+			t.Fatalf("expected containment %v at %v", c.Want, c.Point)
+		}
+	}
+}
+
+func TestShapeQueries(t *testing.T) {
+	evalNums := func(src string) []float64 {
+		prog, err := Parse(src)
+		if err != nil {
+			t.Fatalf("parse failed: %v", err)
+		}
+		e := newEnv(Hooks{})
+		if _, err := evalStmts(e, prog.Stmts); err != nil {
+			t.Fatalf("eval failed: %v", err)
+		}
+		out, _ := e.get("out")
+		var flatten func(v Value) []float64
+		flatten = func(v Value) []float64 {
+			switch v.Kind {
+			case ValList:
+				var res []float64
+				for _, x := range v.List {
+					res = append(res, flatten(x)...)
+				}
+				return res
+			case ValBool:
+				return []float64{boolAsNum(v.Bool)}
+			default:
+				return []float64{v.Num}
+			}
+		}
+		return flatten(out)
+	}
+	assertNums := func(src string, expected []float64, tol float64) {
+		actual := evalNums(src)
+		if len(actual) != len(expected) {
+			t.Fatalf("%s: expected %v, got %v", src, expected, actual)
+		}
+		for i, x := range expected {
+			if math.Abs(actual[i]-x) > tol {
+				t.Fatalf("%s: expected %v, got %v", src, expected, actual)
+			}
+		}
+	}
+
+	// Meshes are measured exactly.
+	prism := `s = shape { linear_extrude(4) polygon_mesh([[0, 0], [6, 0], [0, 3]]); };`
+	assertNums(prism+`out = [bounds(s), volume(s), centroid(s)];`,
+		[]float64{0, 0, 0, 6, 3, 4, 36, 2, 1, 2}, 1e-8)
+	assertNums(prism+`out = [area(s), contains(s, [1, 1, 1]), contains(s, [5, 2, 1])];`,
+		[]float64{18 + 24 + 12 + 4*math.Sqrt(45), 1, 0}, 1e-8)
+	triangle := `s = shape { polygon_mesh([[0, 0], [6, 0], [0, 3]]); };`
+	assertNums(triangle+`out = [bounds(s), area(s), centroid(s), contains(s, [1, 1])];`,
+		[]float64{0, 0, 6, 3, 9, 2, 1, 1}, 1e-8)
+
+	// Solids and SDFs are sampled.
+	assertNums(`s = shape { sphere_sdf(5); }; out = [bounds(s), volume(s), centroid(s)];`,
+		[]float64{-5, -5, -5, 5, 5, 5, 4.0 / 3 * math.Pi * 125, 0, 0, 0}, 0.5)
+	assertNums(`s = shape { difference() { cube(10); translate([5, 0, 0]) cube(10); } };
+		out = [bounds(s), volume(s), centroid(s), contains(s, [4, 5, 5]), contains(s, [6, 5, 5])];`,
+		[]float64{0, 0, 0, 5, 10, 10, 500, 2.5, 5, 5, 1, 0}, 0.1)
+	assertNums(`s = shape { translate([2, 1]) circle(3); };
+		out = [area(s, samples=128), centroid(s), contains(s, [4.9, 1])];`,
+		[]float64{9 * math.Pi, 2, 1, 1}, 0.01)
+
+	// Alignment, e.g. centering one part on another.
+	assertNums(`
+		base = shape { translate([20, 10, 0]) cube([8, 4, 2]); };
+		label = shape { cube([2, 2, 1]); };
+		b = bounds(base);
+		l = bounds(label);
+		out = bounds(shape {
+			translate((b[0] + b[1]) / 2 - (l[0] + l[1]) / 2 + [0, 0, b[1].z]) instance(label);
+		});
+	`, []float64{23, 11, 2.5, 25, 13, 3.5}, 1e-6)
+}
+
+func TestShapeValueErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`s = shape { };`, "shape literal produced no geometry"},
+		{`instance(3);`, "instance(): shape must be a shape value"},
+		{`x = bounds([1, 2]);`, "bounds(): shape must be a shape value"},
+		{`s = shape { circle(1); }; x = volume(s);`, "volume(): requires a 3D shape"},
+		{`s = shape { sphere(1); }; x = area(s, samples=0);`, "samples must be a positive integer"},
+		{`s = shape { sphere(1); }; x = contains(s, [0, 0]);`, "point must be a 3D point"},
+		{`s = shape { sphere_metaball(1); }; x = centroid(s);`, "metaball_solid()"},
+	}
+	for _, tc := range tests {
+		prog, err := Parse(tc.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = Eval(prog, Hooks{})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", tc.src, tc.want, err)
+		}
+	}
+}
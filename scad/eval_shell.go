@@ -0,0 +1,199 @@
+package scad
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/unixpickle/model3d/model3d"
+	shapekernel "github.com/unixpickle/webgpu-meshes/shapekernel"
+)
+
+func handleShell(e *env, st *CallStmt, _ []ShapeRep, childUnion *ShapeRep) (ShapeRep, error) {
+	opName := st.Call.Name
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "thickness", Pos: 0, Required: true},
+		{Name: "side", Pos: 1, Default: String("inside")},
+		{Name: "drain_holes", Pos: -1, Default: List(nil)},
+		{Name: "drain_radius", Pos: -1, Default: Num(1)},
+		{Name: "delta", Pos: -1},
+	})
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	thickness, err := argNum(args, "thickness")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	if thickness <= 0 {
+		return ShapeRep{}, fmt.Errorf("%s(): thickness must be positive", opName)
+	}
+	side, err := argString(args, "side")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+
+	// The wall is the region where the child's SDF is between lo and hi.
+	var lo, hi float64
+	switch side {
+	case "inside":
+		lo, hi = 0, thickness
+	case "outside":
+		lo, hi = -thickness, 0
+	case "center":
+		lo, hi = -thickness/2, thickness/2
+	default:
+		return ShapeRep{}, fmt.Errorf("%s(): side must be \"inside\", \"outside\" or \"center\"", opName)
+	}
+
+	drainRadius, err := argNum(args, "drain_radius")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	holesVal := args["drain_holes"]
+	if holesVal.Kind != ValList {
+		return ShapeRep{}, fmt.Errorf("%s(): drain_holes must be a list of [x, y, z] points", opName)
+	}
+	holes := make([]model3d.Coord3D, len(holesVal.List))
+	for i, v := range holesVal.List {
+		if v.Kind != ValList || len(v.List) != 3 {
+			return ShapeRep{}, fmt.Errorf("%s(): drain_holes must be a list of [x, y, z] points", opName)
+		}
+		xyz, err := v.AsVec3()
+		if err != nil {
+			return ShapeRep{}, err
+		}
+		holes[i] = model3d.NewCoord3DArray(xyz)
+	}
+	if len(holes) > 0 && drainRadius <= 0 {
+		return ShapeRep{}, fmt.Errorf("%s(): drain_radius must be positive", opName)
+	}
+
+	sdf, k, err := childSDF3D(e, opName, childUnion, args, thickness/4)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+
+	var drills []*model3d.Cylinder
+	for _, hole := range holes {
+		normal, ok := shellSurfaceNormal(sdf, hole)
+		if !ok {
+			return ShapeRep{}, fmt.Errorf("%s(): cannot find the surface direction at drain hole %v", opName, hole.Array())
+		}
+		// Drill from outside the wall to past its inner side.
+		drills = append(drills, &model3d.Cylinder{
+			P1:     hole.Add(normal.Scale(drainRadius - lo)),
+			P2:     hole.Sub(normal.Scale(drainRadius + hi)),
+			Radius: drainRadius,
+		})
+	}
+
+	result := shellSDF(sdf, lo, hi, drills)
+	if k != nil {
+		k = asPtr(shellSDFKernel(e.hooks.Numerics, *k, lo, hi, drills))
+	}
+	return shapeSDF3D(result, k), nil
+}
+
+// childSDF3D gets the distance field of a 3D SDF, mesh or solid child.
+//
+// Plain solids have no distance field, so they are meshed first with
+// the grid spacing from the "delta" argument. If it is unset, they are
+// meshed with defaultDelta, coarsened to limit the number of cells for
+// large solids.
+func childSDF3D(
+	e *env,
+	opName string,
+	childUnion *ShapeRep,
+	args map[string]Value,
+	defaultDelta float64,
+) (model3d.SDF, *shapekernel.ShapeKernel, error) {
+	switch childUnion.Kind {
+	case ShapeSDF3D:
+		return childUnion.SDF3, childUnion.Kernel, nil
+	case ShapeMesh3D:
+		return model3d.MeshToSDF(childUnion.M3), asPtr(shapekernel.Mesh3DSDF(e.hooks.Numerics, childUnion.M3)), nil
+	case ShapeSolid3D:
+		delta := solidSDFDelta(*childUnion, defaultDelta)
+		if args["delta"].Kind != ValNull {
+			var err error
+			delta, err = argNum(args, "delta")
+			if err != nil {
+				return nil, nil, err
+			}
+			if delta <= 0 {
+				return nil, nil, fmt.Errorf("%s(): delta must be > 0", opName)
+			}
+		}
+		rep, err := solidToSDF(e, opName, *childUnion, delta)
+		if err != nil {
+			return nil, nil, err
+		}
+		return rep.SDF3, rep.Kernel, nil
+	default:
+		return nil, nil, fmt.Errorf("%s(): requires a 3D SDF, solid, or mesh", opName)
+	}
+}
+
+// shellSurfaceNormal estimates the outward normal of an SDF's surface
+// near a point.
+func shellSurfaceNormal(sdf model3d.SDF, c model3d.Coord3D) (model3d.Coord3D, bool) {
+	eps := 1e-4 * math.Max(1, sdf.Max().Sub(sdf.Min()).MaxCoord())
+	diff := func(d model3d.Coord3D) float64 {
+		return (sdf.SDF(c.Sub(d)) - sdf.SDF(c.Add(d))) / (2 * eps)
+	}
+	grad := model3d.XYZ(diff(model3d.X(eps)), diff(model3d.Y(eps)), diff(model3d.Z(eps)))
+	if grad.Norm() < 1e-8 {
+		return model3d.Coord3D{}, false
+	}
+	return grad.Normalize(), true
+}
+
+// shellSDF keeps the part of an SDF between two levels, minus the
+// drilled cylinders.
+func shellSDF(sdf model3d.SDF, lo, hi float64, drills []*model3d.Cylinder) model3d.SDF {
+	return model3d.FuncSDF(
+		sdf.Min().AddScalar(lo),
+		sdf.Max().AddScalar(-lo),
+		func(c model3d.Coord3D) float64 {
+			d := sdf.SDF(c)
+			res := math.Min(d-lo, hi-d)
+			for _, drill := range drills {
+				res = math.Min(res, -drill.SDF(c))
+			}
+			return res
+		},
+	)
+}
+
+// shellSDFKernel creates a kernel matching shellSDF.
+func shellSDFKernel(
+	n shapekernel.Numerics,
+	k shapekernel.ShapeKernel,
+	lo, hi float64,
+	drills []*model3d.Cylinder,
+) shapekernel.ShapeKernel {
+	if k.Kind != shapekernel.SDF3D {
+		panic("expected 3D SDF kernel")
+	}
+	fnName := kernelFnID(&k.IDs, "shell_sdf")
+	shapekernel.AppendWGSL(
+		&k,
+		`
+			fn {{.Entrypoint}}(p: {{.N.Dtype3}}) -> {{.N.Dtype}} {
+				let d = {{.N.AsFloat}}({{.Inner}}(p));
+				return {{.N.FromFloat}}(min(d - {{.Lo}}, {{.Hi}} - d));
+			}
+		`,
+		"N", n.Symbols,
+		"Entrypoint", fnName,
+		"Inner", k.EntrypointName,
+		"Lo", float32(lo),
+		"Hi", float32(hi),
+	)
+	k.EntrypointName = fnName
+	for _, drill := range drills {
+		cyl := shapekernel.CylinderSDF(n, coordToVec3(drill.P1), coordToVec3(drill.P2), drill.Radius)
+		k = shapekernel.SubtractSDF(n, k, cyl)
+	}
+	return k
+}
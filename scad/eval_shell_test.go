@@ -0,0 +1,122 @@
+package scad
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/model3d/model3d"
+)
+
+func TestShellSDF(t *testing.T) {
+	for _, tc := range []struct {
+		side   string
+		inner  float64
+		outer  float64
+		bounds float64
+	}{
+		{"inside", 4, 5, 5},
+		{"outside", 5, 6, 6},
+		{"center", 4.5, 5.5, 5.5},
+	} {
+		shape := mustEvalShape(t, `shell(1, side="`+tc.side+`") sphere_sdf(r=5);`)
+		if shape.Kind != ShapeSDF3D || shape.Kernel == nil {
+			t.Fatalf("%s: expected SDF3D with kernel, got %v", tc.side, shape.Kind)
+		}
+		for _, r := range []float64{0, tc.inner - 0.1, tc.outer + 0.1} {
+			if d := shape.SDF3.SDF(model3d.XYZ(r, 0, 0)); d >= 0 {
+				t.Fatalf("%s: expected radius %f to be outside the wall, got %f", tc.side, r, d)
+			}
+		}
+		mid := (tc.inner + tc.outer) / 2
+		if d := shape.SDF3.SDF(model3d.XYZ(0, mid, 0)); math.Abs(d-0.5) > 1e-8 {
+			t.Fatalf("%s: expected distance 0.5 in the middle of the wall, got %f", tc.side, d)
+		}
+		if max := shape.SDF3.Max(); max.Dist(model3d.XYZ(1, 1, 1).Scale(tc.bounds)) > 1e-8 {
+			t.Fatalf("%s: unexpected bounds max %v", tc.side, max)
+		}
+	}
+}
+
+func TestShellMeshAndSolid(t *testing.T) {
+	for _, src := range []string{
+		`hollow(1) ` + boxMeshSource([3]float64{0, 0, 0}, [3]float64{10, 10, 10}),
+		`hollow(1, delta=0.1) cube(10);`,
+	} {
+		shape := mustEvalShape(t, src)
+		if shape.Kind != ShapeSDF3D {
+			t.Fatalf("%s: expected SDF3D, got %v", src, shape.Kind)
+		}
+		if d := shape.SDF3.SDF(model3d.XYZ(5, 5, 5)); math.Abs(d+4) > 0.05 {
+			t.Fatalf("%s: unexpected SDF %f at the center", src, d)
+		}
+		if d := shape.SDF3.SDF(model3d.XYZ(5, 5, 0.5)); math.Abs(d-0.5) > 0.05 {
+			t.Fatalf("%s: unexpected SDF %f in the bottom wall", src, d)
+		}
+	}
+}
+
+func TestShellDefaultDelta(t *testing.T) {
+	var delta float64
+	hooks := Hooks{
+		MarchingCubes: func(obj ShapeRep, d float64, iters int) (*model3d.Mesh, error) {
+			delta = d
+			return model3d.NewMeshRect(obj.S3.Min(), obj.S3.Max()), nil
+		},
+	}
+	for _, tc := range []struct {
+		src  string
+		want float64
+	}{
+		{`shell(1) cube(10);`, 0.25},
+		{`shell(0.1) cube(256);`, 2},
+		{`shell(0.1, delta=0.5) cube(256);`, 0.5},
+	} {
+		prog, err := Parse(tc.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Eval(prog, hooks); err != nil {
+			t.Fatalf("%s: %v", tc.src, err)
+		}
+		if math.Abs(delta-tc.want) > 1e-8 {
+			t.Fatalf("%s: expected delta %f, got %f", tc.src, tc.want, delta)
+		}
+	}
+}
+
+func TestShellDrainHoles(t *testing.T) {
+	shape := mustEvalShape(t, `shell(1, drain_holes=[[0, 0, 5]], drain_radius=0.5) sphere_sdf(r=5);`)
+	if d := shape.SDF3.SDF(model3d.XYZ(0, 0, 4.5)); d >= 0 {
+		t.Fatalf("expected the drain hole to be empty, got %f", d)
+	}
+	if d := shape.SDF3.SDF(model3d.XYZ(0.7, 0, 4.5)); d <= 0 {
+		t.Fatalf("expected the wall next to the drain hole to be solid, got %f", d)
+	}
+	if d := shape.SDF3.SDF(model3d.XYZ(0, 0, -4.5)); d <= 0 {
+		t.Fatalf("expected the opposite wall to be solid, got %f", d)
+	}
+}
+
+func TestShellErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`shell(0) sphere_sdf(r=1);`, "thickness must be positive"},
+		{`shell(1, side="middle") sphere_sdf(r=1);`, "side must be"},
+		{`shell(1) circle_sdf(r=1);`, "requires a 3D SDF, solid, or mesh"},
+		{`hollow(1, drain_holes=[0, 0, 1]) sphere_sdf(r=1);`, "drain_holes must be"},
+		{`shell(1, drain_holes=[[0, 0, 0]]) sphere_sdf(r=1);`, "surface direction"},
+	}
+	for _, tc := range tests {
+		prog, err := Parse(tc.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = Eval(prog, Hooks{})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", tc.src, tc.want, err)
+		}
+	}
+}
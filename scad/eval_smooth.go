@@ -0,0 +1,379 @@
+package scad
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/unixpickle/model3d/model2d"
+	"github.com/unixpickle/model3d/model3d"
+	shapekernel "github.com/unixpickle/webgpu-meshes/shapekernel"
+)
+
+// A smoothOp describes a smooth boolean as signs applied around a
+// smooth maximum, such that the result is Out*smax(A*a, B*b).
+//
+// SDFs are positive inside, so a union is a maximum, an intersection
+// is a minimum, and a difference is min(a, -b).
+type smoothOp struct {
+	Name string
+	A    float64
+	B    float64
+	Out  float64
+}
+
+// Named creates a copy of the operation that reports errors under a
+// different name.
+func (s smoothOp) Named(name string) smoothOp {
+	s.Name = name
+	return s
+}
+
+var (
+	smoothUnionOp        = smoothOp{Name: "smooth_union", A: 1, B: 1, Out: 1}
+	smoothIntersectionOp = smoothOp{Name: "smooth_intersection", A: -1, B: -1, Out: -1}
+	smoothDifferenceOp   = smoothOp{Name: "smooth_difference", A: -1, B: 1, Out: -1}
+)
+
+// A smoothMode selects the shape of the blend between two SDFs.
+type smoothMode int
+
+const (
+	smoothPolynomial smoothMode = iota
+	smoothExponential
+
+	// smoothFillet joins the surfaces with a circular arc of radius K,
+	// tangent to both of them.
+	smoothFillet
+
+	// smoothChamfer joins the surfaces with a 45 degree bevel.
+	smoothChamfer
+)
+
+type smoothBlend struct {
+	K    float64
+	Mode smoothMode
+}
+
+func (s smoothBlend) Apply(op smoothOp, a, b float64) float64 {
+	return op.Out * s.smoothMax(op.A*a, op.B*b)
+}
+
+func (s smoothBlend) smoothMax(a, b float64) float64 {
+	switch s.Mode {
+	case smoothExponential:
+		m := math.Max(a, b)
+		return m + s.K*math.Log(math.Exp((a-m)/s.K)+math.Exp((b-m)/s.K))
+	case smoothFillet:
+		return math.Hypot(math.Max(0, a+s.K), math.Max(0, b+s.K)) + math.Min(-s.K, math.Max(a, b))
+	case smoothChamfer:
+		return math.Max(math.Max(a, b), (a+b+s.K)*math.Sqrt(0.5))
+	}
+	h := math.Max(0, math.Min(1, 0.5+0.5*(a-b)/s.K))
+	return b + (a-b)*h + s.K*h*(1-h)
+}
+
+// Growth is the maximum distance that a smooth union can extend past
+// the union of its inputs.
+func (s smoothBlend) Growth() float64 {
+	switch s.Mode {
+	case smoothExponential:
+		return s.K * math.Ln2
+	case smoothFillet, smoothChamfer:
+		return s.K
+	}
+	return s.K / 4
+}
+
+func handleSmoothUnion(e *env, st *CallStmt, children []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	blend, err := parseSmoothBlend(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	if err := checkBlendTags("smooth_union", children); err != nil {
+		return ShapeRep{}, err
+	}
+	return smoothCombine(e.hooks.Numerics, smoothUnionOp, blend, children)
+}
+
+func handleSmoothIntersection(e *env, st *CallStmt, children []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	blend, err := parseSmoothBlend(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return smoothCombine(e.hooks.Numerics, smoothIntersectionOp, blend, children)
+}
+
+func handleSmoothDifference(e *env, st *CallStmt, children []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	blend, err := parseSmoothBlend(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	if len(children) < 2 {
+		return smoothCombine(e.hooks.Numerics, smoothDifferenceOp, blend, children)
+	}
+	if _, err := ensureSameKind(children); err != nil {
+		return ShapeRep{}, fmt.Errorf("smooth_difference(): %w", err)
+	}
+	subUnion, err := unionGeometry(e.hooks.Numerics, children[1:])
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return smoothCombine(e.hooks.Numerics, smoothDifferenceOp, blend, []ShapeRep{children[0], subUnion})
+}
+
+func parseSmoothBlend(e *env, st *CallStmt) (smoothBlend, error) {
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "k", Pos: 0, Required: true},
+		{Name: "blend", Pos: 1, Default: String("polynomial")},
+	})
+	if err != nil {
+		return smoothBlend{}, err
+	}
+	k, err := argNum(args, "k")
+	if err != nil {
+		return smoothBlend{}, err
+	}
+	if k <= 0 {
+		return smoothBlend{}, fmt.Errorf("%s(): k must be positive", st.Call.Name)
+	}
+	name, err := argString(args, "blend")
+	if err != nil {
+		return smoothBlend{}, err
+	}
+	switch strings.ToLower(name) {
+	case "polynomial":
+		return smoothBlend{K: k, Mode: smoothPolynomial}, nil
+	case "exponential":
+		return smoothBlend{K: k, Mode: smoothExponential}, nil
+	default:
+		return smoothBlend{}, fmt.Errorf(
+			"%s(): unknown blend %q (expected \"polynomial\" or \"exponential\")",
+			st.Call.Name, name,
+		)
+	}
+}
+
+// csgBlendArgs are the arguments for rounding or beveling the edges
+// created by a CSG operation.
+var csgBlendArgs = []ArgSpec{
+	{Name: "fillet", Pos: -1, Default: Num(0)},
+	{Name: "chamfer", Pos: -1, Default: Num(0)},
+	{Name: "delta", Pos: -1},
+}
+
+// parseCSGBlend parses csgBlendArgs, returning false if the edges
+// should be left sharp.
+func parseCSGBlend(opName string, args map[string]Value) (smoothBlend, bool, error) {
+	fillet, err := argNum(args, "fillet")
+	if err != nil {
+		return smoothBlend{}, false, err
+	}
+	chamfer, err := argNum(args, "chamfer")
+	if err != nil {
+		return smoothBlend{}, false, err
+	}
+	if fillet < 0 || chamfer < 0 {
+		return smoothBlend{}, false, fmt.Errorf("%s(): fillet and chamfer must not be negative", opName)
+	} else if fillet > 0 && chamfer > 0 {
+		return smoothBlend{}, false, fmt.Errorf("%s(): cannot provide both fillet and chamfer", opName)
+	} else if fillet > 0 {
+		return smoothBlend{K: fillet, Mode: smoothFillet}, true, nil
+	} else if chamfer > 0 {
+		return smoothBlend{K: chamfer, Mode: smoothChamfer}, true, nil
+	}
+	return smoothBlend{}, false, nil
+}
+
+// blendedCSG applies a CSG operation with filleted or chamfered edges.
+//
+// Plain solids are converted to approximate SDFs by meshing them, and
+// the result is converted back into a solid. By default they are meshed
+// with a quarter of the blend size as the grid spacing, but the number
+// of grid cells is limited, so large solids with small blends require
+// an explicit delta.
+func blendedCSG(
+	e *env,
+	op smoothOp,
+	blend smoothBlend,
+	args map[string]Value,
+	children []ShapeRep,
+) (ShapeRep, error) {
+	kind, err := ensureSameKind(children)
+	if err != nil {
+		return ShapeRep{}, fmt.Errorf("%s(): %w", op.Name, err)
+	}
+	isSolid := kind == ShapeSolid2D || kind == ShapeSolid3D
+	if isSolid {
+		var delta float64
+		if args["delta"].Kind != ValNull {
+			delta, err = argNum(args, "delta")
+			if err != nil {
+				return ShapeRep{}, err
+			}
+			if delta <= 0 {
+				return ShapeRep{}, fmt.Errorf("%s(): delta must be > 0", op.Name)
+			}
+		} else {
+			for _, ch := range children {
+				delta = math.Max(delta, solidSDFDelta(ch, blend.K/4))
+			}
+			if delta > blend.K/2 {
+				return ShapeRep{}, fmt.Errorf(
+					"%s(): fillet or chamfer is too small for the default grid spacing of %g; pass delta explicitly",
+					op.Name, delta,
+				)
+			}
+		}
+		sdfs := make([]ShapeRep, len(children))
+		for i, ch := range children {
+			sdfs[i], err = solidToSDF(e, op.Name, ch, delta)
+			if err != nil {
+				return ShapeRep{}, err
+			}
+		}
+		children = sdfs
+	} else if kind != ShapeSDF2D && kind != ShapeSDF3D {
+		return ShapeRep{}, fmt.Errorf("%s(): fillet and chamfer require SDF or solid children", op.Name)
+	}
+
+	if op.A < 0 && op.B > 0 && len(children) > 2 {
+		// The edges of a difference are only blended where the first child
+		// meets the rest.
+		subUnion, err := unionGeometry(e.hooks.Numerics, children[1:])
+		if err != nil {
+			return ShapeRep{}, err
+		}
+		children = []ShapeRep{children[0], subUnion}
+	}
+	res, err := smoothCombine(e.hooks.Numerics, op, blend, children)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	if isSolid {
+		res = SDFToSolid(e.hooks.Numerics, res)
+	}
+	return res, nil
+}
+
+// smoothCombine folds the children from left to right with a smooth
+// boolean operation.
+func smoothCombine(n shapekernel.Numerics, op smoothOp, blend smoothBlend, children []ShapeRep) (ShapeRep, error) {
+	if len(children) == 0 {
+		return ShapeRep{}, fmt.Errorf("%s() had no solids", op.Name)
+	}
+	kind, err := ensureSameKind(children)
+	if err != nil {
+		return ShapeRep{}, fmt.Errorf("%s(): %w", op.Name, err)
+	}
+	if kind != ShapeSDF2D && kind != ShapeSDF3D {
+		return ShapeRep{}, fmt.Errorf("%s(): requires SDF children", op.Name)
+	}
+	res := children[0]
+	for _, ch := range children[1:] {
+		var k *shapekernel.ShapeKernel
+		if res.Kernel != nil && ch.Kernel != nil {
+			k = asPtr(smoothSDFKernel(n, op, blend, *res.Kernel, *ch.Kernel))
+		}
+		if kind == ShapeSDF3D {
+			res = shapeSDF3D(smoothSDF3D(op, blend, res.SDF3, ch.SDF3), k)
+		} else {
+			res = shapeSDF2D(smoothSDF2D(op, blend, res.SDF2, ch.SDF2), k)
+		}
+	}
+	return res, nil
+}
+
+func smoothSDF3D(op smoothOp, blend smoothBlend, a, b model3d.SDF) model3d.SDF {
+	var min, max model3d.Coord3D
+	switch {
+	case op.A > 0 && op.B > 0:
+		min, max = model3d.BoundsUnion([]model3d.SDF{a, b})
+		growth := model3d.XYZ(1, 1, 1).Scale(blend.Growth())
+		min, max = min.Sub(growth), max.Add(growth)
+	case op.A < 0 && op.B < 0:
+		min, max = a.Min().Max(b.Min()), a.Max().Min(b.Max())
+		max = max.Max(min)
+	default:
+		min, max = a.Min(), a.Max()
+	}
+	return model3d.FuncSDF(min, max, func(c model3d.Coord3D) float64 {
+		return blend.Apply(op, a.SDF(c), b.SDF(c))
+	})
+}
+
+func smoothSDF2D(op smoothOp, blend smoothBlend, a, b model2d.SDF) model2d.SDF {
+	var min, max model2d.Coord
+	switch {
+	case op.A > 0 && op.B > 0:
+		min, max = model2d.BoundsUnion([]model2d.SDF{a, b})
+		growth := model2d.XY(1, 1).Scale(blend.Growth())
+		min, max = min.Sub(growth), max.Add(growth)
+	case op.A < 0 && op.B < 0:
+		min, max = a.Min().Max(b.Min()), a.Max().Min(b.Max())
+		max = max.Max(min)
+	default:
+		min, max = a.Min(), a.Max()
+	}
+	return model2d.FuncSDF(min, max, func(c model2d.Coord) float64 {
+		return blend.Apply(op, a.SDF(c), b.SDF(c))
+	})
+}
+
+// smoothSDFKernel combines two SDF kernels with a smooth boolean.
+//
+// The blend is computed in floating point, since the numerics library
+// does not provide a logarithm.
+func smoothSDFKernel(
+	n shapekernel.Numerics,
+	op smoothOp,
+	blend smoothBlend,
+	a, b shapekernel.ShapeKernel,
+) shapekernel.ShapeKernel {
+	k := a
+	nextK := shapekernel.ShiftIDs(b, k.IDs)
+	k.IDs = nextK.IDs
+	k.Buffers = append(append([]shapekernel.Buffer{}, k.Buffers...), nextK.Buffers...)
+	k.Code += "\n" + nextK.Code
+
+	blendExpr := "mix(b, a, h) + k * h * (1.0 - h)"
+	blendLets := "let h = clamp(0.5 + 0.5 * (a - b) / k, 0.0, 1.0);"
+	switch blend.Mode {
+	case smoothExponential:
+		blendExpr = "m + k * log(exp((a - m) / k) + exp((b - m) / k))"
+		blendLets = "let m = max(a, b);"
+	case smoothFillet:
+		blendExpr = "length(max(vec2f(a + k, b + k), vec2f(0.0))) + min(-k, max(a, b))"
+		blendLets = ""
+	case smoothChamfer:
+		blendExpr = "max(max(a, b), (a + b + k) * 0.70710677)"
+		blendLets = ""
+	}
+	fnName := kernelFnID(&k.IDs, op.Name+"_sdf")
+	shapekernel.AppendWGSL(
+		&k,
+		`
+			fn {{.Entrypoint}}(p: {{.ArgType}}) -> {{.ReturnType}} {
+				let k = {{.K}};
+				let a = {{.SignA}} * {{.N.AsFloat}}({{.First}}(p));
+				let b = {{.SignB}} * {{.N.AsFloat}}({{.Second}}(p));
+				{{.BlendLets}}
+				return {{.N.FromFloat}}({{.SignOut}} * ({{.BlendExpr}}));
+			}
+		`,
+		"N", n.Symbols,
+		"Entrypoint", fnName,
+		"ArgType", k.Kind.ArgType(n),
+		"ReturnType", k.Kind.ReturnType(n),
+		"K", float32(blend.K),
+		"SignA", float32(op.A),
+		"SignB", float32(op.B),
+		"SignOut", float32(op.Out),
+		"First", k.EntrypointName,
+		"Second", nextK.EntrypointName,
+		"BlendLets", blendLets,
+		"BlendExpr", blendExpr,
+	)
+	k.EntrypointName = fnName
+	return k
+}
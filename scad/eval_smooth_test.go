@@ -0,0 +1,111 @@
+package scad
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/model3d/model2d"
+	"github.com/unixpickle/model3d/model3d"
+)
+
+func TestSmoothUnion(t *testing.T) {
+	for _, blend := range []string{"polynomial", "exponential"} {
+		shape := mustEvalShape(t, `
+			smooth_union(k=1, blend="`+blend+`") {
+				sphere_sdf(r=1);
+				translate([2.2, 0, 0]) sphere_sdf(r=1);
+			}
+		`)
+		if shape.Kind != ShapeSDF3D || shape.Kernel == nil {
+			t.Fatalf("%s: expected SDF3D with kernel, got %v", blend, shape.Kind)
+		}
+		if !strings.Contains(shape.Kernel.Code, "smooth_union_sdf") {
+			t.Fatalf("%s: expected smooth union in kernel code", blend)
+		}
+		// The gap between the spheres is filled in.
+		if d := shape.SDF3.SDF(model3d.XYZ(1.1, 0, 0)); d <= 0 {
+			t.Fatalf("%s: expected blended region inside, got %f", blend, d)
+		}
+		// Away from the blend region, the field is close to the hard union.
+		if d := shape.SDF3.SDF(model3d.XYZ(-0.5, 0, 0)); math.Abs(d-0.5) > 0.15 {
+			t.Fatalf("%s: unexpected SDF %f", blend, d)
+		}
+	}
+
+	solid := mustEvalSolid(t, `solid() smooth_union(k=1) { sphere_sdf(r=1); translate([2.2, 0, 0]) sphere_sdf(r=1); }`)
+	assertContains(t, solid, model3d.XYZ(1.1, 0, 0), true)
+
+	hard := mustEvalShape(t, `union() { sphere_sdf(r=1); translate([2.2, 0, 0]) sphere_sdf(r=1); }`)
+	if d := hard.SDF3.SDF(model3d.XYZ(1.1, 0, 0)); d >= 0 {
+		t.Fatalf("expected gap in hard union, got %f", d)
+	}
+}
+
+func TestSmoothDifferenceIntersection(t *testing.T) {
+	shape := mustEvalShape(t, `
+		smooth_difference(0.5) {
+			square_sdf(size=4, center=true);
+			translate([2, 0]) circle_sdf(r=1);
+			translate([-2, 0]) circle_sdf(r=1);
+		}
+	`)
+	if shape.Kind != ShapeSDF2D || shape.Kernel == nil {
+		t.Fatalf("expected SDF2D with kernel, got %v", shape.Kind)
+	}
+	for _, c := range []struct {
+		P      model2d.Coord
+		Inside bool
+	}{
+		{model2d.XY(0, 0), true},
+		{model2d.XY(1.5, 0), false},
+		{model2d.XY(-1.5, 0), false},
+		{model2d.XY(1.5, 1.5), true},
+	} {
+		if d := shape.SDF2.SDF(c.P); (d > 0) != c.Inside {
+			t.Fatalf("point %v: unexpected SDF %f", c.P, d)
+		}
+	}
+	// The concave corner where the circle meets the square is rounded.
+	if d := shape.SDF2.SDF(model2d.XY(1.9, 1.05)); d >= 0 {
+		t.Fatalf("expected rounded corner, got %f", d)
+	}
+
+	shape = mustEvalShape(t, `
+		smooth_intersection(k=0.5, blend="exponential") {
+			cube_sdf(size=2, center=true);
+			sphere_sdf(r=1.3);
+		}
+	`)
+	if d := shape.SDF3.SDF(model3d.XYZ(0, 0, 0)); d <= 0 {
+		t.Fatalf("expected center inside, got %f", d)
+	}
+	if d := shape.SDF3.SDF(model3d.XYZ(0.95, 0.95, 0)); d >= 0 {
+		t.Fatalf("expected corner outside, got %f", d)
+	}
+	if max := shape.SDF3.Max(); max.Dist(model3d.XYZ(1, 1, 1)) > 1e-8 {
+		t.Fatalf("unexpected bounds max %v", max)
+	}
+}
+
+func TestSmoothBooleanErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`smooth_union(k=1) { cube(1); sphere(1); }`, "requires SDF children"},
+		{`smooth_union(k=0) { sphere_sdf(r=1); }`, "k must be positive"},
+		{`smooth_union(k=1, blend="cubic") { sphere_sdf(r=1); }`, "unknown blend"},
+		{`smooth_difference(k=1) { sphere_sdf(r=1); circle_sdf(r=1); }`, "mixed shape kinds"},
+	}
+	for _, tc := range tests {
+		prog, err := Parse(tc.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = Eval(prog, Hooks{})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", tc.src, tc.want, err)
+		}
+	}
+}
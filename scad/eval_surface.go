@@ -0,0 +1,313 @@
+package scad
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/unixpickle/model3d/model3d"
+	shapekernel "github.com/unixpickle/webgpu-meshes/shapekernel"
+)
+
+func handleSurface(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	hf, err := parseSurface(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return shapeSolid3D(hf, hf.Kernel(e.hooks.Numerics)), nil
+}
+
+func handleSurfaceMesh(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	hf, err := parseSurface(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return shapeMesh3D(hf.Mesh()), nil
+}
+
+func parseSurface(e *env, st *CallStmt) (*heightfield, error) {
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "file", Pos: 0, Required: true},
+		{Name: "center", Pos: 1, Default: Bool(false)},
+		{Name: "invert", Pos: 2, Default: Bool(false)},
+		{Name: "convexity", Pos: 3, Default: Num(1)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	file, err := argString(args, "file")
+	if err != nil {
+		return nil, err
+	}
+	center, err := argBool(args, "center")
+	if err != nil {
+		return nil, err
+	}
+	invert, err := argBool(args, "invert")
+	if err != nil {
+		return nil, err
+	}
+	data, err := e.hooks.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s(): %w", st.Call.Name, err)
+	}
+
+	var hf *heightfield
+	if strings.ToLower(filepath.Ext(file)) == ".png" {
+		hf, err = readSurfacePNG(data, invert)
+	} else {
+		hf, err = readSurfaceDat(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s(): %s: %w", st.Call.Name, file, err)
+	}
+	if hf.Cols < 2 || hf.Rows < 2 {
+		return nil, fmt.Errorf("%s(): %s: heightmap must be at least 2x2", st.Call.Name, file)
+	}
+	// Like OpenSCAD, the base is one unit below the lowest height, so
+	// that the solid has a positive thickness everywhere, and it is never
+	// above z=0.
+	minHeight := math.Inf(1)
+	for _, h := range hf.Heights {
+		minHeight = math.Min(minHeight, h)
+	}
+	hf.Bottom = math.Min(minHeight-1, 0)
+	if center {
+		hf.Origin = model3d.XY(-float64(hf.Cols-1)/2, -float64(hf.Rows-1)/2)
+	}
+	return hf, nil
+}
+
+// readSurfaceDat reads a whitespace-separated matrix of heights. The
+// first row is placed at y=0.
+func readSurfaceDat(data []byte) (*heightfield, error) {
+	hf := &heightfield{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1<<24)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		if idx := strings.IndexByte(line, '#'); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if hf.Rows == 0 {
+			hf.Cols = len(fields)
+		} else if len(fields) != hf.Cols {
+			return nil, fmt.Errorf("line %d: expected %d values but got %d", lineNum, hf.Cols, len(fields))
+		}
+		for _, f := range fields {
+			x, err := strconv.ParseFloat(f, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNum, err)
+			}
+			hf.Heights = append(hf.Heights, x)
+		}
+		hf.Rows++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(hf.Heights) == 0 {
+		return nil, fmt.Errorf("no data")
+	}
+	return hf, nil
+}
+
+// readSurfacePNG converts the luminance of an image to heights between
+// 0 and 100, with the top row of the image at the maximum y.
+func readSurfacePNG(data []byte, invert bool) (*heightfield, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	bounds := img.Bounds()
+	hf := &heightfield{
+		Cols:    bounds.Dx(),
+		Rows:    bounds.Dy(),
+		Heights: make([]float64, 0, bounds.Dx()*bounds.Dy()),
+	}
+	for y := bounds.Max.Y - 1; y >= bounds.Min.Y; y-- {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			luma := imageLuminance(img, x, y)
+			if invert {
+				luma = 1 - luma
+			}
+			hf.Heights = append(hf.Heights, 100*luma)
+		}
+	}
+	return hf, nil
+}
+
+func imageLuminance(img image.Image, x, y int) float64 {
+	r, g, b, _ := img.At(x, y).RGBA()
+	return (0.2126*float64(r) + 0.7152*float64(g) + 0.0722*float64(b)) / 0xffff
+}
+
+// heightfield is a solid bounded below by a flat base and above by a
+// grid of heights with unit spacing.
+//
+// Each grid cell is split into two triangles along the diagonal from
+// its minimum to its maximum corner, so that the solid exactly matches
+// the mesh.
+type heightfield struct {
+	Cols    int
+	Rows    int
+	Heights []float64
+	Bottom  float64
+
+	// Origin is the xy position of the first height sample.
+	Origin model3d.Coord3D
+}
+
+func (h *heightfield) at(row, col int) float64 {
+	return h.Heights[row*h.Cols+col]
+}
+
+func (h *heightfield) maxHeight() float64 {
+	res := h.Bottom
+	for _, x := range h.Heights {
+		res = math.Max(res, x)
+	}
+	return res
+}
+
+func (h *heightfield) Min() model3d.Coord3D {
+	return model3d.XYZ(h.Origin.X, h.Origin.Y, h.Bottom)
+}
+
+func (h *heightfield) Max() model3d.Coord3D {
+	return model3d.XYZ(
+		h.Origin.X+float64(h.Cols-1),
+		h.Origin.Y+float64(h.Rows-1),
+		h.maxHeight(),
+	)
+}
+
+func (h *heightfield) Contains(c model3d.Coord3D) bool {
+	if !model3d.InBounds(h, c) {
+		return false
+	}
+	x := c.X - h.Origin.X
+	y := c.Y - h.Origin.Y
+	col := min(int(x), h.Cols-2)
+	row := min(int(y), h.Rows-2)
+	fx := x - float64(col)
+	fy := y - float64(row)
+	h00 := h.at(row, col)
+	h11 := h.at(row+1, col+1)
+	var height float64
+	if fx >= fy {
+		h10 := h.at(row, col+1)
+		height = h00 + fx*(h10-h00) + fy*(h11-h10)
+	} else {
+		h01 := h.at(row+1, col)
+		height = h00 + fy*(h01-h00) + fx*(h11-h01)
+	}
+	return c.Z <= height
+}
+
+// Mesh creates a closed mesh for the heightfield.
+func (h *heightfield) Mesh() *model3d.Mesh {
+	mesh := model3d.NewMesh()
+	top := func(row, col int) model3d.Coord3D {
+		return model3d.XYZ(float64(col), float64(row), h.at(row, col)).Add(h.Origin)
+	}
+	bottom := func(row, col int) model3d.Coord3D {
+		return model3d.XYZ(float64(col), float64(row), h.Bottom).Add(h.Origin)
+	}
+	for row := 0; row+1 < h.Rows; row++ {
+		for col := 0; col+1 < h.Cols; col++ {
+			mesh.Add(&model3d.Triangle{top(row, col), top(row, col+1), top(row+1, col+1)})
+			mesh.Add(&model3d.Triangle{top(row, col), top(row+1, col+1), top(row+1, col)})
+			mesh.Add(&model3d.Triangle{bottom(row, col), bottom(row+1, col+1), bottom(row, col+1)})
+			mesh.Add(&model3d.Triangle{bottom(row, col), bottom(row+1, col), bottom(row+1, col+1)})
+		}
+	}
+	addWall := func(r1, c1, r2, c2 int) {
+		// The wall faces to the right of the edge when viewed from above.
+		mesh.Add(&model3d.Triangle{bottom(r1, c1), bottom(r2, c2), top(r2, c2)})
+		mesh.Add(&model3d.Triangle{bottom(r1, c1), top(r2, c2), top(r1, c1)})
+	}
+	for col := 0; col+1 < h.Cols; col++ {
+		addWall(0, col, 0, col+1)
+		addWall(h.Rows-1, col+1, h.Rows-1, col)
+	}
+	for row := 0; row+1 < h.Rows; row++ {
+		addWall(row+1, 0, row, 0)
+		addWall(row, h.Cols-1, row+1, h.Cols-1)
+	}
+	return mesh
+}
+
+// Kernel creates a shape kernel which samples the heights from a
+// buffer, splitting cells in the same way as Contains().
+func (h *heightfield) Kernel(n shapekernel.Numerics) *shapekernel.ShapeKernel {
+	ids := shapekernel.IDTracker{}
+	entrypoint := kernelFnID(&ids, "heightfield_solid")
+	heightsBuf := kernelBufferID(&ids, "heights")
+	heights := make([]float32, len(h.Heights))
+	for i, x := range h.Heights {
+		heights[i] = float32(x)
+	}
+	min, max := h.Min(), h.Max()
+	return &shapekernel.ShapeKernel{
+		Kind: shapekernel.Solid3D,
+		IDs:  ids,
+		Buffers: []shapekernel.Buffer{
+			shapekernel.Float32Buffer(heightsBuf, func() []float32 {
+				return heights
+			}),
+		},
+		Code: shapekernel.WGSL(
+			`
+				fn {{.Entrypoint}}(p_raw: {{.N.Dtype3}}) -> bool {
+					let p = {{.N.AsFloat3}}(p_raw);
+					if (p.x < {{.MinX}} || p.y < {{.MinY}} || p.z < {{.MinZ}} ||
+						p.x > {{.MaxX}} || p.y > {{.MaxY}} || p.z > {{.MaxZ}}) {
+						return false;
+					}
+					let x = p.x - {{.MinX}};
+					let y = p.y - {{.MinY}};
+					let col = min(u32(floor(x)), {{.Cols}}u - 2u);
+					let row = min(u32(floor(y)), {{.Rows}}u - 2u);
+					let fx = x - f32(col);
+					let fy = y - f32(row);
+					let h00 = {{.Heights}}[row * {{.Cols}}u + col];
+					let h11 = {{.Heights}}[(row + 1u) * {{.Cols}}u + col + 1u];
+					var height: f32;
+					if (fx >= fy) {
+						let h10 = {{.Heights}}[row * {{.Cols}}u + col + 1u];
+						height = h00 + fx * (h10 - h00) + fy * (h11 - h10);
+					} else {
+						let h01 = {{.Heights}}[(row + 1u) * {{.Cols}}u + col];
+						height = h00 + fy * (h01 - h00) + fx * (h11 - h01);
+					}
+					return p.z <= height;
+				}
+			`,
+			"N", n.Symbols,
+			"Entrypoint", entrypoint,
+			"Heights", heightsBuf,
+			"Cols", h.Cols,
+			"Rows", h.Rows,
+			"MinX", float32(min.X),
+			"MinY", float32(min.Y),
+			"MinZ", float32(min.Z),
+			"MaxX", float32(max.X),
+			"MaxY", float32(max.Y),
+			"MaxZ", float32(max.Z),
+		),
+		EntrypointName: entrypoint,
+	}
+}
@@ -0,0 +1,150 @@
+package scad
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/model3d/model3d"
+)
+
+func TestSurfaceDat(t *testing.T) {
+	files := map[string][]byte{
+		"heights.dat": []byte("# a ramp\n0 1 2\n0 1 2\n\n0 1 2 # trailing comment\n"),
+	}
+	mesh := mustEvalShapeWithFiles(t, `surface_mesh("heights.dat");`, files)
+	if mesh.Kind != ShapeMesh3D {
+		t.Fatalf("expected ShapeMesh3D, got %v", mesh.Kind)
+	}
+	if mesh.M3.NeedsRepair() || len(mesh.M3.SingularVertices()) != 0 {
+		t.Fatal("expected manifold mesh")
+	}
+	// The base is one unit below the minimum height, and the top is a
+	// ramp from 0 to 2 over a 2x2 area.
+	if v := mesh.M3.Volume(); math.Abs(v-8) > 1e-8 {
+		t.Fatalf("expected volume 8, got %f", v)
+	}
+	if min := mesh.M3.Min(); min.Dist(model3d.XYZ(0, 0, -1)) > 1e-8 {
+		t.Fatalf("unexpected min %v", min)
+	}
+
+	solid := mustEvalShapeWithFiles(t, `surface("heights.dat", center=true);`, files)
+	if solid.Kind != ShapeSolid3D || solid.Kernel == nil {
+		t.Fatalf("expected ShapeSolid3D with kernel, got %v", solid.Kind)
+	}
+	if !solid.S3.Contains(model3d.XYZ(0.5, 0, 1.4)) || solid.S3.Contains(model3d.XYZ(0.5, 0, 1.6)) {
+		t.Fatal("unexpected containment near the surface")
+	}
+	if solid.S3.Contains(model3d.XYZ(0, 0, -1.1)) {
+		t.Fatal("point below the base should be outside")
+	}
+}
+
+func TestSurfaceMeshMatchesSolid(t *testing.T) {
+	files := map[string][]byte{
+		"bumps.dat": []byte("0 3 1 2\n4 0 2 1\n1 2 5 0\n"),
+	}
+	mesh := mustEvalShapeWithFiles(t, `surface_mesh("bumps.dat");`, files)
+	solid := mustEvalShapeWithFiles(t, `surface("bumps.dat");`, files)
+	meshSolid := model3d.NewColliderSolid(model3d.MeshToCollider(mesh.M3))
+	meshSDF := model3d.MeshToSDF(mesh.M3)
+	for i := 0; i < 2000; i++ {
+		c := model3d.XYZ(
+			float64(i%17)/16*3.2-0.1,
+			float64((i/17)%13)/12*2.2-0.1,
+			float64(i)/2000*7-1.5,
+		)
+		if math.Abs(meshSDF.SDF(c)) < 1e-3 {
+			continue
+		}
+		if a, b := meshSolid.Contains(c), solid.S3.Contains(c); a != b {
+			t.Fatalf("mismatch at %v: mesh=%v solid=%v", c, a, b)
+		}
+	}
+}
+
+func TestSurfacePNG(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 3, 2))
+	img.SetGray(0, 0, color.Gray{Y: 255})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{"img.png": buf.Bytes()}
+
+	// The top-left pixel ends up at the maximum y coordinate.
+	shape := mustEvalShapeWithFiles(t, `surface_mesh("img.png");`, files)
+	if max := shape.M3.Max(); max.Dist(model3d.XYZ(2, 1, 100)) > 1e-8 {
+		t.Fatalf("unexpected max %v", max)
+	}
+	solid := mustEvalShapeWithFiles(t, `surface("img.png");`, files)
+	if !solid.S3.Contains(model3d.XYZ(0.01, 0.99, 90)) || solid.S3.Contains(model3d.XYZ(0.01, 0.01, 10)) {
+		t.Fatal("unexpected containment for image heights")
+	}
+
+	inverted := mustEvalShapeWithFiles(t, `surface("img.png", invert=true);`, files)
+	if inverted.S3.Contains(model3d.XYZ(0.01, 0.99, 10)) || !inverted.S3.Contains(model3d.XYZ(0.01, 0.01, 90)) {
+		t.Fatal("unexpected containment for inverted image heights")
+	}
+}
+
+func TestSurfacePNGBlackPixels(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 4, 3))
+	img.SetGray(1, 1, color.Gray{Y: 255})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{"img.png": buf.Bytes()}
+
+	// Black pixels are at height 0, so the base is one unit below them.
+	shape := mustEvalShapeWithFiles(t, `surface_mesh("img.png");`, files)
+	mesh := shape.M3
+	if mesh.NeedsRepair() || len(mesh.SingularVertices()) != 0 {
+		t.Fatal("expected manifold mesh")
+	}
+	if min := mesh.Min(); min.Dist(model3d.XYZ(0, 0, -1)) > 1e-8 {
+		t.Fatalf("unexpected min %v", min)
+	}
+	solid := mesh.Solid()
+	for row := 0; row < 2; row++ {
+		for col := 0; col < 3; col++ {
+			c := model3d.XYZ(float64(col)+0.5, float64(row)+0.5, -0.5)
+			if !solid.Contains(c) {
+				t.Fatalf("expected the base to be solid at %v", c)
+			}
+		}
+	}
+	// The base adds a unit of height to every cell, under a single bump
+	// with a volume of 100.
+	if v := mesh.Volume(); math.Abs(v-106) > 1e-8 {
+		t.Fatalf("unexpected volume %f", v)
+	}
+}
+
+func TestSurfaceErrors(t *testing.T) {
+	files := map[string][]byte{
+		"ragged.dat": []byte("1 2\n3\n"),
+		"single.dat": []byte("1 2 3\n"),
+		"bad.dat":    []byte("1 x\n2 3\n"),
+	}
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`surface("missing.dat");`, "file not found"},
+		{`surface("ragged.dat");`, "expected 2 values"},
+		{`surface("single.dat");`, "at least 2x2"},
+		{`surface_mesh("bad.dat");`, "invalid syntax"},
+	}
+	for _, tc := range tests {
+		_, err := evalWithFiles(tc.src, files)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", tc.src, tc.want, err)
+		}
+	}
+}
@@ -0,0 +1,717 @@
+package scad
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/unixpickle/model3d/model2d"
+	"github.com/unixpickle/model3d/model3d"
+	shapekernel "github.com/unixpickle/webgpu-meshes/shapekernel"
+)
+
+func handleSweep(e *env, st *CallStmt, _ []ShapeRep, childUnion *ShapeRep) (ShapeRep, error) {
+	if childUnion.Kind != ShapeSolid2D && childUnion.Kind != ShapeMesh2D && childUnion.Kind != ShapeSDF2D {
+		return ShapeRep{}, fmt.Errorf("sweep() requires 2D children")
+	}
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "path", Pos: 0, Required: true},
+		{Name: "closed", Pos: 1, Default: Bool(false)},
+		{Name: "twist", Pos: 2, Default: Num(0.0)},
+		{Name: "scale", Pos: 3, Default: Num(1.0)},
+		{Name: "spline", Pos: -1, Default: Bool(false)},
+		{Name: "segments", Pos: -1, Default: Num(8)},
+	})
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	points, err := parseSweepPath(args["path"])
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	closed, err := argBool(args, "closed")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	twist, err := argNum(args, "twist")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	scaleV, ok := args["scale"]
+	if !ok {
+		return ShapeRep{}, fmt.Errorf("missing parameter \"scale\"")
+	}
+	scale, err := scaleV.AsVec2()
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	if scale[0] <= 0 || scale[1] <= 0 {
+		return ShapeRep{}, fmt.Errorf("sweep(): scale must be positive")
+	}
+	spline, err := argBool(args, "spline")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	if spline {
+		segments, err := argNum(args, "segments")
+		if err != nil {
+			return ShapeRep{}, err
+		}
+		if segments < 1 || segments != math.Floor(segments) {
+			return ShapeRep{}, fmt.Errorf("sweep(): segments must be a positive integer")
+		}
+		points = catmullRomPoints(points, closed, int(segments))
+	}
+	if closed {
+		if !isIdentityExtrude(0, scale) {
+			return ShapeRep{}, fmt.Errorf("sweep(): scale must be 1 for closed paths")
+		}
+		if turns := twist / 360; math.Abs(turns-math.Round(turns)) > 1e-9 {
+			return ShapeRep{}, fmt.Errorf("sweep(): twist must be a multiple of 360 for closed paths")
+		}
+	}
+	path, err := newSweepPath(points, closed, twist*math.Pi/180, scale)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+
+	switch childUnion.Kind {
+	case ShapeMesh2D:
+		mesh, err := repairedProfileMesh("sweep", childUnion.M2)
+		if err != nil {
+			return ShapeRep{}, err
+		}
+		if _, err := path.Spans(maxCornerRadius(mesh.Min(), mesh.Max())); err != nil {
+			return ShapeRep{}, err
+		}
+		return shapeMesh3D(path.Mesh(mesh)), nil
+	case ShapeSDF2D:
+		profile := childUnion.SDF2
+		spans, err := path.Spans(maxCornerRadius(profile.Min(), profile.Max()))
+		if err != nil {
+			return ShapeRep{}, err
+		}
+		var k *shapekernel.ShapeKernel
+		if childUnion.Kernel != nil {
+			k = asPtr(sweepKernel(e.hooks.Numerics, *childUnion.Kernel, spans, true))
+		}
+		return shapeSDF3D(sweepSDF(spans, profile), k), nil
+	default:
+		profile := childUnion.S2
+		spans, err := path.Spans(maxCornerRadius(profile.Min(), profile.Max()))
+		if err != nil {
+			return ShapeRep{}, err
+		}
+		var k *shapekernel.ShapeKernel
+		if childUnion.Kernel != nil {
+			k = asPtr(sweepKernel(e.hooks.Numerics, *childUnion.Kernel, spans, false))
+		}
+		return shapeSolid3D(sweepSolid(spans, profile), k), nil
+	}
+}
+
+func parseSweepPath(val Value) ([]model3d.Coord3D, error) {
+	if val.Kind != ValList {
+		return nil, fmt.Errorf("sweep(): path must be a list")
+	}
+	points := make([]model3d.Coord3D, 0, len(val.List))
+	for _, v := range val.List {
+		if v.Kind != ValList || len(v.List) != 3 {
+			return nil, fmt.Errorf("sweep(): path must be a list of [x, y, z] vectors")
+		}
+		xyz, err := v.AsVec3()
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, model3d.NewCoord3DArray(xyz))
+	}
+	return points, nil
+}
+
+// catmullRomPoints interpolates a uniform Catmull-Rom spline through
+// the points, with the given number of segments between each pair of
+// points. Open splines extend their end tangents linearly.
+func catmullRomPoints(points []model3d.Coord3D, closed bool, segments int) []model3d.Coord3D {
+	n := len(points)
+	if n < 2 {
+		return points
+	}
+	at := func(i int) model3d.Coord3D {
+		if closed {
+			return points[((i%n)+n)%n]
+		} else if i < 0 {
+			return points[0].Scale(2).Sub(points[1])
+		} else if i >= n {
+			return points[n-1].Scale(2).Sub(points[n-2])
+		}
+		return points[i]
+	}
+	spans := n - 1
+	if closed {
+		spans = n
+	}
+	res := make([]model3d.Coord3D, 0, spans*segments+1)
+	for i := 0; i < spans; i++ {
+		p0, p1, p2, p3 := at(i-1), at(i), at(i+1), at(i+2)
+		for j := 0; j < segments; j++ {
+			t := float64(j) / float64(segments)
+			t2, t3 := t*t, t*t*t
+			res = append(res, p1.Scale(2).
+				Add(p2.Sub(p0).Scale(t)).
+				Add(p0.Scale(2).Sub(p1.Scale(5)).Add(p2.Scale(4)).Sub(p3).Scale(t2)).
+				Add(p1.Scale(3).Sub(p0).Sub(p2.Scale(3)).Add(p3).Scale(t3)).
+				Scale(0.5))
+		}
+	}
+	if !closed {
+		res = append(res, points[n-1])
+	}
+	return res
+}
+
+// A sweepPath is a polyline with a frame for each of its spans.
+//
+// Frames are carried from span to span by the smallest rotation between
+// their directions, which is a rotation-minimizing frame for a polyline.
+// Neighboring spans meet at the plane that bisects their directions, so
+// the profile is stretched across the bend at each corner.
+type sweepPath struct {
+	Points []model3d.Coord3D
+	Closed bool
+
+	// Per-span directions and frames. The profile's X and Y axes map to
+	// Normals and Binormals, respectively.
+	Dirs      []model3d.Coord3D
+	Normals   []model3d.Coord3D
+	Binormals []model3d.Coord3D
+
+	// Plane normals at each point, pointing along the path.
+	Planes []model3d.Coord3D
+
+	// Arc length at each point, and the total length of the path.
+	Lengths []float64
+	Length  float64
+
+	// Total clockwise rotation of the profile, including the correction
+	// to close the frames of closed paths.
+	Twist float64
+	Scale [2]float64
+}
+
+func newSweepPath(points []model3d.Coord3D, closed bool, twist float64, scale [2]float64) (*sweepPath, error) {
+	var deduped []model3d.Coord3D
+	for i, p := range points {
+		if i == 0 || p != deduped[len(deduped)-1] {
+			deduped = append(deduped, p)
+		}
+	}
+	if closed && len(deduped) > 1 && deduped[0] == deduped[len(deduped)-1] {
+		deduped = deduped[:len(deduped)-1]
+	}
+	if len(deduped) < 2 || (closed && len(deduped) < 3) {
+		return nil, fmt.Errorf("sweep(): path needs at least %d distinct points", 2+boolToInt(closed))
+	}
+
+	p := &sweepPath{Points: deduped, Closed: closed, Scale: scale}
+	numSpans := len(deduped) - 1
+	if closed {
+		numSpans++
+	}
+	p.Lengths = make([]float64, len(deduped)+1)
+	for i := 0; i < numSpans; i++ {
+		delta := p.point(i + 1).Sub(p.point(i))
+		p.Dirs = append(p.Dirs, delta.Normalize())
+		p.Lengths[i+1] = p.Lengths[i] + delta.Norm()
+	}
+	p.Length = p.Lengths[numSpans]
+
+	for i := range deduped {
+		var prev, next model3d.Coord3D
+		if i > 0 || closed {
+			prev = p.Dirs[(i+numSpans-1)%numSpans]
+		}
+		if i < numSpans {
+			next = p.Dirs[i]
+		}
+		if prev == (model3d.Coord3D{}) {
+			prev = next
+		} else if next == (model3d.Coord3D{}) {
+			next = prev
+		}
+		if prev.Dot(next) < -1+1e-8 {
+			return nil, fmt.Errorf("sweep(): path turns back on itself")
+		}
+		p.Planes = append(p.Planes, prev.Add(next).Normalize())
+	}
+
+	normal := sweepInitialNormal(p.Dirs[0])
+	for i, dir := range p.Dirs {
+		if i > 0 {
+			normal = minimalRotation(p.Dirs[i-1], dir, normal)
+		}
+		p.Normals = append(p.Normals, normal)
+		p.Binormals = append(p.Binormals, dir.Cross(normal))
+	}
+	p.Twist = twist
+	if closed {
+		// Carrying the last frame back to the first span rotates it around
+		// the path direction, and this is undone gradually along the path.
+		last := minimalRotation(p.Dirs[numSpans-1], p.Dirs[0], p.Normals[numSpans-1])
+		p.Twist += math.Atan2(p.Normals[0].Cross(last).Dot(p.Dirs[0]), p.Normals[0].Dot(last))
+	}
+	return p, nil
+}
+
+// sweepInitialNormal picks the direction of the profile's X axis for the
+// first span, so that the profile's Y axis points up (or along Y for
+// vertical paths).
+func sweepInitialNormal(dir model3d.Coord3D) model3d.Coord3D {
+	up := model3d.Z(1)
+	if math.Abs(dir.Z) > 1-1e-8 {
+		up = model3d.Y(1)
+	}
+	binormal := up.Sub(dir.Scale(dir.Dot(up))).Normalize()
+	return binormal.Cross(dir)
+}
+
+// minimalRotation applies the smallest rotation from unit vector a to
+// unit vector b to the vector v.
+func minimalRotation(a, b, v model3d.Coord3D) model3d.Coord3D {
+	k := a.Cross(b)
+	c := a.Dot(b)
+	return v.Scale(c).Add(k.Cross(v)).Add(k.Scale(k.Dot(v) / (1 + c)))
+}
+
+func (s *sweepPath) point(i int) model3d.Coord3D {
+	return s.Points[i%len(s.Points)]
+}
+
+func (s *sweepPath) numSpans() int {
+	return len(s.Dirs)
+}
+
+// transform computes the forward twist and scale of profile coordinates
+// at the given fraction of the path length.
+func (s *sweepPath) transform(c model2d.Coord, t float64) model2d.Coord {
+	x := c.X * (1 + t*(s.Scale[0]-1))
+	y := c.Y * (1 + t*(s.Scale[1]-1))
+	cosA, sinA := math.Cos(s.Twist*t), math.Sin(s.Twist*t)
+	return model2d.XY(x*cosA+y*sinA, y*cosA-x*sinA)
+}
+
+// spanParam computes the fraction of the path length at a fraction of
+// the way through a span.
+func (s *sweepPath) spanParam(span int, frac float64) float64 {
+	return (s.Lengths[span] + frac*(s.Lengths[span+1]-s.Lengths[span])) / s.Length
+}
+
+// sectionAt places profile coordinates in a span. The fraction selects a
+// plane in the pencil of planes between the two ends of the span, so
+// that fractions 0 and 1 are the corner planes at either end.
+func (s *sweepPath) sectionAt(c model2d.Coord, span int, frac float64) model3d.Coord3D {
+	c = s.transform(c, s.spanParam(span, frac))
+	start, end := s.point(span), s.point(span+1)
+	dir := s.Dirs[span]
+	plane0, plane1 := s.Planes[span%len(s.Points)], s.Planes[(span+1)%len(s.Points)]
+	offset := s.Normals[span].Scale(c.X).Add(s.Binormals[span].Scale(c.Y))
+
+	// Solve h0 = frac * (h0 + h1) for the offset along the span, where h0
+	// and h1 are the distances to the corner planes.
+	a0 := offset.Dot(plane0)
+	a1 := end.Sub(start).Sub(offset).Dot(plane1)
+	b0, b1 := dir.Dot(plane0), dir.Dot(plane1)
+	lambda := (frac*(a0+a1) - a0) / ((1-frac)*b0 + frac*b1)
+	return start.Add(offset).Add(dir.Scale(lambda))
+}
+
+// section places profile coordinates in the corner plane at point i.
+func (s *sweepPath) section(c model2d.Coord, i int) model3d.Coord3D {
+	if i == s.numSpans() {
+		return s.sectionAt(c, i-1, 1)
+	}
+	return s.sectionAt(c, i, 0)
+}
+
+// Mesh sweeps a consistently oriented profile mesh along the path.
+//
+// Twisted spans are split into slices of at most 5 degrees.
+func (s *sweepPath) Mesh(m *model2d.Mesh) *model3d.Mesh {
+	res := model3d.NewMesh()
+	addTri := func(t *model3d.Triangle) {
+		if t[0] != t[1] && t[1] != t[2] && t[2] != t[0] {
+			res.Add(t)
+		}
+	}
+	numSpans := s.numSpans()
+	slices := make([]int, numSpans)
+	for i := range slices {
+		twist := s.Twist * (s.Lengths[i+1] - s.Lengths[i]) / s.Length
+		slices[i] = int(math.Max(1, math.Ceil(math.Abs(twist)/(math.Pi/36)-1e-9)))
+	}
+
+	// Corner sections are shared between spans, so that the mesh is
+	// connected even if the spans compute them slightly differently.
+	corners := map[model2d.Coord][]model3d.Coord3D{}
+	sectionAt := func(c model2d.Coord, span, slice int) model3d.Coord3D {
+		if slice == 0 || slice == slices[span] {
+			points, ok := corners[c]
+			if !ok {
+				points = make([]model3d.Coord3D, len(s.Points))
+				for i := range points {
+					points[i] = s.section(c, i)
+				}
+				corners[c] = points
+			}
+			return points[(span+slice/slices[span])%len(points)]
+		}
+		return s.sectionAt(c, span, float64(slice)/float64(slices[span]))
+	}
+
+	m.Iterate(func(seg *model2d.Segment) {
+		a, b := seg[1], seg[0]
+		for i := 0; i < numSpans; i++ {
+			for j := 0; j < slices[i]; j++ {
+				addTri(&model3d.Triangle{sectionAt(a, i, j), sectionAt(b, i, j), sectionAt(a, i, j+1)})
+				addTri(&model3d.Triangle{sectionAt(b, i, j), sectionAt(b, i, j+1), sectionAt(a, i, j+1)})
+			}
+		}
+	})
+	if !s.Closed {
+		last := numSpans - 1
+		for _, t := range model2d.TriangulateMesh(m) {
+			addTri(&model3d.Triangle{sectionAt(t[0], 0, 0), sectionAt(t[1], 0, 0), sectionAt(t[2], 0, 0)})
+			addTri(&model3d.Triangle{
+				sectionAt(t[1], last, slices[last]),
+				sectionAt(t[0], last, slices[last]),
+				sectionAt(t[2], last, slices[last]),
+			})
+		}
+	}
+	return res
+}
+
+// A sweepSpan evaluates the profile in the local coordinates of a single
+// span of a sweepPath.
+type sweepSpan struct {
+	Path  *sweepPath
+	Index int
+	Min   model3d.Coord3D
+	Max   model3d.Coord3D
+
+	// Lipschitz is a bound on how much the local coordinates stretch
+	// distances within the span.
+	Lipschitz float64
+}
+
+// Spans creates a bounded sweepSpan for each span of the path, given the
+// largest distance from the origin of the profile.
+//
+// An error is returned if the profile is too large for a bend in the
+// path, in which case the corner planes would cross inside the sweep.
+func (s *sweepPath) Spans(radius float64) ([]sweepSpan, error) {
+	radius *= math.Max(1, math.Max(s.Scale[0], s.Scale[1]))
+	minScale := math.Min(1, math.Min(s.Scale[0], s.Scale[1]))
+	scaleRate := math.Max(math.Abs(s.Scale[0]-1), math.Abs(s.Scale[1]-1))
+
+	res := make([]sweepSpan, s.numSpans())
+	for i := range res {
+		span := sweepSpan{Path: s, Index: i}
+		dir := s.Dirs[i]
+		start, end := s.point(i), s.point(i+1)
+		plane0, plane1 := s.Planes[i%len(s.Points)], s.Planes[(i+1)%len(s.Points)]
+
+		// The profile is stretched by the miter at each corner.
+		r := math.Max(radius/plane0.Dot(dir), radius/plane1.Dot(dir))
+		span.Min = start.Min(end).AddScalar(-r)
+		span.Max = start.Max(end).AddScalar(r)
+
+		// The span parameter is h0 / (h0 + h1), where the sum is affine
+		// and must stay positive throughout the span. Along the path the
+		// sum is linear, and profile offsets perpendicular to the path
+		// change it by at most the radius times the change in plane normal.
+		length := end.Dist(start)
+		planeDiff := plane0.Sub(plane1)
+		planeDiff = planeDiff.Sub(dir.Scale(dir.Dot(planeDiff)))
+		minSum := length*math.Min(dir.Dot(plane0), dir.Dot(plane1)) - radius*planeDiff.Norm()
+		if minSum <= 0 {
+			return nil, fmt.Errorf("sweep(): profile is too large for a bend in the path")
+		}
+		paramGrad := length / s.Length * (1 + plane0.Sub(plane1).Norm()) / minSum
+		shear := radius * paramGrad * (math.Abs(s.Twist)/minScale + scaleRate/(minScale*minScale))
+		span.Lipschitz = shearLipschitz(1/minScale, shear)
+		res[i] = span
+	}
+	return res, nil
+}
+
+// Local computes the untransformed profile coordinates of a point, and
+// the signed distances to the planes at both ends of the span. The
+// distances are positive between the planes.
+func (s *sweepSpan) Local(c model3d.Coord3D) (model2d.Coord, float64, float64) {
+	p := s.Path
+	i := s.Index
+	rel := c.Sub(p.point(i))
+	h0 := rel.Dot(p.Planes[i%len(p.Points)])
+	h1 := p.point(i + 1).Sub(c).Dot(p.Planes[(i+1)%len(p.Points)])
+	frac := 0.0
+	if h1 <= 0 {
+		frac = 1
+	} else if h0 > 0 {
+		frac = h0 / (h0 + h1)
+	}
+	t := p.spanParam(i, frac)
+
+	x := rel.Dot(p.Normals[i])
+	y := rel.Dot(p.Binormals[i])
+	sx := 1 + t*(p.Scale[0]-1)
+	sy := 1 + t*(p.Scale[1]-1)
+	cosA, sinA := math.Cos(p.Twist*t), math.Sin(p.Twist*t)
+	local := model2d.XY((x*cosA-y*sinA)/sx, (x*sinA+y*cosA)/sy)
+	return local, h0, h1
+}
+
+// IsEnd checks if the start or end of the span is an end of the path.
+func (s *sweepSpan) IsEnd() (bool, bool) {
+	if s.Path.Closed {
+		return false, false
+	}
+	return s.Index == 0, s.Index == s.Path.numSpans()-1
+}
+
+func (s *sweepSpan) Rect() *model3d.Rect {
+	return model3d.NewRect(s.Min, s.Max)
+}
+
+func sweepSpansBounds(spans []sweepSpan) (model3d.Coord3D, model3d.Coord3D) {
+	min, max := spans[0].Min, spans[0].Max
+	for _, span := range spans[1:] {
+		min, max = min.Min(span.Min), max.Max(span.Max)
+	}
+	return min, max
+}
+
+// sweepSolid sweeps a solid profile along the spans of a path.
+func sweepSolid(spans []sweepSpan, profile model2d.Solid) model3d.Solid {
+	min, max := sweepSpansBounds(spans)
+	return model3d.CheckedFuncSolid(min, max, func(c model3d.Coord3D) bool {
+		for i := range spans {
+			span := &spans[i]
+			if !span.Rect().Contains(c) {
+				continue
+			}
+			local, h0, h1 := span.Local(c)
+			if h0 >= 0 && h1 >= 0 && profile.Contains(local) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// sweepSDF sweeps a profile SDF along the spans of a path.
+//
+// The result is a lower bound on the true distance. The profile
+// distance is scaled down by the Lipschitz bound of each span, and the
+// planes between spans only bound the distance outside of a span, since
+// they are not part of the surface.
+func sweepSDF(spans []sweepSpan, profile model2d.SDF) model3d.SDF {
+	min, max := sweepSpansBounds(spans)
+	return model3d.FuncSDF(min, max, func(c model3d.Coord3D) float64 {
+		result := math.Inf(-1)
+		for i := range spans {
+			span := &spans[i]
+			if boxDist := span.Rect().SDF(c); boxDist < 0 {
+				// The distance to the bounding box is a lower bound on the
+				// distance to this span.
+				result = math.Max(result, boxDist)
+				continue
+			}
+			local, h0, h1 := span.Local(c)
+			d := profile.SDF(local) / span.Lipschitz
+			startEnd, endEnd := span.IsEnd()
+			if startEnd || h0 < 0 {
+				d = math.Min(d, h0)
+			}
+			if endEnd || h1 < 0 {
+				d = math.Min(d, h1)
+			}
+			result = math.Max(result, d)
+		}
+		return result
+	})
+}
+
+// sweepKernelStride is the number of floats stored for each span in the
+// buffer of a sweepKernel.
+const sweepKernelStride = 27
+
+// sweepKernel creates a kernel matching sweepSDF or sweepSolid from the
+// kernel of the profile.
+//
+// The frame, corner planes and bounds of each span are stored in a
+// buffer, and the spans are checked in order like on the CPU.
+func sweepKernel(
+	n shapekernel.Numerics,
+	profile shapekernel.ShapeKernel,
+	spans []sweepSpan,
+	sdf bool,
+) shapekernel.ShapeKernel {
+	if sdf && profile.Kind != shapekernel.SDF2D {
+		panic("expected 2D SDF kernel")
+	} else if !sdf && profile.Kind != shapekernel.Solid2D {
+		panic("expected 2D solid kernel")
+	}
+	path := spans[0].Path
+	data := make([]float32, 0, len(spans)*sweepKernelStride)
+	for i := range spans {
+		span := &spans[i]
+		idx := span.Index
+		for _, c := range []model3d.Coord3D{
+			path.point(idx),
+			path.point(idx + 1),
+			path.Planes[idx%len(path.Points)],
+			path.Planes[(idx+1)%len(path.Points)],
+			path.Normals[idx],
+			path.Binormals[idx],
+			span.Min,
+			span.Max,
+		} {
+			data = append(data, float32(c.X), float32(c.Y), float32(c.Z))
+		}
+		data = append(
+			data,
+			float32(path.spanParam(idx, 0)),
+			float32(path.spanParam(idx, 1)),
+			float32(span.Lipschitz),
+		)
+	}
+
+	k := profile
+	k.Buffers = append([]shapekernel.Buffer{}, k.Buffers...)
+	spansBuf := kernelBufferID(&k.IDs, "sweep_spans")
+	k.Buffers = append(k.Buffers, shapekernel.Float32Buffer(spansBuf, func() []float32 {
+		return data
+	}))
+	vecName := kernelFnID(&k.IDs, "sweep_vec")
+	localName := kernelFnID(&k.IDs, "sweep_local")
+	shapekernel.AppendWGSL(
+		&k,
+		`
+			fn {{.Vec}}(i: u32) -> vec3f {
+				return vec3f({{.Spans}}[i], {{.Spans}}[i + 1u], {{.Spans}}[i + 2u]);
+			}
+
+			// Computes the untransformed profile coordinates of a point,
+			// followed by the distances to the planes at both ends of the
+			// span starting at offset b.
+			fn {{.Local}}(b: u32, c: vec3f) -> vec4f {
+				let rel = c - {{.Vec}}(b);
+				let h0 = dot(rel, {{.Vec}}(b + 6u));
+				let h1 = dot({{.Vec}}(b + 3u) - c, {{.Vec}}(b + 9u));
+				var frac = 0.0;
+				if (h1 <= 0.0) {
+					frac = 1.0;
+				} else if (h0 > 0.0) {
+					frac = h0 / (h0 + h1);
+				}
+				let t0 = {{.Spans}}[b + 24u];
+				let t = t0 + frac * ({{.Spans}}[b + 25u] - t0);
+				let x = dot(rel, {{.Vec}}(b + 12u));
+				let y = dot(rel, {{.Vec}}(b + 15u));
+				let sx = 1.0 + t * ({{.ScaleX}} - 1.0);
+				let sy = 1.0 + t * ({{.ScaleY}} - 1.0);
+				let cosA = cos({{.Twist}} * t);
+				let sinA = sin({{.Twist}} * t);
+				return vec4f((x * cosA - y * sinA) / sx, (x * sinA + y * cosA) / sy, h0, h1);
+			}
+		`,
+		"Vec", vecName,
+		"Local", localName,
+		"Spans", spansBuf,
+		"ScaleX", float32(path.Scale[0]),
+		"ScaleY", float32(path.Scale[1]),
+		"Twist", float32(path.Twist),
+	)
+
+	if sdf {
+		fnName := kernelFnID(&k.IDs, "sweep_sdf")
+		shapekernel.AppendWGSL(
+			&k,
+			`
+				fn {{.Entrypoint}}(p_raw: {{.N.Dtype3}}) -> {{.N.Dtype}} {
+					let c = {{.N.AsFloat3}}(p_raw);
+					var result = -3.0e38;
+					for (var i = 0u; i < {{.NumSpans}}u; i++) {
+						let b = i * {{.Stride}}u;
+						let nearest = clamp(c, {{.Vec}}(b + 18u), {{.Vec}}(b + 21u));
+						if (any(nearest != c)) {
+							result = max(result, -distance(c, nearest));
+							continue;
+						}
+						let loc = {{.Local}}(b, c);
+						let q = {{.N.Make2}}({{.N.FromFloat}}(loc.x), {{.N.FromFloat}}(loc.y));
+						var d = {{.N.AsFloat}}({{.Profile}}(q)) / {{.Spans}}[b + 26u];
+						if ((!{{.Closed}} && i == 0u) || loc.z < 0.0) {
+							d = min(d, loc.z);
+						}
+						if ((!{{.Closed}} && i + 1u == {{.NumSpans}}u) || loc.w < 0.0) {
+							d = min(d, loc.w);
+						}
+						result = max(result, d);
+					}
+					return {{.N.FromFloat}}(result);
+				}
+			`,
+			"N", n.Symbols,
+			"Entrypoint", fnName,
+			"Profile", profile.EntrypointName,
+			"Vec", vecName,
+			"Local", localName,
+			"Spans", spansBuf,
+			"NumSpans", len(spans),
+			"Stride", sweepKernelStride,
+			"Closed", path.Closed,
+		)
+		k.Kind = shapekernel.SDF3D
+		k.EntrypointName = fnName
+	} else {
+		fnName := kernelFnID(&k.IDs, "sweep_solid")
+		shapekernel.AppendWGSL(
+			&k,
+			`
+				fn {{.Entrypoint}}(p_raw: {{.N.Dtype3}}) -> bool {
+					let c = {{.N.AsFloat3}}(p_raw);
+					for (var i = 0u; i < {{.NumSpans}}u; i++) {
+						let b = i * {{.Stride}}u;
+						if (any(c < {{.Vec}}(b + 18u)) || any(c > {{.Vec}}(b + 21u))) {
+							continue;
+						}
+						let loc = {{.Local}}(b, c);
+						let q = {{.N.Make2}}({{.N.FromFloat}}(loc.x), {{.N.FromFloat}}(loc.y));
+						if (loc.z >= 0.0 && loc.w >= 0.0 && {{.Profile}}(q)) {
+							return true;
+						}
+					}
+					return false;
+				}
+			`,
+			"N", n.Symbols,
+			"Entrypoint", fnName,
+			"Profile", profile.EntrypointName,
+			"Vec", vecName,
+			"Local", localName,
+			"Spans", spansBuf,
+			"NumSpans", len(spans),
+			"Stride", sweepKernelStride,
+		)
+		k.Kind = shapekernel.Solid3D
+		k.EntrypointName = fnName
+	}
+	return k
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
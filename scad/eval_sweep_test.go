@@ -0,0 +1,175 @@
+package scad
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/model3d/model3d"
+	shapekernel "github.com/unixpickle/webgpu-meshes/shapekernel"
+)
+
+func TestSweepMatchesLinearExtrude(t *testing.T) {
+	sweep := mustEvalShape(t, `
+		sweep([[0, 0, 0], [0, 0, 1], [0, 0, 2]], twist=90, scale=[0.5, 0.8])
+			square(size=[2, 1], center=true);
+	`)
+	extrude := mustEvalShape(t, `
+		linear_extrude(height=2, twist=90, scale=[0.5, 0.8])
+			square(size=[2, 1], center=true);
+	`)
+	if sweep.Kind != ShapeSolid3D {
+		t.Fatalf("expected Solid3D, got %v", sweep.Kind)
+	}
+	rng := rand.New(rand.NewSource(0))
+	for i := 0; i < 2000; i++ {
+		c := model3d.XYZ(rng.Float64()*3-1.5, rng.Float64()*3-1.5, rng.Float64()*2.4-0.2)
+		if sweep.S3.Contains(c) != extrude.S3.Contains(c) {
+			t.Fatalf("point %v: sweep and linear_extrude disagree", c)
+		}
+	}
+}
+
+func TestSweepMesh(t *testing.T) {
+	for _, tc := range []struct {
+		src    string
+		volume float64
+	}{
+		// An L-shaped path with a mitered corner.
+		{`sweep([[0, 0, 0], [4, 0, 0], [4, 4, 0]]) path_mesh("M-1,-1 L1,-1 L1,1 L-1,1 Z");`, 32},
+		// A closed square loop, which is a square frame.
+		{`sweep([[0, 0, 0], [4, 0, 0], [4, 4, 0], [0, 4, 0]], closed=true) path_mesh("M-1,-1 L1,-1 L1,1 L-1,1 Z");`, 64},
+		{`sweep([[0, 0, 0], [0, 0, 2]], scale=0.5) path_mesh("M-1,-1 L1,-1 L1,1 L-1,1 Z");`, 2 * 7.0 / 3},
+	} {
+		shape := mustEvalShape(t, tc.src)
+		if shape.Kind != ShapeMesh3D {
+			t.Fatalf("%s: expected Mesh3D, got %v", tc.src, shape.Kind)
+		}
+		if shape.M3.NeedsRepair() {
+			t.Fatalf("%s: mesh needs repair", tc.src)
+		}
+		if v := shape.M3.Volume(); math.Abs(v-tc.volume) > tc.volume*1e-3 {
+			t.Fatalf("%s: expected volume %f, got %f", tc.src, tc.volume, v)
+		}
+	}
+
+	// A closed circular path makes a torus.
+	shape := mustEvalShape(t, `
+		sweep([for (i = [0:63]) [3 * cos(i * 360 / 64), 3 * sin(i * 360 / 64), 0]], closed=true)
+			path_mesh("M1,0 A1,1 0 1 0 -1,0 A1,1 0 1 0 1,0");
+	`)
+	if shape.M3.NeedsRepair() {
+		t.Fatal("torus mesh needs repair")
+	}
+	if v, expected := shape.M3.Volume(), 2*math.Pi*math.Pi*3; math.Abs(v-expected) > expected*0.01 {
+		t.Fatalf("expected torus volume %f, got %f", expected, v)
+	}
+}
+
+func TestSweepSolidMatchesMesh(t *testing.T) {
+	for _, path := range []string{
+		`[[0, 0, 0], [4, 0, 0], [4, 4, 1], [0, 5, 3]], twist=45`,
+		`[[0, 0, 0], [4, 0, 0], [4, 4, 0], [0, 4, 2]], closed=true`,
+	} {
+		solid := mustEvalShape(t, `sweep(`+path+`) square(size=[2, 1], center=true);`)
+		mesh := mustEvalShape(t, `sweep(`+path+`) path_mesh("M-1,-0.5 L1,-0.5 L1,0.5 L-1,0.5 Z");`)
+		sdf := mustEvalShape(t, `sweep(`+path+`) square_sdf(size=[2, 1], center=true);`)
+		meshSolid := model3d.NewColliderSolid(model3d.MeshToCollider(mesh.M3))
+		meshSDF := model3d.MeshToSDF(mesh.M3)
+
+		min, max := mesh.M3.Min().AddScalar(-0.5), mesh.M3.Max().AddScalar(0.5)
+		rng := rand.New(rand.NewSource(0))
+		mismatches := 0
+		for i := 0; i < 4000; i++ {
+			c := model3d.NewCoord3DRandBounds(min, max, rng)
+			expected := meshSDF.SDF(c)
+			if math.Abs(expected) < 0.02 {
+				// Twisted spans of the mesh are only approximately ruled
+				// surfaces.
+				continue
+			}
+			if solid.S3.Contains(c) != meshSolid.Contains(c) {
+				mismatches++
+			}
+			d := sdf.SDF3.SDF(c)
+			if (d > 0) != (expected > 0) || math.Abs(d) > math.Abs(expected)+1e-3 {
+				t.Fatalf("%s: point %v: SDF %f but mesh distance %f", path, c, d, expected)
+			}
+		}
+		if mismatches > 0 {
+			t.Fatalf("%s: %d solid and mesh mismatches", path, mismatches)
+		}
+	}
+}
+
+func TestSweepKernels(t *testing.T) {
+	path := `[[0, 0, 0], [4, 0, 0], [4, 4, 1], [0, 5, 3]], twist=45`
+	for _, tc := range []struct {
+		profile string
+		kind    ShapeKind
+		kernel  shapekernel.ShapeKind
+	}{
+		{`square(size=[2, 1], center=true)`, ShapeSolid3D, shapekernel.Solid3D},
+		{`square_sdf(size=[2, 1], center=true)`, ShapeSDF3D, shapekernel.SDF3D},
+	} {
+		shape := mustEvalShape(t, `sweep(`+path+`) `+tc.profile+`;`)
+		if shape.Kind != tc.kind || shape.Kernel == nil {
+			t.Fatalf("%s: expected %v with kernel, got %v", tc.profile, tc.kind, shape.Kind)
+		}
+		k := shape.Kernel
+		if k.Kind != tc.kernel {
+			t.Fatalf("%s: unexpected kernel kind %v", tc.profile, k.Kind)
+		}
+		spans := k.Buffers[len(k.Buffers)-1]
+		if n := len(spans.Constructor()); n != 3*sweepKernelStride {
+			t.Fatalf("%s: expected 3 spans in kernel buffer, got %d floats", tc.profile, n)
+		}
+	}
+}
+
+func TestSweepSpline(t *testing.T) {
+	shape := mustEvalShape(t, `
+		sweep([[0, 0, 0], [5, 0, 0], [5, 5, 0]], spline=true, segments=16)
+			circle(r=0.5);
+	`)
+	// The spline passes through the control points.
+	for _, c := range []model3d.Coord3D{
+		model3d.XYZ(0.01, 0, 0),
+		model3d.XYZ(5, 0, 0),
+		model3d.XYZ(5, 4.99, 0),
+	} {
+		if !shape.S3.Contains(c) {
+			t.Fatalf("expected %v to be inside", c)
+		}
+	}
+	// The corner is rounded off, unlike a polyline.
+	if shape.S3.Contains(model3d.XYZ(5.4, -0.4, 0)) {
+		t.Fatal("expected the corner to be rounded")
+	}
+}
+
+func TestSweepErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`sweep([[0, 0, 0], [0, 0, 0]]) circle(r=1);`, "at least 2 distinct points"},
+		{`sweep([[0, 0, 0], [1, 0, 0], [0, 0, 0]]) circle(r=1);`, "turns back on itself"},
+		{`sweep([[0, 0, 0], [1, 0, 0], [1, 1, 0]], closed=true, twist=90) circle(r=0.1);`, "multiple of 360"},
+		{`sweep([[0, 0, 0], [1, 0, 0], [1, 1, 0]], closed=true, scale=2) circle(r=0.1);`, "scale must be 1"},
+		{`sweep([[0, 0, 0], [4, 0, 0], [4, 1, 0]]) square(10, center=true);`, "too large for a bend"},
+		{`sweep([[0, 0], [1, 0]]) circle(r=1);`, "[x, y, z]"},
+		{`sweep([[0, 0, 0], [0, 0, 1]]) sphere(r=1);`, "requires 2D children"},
+	}
+	for _, tc := range tests {
+		prog, err := Parse(tc.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = Eval(prog, Hooks{})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", tc.src, tc.want, err)
+		}
+	}
+}
@@ -1052,6 +1052,61 @@ func TestBindArgsStrictErrors(t *testing.T) {
 	}
 }
 
+func TestLinearExtrudeMeshTransform(t *testing.T) {
+	for _, tc := range []struct {
+		src    string
+		volume float64
+	}{
+		{`linear_extrude(height=1, scale=0.5) path_mesh("M-1,-1 L1,-1 L1,1 L-1,1 Z");`, 7.0 / 3},
+		{`linear_extrude(height=1, scale=0) path_mesh("M-1,-1 L1,-1 L1,1 L-1,1 Z");`, 4.0 / 3},
+		{`linear_extrude(height=2, twist=90, slices=30) path_mesh("M-1,-1 L1,-1 L1,1 L-1,1 Z");`, 8},
+	} {
+		shape := mustEvalShape(t, tc.src)
+		if shape.Kind != ShapeMesh3D {
+			t.Fatalf("%s: expected Mesh3D, got %v", tc.src, shape.Kind)
+		}
+		if shape.M3.NeedsRepair() {
+			t.Fatalf("%s: mesh needs repair", tc.src)
+		}
+		if v := shape.M3.Volume(); math.Abs(v-tc.volume) > tc.volume*0.01 {
+			t.Fatalf("%s: expected volume %f, got %f", tc.src, tc.volume, v)
+		}
+	}
+
+	// The top of a twisted extrusion is rotated clockwise, as for solids.
+	shape := mustEvalShape(t, `linear_extrude(height=1, twist=90) path_mesh("M0,-0.1 L2,-0.1 L2,0.1 L0,0.1 Z");`)
+	max := shape.M3.Max()
+	if math.Abs(max.X-2) > 0.01 || shape.M3.Min().Y > -1.99 {
+		t.Fatalf("unexpected bounds %v %v", shape.M3.Min(), max)
+	}
+}
+
+func TestLinearExtrudeSDFTransform(t *testing.T) {
+	sdfShape := mustEvalShape(t, `linear_extrude(height=2, twist=60, scale=[0.5, 0.8]) square_sdf(size=[2, 1], center=true);`)
+	if sdfShape.Kind != ShapeSDF3D || sdfShape.Kernel == nil {
+		t.Fatalf("expected SDF3D with kernel, got %v", sdfShape.Kind)
+	}
+	if !strings.Contains(sdfShape.Kernel.Code, "linear_extrude") {
+		t.Fatal("expected linear_extrude in kernel code")
+	}
+	solidShape := mustEvalShape(t, `linear_extrude(height=2, twist=60, scale=[0.5, 0.8]) square(size=[2, 1], center=true);`)
+	rng := rand.New(rand.NewSource(0))
+	for i := 0; i < 1000; i++ {
+		c := model3d.XYZ(rng.Float64()*3-1.5, rng.Float64()*3-1.5, rng.Float64()*2.4-0.2)
+		d := sdfShape.SDF3.SDF(c)
+		if math.Abs(d) < 1e-6 {
+			continue
+		}
+		if (d > 0) != solidShape.S3.Contains(c) {
+			t.Fatalf("point %v: SDF %f disagrees with solid", c, d)
+		}
+	}
+	// The distance field is scaled down to remain a lower bound.
+	if d := sdfShape.SDF3.SDF(model3d.XYZ(0, 0, 1)); d <= 0 || d > 0.5 {
+		t.Fatalf("unexpected center SDF %f", d)
+	}
+}
+
 func TestLinearExtrudeArgErrors(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -1059,36 +1114,36 @@ func TestLinearExtrudeArgErrors(t *testing.T) {
 		wantErr string
 	}{
 		{
-			name: "MeshScaleUnsupported",
+			name: "MeshScaleNegative",
 			src: `
-				linear_extrude(height=1, scale=2)
+				linear_extrude(height=1, scale=-1)
 					path_mesh("M0,0 L1,0 L1,1 Z");
 			`,
-			wantErr: "scale != 1 is unsupported for Mesh",
+			wantErr: "scale must be positive for Mesh",
 		},
 		{
-			name: "MeshTwistUnsupported",
+			name: "MeshScaleLine",
 			src: `
-				linear_extrude(height=1, twist=10)
+				linear_extrude(height=1, scale=[0, 1])
 					path_mesh("M0,0 L1,0 L1,1 Z");
 			`,
-			wantErr: "twist != 0 is unsupported for Mesh",
+			wantErr: "scale must be positive for Mesh",
 		},
 		{
-			name: "SDFScaleUnsupported",
+			name: "SDFScaleZero",
 			src: `
-				linear_extrude(height=1, scale=2)
+				linear_extrude(height=1, scale=0)
 					circle_sdf(r=1);
 			`,
-			wantErr: "scale != 1 is unsupported for SDF",
+			wantErr: "scale must be positive for SDF",
 		},
 		{
-			name: "SDFTwistUnsupported",
+			name: "SlicesNotInteger",
 			src: `
-				linear_extrude(height=1, twist=10)
+				linear_extrude(height=1, twist=10, slices=2.5)
 					circle_sdf(r=1);
 			`,
-			wantErr: "twist != 0 is unsupported for SDF",
+			wantErr: "slices must be a positive integer",
 		},
 	}
 	for _, tc := range tests {
@@ -2277,6 +2332,77 @@ func TestRotateExtrudeSDFTorusAgreement(t *testing.T) {
 	}
 }
 
+func TestRotateExtrudeSDFPartial(t *testing.T) {
+	shape := mustEvalShape(t, `
+		rotate_extrude(angle=90)
+			translate([3, 0, 0])
+			circle_sdf(r=1);
+	`)
+	if shape.Kind != ShapeSDF3D || shape.Kernel == nil {
+		t.Fatalf("expected ShapeSDF3D with kernel, got %v", shape.Kind)
+	}
+	for _, c := range []struct {
+		P    model3d.Coord3D
+		Dist float64
+	}{
+		// Inside, the closest point may be on a cap.
+		{model3d.XYZ(0, 3, 0), 0},
+		{model3d.XYZ(3, 0.2, 0), 0.2},
+		{model3d.XYZ(3*math.Sqrt(0.5), 3*math.Sqrt(0.5), 0.5), 0.5},
+		// Outside the angle range, the closest point is on a cap.
+		{model3d.XYZ(3, -0.5, 0), -0.5},
+		{model3d.XYZ(3, -0.5, 2), -math.Hypot(0.5, 1)},
+		// Behind the axis, the distance to the axis is used as a bound.
+		{model3d.XYZ(-3, -3, 0), -math.Hypot(math.Sqrt(18), 2)},
+		// Inside the angle range, it is on the revolved surface.
+		{model3d.XYZ(0, 5, 0), -1},
+	} {
+		if d := shape.SDF3.SDF(c.P); math.Abs(d-c.Dist) > 1e-8 {
+			t.Fatalf("point %v: expected %f, got %f", c.P, c.Dist, d)
+		}
+	}
+
+	shape = mustEvalShape(t, `
+		rotate_extrude(angle=-90, start=90)
+			translate([3, 0, 0])
+			circle_sdf(r=1);
+	`)
+	if d := shape.SDF3.SDF(model3d.XYZ(3, 0.2, 0)); math.Abs(d-0.2) > 1e-8 {
+		t.Fatalf("unexpected SDF for negative angle: %f", d)
+	}
+}
+
+func TestRotateExtrudeMesh(t *testing.T) {
+	for _, tc := range []struct {
+		src    string
+		volume float64
+	}{
+		{`rotate_extrude() path_mesh("M1,0 L2,0 L2,1 L1,1 Z");`, 3 * math.Pi},
+		{`rotate_extrude(angle=90) path_mesh("M1,0 L2,0 L2,1 L1,1 Z");`, 3 * math.Pi / 4},
+		{`rotate_extrude(angle=-270, start=45) path_mesh("M-1,0 L-2,0 L-2,1 L-1,1 Z");`, 9 * math.Pi / 4},
+		{`rotate_extrude(segments=128) path_mesh("M0,0 L1,0 L1,1 L0,1 Z");`, math.Pi},
+	} {
+		shape := mustEvalShape(t, tc.src)
+		if shape.Kind != ShapeMesh3D {
+			t.Fatalf("%s: expected ShapeMesh3D, got %v", tc.src, shape.Kind)
+		}
+		if shape.M3.NeedsRepair() {
+			t.Fatalf("%s: mesh needs repair", tc.src)
+		}
+		if v := shape.M3.Volume(); math.Abs(v-tc.volume) > tc.volume*0.01 {
+			t.Fatalf("%s: expected volume %f, got %f", tc.src, tc.volume, v)
+		}
+	}
+
+	prog, err := Parse(`rotate_extrude() path_mesh("M-1,0 L1,0 L1,1 L-1,1 Z");`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Eval(prog, Hooks{}); err == nil || !strings.Contains(err.Error(), "cross the Z axis") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 func TestRootLevelUnion(t *testing.T) {
 	code := `
 	dual_contour(0.05) sphere(r=1);
@@ -2,17 +2,12 @@ package scad
 
 import (
 	"fmt"
-	"sync"
+	"unicode"
 
-	m3dfonts "github.com/unixpickle/m3dscad/fonts"
+	"github.com/go-text/typesetting/language"
 	"github.com/unixpickle/model3d/model2d"
 	"github.com/unixpickle/textcurve"
-)
-
-var (
-	liberationFontOnce sync.Once
-	liberationFont     *textcurve.ParsedFont
-	liberationFontErr  error
+	"golang.org/x/image/math/fixed"
 )
 
 func handleText(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
@@ -40,114 +35,604 @@ func handleTextSDF(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, e
 }
 
 func parseTextMesh(e *env, st *CallStmt) (*model2d.Mesh, error) {
-	args, err := bindArgs(e, st.Call, []ArgSpec{
+	layout, text, align, err := parseTextLayout(e, "text", st.Call)
+	if err != nil {
+		return nil, err
+	}
+	return alignedTextMesh("text", layout, text, align)
+}
+
+// alignedTextMesh lays out text and applies the alignment, failing if the
+// text has no visible glyphs.
+func alignedTextMesh(opName string, layout *textLayout, text []rune, align textAlign) (*model2d.Mesh, error) {
+	mesh, advance := layout.Mesh(text)
+	if mesh.NumSegments() == 0 {
+		return nil, fmt.Errorf("%s(): no outlines produced", opName)
+	}
+	offset := align.Offset(mesh.Min(), mesh.Max(), advance, layout.Direction.IsVertical())
+	return mesh.Translate(offset), nil
+}
+
+// evalTextMetrics implements textmetrics(), which measures text with the
+// same layout as text(). The result is a list of
+// [position, size, ascent, descent, offset, advance], where position and
+// size give the aligned outline bounds, ascent and descent are measured
+// from the baseline, offset is the alignment translation, and advance is
+// the pen movement.
+func evalTextMetrics(e *env, c Call) (Value, error) {
+	layout, text, align, err := parseTextLayout(e, "textmetrics", c)
+	if err != nil {
+		return Value{}, err
+	}
+	mesh, advance := layout.Mesh(text)
+	var min, max model2d.Coord
+	if mesh.NumSegments() > 0 {
+		min, max = mesh.Min(), mesh.Max()
+	}
+	offset := align.Offset(min, max, advance, layout.Direction.IsVertical())
+	vec := func(c model2d.Coord) Value {
+		return List([]Value{Num(c.X), Num(c.Y)})
+	}
+	return List([]Value{
+		vec(min.Add(offset)),
+		vec(max.Sub(min)),
+		Num(max.Y),
+		Num(-min.Y),
+		vec(offset),
+		vec(advance),
+	}), nil
+}
+
+// evalFontMetrics implements fontmetrics(size=10, font). The result is a
+// list of [[ascent, descent], [max_ascent, max_descent], interline,
+// [family, style]] in model units, where the nominal ascent equals the
+// size, like the text() scaling.
+func evalFontMetrics(e *env, c Call) (Value, error) {
+	args, err := bindArgs(e, c, []ArgSpec{
+		{Name: "size", Pos: 0, Default: Num(10)},
+		{Name: "font", Pos: 1, Default: String(defaultTextFamily)},
+	})
+	if err != nil {
+		return Value{}, err
+	}
+	size, err := argNum(args, "size")
+	if err != nil {
+		return Value{}, err
+	}
+	fontName, err := argString(args, "font")
+	if err != nil {
+		return Value{}, err
+	}
+	if size <= 0 {
+		return Value{}, fmt.Errorf("fontmetrics(): size must be > 0")
+	}
+	fonts, err := selectTextFonts(e, "fontmetrics", fontName)
+	if err != nil {
+		return Value{}, err
+	}
+	f := fonts[0]
+	scale := f.Scale(size)
+	return List([]Value{
+		List([]Value{Num(f.Ascent * scale), Num(f.Descent * scale)}),
+		List([]Value{Num(f.MaxAscent * scale), Num(f.MaxDescent * scale)}),
+		Num((f.Ascent + f.Descent + f.LineGap) * scale),
+		List([]Value{String(f.Family), String(f.Style)}),
+	}), nil
+}
+
+// textArgSpecs returns the arguments shared by text() and the functions
+// and modules built on its layout.
+func textArgSpecs() []ArgSpec {
+	return []ArgSpec{
 		{Name: "text", Pos: 0, Required: true},
 		{Name: "size", Pos: 1, Default: Num(10)},
-		{Name: "font", Pos: 2, Default: String("Liberation Sans")},
+		{Name: "font", Pos: 2, Default: String(defaultTextFamily)},
 		{Name: "halign", Pos: 3, Default: String("left")},
 		{Name: "valign", Pos: 4, Default: String("baseline")},
 		{Name: "spacing", Pos: 5, Default: Num(1)},
 		{Name: "segments", Pos: 6, Default: Num(8)},
-	})
+		{Name: "direction", Pos: -1, Default: Value{}},
+		{Name: "language", Pos: -1, Default: String("en")},
+		{Name: "script", Pos: -1, Default: Value{}},
+	}
+}
+
+func parseTextLayout(e *env, opName string, c Call) (*textLayout, []rune, textAlign, error) {
+	args, err := bindArgs(e, c, textArgSpecs())
 	if err != nil {
-		return nil, err
+		return nil, nil, textAlign{}, err
 	}
+	return parseTextLayoutArgs(e, opName, args)
+}
 
+// parseTextLayoutArgs parses arguments bound with textArgSpecs().
+func parseTextLayoutArgs(e *env, opName string, args map[string]Value) (*textLayout, []rune, textAlign,
+	error) {
 	text, err := argString(args, "text")
 	if err != nil {
-		return nil, err
+		return nil, nil, textAlign{}, err
 	}
 	size, err := argNum(args, "size")
 	if err != nil {
-		return nil, err
+		return nil, nil, textAlign{}, err
 	}
-	font, err := argString(args, "font")
+	fontName, err := argString(args, "font")
 	if err != nil {
-		return nil, err
+		return nil, nil, textAlign{}, err
 	}
 	halign, err := argString(args, "halign")
 	if err != nil {
-		return nil, err
+		return nil, nil, textAlign{}, err
 	}
 	valign, err := argString(args, "valign")
 	if err != nil {
-		return nil, err
+		return nil, nil, textAlign{}, err
 	}
 	spacing, err := argNum(args, "spacing")
 	if err != nil {
-		return nil, err
+		return nil, nil, textAlign{}, err
 	}
 	segments, err := argNum(args, "segments")
 	if err != nil {
-		return nil, err
+		return nil, nil, textAlign{}, err
+	}
+	// The language is accepted for compatibility with OpenSCAD, but
+	// textcurve shapes every string with the font's default features.
+	if _, err := argString(args, "language"); err != nil {
+		return nil, nil, textAlign{}, err
 	}
 	if float64(int(segments)) != segments {
-		return nil, fmt.Errorf("text(): segments must be an integer")
+		return nil, nil, textAlign{}, fmt.Errorf("%s(): segments must be an integer", opName)
+	}
+	if segments < 1 {
+		return nil, nil, textAlign{}, fmt.Errorf("%s(): segments must be positive", opName)
 	}
 	if size <= 0 {
-		return nil, fmt.Errorf("text(): size must be > 0")
+		return nil, nil, textAlign{}, fmt.Errorf("%s(): size must be > 0", opName)
 	}
-
-	if font != "Liberation Sans" && font != "Liberation Sans:style=Regular" {
-		return nil, fmt.Errorf("text(): unsupported font %q", font)
+	if spacing <= 0 {
+		return nil, nil, textAlign{}, fmt.Errorf("%s(): spacing must be positive", opName)
 	}
 
-	align, err := parseTextAlign(halign, valign)
+	align, err := parseTextAlign(opName, halign, valign)
 	if err != nil {
-		return nil, err
+		return nil, nil, textAlign{}, err
 	}
 
-	parsed, err := getLiberationFont()
+	fonts, err := selectTextFonts(e, opName, fontName)
 	if err != nil {
-		return nil, err
+		return nil, nil, textAlign{}, err
 	}
-	outlines, err := textcurve.TextOutlines(parsed, text, textcurve.Options{
-		Size:      size,
-		CurveSegs: int(segments),
-		Align:     align,
-		Kerning:   true,
-		Spacing:   spacing,
-	})
-	if err != nil {
-		return nil, err
+	layout := &textLayout{
+		Fonts:    fonts,
+		Size:     size,
+		Spacing:  spacing,
+		Segments: int(segments),
+	}
+	runes := []rune(text)
+	layout.Direction = guessTextDirection(runes)
+	if scriptArg := args["script"]; scriptArg.Kind != ValNull {
+		name, err := argString(args, "script")
+		if err != nil {
+			return nil, nil, textAlign{}, err
+		}
+		script, err := parseTextScript(opName, name)
+		if err != nil {
+			return nil, nil, textAlign{}, err
+		}
+		layout.Direction = scriptDirection(script)
 	}
-	mesh := textcurve.OutlinesMesh(outlines)
-	if mesh == nil || mesh.NumSegments() == 0 {
-		return nil, fmt.Errorf("text(): no outlines produced")
+	if dirArg := args["direction"]; dirArg.Kind != ValNull {
+		name, err := argString(args, "direction")
+		if err != nil {
+			return nil, nil, textAlign{}, err
+		}
+		if layout.Direction, err = parseTextDirection(opName, name); err != nil {
+			return nil, nil, textAlign{}, err
+		}
 	}
-	return mesh, nil
+	return layout, runes, align, nil
+}
+
+// textAlign is a validated halign/valign pair.
+type textAlign struct {
+	HAlign string
+	VAlign string
 }
 
-func parseTextAlign(halign, valign string) (textcurve.Align, error) {
-	var res textcurve.Align
+func parseTextAlign(opName, halign, valign string) (textAlign, error) {
 	switch halign {
+	case "left", "center", "right":
+	default:
+		return textAlign{}, fmt.Errorf("%s(): invalid halign %q", opName, halign)
+	}
+	switch valign {
+	case "baseline", "top", "center", "bottom":
+	default:
+		return textAlign{}, fmt.Errorf("%s(): invalid valign %q", opName, valign)
+	}
+	return textAlign{HAlign: halign, VAlign: valign}, nil
+}
+
+// Offset computes the translation that aligns text with the given outline
+// bounds and pen advance.
+//
+// Like OpenSCAD, horizontal text aligns "left" and "right" to the pen
+// origin and advance rather than to the outline bounds. Vertical text
+// aligns "baseline" and "bottom" to the pen origin and advance instead.
+func (t textAlign) Offset(min, max, advance model2d.Coord, vertical bool) model2d.Coord {
+	var res model2d.Coord
+	switch t.HAlign {
 	case "left":
-		res.HAlign = textcurve.HAlignLeft
+		if vertical {
+			res.X = -min.X
+		}
 	case "center":
-		res.HAlign = textcurve.HAlignCenter
+		res.X = -(min.X + max.X) / 2
 	case "right":
-		res.HAlign = textcurve.HAlignRight
-	default:
-		return textcurve.Align{}, fmt.Errorf("text(): invalid halign %q", halign)
+		if vertical {
+			res.X = -max.X
+		} else {
+			res.X = -advance.X
+		}
 	}
-	switch valign {
-	case "baseline":
-		res.VAlign = textcurve.VAlignBaseline
+	switch t.VAlign {
 	case "top":
-		res.VAlign = textcurve.VAlignTop
+		res.Y = -max.Y
 	case "center":
-		res.VAlign = textcurve.VAlignCenter
+		res.Y = -(min.Y + max.Y) / 2
 	case "bottom":
-		res.VAlign = textcurve.VAlignBottom
+		if vertical {
+			res.Y = -advance.Y
+		} else {
+			res.Y = -min.Y
+		}
+	}
+	return res
+}
+
+// textDirection is the direction in which text is laid out.
+type textDirection int
+
+const (
+	textLTR textDirection = iota
+	textRTL
+	textTTB
+)
+
+// IsVertical checks if glyphs are stacked from top to bottom.
+func (t textDirection) IsVertical() bool {
+	return t == textTTB
+}
+
+func parseTextDirection(opName, name string) (textDirection, error) {
+	switch name {
+	case "ltr":
+		return textLTR, nil
+	case "rtl":
+		return textRTL, nil
+	case "ttb":
+		return textTTB, nil
 	default:
-		return textcurve.Align{}, fmt.Errorf("text(): invalid valign %q", valign)
+		return 0, fmt.Errorf("%s(): direction must be \"ltr\", \"rtl\" or \"ttb\", got %q", opName, name)
 	}
-	return res, nil
 }
 
-func getLiberationFont() (*textcurve.ParsedFont, error) {
-	liberationFontOnce.Do(func() {
-		liberationFont, liberationFontErr = textcurve.ParseTTF(m3dfonts.LiberationSansRegularTTF)
-	})
-	return liberationFont, liberationFontErr
+// scriptDirection returns the direction in which a script is written.
+func scriptDirection(script language.Script) textDirection {
+	switch script {
+	case language.Arabic, language.Hebrew, language.Syriac, language.Thaana, language.Nko,
+		language.Samaritan, language.Mandaic, language.Adlam, language.Hanifi_Rohingya:
+		return textRTL
+	}
+	return textLTR
+}
+
+// guessTextDirection picks right-to-left layout if the first strongly typed
+// character belongs to a right-to-left script.
+func guessTextDirection(text []rune) textDirection {
+	for _, r := range text {
+		script := language.LookupScript(r)
+		if script.Strong() && script != language.Unknown {
+			return scriptDirection(script)
+		}
+	}
+	return textLTR
+}
+
+// parseTextScript parses an ISO 15924 script code such as "latn" or "arab".
+func parseTextScript(opName, name string) (language.Script, error) {
+	if len(name) == 4 {
+		if script, err := language.ParseScript(name); err == nil {
+			for _, r := range language.ScriptRanges {
+				if r.Script == script {
+					return script, nil
+				}
+			}
+		}
+	}
+	return 0, fmt.Errorf("%s(): unknown script %q (expected an ISO 15924 code like \"latn\")", opName, name)
+}
+
+// textLayout positions text with textcurve, using a primary font and
+// fallbacks.
+//
+// textcurve lays out a string in one font from left to right, so text is
+// split into runs which each use one font, and the runs are placed one
+// after another. Right-to-left text is laid out by reversing the
+// characters, and vertical text by stacking the characters.
+type textLayout struct {
+	Fonts     []*textFont
+	Size      float64
+	Spacing   float64
+	Segments  int
+	Direction textDirection
+}
+
+// A textRun is a range of text laid out with one font.
+type textRun struct {
+	Font       *textFont
+	Start, End int
+}
+
+// Runs splits text wherever the font changes.
+// Each character uses the first font that has a glyph for it, while spaces
+// and combining marks stay in the surrounding run.
+func (t *textLayout) Runs(text []rune) []textRun {
+	var runs []textRun
+	for i, r := range text {
+		if len(runs) > 0 && isTextJoiner(r) {
+			runs[len(runs)-1].End = i + 1
+			continue
+		}
+		f := t.Fonts[0]
+		for _, candidate := range t.Fonts {
+			if candidate.HasGlyph(r) {
+				f = candidate
+				break
+			}
+		}
+		if len(runs) > 0 && runs[len(runs)-1].Font == f {
+			runs[len(runs)-1].End = i + 1
+			continue
+		}
+		runs = append(runs, textRun{Font: f, Start: i, End: i + 1})
+	}
+	return runs
+}
+
+// isTextJoiner checks if a character is laid out with the font of the
+// character before it.
+func isTextJoiner(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsControl(r) || unicode.Is(unicode.Mn, r)
+}
+
+// visualRuns returns the runs in the order they are placed, with the
+// characters of each run in the order textcurve should lay them out.
+func (t *textLayout) visualRuns(text []rune) ([]*textFont, [][]rune) {
+	runs := t.Runs(text)
+	fonts := make([]*textFont, len(runs))
+	strs := make([][]rune, len(runs))
+	for i, run := range runs {
+		fonts[i] = run.Font
+		strs[i] = append([]rune{}, text[run.Start:run.End]...)
+	}
+	if t.Direction == textRTL {
+		for i := 0; i < len(runs)/2; i++ {
+			j := len(runs) - 1 - i
+			fonts[i], fonts[j] = fonts[j], fonts[i]
+			strs[i], strs[j] = strs[j], strs[i]
+		}
+		for _, s := range strs {
+			reverseTextClusters(s)
+		}
+	}
+	return fonts, strs
+}
+
+// reverseTextClusters reverses the characters of text in place, keeping
+// combining marks after the characters they modify.
+func reverseTextClusters(text []rune) {
+	for i := 0; i < len(text)/2; i++ {
+		text[i], text[len(text)-1-i] = text[len(text)-1-i], text[i]
+	}
+	for i := 0; i < len(text); {
+		j := i
+		for j < len(text) && unicode.Is(unicode.Mn, text[j]) {
+			j++
+		}
+		if j > i && j < len(text) {
+			// The marks now precede their base character.
+			for a, b := i, j; a < b; a, b = a+1, b-1 {
+				text[a], text[b] = text[b], text[a]
+			}
+		}
+		i = j + 1
+	}
+}
+
+// textClusters splits text into characters along with the combining
+// marks that follow them.
+func textClusters(text []rune) [][]rune {
+	var res [][]rune
+	for i, r := range text {
+		if len(res) > 0 && unicode.Is(unicode.Mn, r) {
+			res[len(res)-1] = text[i-len(res[len(res)-1]) : i+1]
+			continue
+		}
+		res = append(res, text[i:i+1])
+	}
+	return res
+}
+
+// layoutRun lays out a string in one font with textcurve, starting from a
+// pen at the origin, and returns the outlines and the pen advance.
+func (t *textLayout) layoutRun(f *textFont, text []rune) ([][]model2d.Coord, float64) {
+	opts := textcurve.Options{
+		Size:      t.Size,
+		CurveSegs: t.Segments,
+		Kerning:   true,
+		Spacing:   t.Spacing,
+	}
+	outlines, err := textcurve.TextOutlines(f.Parsed, string(text), opts)
+	if err != nil || len(outlines) == 0 {
+		// Without outlines, the advance is the sum of the glyph advances.
+		ttf := f.Parsed.TTFont
+		upem := fixed.Int26_6(ttf.FUnitsPerEm() * 64)
+		var advance float64
+		for _, r := range text {
+			advance += float64(ttf.HMetric(upem, ttf.Index(r)).AdvanceWidth) / 64
+		}
+		return nil, advance * f.Scale(t.Size) * t.Spacing
+	}
+
+	// textcurve aligns text to the right by its advance, which is otherwise
+	// not exposed.
+	opts.Align.HAlign = textcurve.HAlignRight
+	right, err := textcurve.TextOutlines(f.Parsed, string(text), opts)
+	if err != nil || len(right) == 0 {
+		return nil, 0
+	}
+	res := make([][]model2d.Coord, len(outlines))
+	for i, contour := range outlines {
+		res[i] = textContour(contour)
+	}
+	return res, outlines[0][0].X - right[0][0].X
+}
+
+// textContour converts a closed textcurve contour to a polyline without
+// repeated points.
+func textContour(contour textcurve.Contour) []model2d.Coord {
+	var res []model2d.Coord
+	for _, p := range contour {
+		if len(res) == 0 || p != res[len(res)-1] {
+			res = append(res, p)
+		}
+	}
+	if len(res) > 1 && res[0] == res[len(res)-1] {
+		res = res[:len(res)-1]
+	}
+	return res
+}
+
+// A textGlyph is a character positioned by the layout.
+type textGlyph struct {
+	// Contours are closed polylines in model units.
+	Contours [][]model2d.Coord
+
+	// Pen is the pen position where the glyph was placed, and Advance is
+	// the glyph's own advance, including letter spacing.
+	Pen     model2d.Coord
+	Advance model2d.Coord
+}
+
+// Glyphs lays out the text starting from a pen at the origin, returning the
+// characters in visual order along with the final pen position.
+//
+// Characters are placed as in Mesh, including kerning, but ligatures are
+// drawn as their separate characters.
+func (t *textLayout) Glyphs(text []rune) ([]textGlyph, model2d.Coord) {
+	if t.Direction.IsVertical() {
+		return t.verticalGlyphs(text)
+	}
+	var glyphs []textGlyph
+	var pen model2d.Coord
+	fonts, strs := t.visualRuns(text)
+	for i, s := range strs {
+		var prefix []rune
+		var prefixAdvance float64
+		for _, cluster := range textClusters(s) {
+			contours, advance := t.layoutRun(fonts[i], cluster)
+
+			// Kerning changes the advance of the previous character, so
+			// the character is placed at the end of the text up to and
+			// including it, minus its own advance.
+			prefix = append(prefix, cluster...)
+			_, prefixAdvance = t.layoutRun(fonts[i], prefix)
+			origin := pen.Add(model2d.X(prefixAdvance - advance))
+			glyphs = append(glyphs, textGlyph{
+				Contours: translateTextContours(contours, origin),
+				Pen:      origin,
+				Advance:  model2d.X(advance),
+			})
+		}
+		pen = pen.Add(model2d.X(prefixAdvance))
+	}
+	return glyphs, pen
+}
+
+// verticalGlyphs stacks characters from top to bottom, centered on the
+// y-axis, moving the pen down by the height of the font for each one.
+func (t *textLayout) verticalGlyphs(text []rune) ([]textGlyph, model2d.Coord) {
+	var glyphs []textGlyph
+	var pen model2d.Coord
+	runs := t.Runs(text)
+	for _, run := range runs {
+		for _, cluster := range textClusters(text[run.Start:run.End]) {
+			contours, _ := t.layoutRun(run.Font, cluster)
+			var min, max model2d.Coord
+			for i, contour := range contours {
+				for j, c := range contour {
+					if i == 0 && j == 0 {
+						min, max = c, c
+					}
+					min, max = min.Min(c), max.Max(c)
+				}
+			}
+			origin := pen.Add(model2d.XY(-(min.X+max.X)/2, -t.Size))
+			f := run.Font
+			advance := model2d.Y(-(f.Ascent + f.Descent) * f.Scale(t.Size) * t.Spacing)
+			glyphs = append(glyphs, textGlyph{
+				Contours: translateTextContours(contours, origin),
+				Pen:      pen,
+				Advance:  advance,
+			})
+			pen = pen.Add(advance)
+		}
+	}
+	return glyphs, pen
+}
+
+// Mesh lays out the text and joins the contours into a mesh, along with
+// the final pen position.
+//
+// Horizontal runs are laid out by textcurve as a whole, so the outlines
+// of text in a single font are exactly those of textcurve.
+func (t *textLayout) Mesh(text []rune) (*model2d.Mesh, model2d.Coord) {
+	mesh := model2d.NewMesh()
+	if t.Direction.IsVertical() {
+		glyphs, advance := t.verticalGlyphs(text)
+		for _, g := range glyphs {
+			addTextContours(mesh, g.Contours)
+		}
+		return mesh, advance
+	}
+	var pen model2d.Coord
+	fonts, strs := t.visualRuns(text)
+	for i, s := range strs {
+		contours, advance := t.layoutRun(fonts[i], s)
+		addTextContours(mesh, translateTextContours(contours, pen))
+		pen = pen.Add(model2d.X(advance))
+	}
+	return mesh, pen
+}
+
+func translateTextContours(contours [][]model2d.Coord, offset model2d.Coord) [][]model2d.Coord {
+	res := make([][]model2d.Coord, len(contours))
+	for i, contour := range contours {
+		res[i] = make([]model2d.Coord, len(contour))
+		for j, c := range contour {
+			res[i][j] = c.Add(offset)
+		}
+	}
+	return res
+}
+
+// addTextContours adds closed polylines to a mesh.
+func addTextContours(mesh *model2d.Mesh, contours [][]model2d.Coord) {
+	for _, contour := range contours {
+		for i, p := range contour {
+			mesh.Add(&model2d.Segment{p, contour[(i+1)%len(contour)]})
+		}
+	}
 }
@@ -1,11 +1,16 @@
 package scad
 
 import (
+	"math"
 	"math/rand"
 	"strings"
 	"testing"
 
+	m3dfonts "github.com/unixpickle/m3dscad/fonts"
 	"github.com/unixpickle/model3d/model2d"
+	"github.com/unixpickle/textcurve"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
 )
 
 func TestTextAndTextMeshParity(t *testing.T) {
@@ -38,6 +43,39 @@ func TestTextAndTextSDFParity(t *testing.T) {
 	assertSolids2DEqual(t, shapeA.S2, shapeB.S2)
 }
 
+func TestTextcurveParity(t *testing.T) {
+	// Text in a single font is laid out by textcurve as a whole.
+	parsed, err := textcurve.ParseTTF(m3dfonts.LiberationSansRegularTTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outlines, err := textcurve.TextOutlines(parsed, "AVATAR fit", textcurve.Options{
+		Size:      7,
+		CurveSegs: 5,
+		Kerning:   true,
+		Spacing:   1.1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := textcurve.OutlinesMesh(outlines)
+	actual := mustEvalShape(t, `text_mesh("AVATAR fit", size=7, spacing=1.1, segments=5);`).M2
+	if actual.NumSegments() != expected.NumSegments() {
+		t.Fatalf("expected %d segments, got %d", expected.NumSegments(), actual.NumSegments())
+	}
+	expected.Iterate(func(s *model2d.Segment) {
+		if len(actual.Find(s[0], s[1])) != 1 {
+			t.Fatalf("missing segment %v", *s)
+		}
+	})
+
+	// Each character of text_on_path() is placed where textcurve put it,
+	// although ligatures such as "fi" are split.
+	glyphs := mustEvalShape(t, `text_mesh("AVATAR", size=7, spacing=1.1, segments=5);`).M2
+	onPath := mustEvalShape(t, `text_on_path("AVATAR", size=7, spacing=1.1, segments=5, path=[[0, 0], [100, 0]]);`)
+	assertSolids2DEqual(t, glyphs.Solid(), onPath.S2)
+}
+
 func TestTextUnsupportedFont(t *testing.T) {
 	prog, err := Parse(`text("Hello", font="Arial");`)
 	if err != nil {
@@ -52,6 +90,178 @@ func TestTextUnsupportedFont(t *testing.T) {
 	}
 }
 
+func TestTextFonts(t *testing.T) {
+	evalText := func(src string, hooks Hooks) (*model2d.Mesh, error) {
+		prog, err := Parse(src)
+		if err != nil {
+			t.Fatalf("parse failed: %v", err)
+		}
+		shape, err := Eval(prog, hooks)
+		if err != nil {
+			return nil, err
+		}
+		return shape.M2, nil
+	}
+	mustEvalText := func(src string, hooks Hooks) *model2d.Mesh {
+		mesh, err := evalText(src, hooks)
+		if err != nil {
+			t.Fatalf("%s: eval failed: %v", src, err)
+		}
+		return mesh
+	}
+	registered := Hooks{Fonts: [][]byte{goregular.TTF, gobold.TTF}}
+
+	regular := mustEvalText(`text_mesh("Label", font="Go");`, registered)
+	bold := mustEvalText(`text_mesh("Label", font="go:style=Bold");`, registered)
+	if bold.Max().X <= regular.Max().X {
+		t.Fatalf("expected bold text to be wider: %f <= %f", bold.Max().X, regular.Max().X)
+	}
+	if math.Abs(bold.Max().Y-regular.Max().Y) > 1e-8 {
+		t.Fatalf("expected equal cap heights: %f != %f", bold.Max().Y, regular.Max().Y)
+	}
+	liberation := mustEvalText(`text_mesh("Label");`, registered)
+	if liberation.Max().X == regular.Max().X {
+		t.Fatal("expected the default font to stay Liberation Sans")
+	}
+
+	fromFile := mustEvalShapeWithFiles(t, `text_mesh("Label", font="fonts/Go-Bold.ttf");`,
+		map[string][]byte{"fonts/Go-Bold.ttf": gobold.TTF}).M2
+	if fromFile.Min() != bold.Min() || fromFile.Max() != bold.Max() {
+		t.Fatalf("expected file font to match registered font: %v %v", fromFile.Max(), bold.Max())
+	}
+
+	// Go lacks U+2010, so it falls back to Liberation Sans.
+	fallback := mustEvalText(`text_mesh("‐", font="Go:style=Bold");`, registered)
+	expected := mustEvalText(`text_mesh("‐");`, Hooks{})
+	if fallback.Min().Dist(expected.Min()) > 1e-8 || fallback.Max().Dist(expected.Max()) > 1e-8 {
+		t.Fatalf("expected fallback glyph %v %v, got %v %v", expected.Min(), expected.Max(),
+			fallback.Min(), fallback.Max())
+	}
+
+	for _, tc := range []struct {
+		src  string
+		want string
+	}{
+		{`text("Label", font="Liberation Sans:style=Bold");`, `font "Liberation Sans" has no style "Bold"`},
+		{`text("Label", font="Go:style=Wobbly");`, "unknown font style"},
+		{`text("Label", font="missing.ttf");`, "read font"},
+	} {
+		if _, err := evalText(tc.src, registered); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", tc.src, tc.want, err)
+		}
+	}
+}
+
+func TestTextDirection(t *testing.T) {
+	rtl := mustEvalShape(t, `text("AB", direction="rtl");`)
+	ltr := mustEvalShape(t, `text("BA");`)
+	assertSolids2DEqual(t, rtl.S2, ltr.S2)
+
+	// Hebrew and Arabic are laid out right-to-left by default.
+	for _, text := range []string{"שלום", "1 مرحبا", "Hello"} {
+		want := textLTR
+		if text != "Hello" {
+			want = textRTL
+		}
+		if got := guessTextDirection([]rune(text)); got != want {
+			t.Fatalf("%q: expected direction %v, got %v", text, want, got)
+		}
+	}
+
+	single := mustEvalShape(t, `text_mesh("A", direction="ttb");`).M2
+	vertical := mustEvalShape(t, `text_mesh("AB", direction="ttb", script="latn", language="de");`).M2
+	if math.Abs(single.Max().Y-vertical.Max().Y) > 1e-8 || vertical.Min().Y >= single.Min().Y-5 {
+		t.Fatalf("expected glyphs stacked downward, got %v %v", vertical.Min(), vertical.Max())
+	}
+	if size := vertical.Max().Sub(vertical.Min()); size.Y < 2*size.X {
+		t.Fatalf("expected tall vertical text, got size %v", size)
+	}
+
+	for _, tc := range []struct {
+		src  string
+		want string
+	}{
+		{`text("AB", direction="up");`, "direction must be"},
+		{`text("AB", script="latin");`, "unknown script"},
+	} {
+		prog, err := Parse(tc.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Eval(prog, Hooks{}); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", tc.src, tc.want, err)
+		}
+	}
+}
+
+func TestTextMetrics(t *testing.T) {
+	evalValue := func(src string) Value {
+		prog, err := Parse("out = " + src + ";")
+		if err != nil {
+			t.Fatalf("parse failed: %v", err)
+		}
+		e := newEnv(Hooks{})
+		if _, err := evalStmts(e, prog.Stmts); err != nil {
+			t.Fatalf("eval failed: %v", err)
+		}
+		out, _ := e.get("out")
+		return out
+	}
+	vec := func(v Value) model2d.Coord {
+		x, err := v.List[0].AsNum()
+		if err != nil {
+			t.Fatal(err)
+		}
+		y, err := v.List[1].AsNum()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return model2d.XY(x, y)
+	}
+
+	for _, args := range []string{
+		`"Hello", 4, halign="center", valign="center", spacing=1.05`,
+		`"Label", size=7, halign="right", valign="top"`,
+		`"AB", direction="ttb", valign="bottom"`,
+	} {
+		metrics := evalValue("textmetrics(" + args + ")")
+		mesh := mustEvalShape(t, "text_mesh("+args+");").M2
+		position, size := vec(metrics.List[0]), vec(metrics.List[1])
+		if position.Dist(mesh.Min()) > 1e-8 || position.Add(size).Dist(mesh.Max()) > 1e-8 {
+			t.Fatalf("%s: metrics %v %v do not match geometry %v %v", args, position, size,
+				mesh.Min(), mesh.Max())
+		}
+		offset := vec(metrics.List[4])
+		ascent, _ := metrics.List[2].AsNum()
+		descent, _ := metrics.List[3].AsNum()
+		if math.Abs(offset.Y+ascent-mesh.Max().Y) > 1e-8 || math.Abs(offset.Y-descent-mesh.Min().Y) > 1e-8 {
+			t.Fatalf("%s: unexpected ascent %f and descent %f", args, ascent, descent)
+		}
+	}
+
+	right := evalValue(`textmetrics("Label", halign="right")`)
+	if offset, advance := vec(right.List[4]), vec(right.List[5]); offset.X != -advance.X || advance.X <= 0 {
+		t.Fatalf("expected right alignment by the advance, got %v %v", offset, advance)
+	}
+	empty := evalValue(`textmetrics(" ")`)
+	if size, advance := vec(empty.List[1]), vec(empty.List[5]); size.Norm() != 0 || advance.X <= 0 {
+		t.Fatalf("unexpected metrics for a space: %v %v", size, advance)
+	}
+
+	font := evalValue(`fontmetrics(5)`)
+	nominal, max := vec(font.List[0]), vec(font.List[1])
+	if nominal.X != 5 || nominal.Y <= 0 || max.X < nominal.X || max.Y < nominal.Y {
+		t.Fatalf("unexpected font metrics %v %v", nominal, max)
+	}
+	if interline, _ := font.List[2].AsNum(); interline <= nominal.X+nominal.Y {
+		t.Fatalf("expected interline to exceed the em height, got %f", interline)
+	}
+	if family, style := font.List[3].List[0].Str, font.List[3].List[1].Str; family != "Liberation Sans" ||
+		style != "Regular" {
+		t.Fatalf("unexpected font %q %q", family, style)
+	}
+}
+
 func assertSolids2DEqual(t *testing.T, a, b model2d.Solid) {
 	t.Helper()
 	min := a.Min().Min(b.Min())
@@ -0,0 +1,286 @@
+package scad
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/unixpickle/model3d/model2d"
+	"github.com/unixpickle/model3d/model3d"
+	"github.com/unixpickle/path2d"
+	shapekernel "github.com/unixpickle/webgpu-meshes/shapekernel"
+)
+
+// textPathSegments is the number of segments used to sample SVG paths that
+// text follows, matching the default of path().
+const textPathSegments = 1000
+
+func handleTextOnPath(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	mesh, err := parseTextOnPath(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return shapeSolid2D(mesh.Solid(), meshSolidKernel2D(e.hooks.Numerics, mesh)), nil
+}
+
+// parseTextOnPath places each glyph upright along the path, rotated to the
+// path's direction at the middle of the glyph.
+//
+// The text is laid out as for text(), with the baseline following the path
+// and halign choosing whether the text starts at, is centered on, or ends
+// at the end of the path.
+func parseTextOnPath(e *env, st *CallStmt) (*model2d.Mesh, error) {
+	specs := append(textArgSpecs(),
+		ArgSpec{Name: "path", Pos: -1, Required: true},
+		ArgSpec{Name: "offset", Pos: -1, Default: Num(0)},
+	)
+	args, err := bindArgs(e, st.Call, specs)
+	if err != nil {
+		return nil, err
+	}
+	layout, text, align, err := parseTextLayoutArgs(e, "text_on_path", args)
+	if err != nil {
+		return nil, err
+	}
+	if layout.Direction.IsVertical() {
+		return nil, fmt.Errorf("text_on_path(): direction must be \"ltr\" or \"rtl\"")
+	}
+	path, err := parseTextPath(args["path"])
+	if err != nil {
+		return nil, err
+	}
+	offset, err := argNum(args, "offset")
+	if err != nil {
+		return nil, err
+	}
+
+	glyphs, advance := layout.Glyphs(text)
+	all := model2d.NewMesh()
+	for _, g := range glyphs {
+		addTextContours(all, g.Contours)
+	}
+	if all.NumSegments() == 0 {
+		return nil, fmt.Errorf("text_on_path(): no outlines produced")
+	}
+	shift := align.Offset(all.Min(), all.Max(), advance, false)
+	switch align.HAlign {
+	case "center":
+		shift.X += path.Length() / 2
+	case "right":
+		shift.X += path.Length()
+	}
+
+	mesh := model2d.NewMesh()
+	for _, g := range glyphs {
+		center := g.Pen.X + g.Advance.X/2 + shift.X
+		origin, tangent := path.At(center)
+		normal := model2d.XY(-tangent.Y, tangent.X)
+		var placed [][]model2d.Coord
+		for _, contour := range g.Contours {
+			newContour := make([]model2d.Coord, len(contour))
+			for i, c := range contour {
+				c = c.Add(shift)
+				newContour[i] = origin.Add(tangent.Scale(c.X - center)).Add(normal.Scale(c.Y + offset))
+			}
+			placed = append(placed, newContour)
+		}
+		addTextContours(mesh, placed)
+	}
+	return mesh, nil
+}
+
+// parseTextPath parses a list of [x, y] points or an SVG path string. Only
+// the first subpath of an SVG path is used.
+func parseTextPath(val Value) (*textPath, error) {
+	var points []model2d.Coord
+	switch val.Kind {
+	case ValString:
+		curves, err := path2d.ParseSVGPath(val.Str)
+		if err != nil {
+			return nil, fmt.Errorf("text_on_path(): %w", err)
+		}
+		if len(curves) == 0 {
+			return nil, fmt.Errorf("text_on_path(): path is empty")
+		}
+		for i := 0; i <= textPathSegments; i++ {
+			points = append(points, curves[0].Eval(float64(i)/textPathSegments))
+		}
+	case ValList:
+		for _, v := range val.List {
+			xy, err := v.AsVec2()
+			if err != nil {
+				return nil, fmt.Errorf("text_on_path(): path must be a list of [x, y] points")
+			}
+			points = append(points, model2d.NewCoordArray(xy))
+		}
+	default:
+		return nil, fmt.Errorf("text_on_path(): path must be a list of [x, y] points or an SVG path string")
+	}
+	return newTextPath(points)
+}
+
+// A textPath is a polyline parameterized by arc length.
+type textPath struct {
+	Points []model2d.Coord
+
+	// Lengths[i] is the arc length from the start to Points[i].
+	Lengths []float64
+}
+
+func newTextPath(points []model2d.Coord) (*textPath, error) {
+	res := &textPath{}
+	for _, p := range points {
+		if len(res.Points) > 0 && p == res.Points[len(res.Points)-1] {
+			continue
+		}
+		length := 0.0
+		if len(res.Points) > 0 {
+			length = res.Lengths[len(res.Lengths)-1] + p.Dist(res.Points[len(res.Points)-1])
+		}
+		res.Points = append(res.Points, p)
+		res.Lengths = append(res.Lengths, length)
+	}
+	if len(res.Points) < 2 {
+		return nil, fmt.Errorf("text_on_path(): path must have at least two distinct points")
+	}
+	return res, nil
+}
+
+// Length returns the total arc length of the path.
+func (t *textPath) Length() float64 {
+	return t.Lengths[len(t.Lengths)-1]
+}
+
+// At returns the point at arc length s and the unit tangent there.
+// Points before the start or past the end extend the first or last
+// segment in a straight line.
+func (t *textPath) At(s float64) (model2d.Coord, model2d.Coord) {
+	i := sort.SearchFloat64s(t.Lengths, s) - 1
+	i = max(0, min(len(t.Points)-2, i))
+	p0, p1 := t.Points[i], t.Points[i+1]
+	tangent := p1.Sub(p0).Normalize()
+	return p0.Add(tangent.Scale(s - t.Lengths[i])), tangent
+}
+
+func handleTextOnCylinder(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	sdf, err := parseTextOnCylinder(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return SDFToSolid(e.hooks.Numerics, sdf), nil
+}
+
+func handleTextOnCylinderSDF(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	return parseTextOnCylinder(e, st)
+}
+
+// parseTextOnCylinder wraps text around the Z axis, with the baseline
+// along the circle of radius r at z=0 and the text reading
+// counter-clockwise from the +X axis, as seen from outside.
+//
+// The text is extruded radially from r to r+depth, so a negative depth
+// sinks the text into the surface for engraving with difference().
+func parseTextOnCylinder(e *env, st *CallStmt) (ShapeRep, error) {
+	specs := append(textArgSpecs(),
+		ArgSpec{Name: "r", Pos: -1, Required: true},
+		ArgSpec{Name: "depth", Pos: -1, Default: Num(1)},
+	)
+	args, err := bindArgs(e, st.Call, specs)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	layout, text, align, err := parseTextLayoutArgs(e, "text_on_cylinder", args)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	r, err := argNum(args, "r")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	depth, err := argNum(args, "depth")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	if r <= 0 {
+		return ShapeRep{}, fmt.Errorf("text_on_cylinder(): r must be positive")
+	}
+	if depth == 0 {
+		return ShapeRep{}, fmt.Errorf("text_on_cylinder(): depth must be non-zero")
+	}
+	inner := r + math.Min(0, depth)
+	if inner <= 0 {
+		return ShapeRep{}, fmt.Errorf("text_on_cylinder(): depth must be greater than -r")
+	}
+	mesh, err := alignedTextMesh("text_on_cylinder", layout, text, align)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	if mesh.Max().X-mesh.Min().X > 2*math.Pi*r {
+		return ShapeRep{}, fmt.Errorf("text_on_cylinder(): text is longer than the circumference")
+	}
+
+	n := e.hooks.Numerics
+	thickness := math.Abs(depth)
+	k := shapekernel.LinearExtrudeSDF(n, *meshSDFKernel2D(n, mesh), thickness, false)
+	flat := shapeSDF3D(model3d.ProfileSDF(model2d.MeshToSDF(mesh), 0, thickness), &k)
+
+	// Points are measured relative to the angle of the middle of the text,
+	// so the text never crosses the branch cut of atan2.
+	center := (mesh.Min().X + mesh.Max().X) / 2
+	centerAngle := center / r
+	cosC, sinC := math.Cos(centerAngle), math.Sin(centerAngle)
+	return applyDomainMap(n, &flat, &domainMap{
+		OpName: "text_on_cylinder",
+		Map: func(c [3]float64) [3]float64 {
+			x := c[0]*cosC + c[1]*sinC
+			y := c[1]*cosC - c[0]*sinC
+			return [3]float64{r*math.Atan2(y, x) + center, c[2], math.Hypot(c[0], c[1]) - inner}
+		},
+		Bounds: func(min, max [3]float64) ([3]float64, [3]float64) {
+			min2, max2 := annularSectorBounds(
+				centerAngle+(min[0]-center)/r,
+				centerAngle+(max[0]-center)/r,
+				inner+min[2],
+				inner+max[2],
+			)
+			return [3]float64{min2.X, min2.Y, min[1]}, [3]float64{max2.X, max2.Y, max[1]}
+		},
+		KernelCode: `
+			let x = {{.Cos}} * p.x + {{.Sin}} * p.y;
+			let y = {{.Cos}} * p.y - {{.Sin}} * p.x;
+			let q = vec3f(
+				{{.Radius}} * atan2(y, x) + {{.Center}},
+				p.z,
+				length(vec2f(p.x, p.y)) - {{.InnerRadius}}
+			);
+		`,
+		KernelArgs: []any{
+			"Cos", float32(cosC),
+			"Sin", float32(sinC),
+			"Radius", float32(r),
+			"Center", float32(center),
+			"InnerRadius", float32(inner),
+		},
+		// Arcs inside the radius are compressed, so the inverse map
+		// stretches them by the ratio of the radii.
+		Lipschitz: r / inner,
+	})
+}
+
+// annularSectorBounds computes the bounds of the part of an annulus between
+// two angles.
+func annularSectorBounds(theta0, theta1, r0, r1 float64) (model2d.Coord, model2d.Coord) {
+	thetas := []float64{theta0, theta1}
+	for k := math.Ceil(theta0 / (math.Pi / 2)); k*math.Pi/2 < theta1; k++ {
+		thetas = append(thetas, k*math.Pi/2)
+	}
+	min := model2d.XY(math.Inf(1), math.Inf(1))
+	max := min.Scale(-1)
+	for _, theta := range thetas {
+		for _, r := range []float64{r0, r1} {
+			c := model2d.XY(r*math.Cos(theta), r*math.Sin(theta))
+			min, max = min.Min(c), max.Max(c)
+		}
+	}
+	return min, max
+}
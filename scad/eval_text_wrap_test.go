@@ -0,0 +1,125 @@
+package scad
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/model3d/model2d"
+	"github.com/unixpickle/model3d/model3d"
+)
+
+func TestTextOnPath(t *testing.T) {
+	// Glyphs along a straight path are not rotated, so the result matches
+	// plain text, moved by the offset and alignment.
+	straight := mustEvalShape(t, `text_on_path("Hello", 5, path=[[0, 0], [30, 0], [100, 0]], offset=2);`)
+	if straight.Kind != ShapeSolid2D || straight.Kernel == nil {
+		t.Fatalf("expected Solid2D with kernel, got %v", straight.Kind)
+	}
+	assertSolids2DEqual(t, straight.S2, mustEvalShape(t, `translate([0, 2]) text("Hello", 5);`).S2)
+
+	svg := mustEvalShape(t, `text_on_path("Hello", 5, path="M 0 0 L 100 0", offset=2);`)
+	assertSolids2DEqual(t, svg.S2, straight.S2)
+
+	vertical := mustEvalShape(t, `text_on_path("Hello", 5, path=[[0, 0], [0, 100]]);`)
+	assertSolids2DEqual(t, vertical.S2, mustEvalShape(t, `rotate(90) text("Hello", 5);`).S2)
+
+	centered := mustEvalShape(t, `text_on_path("Hello", 5, path=[[0, 0], [100, 0]], halign="center");`)
+	if mid := centered.S2.Min().Mid(centered.S2.Max()).X; math.Abs(mid-50) > 1e-8 {
+		t.Fatalf("expected text centered on the path, got center %f", mid)
+	}
+
+	// Glyphs stand on the left side of the path, so text along a clockwise
+	// circle sits on the outside.
+	circle := mustEvalShape(t, `
+		text_on_path("Around the dial", 3, path=[for (a = [360:-5:0]) [20 * cos(a), 20 * sin(a)]]);
+	`)
+	rng := rand.New(rand.NewSource(0))
+	var inside int
+	for i := 0; i < 2000; i++ {
+		c := model2d.NewCoordRandBounds(circle.S2.Min(), circle.S2.Max(), rng)
+		if circle.S2.Contains(c) {
+			inside++
+			if r := c.Norm(); r < 19.5 || r > 23.5 {
+				t.Fatalf("expected glyphs outside the circle, found one at radius %f", r)
+			}
+		}
+	}
+	if inside == 0 {
+		t.Fatal("expected some glyphs")
+	}
+}
+
+func TestTextOnCylinder(t *testing.T) {
+	text := mustEvalShape(t, `text("AB", 4, halign="center", valign="center");`).S2
+	for _, depth := range []float64{1, -1} {
+		shape := mustEvalShape(t, fmt.Sprintf(
+			`text_on_cylinder_sdf("AB", 4, r=10, depth=%f, halign="center", valign="center");`, depth,
+		))
+		if shape.Kind != ShapeSDF3D || shape.Kernel == nil {
+			t.Fatalf("expected SDF3D with kernel, got %v", shape.Kind)
+		}
+		sdf := shape.SDF3
+		r0, r1 := math.Min(10, 10+depth), math.Max(10, 10+depth)
+		if min, max := sdf.Min(), sdf.Max(); max.X > r1+1e-8 || min.X < 0 || math.Abs(max.Z-text.Max().Y) > 1e-8 ||
+			math.Abs(min.Z-text.Min().Y) > 1e-8 {
+			t.Fatalf("depth %f: unexpected bounds %v %v", depth, min, max)
+		}
+
+		rng := rand.New(rand.NewSource(0))
+		for i := 0; i < 2000; i++ {
+			c := model3d.NewCoord3DRandBounds(sdf.Min(), sdf.Max(), rng)
+			d := sdf.SDF(c)
+			if math.Abs(d) < 0.05 {
+				continue
+			}
+			radius := c.XY().Norm()
+			flat := model2d.XY(10*math.Atan2(c.Y, c.X), c.Z)
+			expected := radius > r0 && radius < r1 && text.Contains(flat)
+			if expected != (d > 0) {
+				t.Fatalf("depth %f: unexpected SDF %f at %v", depth, d, c)
+			}
+			c2 := c.Add(model3d.NewCoord3DRandNorm(rng).Scale(0.05))
+			if math.Abs(sdf.SDF(c2)-d) > c.Dist(c2)+1e-8 {
+				t.Fatalf("depth %f: SDF is not 1-Lipschitz at %v", depth, c)
+			}
+		}
+	}
+
+	// Left-aligned text starts at the +X axis and wraps counter-clockwise.
+	solid := mustEvalShape(t, `text_on_cylinder("Mug label", r=20, depth=2);`)
+	if solid.Kind != ShapeSolid3D || solid.Kernel == nil {
+		t.Fatalf("expected Solid3D with kernel, got %v", solid.Kind)
+	}
+	if min := solid.S3.Min(); min.Y < -1e-8 {
+		t.Fatalf("expected text to start at the +X axis, got %v", min)
+	}
+}
+
+func TestTextWrapErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`text_on_path("Hi", path=[[0, 0]]);`, "at least two distinct points"},
+		{`text_on_path("Hi", path=5);`, "path must be a list"},
+		{`text_on_path("Hi", path=[[0, 0], [1, 0]], direction="ttb");`, "direction must be"},
+		{`text_on_path("Hi");`, "missing parameter \"path\""},
+		{`text_on_cylinder("WWWWWWWW", r=1);`, "longer than the circumference"},
+		{`text_on_cylinder("Hi", r=5, depth=0);`, "depth must be non-zero"},
+		{`text_on_cylinder("Hi", r=5, depth=-5);`, "depth must be greater than -r"},
+		{`text_on_cylinder_sdf("Hi", r=0);`, "r must be positive"},
+	}
+	for _, tc := range tests {
+		prog, err := Parse(tc.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = Eval(prog, Hooks{})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", tc.src, tc.want, err)
+		}
+	}
+}
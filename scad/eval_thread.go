@@ -0,0 +1,524 @@
+package scad
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/unixpickle/model3d/model2d"
+	"github.com/unixpickle/model3d/model3d"
+	shapekernel "github.com/unixpickle/webgpu-meshes/shapekernel"
+)
+
+// A threadProfile is the cross section of one pitch of a thread, as
+// fractions of the pitch.
+//
+// Along the axis, each pitch consists of a flat root at the minor
+// radius, a flank up to the major radius, a flat crest, and a flank
+// back down.
+type threadProfile struct {
+	Depth  float64
+	Root   float64
+	FlankA float64
+	Crest  float64
+}
+
+var threadProfiles = map[string]threadProfile{
+	// ISO 68-1 basic profile with a 60 degree thread angle.
+	"iso": {
+		Depth:  5.0 / 8 * math.Sqrt(3) / 2,
+		Root:   1.0 / 4,
+		FlankA: 5.0 / 16,
+		Crest:  1.0 / 8,
+	},
+	// ACME profile with a 29 degree thread angle.
+	"acme": {
+		Depth:  0.5,
+		Root:   (1 - math.Tan(14.5*math.Pi/180)) / 2,
+		FlankA: 0.5 * math.Tan(14.5*math.Pi/180),
+		Crest:  (1 - math.Tan(14.5*math.Pi/180)) / 2,
+	},
+	// Buttress profile with a 7 degree load flank and a 45 degree
+	// trailing flank.
+	"buttress": {
+		Depth:  0.6,
+		Root:   (1 - 0.6 - 0.6*math.Tan(7*math.Pi/180)) / 2,
+		FlankA: 0.6 * math.Tan(7*math.Pi/180),
+		Crest:  (1 - 0.6 - 0.6*math.Tan(7*math.Pi/180)) / 2,
+	},
+}
+
+// A threadPrimitive is a right-handed helical thread along the Z axis.
+type threadPrimitive struct {
+	Profile     threadProfile
+	Pitch       float64
+	MajorRadius float64
+	Z0          float64
+	Z1          float64
+}
+
+func (t *threadPrimitive) minorRadius() float64 {
+	return t.MajorRadius - t.Profile.Depth*t.Pitch
+}
+
+// vertices returns the (radius, height) corners of the profile over a
+// single pitch starting at height 0.
+func (t *threadPrimitive) vertices() [5]model2d.Coord {
+	p := t.Profile
+	rMin, rMax := t.minorRadius(), t.MajorRadius
+	u := [5]float64{0, p.Root, p.Root + p.FlankA, p.Root + p.FlankA + p.Crest, 1}
+	r := [5]float64{rMin, rMin, rMax, rMax, rMin}
+	var res [5]model2d.Coord
+	for i := range res {
+		res[i] = model2d.XY(r[i], u[i]*t.Pitch)
+	}
+	return res
+}
+
+// lipschitz bounds the rate at which the cross section moves as the
+// angle changes, so that cross-sectional distances can be scaled into
+// bounds on the true distance.
+func (t *threadPrimitive) lipschitz() float64 {
+	return math.Sqrt(1 + math.Pow(t.Pitch/t.minorRadius(), 2))
+}
+
+func (t *threadPrimitive) Min() model3d.Coord3D {
+	return model3d.XYZ(-t.MajorRadius, -t.MajorRadius, t.Z0)
+}
+
+func (t *threadPrimitive) Max() model3d.Coord3D {
+	return model3d.XYZ(t.MajorRadius, t.MajorRadius, t.Z1)
+}
+
+func (t *threadPrimitive) Contains(c model3d.Coord3D) bool {
+	return t.SDF(c) >= 0
+}
+
+func (t *threadPrimitive) SDF(c model3d.Coord3D) float64 {
+	r := c.XY().Norm()
+	z := c.Z - math.Atan2(c.Y, c.X)*t.Pitch/(2*math.Pi)
+	z -= math.Floor(z/t.Pitch) * t.Pitch
+	p := model2d.XY(r, z)
+
+	verts := t.vertices()
+	dist := math.Inf(1)
+	inside := false
+	for offset := -1; offset <= 1; offset++ {
+		shift := model2d.Y(float64(offset) * t.Pitch)
+		for i := 0; i < 4; i++ {
+			seg := model2d.Segment{verts[i].Add(shift), verts[i+1].Add(shift)}
+			dist = math.Min(dist, seg.Dist(p))
+			if offset == 0 && z >= seg[0].Y && z <= seg[1].Y && seg[1].Y > seg[0].Y {
+				frac := (z - seg[0].Y) / (seg[1].Y - seg[0].Y)
+				inside = r <= seg[0].X+frac*(seg[1].X-seg[0].X)
+			}
+		}
+	}
+	if !inside {
+		dist = -dist
+	}
+	return math.Min(dist/t.lipschitz(), math.Min(c.Z-t.Z0, t.Z1-c.Z))
+}
+
+func threadSDFKernel(n shapekernel.Numerics, t *threadPrimitive) shapekernel.ShapeKernel {
+	verts := t.vertices()
+	k := shapekernel.ShapeKernel{Kind: shapekernel.SDF3D}
+	segFn := kernelFnID(&k.IDs, "thread_segment_dist")
+	fnName := kernelFnID(&k.IDs, "thread_sdf")
+	shapekernel.AppendWGSL(
+		&k,
+		`
+			fn {{.SegFn}}(p: vec2f, a: vec2f, b: vec2f) -> f32 {
+				let ba = b - a;
+				let pa = p - a;
+				let h = clamp(dot(pa, ba) / dot(ba, ba), 0.0, 1.0);
+				return length(pa - ba * h);
+			}
+
+			fn {{.Entrypoint}}(p: {{.N.Dtype3}}) -> {{.N.Dtype}} {
+				let c = {{.N.AsFloat3}}(p);
+				let r = length(c.xy);
+				var z = c.z - atan2(c.y, c.x) * {{.PitchOverAngle}};
+				z = z - floor(z / {{.Pitch}}) * {{.Pitch}};
+				let q = vec2f(r, z);
+				var verts = array<vec2f, 5>({{.V0}}, {{.V1}}, {{.V2}}, {{.V3}}, {{.V4}});
+				var dist = 1e30;
+				var inside = false;
+				for (var offset = -1; offset <= 1; offset++) {
+					let shift = vec2f(0.0, f32(offset) * {{.Pitch}});
+					for (var i = 0; i < 4; i++) {
+						let a = verts[i] + shift;
+						let b = verts[i + 1] + shift;
+						dist = min(dist, {{.SegFn}}(q, a, b));
+						if (offset == 0 && z >= a.y && z <= b.y && b.y > a.y) {
+							let frac = (z - a.y) / (b.y - a.y);
+							inside = r <= a.x + frac * (b.x - a.x);
+						}
+					}
+				}
+				if (!inside) {
+					dist = -dist;
+				}
+				let caps = min(c.z - {{.Z0}}, {{.Z1}} - c.z);
+				return {{.N.FromFloat}}(min(dist / {{.Lipschitz}}, caps));
+			}
+		`,
+		"N", n.Symbols,
+		"SegFn", segFn,
+		"Entrypoint", fnName,
+		"Pitch", float32(t.Pitch),
+		"PitchOverAngle", float32(t.Pitch/(2*math.Pi)),
+		"V0", wgslVec2f(verts[0]),
+		"V1", wgslVec2f(verts[1]),
+		"V2", wgslVec2f(verts[2]),
+		"V3", wgslVec2f(verts[3]),
+		"V4", wgslVec2f(verts[4]),
+		"Z0", float32(t.Z0),
+		"Z1", float32(t.Z1),
+		"Lipschitz", float32(t.lipschitz()),
+	)
+	k.EntrypointName = fnName
+	return k
+}
+
+// A metricSize describes an ISO metric coarse thread along with the
+// dimensions of its hex bolt head and nut.
+type metricSize struct {
+	Diameter   float64
+	Pitch      float64
+	HexWidth   float64
+	HeadHeight float64
+	NutHeight  float64
+}
+
+var metricSizes = map[string]metricSize{
+	"M2":   {2, 0.4, 4, 1.4, 1.6},
+	"M2.5": {2.5, 0.45, 5, 1.7, 2},
+	"M3":   {3, 0.5, 5.5, 2, 2.4},
+	"M4":   {4, 0.7, 7, 2.8, 3.2},
+	"M5":   {5, 0.8, 8, 3.5, 4.7},
+	"M6":   {6, 1, 10, 4, 5.2},
+	"M8":   {8, 1.25, 13, 5.3, 6.8},
+	"M10":  {10, 1.5, 16, 6.4, 8.4},
+	"M12":  {12, 1.75, 18, 7.5, 10.8},
+	"M16":  {16, 2, 24, 10, 14.8},
+	"M20":  {20, 2.5, 30, 12.5, 18},
+}
+
+func parseMetricSize(opName string, args map[string]Value) (metricSize, error) {
+	name, err := argString(args, "size")
+	if err != nil {
+		return metricSize{}, err
+	}
+	size, ok := metricSizes[strings.ToUpper(name)]
+	if !ok {
+		var names []string
+		for name := range metricSizes {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			return metricSizes[names[i]].Diameter < metricSizes[names[j]].Diameter
+		})
+		return metricSize{}, fmt.Errorf("%s(): unknown size %q (expected one of %s)",
+			opName, name, strings.Join(names, ", "))
+	}
+	return size, nil
+}
+
+func parseTolerance(opName string, args map[string]Value) (float64, error) {
+	tolerance, err := argNum(args, "tolerance")
+	if err != nil {
+		return 0, err
+	}
+	if tolerance < 0 {
+		return 0, fmt.Errorf("%s(): tolerance must be non-negative", opName)
+	}
+	return tolerance, nil
+}
+
+// newThread creates a thread, moving the surface by tolerance to make
+// room for the mating part.
+func newThread(
+	opName string,
+	profile threadProfile,
+	pitch, diameter, z0, z1, tolerance float64,
+	internal bool,
+) (*threadPrimitive, error) {
+	radius := diameter / 2
+	if internal {
+		radius += tolerance
+	} else {
+		radius -= tolerance
+	}
+	t := &threadPrimitive{
+		Profile:     profile,
+		Pitch:       pitch,
+		MajorRadius: radius,
+		Z0:          z0,
+		Z1:          z1,
+	}
+	if t.minorRadius() <= 0 {
+		return nil, fmt.Errorf("%s(): pitch is too large for the diameter", opName)
+	}
+	return t, nil
+}
+
+func handleThread(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	sdf, err := parseThread(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return SDFToSolid(e.hooks.Numerics, sdf), nil
+}
+
+func handleThreadSDF(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	return parseThread(e, st)
+}
+
+func parseThread(e *env, st *CallStmt) (ShapeRep, error) {
+	opName := st.Call.Name
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "pitch", Pos: 0, Required: true},
+		{Name: "diameter", Aliases: []string{"d"}, Pos: 1, Required: true},
+		{Name: "length", Aliases: []string{"h"}, Pos: 2, Required: true},
+		{Name: "profile", Pos: 3, Default: String("iso")},
+		{Name: "internal", Pos: 4, Default: Bool(false)},
+		{Name: "tolerance", Pos: -1, Default: Num(0)},
+		{Name: "center", Pos: -1, Default: Bool(false)},
+	})
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	pitch, err := argNum(args, "pitch")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	diameter, err := argNum(args, "diameter")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	length, err := argNum(args, "length")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	if pitch <= 0 || diameter <= 0 || length <= 0 {
+		return ShapeRep{}, fmt.Errorf("%s(): pitch, diameter and length must be positive", opName)
+	}
+	profileName, err := argString(args, "profile")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	profile, ok := threadProfiles[profileName]
+	if !ok {
+		return ShapeRep{}, fmt.Errorf("%s(): profile must be \"iso\", \"acme\" or \"buttress\"", opName)
+	}
+	internal, err := argBool(args, "internal")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	tolerance, err := parseTolerance(opName, args)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	center, err := argBool(args, "center")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	z0, z1 := 0.0, length
+	if center {
+		z0, z1 = -length/2, length/2
+	}
+	thread, err := newThread(opName, profile, pitch, diameter, z0, z1, tolerance, internal)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return shapeSDF3D(thread, asPtr(threadSDFKernel(e.hooks.Numerics, thread))), nil
+}
+
+// hexPrism creates a hexagonal prism with the given width across flats.
+func hexPrism(width, z0, z1 float64) *prismPrimitive {
+	return &prismPrimitive{
+		Profile: newRegularPolygon(6, width/math.Sqrt(3)),
+		Axis:    2,
+		Start:   z0,
+		End:     z1,
+	}
+}
+
+func handleBolt(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	sdf, err := parseBolt(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return SDFToSolid(e.hooks.Numerics, sdf), nil
+}
+
+func handleBoltSDF(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	return parseBolt(e, st)
+}
+
+func parseBolt(e *env, st *CallStmt) (ShapeRep, error) {
+	opName := st.Call.Name
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "size", Pos: 0, Default: String("M3")},
+		{Name: "length", Aliases: []string{"h"}, Pos: 1, Default: Num(10)},
+		{Name: "head", Pos: 2, Default: String("hex")},
+		{Name: "tolerance", Pos: -1, Default: Num(0)},
+	})
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	size, err := parseMetricSize(opName, args)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	length, err := argNum(args, "length")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	if length <= 0 {
+		return ShapeRep{}, fmt.Errorf("%s(): length must be positive", opName)
+	}
+	head, err := argString(args, "head")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	tolerance, err := parseTolerance(opName, args)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+
+	var headHeight float64
+	switch head {
+	case "hex":
+		headHeight = size.HeadHeight
+	case "none":
+	default:
+		return ShapeRep{}, fmt.Errorf("%s(): head must be \"hex\" or \"none\"", opName)
+	}
+	thread, err := newThread(opName, threadProfiles["iso"], size.Pitch, size.Diameter,
+		headHeight, headHeight+length, tolerance, false)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+
+	n := e.hooks.Numerics
+	threadKernel := threadSDFKernel(n, thread)
+	if head == "none" {
+		return shapeSDF3D(thread, &threadKernel), nil
+	}
+
+	hex := hexPrism(size.HexWidth-2*tolerance, 0, headHeight)
+	k := shapekernel.UnionSDFs(n, []shapekernel.ShapeKernel{
+		*primitiveSDFKernel3D(n, hex),
+		threadKernel,
+	})
+	sdf := model3d.FuncSDF(hex.Min().Min(thread.Min()), hex.Max().Max(thread.Max()), func(c model3d.Coord3D) float64 {
+		return math.Max(hex.SDF(c), thread.SDF(c))
+	})
+	return shapeSDF3D(sdf, &k), nil
+}
+
+func handleNut(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	sdf, err := parseNut(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return SDFToSolid(e.hooks.Numerics, sdf), nil
+}
+
+func handleNutSDF(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	return parseNut(e, st)
+}
+
+func parseNut(e *env, st *CallStmt) (ShapeRep, error) {
+	opName := st.Call.Name
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "size", Pos: 0, Default: String("M3")},
+		{Name: "height", Aliases: []string{"h"}, Pos: 1},
+		{Name: "tolerance", Pos: -1, Default: Num(0)},
+	})
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	size, err := parseMetricSize(opName, args)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	height := size.NutHeight
+	if args["height"].Kind != ValNull {
+		height, err = argNum(args, "height")
+		if err != nil {
+			return ShapeRep{}, err
+		}
+		if height <= 0 {
+			return ShapeRep{}, fmt.Errorf("%s(): height must be positive", opName)
+		}
+	}
+	tolerance, err := parseTolerance(opName, args)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+
+	// The hole extends past both faces so that it cuts cleanly.
+	hex := hexPrism(size.HexWidth, 0, height)
+	hole, err := newThread(opName, threadProfiles["iso"], size.Pitch, size.Diameter,
+		-size.Pitch, height+size.Pitch, tolerance, true)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	n := e.hooks.Numerics
+	k := shapekernel.SubtractSDF(n, *primitiveSDFKernel3D(n, hex), threadSDFKernel(n, hole))
+	sdf := model3d.FuncSDF(hex.Min(), hex.Max(), func(c model3d.Coord3D) float64 {
+		return math.Min(hex.SDF(c), -hole.SDF(c))
+	})
+	return shapeSDF3D(sdf, &k), nil
+}
+
+func handleTappedHole(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	sdf, err := parseTappedHole(e, st)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return SDFToSolid(e.hooks.Numerics, sdf), nil
+}
+
+func handleTappedHoleSDF(e *env, st *CallStmt, _ []ShapeRep, _ *ShapeRep) (ShapeRep, error) {
+	return parseTappedHole(e, st)
+}
+
+// parseTappedHole creates the cavity of a tapped hole, extending
+// downward from the XY plane, to be subtracted from a part.
+func parseTappedHole(e *env, st *CallStmt) (ShapeRep, error) {
+	opName := st.Call.Name
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "size", Pos: 0, Default: String("M3")},
+		{Name: "depth", Aliases: []string{"h"}, Pos: 1, Default: Num(10)},
+		{Name: "tolerance", Pos: -1, Default: Num(0)},
+	})
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	size, err := parseMetricSize(opName, args)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	depth, err := argNum(args, "depth")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	if depth <= 0 {
+		return ShapeRep{}, fmt.Errorf("%s(): depth must be positive", opName)
+	}
+	tolerance, err := parseTolerance(opName, args)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	hole, err := newThread(opName, threadProfiles["iso"], size.Pitch, size.Diameter,
+		-depth, 0, tolerance, true)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	return shapeSDF3D(hole, asPtr(threadSDFKernel(e.hooks.Numerics, hole))), nil
+}
@@ -0,0 +1,168 @@
+package scad
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/model3d/model3d"
+)
+
+func TestThread(t *testing.T) {
+	for _, profile := range []string{"iso", "acme", "buttress"} {
+		shape := mustEvalShape(t, `thread_sdf(2, 10, 6, profile="`+profile+`");`)
+		if shape.Kind != ShapeSDF3D || shape.Kernel == nil {
+			t.Fatalf("%s: expected SDF3D with kernel, got %v", profile, shape.Kind)
+		}
+		if !strings.Contains(shape.Kernel.Code, "thread_sdf") {
+			t.Fatalf("%s: expected thread in kernel code", profile)
+		}
+		sdf := shape.SDF3
+		if sdf.Min().Dist(model3d.XYZ(-5, -5, 0)) > 1e-8 || sdf.Max().Dist(model3d.XYZ(5, 5, 6)) > 1e-8 {
+			t.Fatalf("%s: unexpected bounds %v %v", profile, sdf.Min(), sdf.Max())
+		}
+		// The core is solid and the space past the major radius is empty.
+		if d := sdf.SDF(model3d.XYZ(1, 0, 3)); d <= 0 {
+			t.Fatalf("%s: expected core to be solid, got %f", profile, d)
+		}
+		if d := sdf.SDF(model3d.XYZ(5.5, 0, 3)); d >= 0 {
+			t.Fatalf("%s: expected outside to be empty, got %f", profile, d)
+		}
+
+		// Along a line at the mean radius, the thread is solid for some
+		// heights and empty for others, repeating with the pitch.
+		var inside int
+		for i := 0; i < 100; i++ {
+			z := 1 + 4*float64(i)/100
+			c := model3d.XYZ(4.4, 0, z)
+			d1, d2 := sdf.SDF(c), sdf.SDF(c.Add(model3d.Z(2)))
+			if z+2 < 6 && math.Abs(d1-d2) > 1e-8 {
+				t.Fatalf("%s: thread is not periodic at z=%f", profile, z)
+			}
+			if d1 > 0 {
+				inside++
+			}
+		}
+		if inside < 10 || inside > 90 {
+			t.Fatalf("%s: unexpected thread fraction %d/100", profile, inside)
+		}
+
+		// Turning by a quarter turn advances the thread by a quarter pitch.
+		for i := 0; i < 20; i++ {
+			z := 2 + float64(i)/10
+			d1 := sdf.SDF(model3d.XYZ(4.4, 0, z))
+			d2 := sdf.SDF(model3d.XYZ(0, 4.4, z+0.5))
+			if math.Abs(d1-d2) > 1e-8 {
+				t.Fatalf("%s: thread is not helical at z=%f: %f != %f", profile, z, d1, d2)
+			}
+		}
+
+		rng := rand.New(rand.NewSource(0))
+		for i := 0; i < 2000; i++ {
+			c1 := model3d.NewCoord3DRandBounds(sdf.Min(), sdf.Max(), rng)
+			c2 := c1.Add(model3d.NewCoord3DRandNorm(rng).Scale(0.05))
+			if math.Abs(sdf.SDF(c1)-sdf.SDF(c2)) > c1.Dist(c2)+1e-8 {
+				t.Fatalf("%s: SDF is not 1-Lipschitz between %v and %v", profile, c1, c2)
+			}
+		}
+	}
+
+	shape := mustEvalShape(t, `thread(1, 6, 4, center=true);`)
+	if shape.Kind != ShapeSolid3D || shape.Kernel == nil {
+		t.Fatalf("expected solid with kernel, got %v", shape.Kind)
+	}
+	if min := shape.S3.Min(); math.Abs(min.Z+2) > 1e-8 {
+		t.Fatalf("expected centered thread, got %v", min)
+	}
+
+	// Internal threads are grown by the tolerance and external threads are
+	// shrunk by it.
+	external := mustEvalShape(t, `thread_sdf(1, 6, 4, tolerance=0.2);`)
+	internal := mustEvalShape(t, `thread_sdf(1, 6, 4, internal=true, tolerance=0.2);`)
+	if max := external.SDF3.Max().X; math.Abs(max-2.8) > 1e-8 {
+		t.Fatalf("unexpected external radius %f", max)
+	}
+	if max := internal.SDF3.Max().X; math.Abs(max-3.2) > 1e-8 {
+		t.Fatalf("unexpected internal radius %f", max)
+	}
+}
+
+func TestFasteners(t *testing.T) {
+	bolt := mustEvalShape(t, `bolt_sdf("M6", 20);`)
+	if bolt.Kind != ShapeSDF3D || bolt.Kernel == nil {
+		t.Fatalf("expected SDF3D with kernel, got %v", bolt.Kind)
+	}
+	if max := bolt.SDF3.Max(); math.Abs(max.Z-24) > 1e-8 || math.Abs(max.X-10/math.Sqrt(3)) > 1e-8 {
+		t.Fatalf("unexpected bolt bounds %v", max)
+	}
+	if d := bolt.SDF3.SDF(model3d.XYZ(4.5, 0, 2)); d <= 0 {
+		t.Fatalf("expected the head to be solid, got %f", d)
+	}
+	if d := bolt.SDF3.SDF(model3d.XYZ(4.5, 0, 10)); d >= 0 {
+		t.Fatalf("expected the space beside the shank to be empty, got %f", d)
+	}
+	if d := bolt.SDF3.SDF(model3d.XYZ(1, 0, 10)); d <= 0 {
+		t.Fatalf("expected the shank to be solid, got %f", d)
+	}
+
+	headless := mustEvalShape(t, `bolt("M3", 8, head="none");`)
+	if headless.Kind != ShapeSolid3D || math.Abs(headless.S3.Max().X-1.5) > 1e-8 {
+		t.Fatalf("unexpected headless bolt %v %v", headless.Kind, headless.S3.Max())
+	}
+
+	nut := mustEvalShape(t, `nut_sdf("M8", tolerance=0.1);`)
+	if !strings.Contains(nut.Kernel.Code, "thread_sdf") {
+		t.Fatal("expected thread in nut kernel")
+	}
+	if max := nut.SDF3.Max(); math.Abs(max.Z-6.8) > 1e-8 {
+		t.Fatalf("unexpected nut bounds %v", max)
+	}
+	if d := nut.SDF3.SDF(model3d.XYZ(0, 0, 3)); d >= 0 {
+		t.Fatalf("expected the hole to be empty, got %f", d)
+	}
+	if d := nut.SDF3.SDF(model3d.XYZ(5.5, 0, 3)); d <= 0 {
+		t.Fatalf("expected the nut body to be solid, got %f", d)
+	}
+	if shape := mustEvalShape(t, `nut("M8", h=3);`); shape.Kind != ShapeSolid3D || shape.S3.Max().Z != 3 {
+		t.Fatalf("unexpected nut %v", shape.Kind)
+	}
+
+	hole := mustEvalShape(t, `difference() { cube_sdf(10, center=true); translate([0, 0, 5]) tapped_hole_sdf("M4", 6); }`)
+	if hole.Kind != ShapeSDF3D {
+		t.Fatalf("expected SDF3D, got %v", hole.Kind)
+	}
+	if d := hole.SDF3.SDF(model3d.XYZ(0, 0, 2)); d >= 0 {
+		t.Fatalf("expected the hole to be empty, got %f", d)
+	}
+	if d := hole.SDF3.SDF(model3d.XYZ(0, 0, -2)); d <= 0 {
+		t.Fatalf("expected below the hole to be solid, got %f", d)
+	}
+}
+
+func TestThreadErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`thread(0, 5, 5);`, "must be positive"},
+		{`thread(1, 5, 5, profile="square");`, "profile must be"},
+		{`thread(5, 5, 5);`, "pitch is too large"},
+		{`thread(1, 5, 5, tolerance=-1);`, "tolerance must be non-negative"},
+		{`bolt("M7");`, "unknown size \"M7\""},
+		{`bolt("M3", head="socket");`, "head must be"},
+		{`bolt("M3", 0);`, "length must be positive"},
+		{`nut("M3", h=-1);`, "height must be positive"},
+		{`tapped_hole_sdf("M3", 0);`, "depth must be positive"},
+	}
+	for _, tc := range tests {
+		prog, err := Parse(tc.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = Eval(prog, Hooks{})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", tc.src, tc.want, err)
+		}
+	}
+}
@@ -69,6 +69,106 @@ func handleScale(e *env, st *CallStmt, _ []ShapeRep, childUnion *ShapeRep) (Shap
 	}
 }
 
+func handleResize(e *env, st *CallStmt, _ []ShapeRep, childUnion *ShapeRep) (ShapeRep, error) {
+	n := e.hooks.Numerics
+	args, err := bindArgs(e, st.Call, []ArgSpec{
+		{Name: "newsize", Pos: 0, Required: true},
+		{Name: "auto", Pos: 1, Default: Bool(false)},
+		{Name: "samples", Pos: -1, Default: Num(0)},
+	})
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	newSize, err := argVec3(args, "newsize")
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	auto, err := parseResizeAuto(args["auto"])
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	dim := childUnion.Kind.Dimension()
+	if dim == 2 && newSize[2] != 0 {
+		return ShapeRep{}, fmt.Errorf("resize(): z size not supported for 2D shapes")
+	}
+	min, max, err := parseBoundsSamples(args, "resize", childUnion)
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	scale, err := resizeScale(dim, newSize, auto, max.Sub(min).Array())
+	if err != nil {
+		return ShapeRep{}, err
+	}
+	// The scale comes from the bounds of the whole child, so each part is
+	// scaled by the same amount.
+	return mapTaggedParts(n, *childUnion, func(part ShapeRep) (ShapeRep, error) {
+		if dim == 2 {
+			xf := scaleTransform2D(n, scale)
+			xf.OpName = "resize"
+			return applyTransform2D(part, xf)
+		}
+		xf := scaleTransform3D(n, scale)
+		xf.OpName = "resize"
+		return applyTransform3D(part, xf)
+	})
+}
+
+func parseResizeAuto(v Value) ([3]bool, error) {
+	var out [3]bool
+	switch v.Kind {
+	case ValBool:
+		out = [3]bool{v.Bool, v.Bool, v.Bool}
+	case ValList:
+		if len(v.List) > 3 {
+			return out, fmt.Errorf("resize(): auto must have at most 3 components")
+		}
+		for i, x := range v.List {
+			b, err := x.AsBool()
+			if err != nil {
+				return out, fmt.Errorf("resize(): auto: %w", err)
+			}
+			out[i] = b
+		}
+	default:
+		return out, fmt.Errorf("resize(): auto must be a bool or a list of bools")
+	}
+	return out, nil
+}
+
+// resizeScale computes per-axis scale factors that map a shape of the
+// given size to newSize.
+//
+// Axes with a zero target size keep their size, unless they are marked
+// as auto, in which case they use the scale of the axis with the largest
+// target size, as in OpenSCAD.
+func resizeScale(dim int, newSize [3]float64, auto [3]bool, size [3]float64) ([3]float64, error) {
+	scale := [3]float64{1, 1, 1}
+	autoScale := 0.0
+	maxSize := 0.0
+	for i := 0; i < dim; i++ {
+		if newSize[i] < 0 {
+			return scale, fmt.Errorf("resize(): newsize must be non-negative")
+		} else if newSize[i] == 0 {
+			continue
+		}
+		if size[i] == 0 {
+			return scale, fmt.Errorf("resize(): cannot resize axis %d with zero extent", i)
+		}
+		scale[i] = newSize[i] / size[i]
+		if newSize[i] > maxSize {
+			maxSize, autoScale = newSize[i], scale[i]
+		}
+	}
+	if autoScale != 0 {
+		for i := 0; i < dim; i++ {
+			if newSize[i] == 0 && auto[i] {
+				scale[i] = autoScale
+			}
+		}
+	}
+	return scale, nil
+}
+
 func handleRotate(e *env, st *CallStmt, _ []ShapeRep, childUnion *ShapeRep) (ShapeRep, error) {
 	n := e.hooks.Numerics
 	spec, err := parseRotateSpec(e, st)
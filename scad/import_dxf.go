@@ -0,0 +1,339 @@
+package scad
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/unixpickle/model3d/model2d"
+)
+
+// dxfUnitScales maps $INSUNITS header values to millimeters.
+// Unitless drawings are treated as millimeters.
+var dxfUnitScales = map[int]float64{
+	0:  1,
+	1:  25.4,
+	2:  304.8,
+	4:  1,
+	5:  10,
+	6:  1000,
+	8:  25.4e-6,
+	9:  25.4e-3,
+	10: 914.4,
+	13: 1e-3,
+	14: 100,
+}
+
+type dxfPair struct {
+	Code  int
+	Value string
+}
+
+// dxfEntity is a single entity from the ENTITIES section.
+type dxfEntity struct {
+	Type  string
+	Pairs []dxfPair
+}
+
+func (d *dxfEntity) Layer() string {
+	return d.Str(8)
+}
+
+func (d *dxfEntity) Str(code int) string {
+	for _, p := range d.Pairs {
+		if p.Code == code {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+func (d *dxfEntity) Num(code int, def float64) float64 {
+	for _, p := range d.Pairs {
+		if p.Code == code {
+			if x, err := strconv.ParseFloat(strings.TrimSpace(p.Value), 64); err == nil {
+				return x
+			}
+		}
+	}
+	return def
+}
+
+// readDXFMesh converts the 2D entities of a DXF drawing to a mesh in
+// millimeters.
+//
+// Supported entities are LINE, LWPOLYLINE, POLYLINE, CIRCLE, ARC and
+// ELLIPSE. Lines and arcs are joined into loops by their endpoints.
+// Segments is the number of segments used for a full circle.
+func readDXFMesh(data []byte, opts importOptions2D) (*model2d.Mesh, error) {
+	pairs, err := readDXFPairs(data)
+	if err != nil {
+		return nil, err
+	}
+	units, entities := splitDXFSections(pairs)
+	scale, ok := dxfUnitScales[units]
+	if !ok {
+		return nil, fmt.Errorf("unsupported $INSUNITS value %d", units)
+	}
+
+	snapper := newDXFSnapper()
+	mesh := model2d.NewMesh()
+	addPolyline := func(points []model2d.Coord) {
+		for i := 1; i < len(points); i++ {
+			p1, p2 := snapper.Snap(points[i-1]), snapper.Snap(points[i])
+			if p1 != p2 {
+				mesh.Add(&model2d.Segment{p1, p2})
+			}
+		}
+	}
+	var foundLayer bool
+	for _, ent := range entities {
+		if opts.Layer != "" {
+			if ent.Layer() != opts.Layer {
+				continue
+			}
+			foundLayer = true
+		}
+		switch ent.Type {
+		case "LINE":
+			addPolyline([]model2d.Coord{
+				model2d.XY(ent.Num(10, 0), ent.Num(20, 0)),
+				model2d.XY(ent.Num(11, 0), ent.Num(21, 0)),
+			})
+		case "LWPOLYLINE", "POLYLINE":
+			addPolyline(dxfPolylinePoints(ent, opts.Segments))
+		case "CIRCLE":
+			center := model2d.XY(ent.Num(10, 0), ent.Num(20, 0))
+			addPolyline(dxfArcPoints(center, ent.Num(40, 0), 0, 2*math.Pi, opts.Segments))
+		case "ARC":
+			center := model2d.XY(ent.Num(10, 0), ent.Num(20, 0))
+			start := ent.Num(50, 0) * math.Pi / 180
+			end := ent.Num(51, 360) * math.Pi / 180
+			for end <= start {
+				end += 2 * math.Pi
+			}
+			addPolyline(dxfArcPoints(center, ent.Num(40, 0), start, end, opts.Segments))
+		case "ELLIPSE":
+			addPolyline(dxfEllipsePoints(ent, opts.Segments))
+		}
+	}
+	if opts.Layer != "" && !foundLayer {
+		return nil, fmt.Errorf("no entities on layer %q", opts.Layer)
+	}
+	rule := fillRuleEvenOdd
+	if opts.FillRule != nil {
+		rule = *opts.FillRule
+	}
+	return fillMesh2D(mesh, rule).Scale(scale), nil
+}
+
+func readDXFPairs(data []byte) ([]dxfPair, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1<<24)
+	var res []dxfPair
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		codeStr := strings.TrimSpace(scanner.Text())
+		if codeStr == "" {
+			continue
+		}
+		code, err := strconv.Atoi(codeStr)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid group code %q", lineNum, codeStr)
+		}
+		if !scanner.Scan() {
+			return nil, fmt.Errorf("line %d: missing value for group code %d", lineNum, code)
+		}
+		lineNum++
+		res = append(res, dxfPair{Code: code, Value: strings.TrimSpace(scanner.Text())})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// splitDXFSections extracts the $INSUNITS header variable and groups
+// the ENTITIES section into entities.
+//
+// POLYLINE entities absorb their VERTEX entities up to SEQEND.
+func splitDXFSections(pairs []dxfPair) (int, []*dxfEntity) {
+	units := 0
+	var section string
+	var entities []*dxfEntity
+	var cur *dxfEntity
+	var inPolyline bool
+	for i := 0; i < len(pairs); i++ {
+		p := pairs[i]
+		if p.Code == 0 && p.Value == "SECTION" && i+1 < len(pairs) && pairs[i+1].Code == 2 {
+			section = pairs[i+1].Value
+			cur = nil
+			i++
+			continue
+		}
+		if p.Code == 0 && p.Value == "ENDSEC" {
+			section = ""
+			cur = nil
+			continue
+		}
+		switch section {
+		case "HEADER":
+			if p.Code == 9 && p.Value == "$INSUNITS" && i+1 < len(pairs) {
+				if x, err := strconv.Atoi(pairs[i+1].Value); err == nil {
+					units = x
+				}
+				i++
+			}
+		case "ENTITIES":
+			if p.Code != 0 {
+				if cur != nil {
+					cur.Pairs = append(cur.Pairs, p)
+				}
+				continue
+			}
+			if inPolyline {
+				if p.Value == "VERTEX" {
+					cur.Pairs = append(cur.Pairs, dxfPair{Code: 0, Value: "VERTEX"})
+					continue
+				} else if p.Value == "SEQEND" {
+					inPolyline = false
+					cur = nil
+					continue
+				}
+				inPolyline = false
+			}
+			cur = &dxfEntity{Type: p.Value}
+			entities = append(entities, cur)
+			inPolyline = p.Value == "POLYLINE"
+		}
+	}
+	return units, entities
+}
+
+// dxfPolylinePoints flattens an LWPOLYLINE or POLYLINE, including bulge
+// arcs between vertices.
+func dxfPolylinePoints(ent *dxfEntity, segments int) []model2d.Coord {
+	type vertex struct {
+		P     model2d.Coord
+		Bulge float64
+	}
+	var vertices []vertex
+	var flags int
+	inVertex := ent.Type == "LWPOLYLINE"
+	for _, p := range ent.Pairs {
+		if p.Code == 0 && p.Value == "VERTEX" {
+			inVertex = true
+			continue
+		}
+		if !inVertex {
+			if p.Code == 70 {
+				flags, _ = strconv.Atoi(p.Value)
+			}
+			continue
+		}
+		x, _ := strconv.ParseFloat(p.Value, 64)
+		switch p.Code {
+		case 70:
+			if ent.Type == "LWPOLYLINE" {
+				flags = int(x)
+			}
+		case 10:
+			vertices = append(vertices, vertex{P: model2d.XY(x, 0)})
+		case 20:
+			if len(vertices) > 0 {
+				vertices[len(vertices)-1].P.Y = x
+			}
+		case 42:
+			if len(vertices) > 0 {
+				vertices[len(vertices)-1].Bulge = x
+			}
+		}
+	}
+	if len(vertices) == 0 {
+		return nil
+	}
+	closed := flags&1 != 0
+	n := len(vertices)
+	if !closed {
+		n--
+	}
+	points := []model2d.Coord{vertices[0].P}
+	for i := 0; i < n; i++ {
+		v1, v2 := vertices[i], vertices[(i+1)%len(vertices)]
+		if v1.Bulge == 0 {
+			points = append(points, v2.P)
+			continue
+		}
+		// The bulge is the tangent of a quarter of the included angle,
+		// positive for counter-clockwise arcs.
+		angle := 4 * math.Atan(v1.Bulge)
+		chord := v2.P.Sub(v1.P)
+		radius := chord.Norm() / (2 * math.Sin(math.Abs(angle)/2))
+		mid := v1.P.Mid(v2.P)
+		perp := model2d.XY(-chord.Y, chord.X).Normalize()
+		// Counter-clockwise arcs have their center to the left of the
+		// chord, unless they span more than half a circle.
+		offset := math.Copysign(1, angle) * radius * math.Cos(math.Abs(angle)/2)
+		center := mid.Add(perp.Scale(offset))
+		start := math.Atan2(v1.P.Y-center.Y, v1.P.X-center.X)
+		arc := dxfArcPoints(center, radius, start, start+angle, segments)
+		points = append(points, arc[1:len(arc)-1]...)
+		points = append(points, v2.P)
+	}
+	return points
+}
+
+func dxfArcPoints(center model2d.Coord, r, start, end float64, segments int) []model2d.Coord {
+	n := int(math.Ceil(float64(segments) * math.Abs(end-start) / (2 * math.Pi)))
+	if n < 1 {
+		n = 1
+	}
+	points := make([]model2d.Coord, n+1)
+	for i := range points {
+		theta := start + (end-start)*float64(i)/float64(n)
+		points[i] = center.Add(model2d.XY(math.Cos(theta), math.Sin(theta)).Scale(r))
+	}
+	return points
+}
+
+func dxfEllipsePoints(ent *dxfEntity, segments int) []model2d.Coord {
+	center := model2d.XY(ent.Num(10, 0), ent.Num(20, 0))
+	major := model2d.XY(ent.Num(11, 0), ent.Num(21, 0))
+	minor := model2d.XY(-major.Y, major.X).Scale(ent.Num(40, 1))
+	start := ent.Num(41, 0)
+	end := ent.Num(42, 2*math.Pi)
+	for end <= start {
+		end += 2 * math.Pi
+	}
+	n := int(math.Max(1, math.Ceil(float64(segments)*(end-start)/(2*math.Pi))))
+	points := make([]model2d.Coord, n+1)
+	for i := range points {
+		t := start + (end-start)*float64(i)/float64(n)
+		points[i] = center.Add(major.Scale(math.Cos(t))).Add(minor.Scale(math.Sin(t)))
+	}
+	return points
+}
+
+// dxfSnapper merges nearly-coincident endpoints so that separate LINE
+// and ARC entities form closed loops.
+type dxfSnapper struct {
+	points map[[2]int64]model2d.Coord
+}
+
+func newDXFSnapper() *dxfSnapper {
+	return &dxfSnapper{points: map[[2]int64]model2d.Coord{}}
+}
+
+func (d *dxfSnapper) Snap(c model2d.Coord) model2d.Coord {
+	const resolution = 1e-6
+	key := [2]int64{int64(math.Round(c.X / resolution)), int64(math.Round(c.Y / resolution))}
+	if p, ok := d.points[key]; ok {
+		return p
+	}
+	d.points[key] = c
+	return c
+}
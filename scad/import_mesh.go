@@ -0,0 +1,433 @@
+package scad
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/unixpickle/model3d/model3d"
+)
+
+const (
+	// stlHeaderSize is the size of the header of a binary STL file,
+	// including the triangle count.
+	stlHeaderSize = 84
+
+	// stlTriangleSize is the size of each triangle in a binary STL file.
+	stlTriangleSize = 50
+)
+
+func readSTLMesh(data []byte) (mesh *model3d.Mesh, err error) {
+	defer recoverMeshPanic(&err)
+	if err := checkSTLSize(data); err != nil {
+		return nil, err
+	}
+	tris, err := model3d.ReadSTL(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return model3d.NewMeshTriangles(tris), nil
+}
+
+// checkSTLSize makes sure that the triangle count of a binary STL file
+// fits in the file, since the reader allocates all of the triangles up
+// front.
+//
+// Files are detected as ASCII the same way as model3d.ReadSTL, which only
+// looks at the first 512 bytes.
+func checkSTLSize(data []byte) error {
+	chunk := data[:min(len(data), 512)]
+	if bytes.HasPrefix(chunk, []byte("solid")) && !bytes.ContainsFunc(chunk, func(r rune) bool {
+		return r == 0 || r > unicode.MaxASCII
+	}) {
+		return nil
+	}
+	if len(data) < stlHeaderSize {
+		return fmt.Errorf("binary STL is truncated")
+	}
+	count := binary.LittleEndian.Uint32(data[stlHeaderSize-4:])
+	if room := (len(data) - stlHeaderSize) / stlTriangleSize; uint64(count) > uint64(room) {
+		return fmt.Errorf("binary STL declares %d triangles but only has room for %d", count, room)
+	}
+	return nil
+}
+
+func readOFFMesh(data []byte) (mesh *model3d.Mesh, err error) {
+	defer recoverMeshPanic(&err)
+	if err := checkOFFSize(data); err != nil {
+		return nil, err
+	}
+	tris, err := model3d.ReadOFF(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return model3d.NewMeshTriangles(tris), nil
+}
+
+// checkOFFSize makes sure that the vertex and face counts of an OFF file
+// are no larger than its number of lines, since the reader allocates them
+// up front.
+func checkOFFSize(data []byte) error {
+	header, rest, _ := bytes.Cut(data, []byte("\n"))
+	counts, ok := bytes.CutPrefix(header, []byte("OFF"))
+	if !ok {
+		// Let the reader report the error.
+		return nil
+	}
+	if len(bytes.Fields(counts)) == 0 {
+		counts, _, _ = bytes.Cut(rest, []byte("\n"))
+	}
+	fields := bytes.Fields(counts)
+	if len(fields) != 3 {
+		return nil
+	}
+	numVerts, err1 := strconv.Atoi(string(fields[0]))
+	numFaces, err2 := strconv.Atoi(string(fields[1]))
+	if err1 != nil || err2 != nil {
+		return nil
+	}
+	if numVerts < 0 || numFaces < 0 {
+		return fmt.Errorf("OFF vertex and face counts must be non-negative")
+	}
+	if lines := bytes.Count(data, []byte("\n")) + 1; numVerts > lines || numFaces > lines-numVerts {
+		return fmt.Errorf("OFF declares %d vertices and %d faces but only has %d lines", numVerts, numFaces, lines)
+	}
+	return nil
+}
+
+// recoverMeshPanic turns a panic while decoding a mesh file into an
+// error, since the model3d readers assume well-formed input, e.g. they
+// panic on OFF faces with fewer than three vertices.
+func recoverMeshPanic(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("malformed file: %v", r)
+	}
+}
+
+// readOBJMesh reads the vertices and faces of a Wavefront OBJ file.
+// Polygonal faces are triangulated as fans.
+func readOBJMesh(data []byte) (*model3d.Mesh, error) {
+	var vertices []model3d.Coord3D
+	mesh := model3d.NewMesh()
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1<<24)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		if idx := strings.IndexByte(line, '#'); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "v":
+			if len(fields) < 4 {
+				return nil, fmt.Errorf("line %d: vertex requires 3 coordinates", lineNum)
+			}
+			var c [3]float64
+			for i := range c {
+				x, err := strconv.ParseFloat(fields[i+1], 64)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", lineNum, err)
+				}
+				c[i] = x
+			}
+			vertices = append(vertices, model3d.XYZ(c[0], c[1], c[2]))
+		case "f":
+			if len(fields) < 4 {
+				return nil, fmt.Errorf("line %d: face requires at least 3 vertices", lineNum)
+			}
+			face := make([]model3d.Coord3D, len(fields)-1)
+			for i, f := range fields[1:] {
+				idxStr, _, _ := strings.Cut(f, "/")
+				idx, err := strconv.Atoi(idxStr)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid vertex index %q", lineNum, f)
+				}
+				if idx < 0 {
+					idx += len(vertices)
+				} else {
+					idx--
+				}
+				if idx < 0 || idx >= len(vertices) {
+					return nil, fmt.Errorf("line %d: vertex index %q out of range", lineNum, f)
+				}
+				face[i] = vertices[idx]
+			}
+			for i := 1; i+1 < len(face); i++ {
+				mesh.Add(&model3d.Triangle{face[0], face[i], face[i+1]})
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return mesh, nil
+}
+
+// threeMFMaxComponents limits the number of objects that a 3MF package
+// may place through build items and component references, since a few
+// objects with many components each can reference each other to build
+// exponentially large meshes.
+const threeMFMaxComponents = 1 << 16
+
+type threeMFModel struct {
+	Unit      string `xml:"unit,attr"`
+	Resources struct {
+		Objects []threeMFObject `xml:"object"`
+	} `xml:"resources"`
+	Build struct {
+		Items []threeMFComponent `xml:"item"`
+	} `xml:"build"`
+}
+
+type threeMFObject struct {
+	ID   string `xml:"id,attr"`
+	Mesh *struct {
+		Vertices []struct {
+			X float64 `xml:"x,attr"`
+			Y float64 `xml:"y,attr"`
+			Z float64 `xml:"z,attr"`
+		} `xml:"vertices>vertex"`
+		Triangles []struct {
+			V1 int `xml:"v1,attr"`
+			V2 int `xml:"v2,attr"`
+			V3 int `xml:"v3,attr"`
+		} `xml:"triangles>triangle"`
+	} `xml:"mesh"`
+	Components []threeMFComponent `xml:"components>component"`
+}
+
+type threeMFComponent struct {
+	ObjectID  string `xml:"objectid,attr"`
+	Transform string `xml:"transform,attr"`
+
+	// Path is the production extension's p:path attribute, which refers
+	// to an object in another model part of the package.
+	Path string `xml:"path,attr"`
+}
+
+type threeMFRelationships struct {
+	Relationships []struct {
+		Target string `xml:"Target,attr"`
+		Type   string `xml:"Type,attr"`
+	} `xml:"Relationship"`
+}
+
+// threeMFPackage loads the model parts of a 3MF package as they are
+// referenced.
+type threeMFPackage struct {
+	files  map[string]*zip.File
+	models map[string]map[string]*threeMFObject
+}
+
+// read3MFMesh reads the build items of a 3MF package into a single mesh,
+// converting coordinates to millimeters.
+//
+// Only the objects placed by the build items of the root model are read,
+// following component references into other model parts.
+func read3MFMesh(data []byte) (*model3d.Mesh, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	pkg := &threeMFPackage{
+		files:  map[string]*zip.File{},
+		models: map[string]map[string]*threeMFObject{},
+	}
+	for _, f := range zr.File {
+		pkg.files[normalize3MFPath(f.Name)] = f
+	}
+	rootPath, err := pkg.rootModelPath()
+	if err != nil {
+		return nil, err
+	}
+	contents, err := pkg.readFile(rootPath)
+	if err != nil {
+		return nil, err
+	}
+	var model threeMFModel
+	if err := xml.Unmarshal(contents, &model); err != nil {
+		return nil, fmt.Errorf("%s: %w", rootPath, err)
+	}
+	scale, ok := map[string]float64{
+		"":           1,
+		"micron":     0.001,
+		"millimeter": 1,
+		"centimeter": 10,
+		"inch":       25.4,
+		"foot":       304.8,
+		"meter":      1000,
+	}[model.Unit]
+	if !ok {
+		return nil, fmt.Errorf("unknown unit %q", model.Unit)
+	}
+	pkg.models[rootPath] = index3MFObjects(&model)
+
+	mesh := model3d.NewMesh()
+	var numComponents int
+	var addObject func(path string, c threeMFComponent, xf model3d.Transform, depth int) error
+	addObject = func(path string, c threeMFComponent, parent model3d.Transform, depth int) error {
+		if depth > 32 {
+			return fmt.Errorf("component nesting is too deep")
+		}
+		numComponents++
+		if numComponents > threeMFMaxComponents {
+			return fmt.Errorf("more than %d components", threeMFMaxComponents)
+		}
+		xf, err := parse3MFTransform(c.Transform)
+		if err != nil {
+			return err
+		}
+		if parent != nil {
+			xf = model3d.JoinedTransform{xf, parent}
+		}
+		if c.Path != "" {
+			path = normalize3MFPath(c.Path)
+		}
+		objects, err := pkg.objects(path)
+		if err != nil {
+			return err
+		}
+		obj, ok := objects[c.ObjectID]
+		if !ok {
+			return fmt.Errorf("%s: unknown object id %q", path, c.ObjectID)
+		}
+		if obj.Mesh != nil {
+			vs := obj.Mesh.Vertices
+			for _, t := range obj.Mesh.Triangles {
+				var tri model3d.Triangle
+				for i, idx := range []int{t.V1, t.V2, t.V3} {
+					if idx < 0 || idx >= len(vs) {
+						return fmt.Errorf("object %q: vertex index %d out of range", c.ObjectID, idx)
+					}
+					tri[i] = xf.Apply(model3d.XYZ(vs[idx].X, vs[idx].Y, vs[idx].Z))
+				}
+				mesh.Add(&tri)
+			}
+		}
+		for _, child := range obj.Components {
+			if err := addObject(path, child, xf, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, item := range model.Build.Items {
+		if err := addObject(rootPath, item, nil, 0); err != nil {
+			return nil, err
+		}
+	}
+	if scale != 1 {
+		mesh = mesh.Scale(scale)
+	}
+	return mesh, nil
+}
+
+// rootModelPath finds the model part that the package relationships mark
+// as the 3D model, falling back on the conventional path.
+func (p *threeMFPackage) rootModelPath() (string, error) {
+	if data, err := p.readFile("_rels/.rels"); err == nil {
+		var rels threeMFRelationships
+		if err := xml.Unmarshal(data, &rels); err != nil {
+			return "", fmt.Errorf("_rels/.rels: %w", err)
+		}
+		for _, rel := range rels.Relationships {
+			if strings.HasSuffix(rel.Type, "/3dmodel") {
+				return normalize3MFPath(rel.Target), nil
+			}
+		}
+	}
+	const defaultPath = "3d/3dmodel.model"
+	if _, ok := p.files[defaultPath]; ok {
+		return defaultPath, nil
+	}
+	return "", fmt.Errorf("no model found in 3MF package")
+}
+
+// objects returns the objects of a model part by ID, parsing the part the
+// first time it is referenced.
+func (p *threeMFPackage) objects(path string) (map[string]*threeMFObject, error) {
+	if objects, ok := p.models[path]; ok {
+		return objects, nil
+	}
+	contents, err := p.readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var model threeMFModel
+	if err := xml.Unmarshal(contents, &model); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	objects := index3MFObjects(&model)
+	p.models[path] = objects
+	return objects, nil
+}
+
+func (p *threeMFPackage) readFile(path string) ([]byte, error) {
+	f, ok := p.files[path]
+	if !ok {
+		return nil, fmt.Errorf("missing part %q in 3MF package", path)
+	}
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func index3MFObjects(model *threeMFModel) map[string]*threeMFObject {
+	objects := map[string]*threeMFObject{}
+	for i := range model.Resources.Objects {
+		obj := &model.Resources.Objects[i]
+		objects[obj.ID] = obj
+	}
+	return objects
+}
+
+// normalize3MFPath converts a part name or reference into a key for the
+// files of a package. Part names are absolute and case-insensitive.
+func normalize3MFPath(path string) string {
+	return strings.ToLower(strings.TrimPrefix(path, "/"))
+}
+
+// parse3MFTransform parses a 3MF affine matrix, which lists the twelve
+// entries of a 4x3 matrix applied to row vectors.
+func parse3MFTransform(s string) (model3d.Transform, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return &model3d.Translate{}, nil
+	}
+	if len(fields) != 12 {
+		return nil, fmt.Errorf("transform must have 12 entries")
+	}
+	var m [12]float64
+	for i, f := range fields {
+		x, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transform: %w", err)
+		}
+		m[i] = x
+	}
+	return model3d.JoinedTransform{
+		&model3d.Matrix3Transform{
+			Matrix: &model3d.Matrix3{
+				m[0], m[3], m[6],
+				m[1], m[4], m[7],
+				m[2], m[5], m[8],
+			},
+		},
+		&model3d.Translate{Offset: model3d.XYZ(m[9], m[10], m[11])},
+	}, nil
+}
@@ -0,0 +1,526 @@
+package scad
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/unixpickle/model3d/model2d"
+	"github.com/unixpickle/path2d"
+)
+
+// importOptions2D controls how 2D files are converted to meshes.
+type importOptions2D struct {
+	// ID, if non-empty, selects a single element (and its
+	// descendants) by id.
+	ID string
+
+	// Layer, if non-empty, selects a layer by name.
+	Layer string
+
+	// DPI is the resolution used to convert SVG pixels to
+	// millimeters.
+	DPI float64
+
+	// Segments is the number of segments used per curve.
+	Segments int
+
+	// FillRule, if non-nil, overrides the file's fill rules.
+	FillRule *fillRule
+}
+
+// svgSkippedElements are never rendered directly.
+var svgSkippedElements = map[string]bool{
+	"defs":     true,
+	"clipPath": true,
+	"mask":     true,
+	"symbol":   true,
+	"pattern":  true,
+	"marker":   true,
+	"metadata": true,
+	"title":    true,
+	"desc":     true,
+	"style":    true,
+}
+
+type svgState struct {
+	Selected  bool
+	InLayer   bool
+	FillRule  fillRule
+	Unfilled  bool
+	Transform svgTransform
+	Skip      bool
+}
+
+// readSVGMesh converts the filled paths of an SVG document to a mesh,
+// in millimeters with the y-axis facing up.
+func readSVGMesh(data []byte, opts importOptions2D) (*model2d.Mesh, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+
+	var fills []*model2d.Mesh
+	var toMM func(c model2d.Coord) model2d.Coord
+	var foundID, foundLayer bool
+	stack := []svgState{{
+		Selected:  opts.ID == "",
+		InLayer:   opts.Layer == "",
+		FillRule:  fillRuleNonZero,
+		Transform: svgIdentityTransform,
+	}}
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			parent := stack[len(stack)-1]
+			state := parent
+			name := token.Name.Local
+			if parent.Skip || svgSkippedElements[name] {
+				state.Skip = true
+				stack = append(stack, state)
+				continue
+			}
+			id := svgAttr(token, "id")
+			if opts.ID != "" && id == opts.ID {
+				state.Selected = true
+				foundID = true
+			}
+			if name == "g" && opts.Layer != "" && svgIsLayer(token, opts.Layer) {
+				state.InLayer = true
+				foundLayer = true
+			}
+			if rule, ok := svgStyleAttr(token, "fill-rule"); ok {
+				switch rule {
+				case "evenodd":
+					state.FillRule = fillRuleEvenOdd
+				case "nonzero":
+					state.FillRule = fillRuleNonZero
+				}
+			}
+			if fill, ok := svgStyleAttr(token, "fill"); ok {
+				state.Unfilled = fill == "none"
+			}
+			if display, _ := svgStyleAttr(token, "display"); display == "none" {
+				state.Skip = true
+			}
+			if transform := svgAttr(token, "transform"); transform != "" && name != "svg" {
+				t, err := parseSVGTransform(transform)
+				if err != nil {
+					return nil, fmt.Errorf("%s %q: %w", name, id, err)
+				}
+				state.Transform = parent.Transform.Mul(t)
+			}
+			stack = append(stack, state)
+
+			if name == "svg" && toMM == nil {
+				toMM, err = svgDocumentTransform(token, opts.DPI)
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if state.Skip || state.Unfilled || !state.Selected || !state.InLayer {
+				continue
+			}
+			curves, err := svgElementCurves(token)
+			if err != nil {
+				return nil, fmt.Errorf("%s %q: %w", name, id, err)
+			}
+			if len(curves) == 0 {
+				continue
+			}
+			rule := state.FillRule
+			if opts.FillRule != nil {
+				rule = *opts.FillRule
+			}
+			mesh := svgCurvesMesh(curves, opts.Segments).MapCoords(state.Transform.Apply)
+			if toMM != nil {
+				mesh = mesh.MapCoords(toMM)
+			}
+			fills = append(fills, fillMesh2D(mesh, rule))
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+	if opts.ID != "" && !foundID {
+		return nil, fmt.Errorf("no element with id %q", opts.ID)
+	}
+	if opts.Layer != "" && !foundLayer {
+		return nil, fmt.Errorf("no layer named %q", opts.Layer)
+	}
+
+	// Each filled mesh has winding number 1 inside, so the union of
+	// all the paths is the nonzero fill of their concatenation.
+	joined := model2d.NewMesh()
+	for _, m := range fills {
+		joined.AddMesh(m)
+	}
+	return fillMesh2D(joined, fillRuleNonZero), nil
+}
+
+// svgCurvesMesh converts parsed SVG subpaths into closed loops, using
+// one segment per straight line and the given number of segments for
+// every other curve.
+func svgCurvesMesh(curves []*model2d.JoinedArcLenCurve, segments int) *model2d.Mesh {
+	mesh := model2d.NewMesh()
+	for _, curve := range curves {
+		points := svgCurvePoints(curve, segments)
+		if len(points) > 1 && points[0] != points[len(points)-1] {
+			points = append(points, points[0])
+		}
+		for i := 1; i < len(points); i++ {
+			if points[i-1] != points[i] {
+				mesh.Add(&model2d.Segment{points[i-1], points[i]})
+			}
+		}
+	}
+	return mesh
+}
+
+// svgCurvePoints samples a subpath as a polyline, using one segment per
+// straight line and the given number of segments for every other curve.
+func svgCurvePoints(curve *model2d.JoinedArcLenCurve, segments int) []model2d.Coord {
+	var points []model2d.Coord
+	for _, sub := range curve.Subcurves() {
+		n := segments
+		if b, ok := sub.(model2d.BezierCurve); ok && len(b) == 2 {
+			n = 1
+		}
+		if len(points) == 0 {
+			points = append(points, sub.Eval(0))
+		}
+		for i := 1; i <= n; i++ {
+			points = append(points, sub.Eval(float64(i)/float64(n)))
+		}
+	}
+	return points
+}
+
+// svgElementCurves converts a path or basic shape to closed subpaths in
+// user coordinates. Other elements, and shapes without any area, produce
+// no curves.
+func svgElementCurves(el xml.StartElement) ([]*model2d.JoinedArcLenCurve, error) {
+	var nums map[string]float64
+	readNums := func(names ...string) error {
+		nums = map[string]float64{}
+		for _, name := range names {
+			s := strings.TrimSuffix(strings.TrimSpace(svgAttr(el, name)), "px")
+			if s == "" {
+				continue
+			}
+			x, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s %q", name, svgAttr(el, name))
+			}
+			nums[name] = x
+		}
+		return nil
+	}
+
+	switch el.Name.Local {
+	case "path":
+		d := svgAttr(el, "d")
+		if strings.TrimSpace(d) == "" {
+			return nil, nil
+		}
+		return path2d.ParseSVGPath(d)
+	case "rect":
+		if err := readNums("x", "y", "width", "height", "rx", "ry"); err != nil {
+			return nil, err
+		}
+		w, h := nums["width"], nums["height"]
+		if w <= 0 || h <= 0 {
+			return nil, nil
+		}
+		rx, rxOK := nums["rx"]
+		ry, ryOK := nums["ry"]
+		if !rxOK {
+			rx = ry
+		} else if !ryOK {
+			ry = rx
+		}
+		rx = math.Max(0, math.Min(rx, w/2))
+		ry = math.Max(0, math.Min(ry, h/2))
+		return []*model2d.JoinedArcLenCurve{svgRectCurve(nums["x"], nums["y"], w, h, rx, ry)}, nil
+	case "circle":
+		if err := readNums("cx", "cy", "r"); err != nil {
+			return nil, err
+		}
+		if nums["r"] <= 0 {
+			return nil, nil
+		}
+		center, radius := model2d.XY(nums["cx"], nums["cy"]), nums["r"]
+		return []*model2d.JoinedArcLenCurve{svgEllipseCurve(center, model2d.XY(radius, radius))}, nil
+	case "ellipse":
+		if err := readNums("cx", "cy", "rx", "ry"); err != nil {
+			return nil, err
+		}
+		if nums["rx"] <= 0 || nums["ry"] <= 0 {
+			return nil, nil
+		}
+		center, radii := model2d.XY(nums["cx"], nums["cy"]), model2d.XY(nums["rx"], nums["ry"])
+		return []*model2d.JoinedArcLenCurve{svgEllipseCurve(center, radii)}, nil
+	case "polygon", "polyline":
+		// Polylines are filled like polygons, as if they were closed.
+		fields := strings.FieldsFunc(svgAttr(el, "points"), func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+		})
+		var points []model2d.Coord
+		for i := 0; i+1 < len(fields); i += 2 {
+			x, err1 := strconv.ParseFloat(fields[i], 64)
+			y, err2 := strconv.ParseFloat(fields[i+1], 64)
+			if err1 != nil || err2 != nil {
+				return nil, fmt.Errorf("invalid points %q", svgAttr(el, "points"))
+			}
+			points = append(points, model2d.XY(x, y))
+		}
+		if len(points) < 3 {
+			return nil, nil
+		}
+		return []*model2d.JoinedArcLenCurve{svgPolygonCurve(points)}, nil
+	}
+	return nil, nil
+}
+
+// svgPolygonCurve joins the points with straight lines, returning to the
+// first point.
+func svgPolygonCurve(points []model2d.Coord) *model2d.JoinedArcLenCurve {
+	var curves []model2d.ArcLenCurve
+	for i, p := range points {
+		next := points[(i+1)%len(points)]
+		if p != next {
+			curves = append(curves, model2d.BezierCurve{p, next})
+		}
+	}
+	return model2d.NewJoinedArcLenCurve(curves)
+}
+
+// svgEllipseCurve creates an axis-aligned ellipse from two half arcs.
+func svgEllipseCurve(center, radii model2d.Coord) *model2d.JoinedArcLenCurve {
+	left := center.Sub(model2d.X(radii.X))
+	right := center.Add(model2d.X(radii.X))
+	return model2d.NewJoinedArcLenCurve([]model2d.ArcLenCurve{
+		model2d.NewArcCurve(radii, left, right, 0, false, true),
+		model2d.NewArcCurve(radii, right, left, 0, false, true),
+	})
+}
+
+// svgRectCurve creates a rectangle whose corners are rounded by
+// elliptical arcs with radii rx and ry.
+func svgRectCurve(x, y, w, h, rx, ry float64) *model2d.JoinedArcLenCurve {
+	if rx == 0 || ry == 0 {
+		return svgPolygonCurve([]model2d.Coord{
+			model2d.XY(x, y), model2d.XY(x+w, y), model2d.XY(x+w, y+h), model2d.XY(x, y+h),
+		})
+	}
+	radii := model2d.XY(rx, ry)
+	corners := [][2]model2d.Coord{
+		{model2d.XY(x+w-rx, y), model2d.XY(x+w, y+ry)},
+		{model2d.XY(x+w, y+h-ry), model2d.XY(x+w-rx, y+h)},
+		{model2d.XY(x+rx, y+h), model2d.XY(x, y+h-ry)},
+		{model2d.XY(x, y+ry), model2d.XY(x+rx, y)},
+	}
+	var curves []model2d.ArcLenCurve
+	for i, corner := range corners {
+		curves = append(curves, model2d.NewArcCurve(radii, corner[0], corner[1], 0, false, true))
+		next := corners[(i+1)%len(corners)][0]
+		if corner[1] != next {
+			curves = append(curves, model2d.BezierCurve{corner[1], next})
+		}
+	}
+	return model2d.NewJoinedArcLenCurve(curves)
+}
+
+// svgTransform is an affine transform [a b c d e f], mapping (x, y) to
+// (a*x + c*y + e, b*x + d*y + f) as in SVG's matrix().
+type svgTransform [6]float64
+
+var svgIdentityTransform = svgTransform{1, 0, 0, 1, 0, 0}
+
+// Mul composes two transforms, applying t2 first.
+func (t svgTransform) Mul(t2 svgTransform) svgTransform {
+	return svgTransform{
+		t[0]*t2[0] + t[2]*t2[1],
+		t[1]*t2[0] + t[3]*t2[1],
+		t[0]*t2[2] + t[2]*t2[3],
+		t[1]*t2[2] + t[3]*t2[3],
+		t[0]*t2[4] + t[2]*t2[5] + t[4],
+		t[1]*t2[4] + t[3]*t2[5] + t[5],
+	}
+}
+
+func (t svgTransform) Apply(c model2d.Coord) model2d.Coord {
+	return model2d.XY(t[0]*c.X+t[2]*c.Y+t[4], t[1]*c.X+t[3]*c.Y+t[5])
+}
+
+// parseSVGTransform parses a transform attribute such as
+// "translate(10, 20) rotate(45)".
+func parseSVGTransform(s string) (svgTransform, error) {
+	res := svgIdentityTransform
+	rest := strings.TrimSpace(s)
+	for rest != "" {
+		open := strings.IndexByte(rest, '(')
+		end := strings.IndexByte(rest, ')')
+		if open < 0 || end < open {
+			return res, fmt.Errorf("invalid transform %q", s)
+		}
+		name := strings.TrimSpace(rest[:open])
+		var args []float64
+		for _, f := range strings.FieldsFunc(rest[open+1:end], func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+		}) {
+			x, err := strconv.ParseFloat(f, 64)
+			if err != nil {
+				return res, fmt.Errorf("invalid transform %q", s)
+			}
+			args = append(args, x)
+		}
+		rest = strings.TrimLeft(rest[end+1:], ", \t\n\r")
+
+		var t svgTransform
+		switch {
+		case name == "matrix" && len(args) == 6:
+			copy(t[:], args)
+		case name == "translate" && (len(args) == 1 || len(args) == 2):
+			t = svgIdentityTransform
+			t[4] = args[0]
+			if len(args) == 2 {
+				t[5] = args[1]
+			}
+		case name == "scale" && (len(args) == 1 || len(args) == 2):
+			t = svgTransform{args[0], 0, 0, args[0], 0, 0}
+			if len(args) == 2 {
+				t[3] = args[1]
+			}
+		case name == "rotate" && (len(args) == 1 || len(args) == 3):
+			theta := args[0] * math.Pi / 180
+			cos, sin := math.Cos(theta), math.Sin(theta)
+			t = svgTransform{cos, sin, -sin, cos, 0, 0}
+			if len(args) == 3 {
+				// Rotate about (cx, cy) rather than the origin.
+				cx, cy := args[1], args[2]
+				t[4] = cx - cos*cx + sin*cy
+				t[5] = cy - sin*cx - cos*cy
+			}
+		case name == "skewX" && len(args) == 1:
+			t = svgTransform{1, 0, math.Tan(args[0] * math.Pi / 180), 1, 0, 0}
+		case name == "skewY" && len(args) == 1:
+			t = svgTransform{1, math.Tan(args[0] * math.Pi / 180), 0, 1, 0, 0}
+		default:
+			return res, fmt.Errorf("invalid transform %q", s)
+		}
+		res = res.Mul(t)
+	}
+	return res, nil
+}
+
+// svgDocumentTransform maps user coordinates to millimeters, flipping
+// the y-axis so that the bottom-left corner of the document is at the
+// origin.
+func svgDocumentTransform(root xml.StartElement, dpi float64) (func(model2d.Coord) model2d.Coord, error) {
+	pxToMM := 25.4 / dpi
+	width, widthOK := svgLengthMM(svgAttr(root, "width"), pxToMM)
+	height, heightOK := svgLengthMM(svgAttr(root, "height"), pxToMM)
+
+	viewBox := strings.Fields(strings.ReplaceAll(svgAttr(root, "viewBox"), ",", " "))
+	if len(viewBox) == 4 {
+		var vb [4]float64
+		for i, f := range viewBox {
+			x, err := strconv.ParseFloat(f, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid viewBox: %w", err)
+			}
+			vb[i] = x
+		}
+		if vb[2] <= 0 || vb[3] <= 0 {
+			return nil, fmt.Errorf("invalid viewBox: non-positive size")
+		}
+		scaleX, scaleY := pxToMM, pxToMM
+		if widthOK && heightOK {
+			scaleX, scaleY = width/vb[2], height/vb[3]
+		} else if widthOK {
+			scaleX, scaleY = width/vb[2], width/vb[2]
+		} else if heightOK {
+			scaleX, scaleY = height/vb[3], height/vb[3]
+		}
+		return func(c model2d.Coord) model2d.Coord {
+			return model2d.XY((c.X-vb[0])*scaleX, (vb[1]+vb[3]-c.Y)*scaleY)
+		}, nil
+	}
+
+	var offset float64
+	if heightOK {
+		offset = height
+	}
+	return func(c model2d.Coord) model2d.Coord {
+		return model2d.XY(c.X*pxToMM, offset-c.Y*pxToMM)
+	}, nil
+}
+
+// svgLengthMM parses an absolute SVG length in millimeters.
+func svgLengthMM(s string, pxToMM float64) (float64, bool) {
+	s = strings.TrimSpace(s)
+	units := map[string]float64{
+		"mm": 1,
+		"cm": 10,
+		"in": 25.4,
+		"pt": 25.4 / 72,
+		"pc": 25.4 / 6,
+		"px": pxToMM,
+	}
+	scale := pxToMM
+	for suffix, unitScale := range units {
+		if strings.HasSuffix(s, suffix) {
+			s = strings.TrimSuffix(s, suffix)
+			scale = unitScale
+			break
+		}
+	}
+	x, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || x <= 0 {
+		return 0, false
+	}
+	return x * scale, true
+}
+
+func svgAttr(el xml.StartElement, name string) string {
+	for _, attr := range el.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// svgStyleAttr looks up a presentation property, giving the style
+// attribute precedence over the plain attribute.
+func svgStyleAttr(el xml.StartElement, name string) (string, bool) {
+	for _, decl := range strings.Split(svgAttr(el, "style"), ";") {
+		key, value, ok := strings.Cut(decl, ":")
+		if ok && strings.TrimSpace(key) == name {
+			return strings.TrimSpace(value), true
+		}
+	}
+	for _, attr := range el.Attr {
+		if attr.Name.Local == name {
+			return strings.TrimSpace(attr.Value), true
+		}
+	}
+	return "", false
+}
+
+// svgIsLayer checks if a group is an Inkscape layer with the given
+// label.
+func svgIsLayer(el xml.StartElement, layer string) bool {
+	return svgAttr(el, "groupmode") == "layer" && svgAttr(el, "label") == layer
+}
@@ -0,0 +1,1053 @@
+package scad
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"sort"
+
+	"github.com/unixpickle/model3d/model2d"
+	"github.com/unixpickle/model3d/model3d"
+)
+
+// meshBooleanOp selects which pieces of two meshes a boolean keeps.
+type meshBooleanOp int
+
+const (
+	meshUnion meshBooleanOp = iota
+	meshIntersection
+	meshDifference
+)
+
+// meshBoolean3D computes a union, intersection or difference of two
+// closed, consistently oriented meshes.
+//
+// Every triangle is split along its intersections with the other mesh,
+// and the pieces are kept or discarded based on whether they lie inside
+// the other mesh. Intersection points have exact rational coordinates
+// and all topological decisions use exact predicates, so neighboring
+// triangles always split their shared edges at the same points and the
+// result is watertight. Coordinates are only rounded in the output, and
+// features too small to survive rounding are then removed.
+//
+// It fails if the intersections of a triangle with the other mesh
+// cannot be triangulated, rather than returning a mesh with holes.
+func meshBoolean3D(a, b *model3d.Mesh, op meshBooleanOp) (*model3d.Mesh, error) {
+	trisA := a.TriangleSlice()
+	trisB := b.TriangleSlice()
+	if len(trisA) == 0 || len(trisB) == 0 || !boundsOverlap(a, b) {
+		switch op {
+		case meshUnion:
+			res := model3d.NewMesh()
+			res.AddMesh(a)
+			res.AddMesh(b)
+			return res, nil
+		case meshIntersection:
+			return model3d.NewMesh(), nil
+		default:
+			return model3d.NewMeshTriangles(trisA), nil
+		}
+	}
+
+	points := exactPointSet{}
+	splitsA := make([]meshTriSplit, len(trisA))
+	splitsB := make([]meshTriSplit, len(trisB))
+	tree := newMeshBoolTree(trisB)
+	for i, t1 := range trisA {
+		if degenerateTriangle(t1) {
+			continue
+		}
+		tree.Find(t1.Min(), t1.Max(), func(j int) {
+			if !degenerateTriangle(trisB[j]) {
+				intersectTriangles(points, t1, trisB[j], &splitsA[i], &splitsB[j])
+			}
+		})
+	}
+
+	separators := map[[2]model3d.Coord3D]bool{}
+	piecesA, err := splitMeshTriangles(points, trisA, splitsA, separators)
+	if err != nil {
+		return nil, err
+	}
+	piecesB, err := splitMeshTriangles(points, trisB, splitsB, separators)
+	if err != nil {
+		return nil, err
+	}
+	classA := classifyMeshPieces(piecesA, trisB, separators)
+	classB := classifyMeshPieces(piecesB, trisA, separators)
+
+	var res [][3]model3d.Coord3D
+	for i, p := range piecesA {
+		c := classA[i]
+		var keep bool
+		switch op {
+		case meshUnion:
+			keep = c == pieceOutside || c == pieceSharedSame
+		case meshIntersection:
+			keep = c == pieceInside || c == pieceSharedSame
+		case meshDifference:
+			keep = c == pieceOutside || c == pieceSharedOpposite
+		}
+		if keep {
+			res = append(res, p.Tri)
+		}
+	}
+	for i, p := range piecesB {
+		c := classB[i]
+		switch op {
+		case meshUnion:
+			if c == pieceOutside {
+				res = append(res, p.Tri)
+			}
+		case meshIntersection:
+			if c == pieceInside {
+				res = append(res, p.Tri)
+			}
+		case meshDifference:
+			if c == pieceInside {
+				res = append(res, [3]model3d.Coord3D{p.Tri[0], p.Tri[2], p.Tri[1]})
+			}
+		}
+	}
+
+	// Features of the exact result which are much smaller than the
+	// inputs cannot survive rounding, and may fold over neighboring
+	// triangles.
+	scale := a.Max().Max(b.Max()).Sub(a.Min().Min(b.Min())).Norm()
+	out := model3d.NewMesh()
+	for _, t := range removeRoundedSlivers(res, scale*meshSliverEpsilon) {
+		out.Add(&model3d.Triangle{t[0], t[1], t[2]})
+	}
+	return out, nil
+}
+
+func boundsOverlap(a, b model3d.Bounder) bool {
+	min := a.Min().Max(b.Min())
+	max := a.Max().Min(b.Max())
+	return min.X <= max.X && min.Y <= max.Y && min.Z <= max.Z
+}
+
+// A meshTriSplit accumulates the intersections of one triangle with
+// the triangles of the other mesh.
+type meshTriSplit struct {
+	Points   []meshSplitPoint
+	Segments [][2]*exactPoint
+
+	// Coplanar stores triangles of the other mesh which lie in the same
+	// plane as this triangle and overlap it.
+	Coplanar []*model3d.Triangle
+}
+
+// A meshSplitPoint is a point on a triangle, along with its location:
+// one of the locInterior, locEdge or locVertex values.
+type meshSplitPoint struct {
+	P   *exactPoint
+	Loc int
+}
+
+const locInterior = -1
+
+func locEdge(i int) int {
+	return i
+}
+
+func locVertex(i int) int {
+	return 3 + i
+}
+
+type triIntersection struct {
+	P    *exactPoint
+	Loc1 int
+	Loc2 int
+}
+
+// intersectTriangles records the intersection of t1 and t2 in their
+// respective splits.
+func intersectTriangles(points exactPointSet, t1, t2 *model3d.Triangle, s1, s2 *meshTriSplit) {
+	var side1, side2 [3]int
+	for i := 0; i < 3; i++ {
+		side1[i] = orient3D(t2[0], t2[1], t2[2], t1[i])
+		side2[i] = orient3D(t1[0], t1[1], t1[2], t2[i])
+	}
+	if sameStrictSide(side1) || sameStrictSide(side2) {
+		return
+	}
+
+	var found []triIntersection
+	add := func(p *exactPoint, loc1, loc2 int) {
+		p = points.Add(p)
+		for _, x := range found {
+			if x.P == p {
+				return
+			}
+		}
+		found = append(found, triIntersection{P: p, Loc1: loc1, Loc2: loc2})
+		s1.addPoint(p, loc1)
+		s2.addPoint(p, loc2)
+	}
+
+	if side1 == [3]int{} {
+		intersectCoplanarTriangles(t1, t2, add)
+		s1.Coplanar = append(s1.Coplanar, t2)
+		s2.Coplanar = append(s2.Coplanar, t1)
+		if len(found) == 0 {
+			return
+		}
+		hull := coplanarHull(t1, found)
+		for i, p := range hull {
+			if len(hull) == 2 && i == 1 {
+				break
+			}
+			q := hull[(i+1)%len(hull)]
+			if p != q {
+				s1.Segments = append(s1.Segments, [2]*exactPoint{p, q})
+				s2.Segments = append(s2.Segments, [2]*exactPoint{p, q})
+			}
+		}
+		return
+	}
+
+	edgesThroughTriangle(t1, t2, side1, func(p *exactPoint, edge, loc int) {
+		add(p, locEdge(edge), loc)
+	})
+	edgesThroughTriangle(t2, t1, side2, func(p *exactPoint, edge, loc int) {
+		add(p, loc, locEdge(edge))
+	})
+	for i := 0; i < 3; i++ {
+		if side1[i] == 0 {
+			if loc, ok := pointInTriangle(t1[i], t2); ok {
+				add(newExactVertex(t1[i]), locVertex(i), loc)
+			}
+		}
+		if side2[i] == 0 {
+			if loc, ok := pointInTriangle(t2[i], t1); ok {
+				add(newExactVertex(t2[i]), loc, locVertex(i))
+			}
+		}
+	}
+	if len(found) < 2 {
+		return
+	}
+
+	// All of the points lie on the line where the planes meet, and the
+	// intersection is the segment spanning them. Any direction which is
+	// not perpendicular to the line orders the points correctly.
+	dir := t1.Normal().Cross(t2.Normal())
+	dots := make([]*big.Rat, len(found))
+	for i, p := range found {
+		dots[i] = new(big.Rat)
+		for axis, d := range dir.Array() {
+			term := new(big.Rat).SetFloat64(d)
+			dots[i].Add(dots[i], term.Mul(term, p.P.Rat(axis)))
+		}
+	}
+	first, last := 0, 0
+	for i, d := range dots {
+		if d.Cmp(dots[first]) < 0 {
+			first = i
+		}
+		if d.Cmp(dots[last]) > 0 {
+			last = i
+		}
+	}
+	seg := [2]*exactPoint{found[first].P, found[last].P}
+	s1.Segments = append(s1.Segments, seg)
+	s2.Segments = append(s2.Segments, seg)
+}
+
+func (m *meshTriSplit) addPoint(p *exactPoint, loc int) {
+	if loc < locVertex(0) {
+		m.Points = append(m.Points, meshSplitPoint{P: p, Loc: loc})
+	}
+}
+
+func sameStrictSide(sides [3]int) bool {
+	return sides[0] != 0 && sides[0] == sides[1] && sides[1] == sides[2]
+}
+
+// edgesThroughTriangle finds the edges of t1 which cross the plane of
+// t2 inside of t2, given the sides of t1's vertices relative to t2.
+//
+// The callback receives the crossing point, the index of the edge in
+// t1, and the location of the point on t2. Edges passing through a
+// vertex of t2 are skipped, since that vertex is found separately.
+func edgesThroughTriangle(
+	t1, t2 *model3d.Triangle,
+	sides [3]int,
+	f func(p *exactPoint, edge, loc int),
+) {
+	for i := 0; i < 3; i++ {
+		p, q := t1[i], t1[(i+1)%3]
+		if sides[i]*sides[(i+1)%3] >= 0 {
+			continue
+		}
+		var pos, neg, zeros, zeroIdx int
+		for k := 0; k < 3; k++ {
+			switch orient3D(p, q, t2[k], t2[(k+1)%3]) {
+			case 1:
+				pos++
+			case -1:
+				neg++
+			default:
+				zeros++
+				zeroIdx = k
+			}
+		}
+		if pos > 0 && neg > 0 {
+			continue
+		}
+		switch zeros {
+		case 0:
+			f(edgePlanePoint(p, q, t2), i, locInterior)
+		case 1:
+			f(edgeEdgePoint(p, q, t2[zeroIdx], t2[(zeroIdx+1)%3]), i, locEdge(zeroIdx))
+		}
+	}
+}
+
+// intersectCoplanarTriangles reports the corners of the overlap of two
+// triangles which lie in the same plane.
+func intersectCoplanarTriangles(t1, t2 *model3d.Triangle, add func(p *exactPoint, loc1, loc2 int)) {
+	for i := 0; i < 3; i++ {
+		if loc, ok := pointInTriangle(t1[i], t2); ok {
+			add(newExactVertex(t1[i]), locVertex(i), loc)
+		}
+		if loc, ok := pointInTriangle(t2[i], t1); ok {
+			add(newExactVertex(t2[i]), loc, locVertex(i))
+		}
+	}
+	proj := newTriProjection(t1)
+	for i := 0; i < 3; i++ {
+		p, q := t1[i], t1[(i+1)%3]
+		p2, q2 := proj.Project(p), proj.Project(q)
+		for k := 0; k < 3; k++ {
+			r, s := t2[k], t2[(k+1)%3]
+			r2, s2 := proj.Project(r), proj.Project(s)
+			if orient2D(p2, q2, r2)*orient2D(p2, q2, s2) < 0 &&
+				orient2D(r2, s2, p2)*orient2D(r2, s2, q2) < 0 {
+				add(edgeEdgePoint(p, q, r, s), locEdge(i), locEdge(k))
+			}
+		}
+	}
+}
+
+// coplanarHull orders the corners of a coplanar overlap around its
+// boundary.
+func coplanarHull(t *model3d.Triangle, points []triIntersection) []*exactPoint {
+	proj := newTriProjection(t)
+	coords := make([]*exactPoint, len(points))
+	for i, p := range points {
+		coords[i] = p.P
+	}
+	sort.Slice(coords, func(i, j int) bool {
+		if c := coords[i].Compare(coords[j], proj.U); c != 0 {
+			return c < 0
+		}
+		return coords[i].Compare(coords[j], proj.V) < 0
+	})
+	if len(coords) < 3 {
+		return coords
+	}
+	var hull []*exactPoint
+	for pass := 0; pass < 2; pass++ {
+		start := len(hull)
+		for _, c := range coords {
+			for len(hull) >= start+2 &&
+				orientProjected(proj, hull[len(hull)-2], hull[len(hull)-1], c) <= 0 {
+				hull = hull[:len(hull)-1]
+			}
+			hull = append(hull, c)
+		}
+		hull = hull[:len(hull)-1]
+		for i, j := 0, len(coords)-1; i < j; i, j = i+1, j-1 {
+			coords[i], coords[j] = coords[j], coords[i]
+		}
+	}
+	return hull
+}
+
+// pointInTriangle checks if p, which must lie in the plane of t, is
+// inside t, and returns its location if so.
+func pointInTriangle(p model3d.Coord3D, t *model3d.Triangle) (int, bool) {
+	proj := newTriProjection(t)
+	p2 := proj.Project(p)
+	var zeros [3]bool
+	numZeros := 0
+	for k := 0; k < 3; k++ {
+		switch orient2D(proj.Project(t[k]), proj.Project(t[(k+1)%3]), p2) {
+		case -1:
+			return 0, false
+		case 0:
+			zeros[k] = true
+			numZeros++
+		}
+	}
+	switch numZeros {
+	case 0:
+		return locInterior, true
+	case 1:
+		for k, z := range zeros {
+			if z {
+				return locEdge(k), true
+			}
+		}
+	}
+	for k := 0; k < 3; k++ {
+		if zeros[k] && zeros[(k+1)%3] {
+			return locVertex((k + 1) % 3), true
+		}
+	}
+	return 0, false
+}
+
+// edgePlanePoint computes where the segment p-q crosses the plane of t.
+func edgePlanePoint(p, q model3d.Coord3D, t *model3d.Triangle) *exactPoint {
+	dp := orient3DDet(t[0], t[1], t[2], p)
+	dq := orient3DDet(t[0], t[1], t[2], q)
+	frac := new(big.Rat).Sub(dp, dq)
+	frac.Quo(dp, frac)
+	return exactLerp(p, q, frac)
+}
+
+// edgeEdgePoint computes where two coplanar segments p-q and r-s cross.
+func edgeEdgePoint(p, q, r, s model3d.Coord3D) *exactPoint {
+	pa, qa, ra, sa := p.Array(), q.Array(), r.Array(), s.Array()
+	var d1, d2, w [3]*big.Rat
+	for i := 0; i < 3; i++ {
+		d1[i] = ratSub(qa[i], pa[i])
+		d2[i] = ratSub(sa[i], ra[i])
+		w[i] = ratSub(ra[i], pa[i])
+	}
+
+	// The segments are not parallel, so at least one projection of
+	// their directions has a non-zero cross product.
+	for axis := 0; axis < 3; axis++ {
+		u, v := (axis+1)%3, (axis+2)%3
+		denom := ratCross(d1[u], d1[v], d2[u], d2[v])
+		if denom.Sign() != 0 {
+			frac := ratCross(w[u], w[v], d2[u], d2[v])
+			return exactLerp(p, q, frac.Quo(frac, denom))
+		}
+	}
+	return newExactVertex(p)
+}
+
+func exactLerp(p, q model3d.Coord3D, frac *big.Rat) *exactPoint {
+	pa, qa := p.Array(), q.Array()
+	var res [3]*big.Rat
+	for i := 0; i < 3; i++ {
+		d := ratSub(qa[i], pa[i])
+		d.Mul(d, frac)
+		res[i] = d.Add(d, new(big.Rat).SetFloat64(pa[i]))
+	}
+	return newExactPoint(res[0], res[1], res[2])
+}
+
+func coordLess(a, b model3d.Coord3D) bool {
+	if a.X != b.X {
+		return a.X < b.X
+	} else if a.Y != b.Y {
+		return a.Y < b.Y
+	}
+	return a.Z < b.Z
+}
+
+// degenerateTriangle checks if a triangle has exactly zero area.
+func degenerateTriangle(t *model3d.Triangle) bool {
+	for axis := 0; axis < 3; axis++ {
+		proj := triProjection{U: (axis + 1) % 3, V: (axis + 2) % 3}
+		if orient2D(proj.Project(t[0]), proj.Project(t[1]), proj.Project(t[2])) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// triProjection maps points to 2D by dropping one axis.
+type triProjection struct {
+	U int
+	V int
+}
+
+// newProjection drops the dominant axis of the normal, ordering the
+// remaining axes so that triangles with this normal are counter-
+// clockwise in 2D.
+func newProjection(normal model3d.Coord3D) triProjection {
+	arr := normal.Array()
+	axis := 0
+	for i := 1; i < 3; i++ {
+		if math.Abs(arr[i]) > math.Abs(arr[axis]) {
+			axis = i
+		}
+	}
+	proj := triProjection{U: (axis + 1) % 3, V: (axis + 2) % 3}
+	if arr[axis] < 0 {
+		proj.U, proj.V = proj.V, proj.U
+	}
+	return proj
+}
+
+func newTriProjection(t *model3d.Triangle) triProjection {
+	return newProjection(t[1].Sub(t[0]).Cross(t[2].Sub(t[0])))
+}
+
+func (t triProjection) Project(c model3d.Coord3D) model2d.Coord {
+	arr := c.Array()
+	return model2d.XY(arr[t.U], arr[t.V])
+}
+
+// A meshPiece is a triangle produced by splitting a source triangle.
+type meshPiece struct {
+	Tri      [3]model3d.Coord3D
+	Points   [3]*exactPoint
+	Coplanar []*model3d.Triangle
+}
+
+func splitMeshTriangles(
+	points exactPointSet,
+	tris []*model3d.Triangle,
+	splits []meshTriSplit,
+	separators map[[2]model3d.Coord3D]bool,
+) ([]meshPiece, error) {
+	var res []meshPiece
+	for i, t := range tris {
+		s := &splits[i]
+		if len(s.Points) == 0 && len(s.Segments) == 0 {
+			piece := meshPiece{Tri: *t, Coplanar: s.Coplanar}
+			if len(s.Coplanar) > 0 {
+				for k, c := range t {
+					piece.Points[k] = newExactVertex(c)
+				}
+			}
+			res = append(res, piece)
+			continue
+		}
+		tri := newSplitTriangulation(points, t)
+		tri.InsertPoints(s.Points)
+		for _, seg := range s.Segments {
+			edges, err := tri.RecoverEdge(seg[0], seg[1])
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range edges {
+				separators[edgeKey(e[0].Coord, e[1].Coord)] = true
+			}
+		}
+		for _, sub := range tri.Triangles() {
+			res = append(res, meshPiece{
+				Tri:      [3]model3d.Coord3D{sub[0].Coord, sub[1].Coord, sub[2].Coord},
+				Points:   sub,
+				Coplanar: s.Coplanar,
+			})
+		}
+	}
+	return res, nil
+}
+
+func edgeKey(a, b model3d.Coord3D) [2]model3d.Coord3D {
+	if coordLess(b, a) {
+		return [2]model3d.Coord3D{b, a}
+	}
+	return [2]model3d.Coord3D{a, b}
+}
+
+// splitTriangulation triangulates a triangle in its projected 2D plane
+// while points and constraint edges are inserted.
+type splitTriangulation struct {
+	proj    triProjection
+	coords  []*exactPoint
+	indices map[*exactPoint]int
+	tris    [][3]int
+
+	// constrained contains both directions of the recovered edges.
+	constrained map[[2]int]bool
+}
+
+func newSplitTriangulation(points exactPointSet, t *model3d.Triangle) *splitTriangulation {
+	s := &splitTriangulation{
+		proj:        newTriProjection(t),
+		indices:     map[*exactPoint]int{},
+		constrained: map[[2]int]bool{},
+	}
+	for _, c := range t {
+		s.addPoint(points.Vertex(c))
+	}
+	s.tris = [][3]int{{0, 1, 2}}
+	return s
+}
+
+func (s *splitTriangulation) addPoint(p *exactPoint) int {
+	if idx, ok := s.indices[p]; ok {
+		return idx
+	}
+	idx := len(s.coords)
+	s.indices[p] = idx
+	s.coords = append(s.coords, p)
+	return idx
+}
+
+func (s *splitTriangulation) orient(a, b, c int) int {
+	return orientProjected(s.proj, s.coords[a], s.coords[b], s.coords[c])
+}
+
+// InsertPoints adds points on the boundary and interior of the
+// triangle.
+func (s *splitTriangulation) InsertPoints(points []meshSplitPoint) {
+	for edge := 0; edge < 3; edge++ {
+		var onEdge []*exactPoint
+		for _, p := range points {
+			if p.Loc == locEdge(edge) {
+				if _, ok := s.indices[p.P]; !ok {
+					onEdge = append(onEdge, p.P)
+				}
+			}
+		}
+		s.sortAlongEdge(onEdge, edge)
+		prev := edge
+		for _, p := range onEdge {
+			if _, ok := s.indices[p]; ok {
+				continue
+			}
+			idx := s.addPoint(p)
+			if t, k, ok := s.findEdge(prev, (edge+1)%3); ok {
+				s.splitEdge(t, k, idx)
+			}
+			prev = idx
+		}
+	}
+	for _, p := range points {
+		if p.Loc != locInterior {
+			continue
+		}
+		if _, ok := s.indices[p.P]; ok {
+			continue
+		}
+		s.insertInterior(s.addPoint(p.P))
+	}
+}
+
+// sortAlongEdge orders points on an edge of the original triangle from
+// its start to its end.
+func (s *splitTriangulation) sortAlongEdge(points []*exactPoint, edge int) {
+	start, end := s.coords[edge], s.coords[(edge+1)%3]
+	arr := end.Coord.Sub(start.Coord).Array()
+	axis := 0
+	for i := 1; i < 3; i++ {
+		if math.Abs(arr[i]) > math.Abs(arr[axis]) {
+			axis = i
+		}
+	}
+	sort.Slice(points, func(i, j int) bool {
+		c := points[i].Compare(points[j], axis)
+		if arr[axis] < 0 {
+			return c > 0
+		}
+		return c < 0
+	})
+}
+
+func (s *splitTriangulation) insertInterior(idx int) {
+	for i, t := range s.tris {
+		inside := true
+		zeroEdge := -1
+		for k := 0; k < 3 && inside; k++ {
+			switch s.orient(t[k], t[(k+1)%3], idx) {
+			case -1:
+				inside = false
+			case 0:
+				zeroEdge = k
+			}
+		}
+		if !inside {
+			continue
+		}
+		if zeroEdge == -1 {
+			s.splitInterior(i, idx)
+		} else {
+			s.splitEdge(i, zeroEdge, idx)
+		}
+		return
+	}
+}
+
+func (s *splitTriangulation) splitInterior(t, idx int) {
+	a, b, c := s.tris[t][0], s.tris[t][1], s.tris[t][2]
+	s.tris[t] = [3]int{a, b, idx}
+	s.tris = append(s.tris, [3]int{b, c, idx}, [3]int{c, a, idx})
+}
+
+// splitEdge splits the k-th edge of triangle t, along with the
+// neighboring triangle across that edge if there is one.
+func (s *splitTriangulation) splitEdge(t, k, idx int) {
+	u, v, x := s.tris[t][k], s.tris[t][(k+1)%3], s.tris[t][(k+2)%3]
+	s.tris[t] = [3]int{u, idx, x}
+	s.tris = append(s.tris, [3]int{idx, v, x})
+	if n, nk, ok := s.findEdge(v, u); ok {
+		y := s.tris[n][(nk+2)%3]
+		s.tris[n] = [3]int{v, idx, y}
+		s.tris = append(s.tris, [3]int{idx, u, y})
+	}
+}
+
+// findEdge finds the triangle containing the directed edge u->v.
+func (s *splitTriangulation) findEdge(u, v int) (int, int, bool) {
+	for i, t := range s.tris {
+		for k := 0; k < 3; k++ {
+			if t[k] == u && t[(k+1)%3] == v {
+				return i, k, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+func (s *splitTriangulation) hasEdge(a, b int) bool {
+	if _, _, ok := s.findEdge(a, b); ok {
+		return true
+	}
+	_, _, ok := s.findEdge(b, a)
+	return ok
+}
+
+// RecoverEdge inserts the segment a-b into the triangulation, and
+// returns the edges which make it up.
+//
+// It fails if the segment crosses an edge recovered earlier.
+func (s *splitTriangulation) RecoverEdge(a, b *exactPoint) ([][2]*exactPoint, error) {
+	ia, okA := s.indices[a]
+	ib, okB := s.indices[b]
+	if !okA || !okB || ia == ib {
+		return nil, nil
+	}
+	edges, err := s.recoverEdge(ia, ib)
+	if err != nil {
+		return nil, err
+	}
+	var res [][2]*exactPoint
+	for _, e := range edges {
+		res = append(res, [2]*exactPoint{s.coords[e[0]], s.coords[e[1]]})
+	}
+	return res, nil
+}
+
+func (s *splitTriangulation) recoverEdge(a, b int) ([][2]int, error) {
+	if s.hasEdge(a, b) {
+		s.constrained[[2]int{a, b}] = true
+		s.constrained[[2]int{b, a}] = true
+		return [][2]int{{a, b}}, nil
+	}
+
+	// Split the constraint at the closest vertex lying exactly on it,
+	// comparing positions along an axis on which the segment varies.
+	pa, pb := s.coords[a], s.coords[b]
+	axis := s.proj.U
+	if pa.Compare(pb, axis) == 0 {
+		axis = s.proj.V
+	}
+	dir := pa.Compare(pb, axis)
+	split := -1
+	for i, p := range s.coords {
+		if i == a || i == b || s.orient(a, b, i) != 0 {
+			continue
+		}
+		if p.Compare(pa, axis) == -dir && p.Compare(pb, axis) == dir &&
+			(split == -1 || p.Compare(s.coords[split], axis) == dir) {
+			split = i
+		}
+	}
+	if split != -1 {
+		first, err := s.recoverEdge(a, split)
+		if err != nil {
+			return nil, err
+		}
+		second, err := s.recoverEdge(split, b)
+		if err != nil {
+			return nil, err
+		}
+		return append(first, second...), nil
+	}
+
+	if err := s.insertSegment(a, b); err != nil {
+		return nil, err
+	}
+	s.constrained[[2]int{a, b}] = true
+	s.constrained[[2]int{b, a}] = true
+	return [][2]int{{a, b}}, nil
+}
+
+// insertSegment flips the edges crossing the segment a-b, which has no
+// vertices in its interior, until a-b is an edge.
+//
+// Each crossing edge which is the diagonal of a convex quadrilateral
+// is flipped, and the new diagonal is queued again if it still crosses
+// the segment. This terminates after a number of flips quadratic in
+// the number of crossing edges.
+func (s *splitTriangulation) insertSegment(a, b int) error {
+	edges := map[[2]int]int{}
+	var queue [][2]int
+	for i, t := range s.tris {
+		for k := 0; k < 3; k++ {
+			u, v := t[k], t[(k+1)%3]
+			edges[[2]int{u, v}] = i
+			if u < v && s.crossesSegment(a, b, u, v) {
+				if s.constrained[[2]int{u, v}] {
+					return errors.New("intersection segments cross")
+				}
+				queue = append(queue, [2]int{u, v})
+			}
+		}
+	}
+
+	maxSteps := len(queue)*len(queue) + len(queue)
+	for steps := 0; len(queue) > 0; steps++ {
+		if steps > maxSteps {
+			return errors.New("segment cannot be recovered")
+		}
+		e := queue[0]
+		queue = queue[1:]
+		i, okI := edges[e]
+		j, okJ := edges[[2]int{e[1], e[0]}]
+		if !okI || !okJ {
+			return errors.New("crossing edge is on the boundary")
+		}
+		u, v := e[0], e[1]
+		x := thirdVertex(s.tris[i], u, v)
+		y := thirdVertex(s.tris[j], v, u)
+		if s.orient(x, y, u)*s.orient(x, y, v) >= 0 {
+			queue = append(queue, e)
+			continue
+		}
+		s.tris[i] = [3]int{u, y, x}
+		s.tris[j] = [3]int{y, v, x}
+		delete(edges, [2]int{u, v})
+		delete(edges, [2]int{v, u})
+		edges[[2]int{u, y}] = i
+		edges[[2]int{y, x}] = i
+		edges[[2]int{x, u}] = i
+		edges[[2]int{y, v}] = j
+		edges[[2]int{v, x}] = j
+		edges[[2]int{x, y}] = j
+		if s.crossesSegment(a, b, x, y) {
+			queue = append(queue, [2]int{x, y})
+		}
+	}
+	return nil
+}
+
+// crossesSegment checks if the segments a-b and u-v cross at a point
+// in the interior of both.
+func (s *splitTriangulation) crossesSegment(a, b, u, v int) bool {
+	return s.orient(a, b, u)*s.orient(a, b, v) < 0 && s.orient(u, v, a)*s.orient(u, v, b) < 0
+}
+
+// thirdVertex finds the vertex of t following the directed edge u->v.
+func thirdVertex(t [3]int, u, v int) int {
+	for k := 0; k < 3; k++ {
+		if t[k] == u && t[(k+1)%3] == v {
+			return t[(k+2)%3]
+		}
+	}
+	panic("edge not in triangle")
+}
+
+// Triangles returns the pieces of the triangulation.
+//
+// Pieces whose corners round to the same coordinate are dropped. Both
+// directions of their remaining edge are dropped together, so the
+// result stays watertight.
+func (s *splitTriangulation) Triangles() [][3]*exactPoint {
+	res := make([][3]*exactPoint, 0, len(s.tris))
+	for _, t := range s.tris {
+		a, b, c := s.coords[t[0]], s.coords[t[1]], s.coords[t[2]]
+		if a.Coord == b.Coord || b.Coord == c.Coord || c.Coord == a.Coord {
+			continue
+		}
+		res = append(res, [3]*exactPoint{a, b, c})
+	}
+	return res
+}
+
+type pieceClass int
+
+const (
+	pieceOutside pieceClass = iota
+	pieceInside
+	pieceSharedSame
+	pieceSharedOpposite
+)
+
+// classifyMeshPieces determines where each piece lies relative to the
+// other mesh.
+//
+// Pieces on the surface of the other mesh are classified individually.
+// The remaining pieces are grouped into regions bounded by the
+// separator edges, and each region is classified using the winding
+// number of one of its pieces.
+func classifyMeshPieces(
+	pieces []meshPiece,
+	other []*model3d.Triangle,
+	separators map[[2]model3d.Coord3D]bool,
+) []pieceClass {
+	res := make([]pieceClass, len(pieces))
+	shared := make([]bool, len(pieces))
+	for i, p := range pieces {
+		if c, ok := sharedPieceClass(p); ok {
+			res[i] = c
+			shared[i] = true
+		}
+	}
+
+	edgePieces := map[[2]model3d.Coord3D][]int{}
+	for i, p := range pieces {
+		if shared[i] {
+			continue
+		}
+		for k := 0; k < 3; k++ {
+			key := edgeKey(p.Tri[k], p.Tri[(k+1)%3])
+			if !separators[key] {
+				edgePieces[key] = append(edgePieces[key], i)
+			}
+		}
+	}
+
+	visited := make([]bool, len(pieces))
+	for start := range pieces {
+		if shared[start] || visited[start] {
+			continue
+		}
+		component := []int{start}
+		visited[start] = true
+		for i := 0; i < len(component); i++ {
+			p := pieces[component[i]]
+			for k := 0; k < 3; k++ {
+				for _, j := range edgePieces[edgeKey(p.Tri[k], p.Tri[(k+1)%3])] {
+					if !visited[j] {
+						visited[j] = true
+						component = append(component, j)
+					}
+				}
+			}
+		}
+
+		best, bestArea := start, -1.0
+		for _, i := range component {
+			t := model3d.Triangle(pieces[i].Tri)
+			if area := t.Area(); area > bestArea {
+				best, bestArea = i, area
+			}
+		}
+		t := pieces[best].Tri
+		center := t[0].Add(t[1]).Add(t[2]).Scale(1.0 / 3)
+		class := pieceOutside
+		if math.Abs(meshWindingNumber(other, center)) > 0.5 {
+			class = pieceInside
+		}
+		for _, i := range component {
+			res[i] = class
+		}
+	}
+	return res
+}
+
+// sharedPieceClass checks if a piece lies on a coplanar triangle of the
+// other mesh, and if so, whether their normals agree.
+func sharedPieceClass(p meshPiece) (pieceClass, bool) {
+	if len(p.Coplanar) == 0 {
+		return 0, false
+	}
+	var center [3]*big.Rat
+	for axis := 0; axis < 3; axis++ {
+		center[axis] = new(big.Rat)
+		for _, x := range p.Points {
+			center[axis].Add(center[axis], x.Rat(axis))
+		}
+		center[axis].Quo(center[axis], big.NewRat(3, 1))
+	}
+	centerPoint := newExactPoint(center[0], center[1], center[2])
+	tri := model3d.Triangle(p.Tri)
+	for _, c := range p.Coplanar {
+		proj := newTriProjection(c)
+		corners := [3]*exactPoint{newExactVertex(c[0]), newExactVertex(c[1]), newExactVertex(c[2])}
+		inside := true
+		for k := 0; k < 3; k++ {
+			if orientProjected(proj, corners[k], corners[(k+1)%3], centerPoint) <= 0 {
+				inside = false
+				break
+			}
+		}
+		if inside {
+			if tri.Normal().Dot(c.Normal()) > 0 {
+				return pieceSharedSame, true
+			}
+			return pieceSharedOpposite, true
+		}
+	}
+	return 0, false
+}
+
+// meshWindingNumber computes the generalized winding number of a closed
+// mesh around a point, which is 1 inside and 0 outside.
+func meshWindingNumber(tris []*model3d.Triangle, p model3d.Coord3D) float64 {
+	var total float64
+	for _, t := range tris {
+		a, b, c := t[0].Sub(p), t[1].Sub(p), t[2].Sub(p)
+		la, lb, lc := a.Norm(), b.Norm(), c.Norm()
+		num := a.Dot(b.Cross(c))
+		den := la*lb*lc + a.Dot(b)*lc + a.Dot(c)*lb + b.Dot(c)*la
+		total += 2 * math.Atan2(num, den)
+	}
+	return total / (4 * math.Pi)
+}
+
+// meshBoolTree is a bounding volume hierarchy over triangle indices.
+type meshBoolTree struct {
+	Min      model3d.Coord3D
+	Max      model3d.Coord3D
+	Leaf     int
+	Children []*meshBoolTree
+}
+
+type indexedTriangle struct {
+	*model3d.Triangle
+	Index int
+}
+
+func newMeshBoolTree(tris []*model3d.Triangle) *meshBoolTree {
+	objects := make([]indexedTriangle, len(tris))
+	for i, t := range tris {
+		objects[i] = indexedTriangle{Triangle: t, Index: i}
+	}
+	return meshBoolTreeFromBVH(model3d.NewBVHAreaDensity(objects))
+}
+
+func meshBoolTreeFromBVH(b *model3d.BVH[indexedTriangle]) *meshBoolTree {
+	if b.Leaf.Triangle != nil {
+		return &meshBoolTree{Min: b.Leaf.Min(), Max: b.Leaf.Max(), Leaf: b.Leaf.Index}
+	}
+	res := &meshBoolTree{Leaf: -1}
+	for i, ch := range b.Branch {
+		child := meshBoolTreeFromBVH(ch)
+		if i == 0 {
+			res.Min, res.Max = child.Min, child.Max
+		} else {
+			res.Min, res.Max = res.Min.Min(child.Min), res.Max.Max(child.Max)
+		}
+		res.Children = append(res.Children, child)
+	}
+	return res
+}
+
+// Find calls f for every triangle whose bounds touch the box.
+func (m *meshBoolTree) Find(min, max model3d.Coord3D, f func(i int)) {
+	if min.X > m.Max.X || min.Y > m.Max.Y || min.Z > m.Max.Z ||
+		max.X < m.Min.X || max.Y < m.Min.Y || max.Z < m.Min.Z {
+		return
+	}
+	if m.Leaf >= 0 {
+		f(m.Leaf)
+		return
+	}
+	for _, ch := range m.Children {
+		ch.Find(min, max, f)
+	}
+}
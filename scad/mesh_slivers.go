@@ -0,0 +1,189 @@
+package scad
+
+import (
+	"sort"
+
+	"github.com/unixpickle/model3d/model3d"
+)
+
+// meshSliverEpsilon is the size of the smallest features which are kept
+// in the result of meshBoolean3D, relative to the size of its inputs.
+const meshSliverEpsilon = 1e-9
+
+// removeRoundedSlivers removes edges and triangles of a closed mesh
+// which are shorter or thinner than eps.
+//
+// Short edges are collapsed, and a triangle with a vertex almost on its
+// longest edge is removed by flipping that edge with the neighboring
+// triangle. Both operations keep the mesh watertight, and only move
+// the surface by about eps.
+func removeRoundedSlivers(tris [][3]model3d.Coord3D, eps float64) [][3]model3d.Coord3D {
+	ids := map[model3d.Coord3D]int{}
+	var coords []model3d.Coord3D
+	faces := make([][3]int, len(tris))
+	for i, t := range tris {
+		for k, c := range t {
+			id, ok := ids[c]
+			if !ok {
+				id = len(coords)
+				ids[c] = id
+				coords = append(coords, c)
+			}
+			faces[i][k] = id
+		}
+	}
+
+	parent := make([]int, len(coords))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for iter := 0; iter < 100; iter++ {
+		changed := false
+		for _, f := range faces {
+			for k := 0; k < 3; k++ {
+				u, v := find(f[k]), find(f[(k+1)%3])
+				if u != v && coords[u].Dist(coords[v]) < eps {
+					parent[v] = u
+					changed = true
+				}
+			}
+		}
+		if changed {
+			faces = collapsedFaces(faces, find)
+		}
+		if flipSliverFaces(faces, coords, eps) {
+			changed = true
+		}
+		if !changed {
+			break
+		}
+	}
+
+	res := make([][3]model3d.Coord3D, len(faces))
+	for i, f := range faces {
+		res[i] = [3]model3d.Coord3D{coords[f[0]], coords[f[1]], coords[f[2]]}
+	}
+	return res
+}
+
+// collapsedFaces maps the vertices of faces to their merged vertices.
+//
+// Faces which lose a vertex are dropped, and so are pairs of faces
+// with the same vertices and opposite orientations, which enclose no
+// volume.
+func collapsedFaces(faces [][3]int, find func(int) int) [][3]int {
+	var res [][3]int
+	opposites := map[[3]int][]int{}
+	for _, f := range faces {
+		f = [3]int{find(f[0]), find(f[1]), find(f[2])}
+		if f[0] == f[1] || f[1] == f[2] || f[2] == f[0] {
+			continue
+		}
+		key, flipped := sortedFace(f)
+		if idxs := opposites[key]; len(idxs) > 0 {
+			other := res[idxs[len(idxs)-1]]
+			if _, otherFlipped := sortedFace(other); otherFlipped != flipped {
+				res[idxs[len(idxs)-1]] = [3]int{-1, -1, -1}
+				opposites[key] = idxs[:len(idxs)-1]
+				continue
+			}
+		}
+		opposites[key] = append(opposites[key], len(res))
+		res = append(res, f)
+	}
+	kept := res[:0]
+	for _, f := range res {
+		if f[0] != -1 {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+// sortedFace sorts the vertices of a face, and reports if this reverses
+// its orientation.
+func sortedFace(f [3]int) ([3]int, bool) {
+	sorted := f
+	sort.Ints(sorted[:])
+	for k := 0; k < 3; k++ {
+		if f[k] == sorted[0] {
+			return sorted, f[(k+1)%3] != sorted[1]
+		}
+	}
+	panic("unreachable")
+}
+
+// flipSliverFaces flips the longest edge of each face whose opposite
+// vertex is within eps of that edge, and reports if any edge was
+// flipped.
+//
+// Since the vertex projects onto the longest edge, flipping splits the
+// neighboring face at it instead.
+func flipSliverFaces(faces [][3]int, coords []model3d.Coord3D, eps float64) bool {
+	edges := map[[2]int]int{}
+	for i, f := range faces {
+		for k := 0; k < 3; k++ {
+			edges[[2]int{f[k], f[(k+1)%3]}] = i
+		}
+	}
+	changed := false
+	done := make([]bool, len(faces))
+	for i, f := range faces {
+		if done[i] {
+			continue
+		}
+		k := 0
+		longest := -1.0
+		for j := 0; j < 3; j++ {
+			if d := coords[f[j]].Dist(coords[f[(j+1)%3]]); d > longest {
+				k, longest = j, d
+			}
+		}
+		a, b, c := f[k], f[(k+1)%3], f[(k+2)%3]
+		seg := model3d.NewSegment(coords[a], coords[b])
+		if seg.Dist(coords[c]) >= eps {
+			continue
+		}
+		j, ok := edges[[2]int{b, a}]
+		if !ok || done[j] {
+			continue
+		}
+		n := faces[j]
+		var d int
+		for m := 0; m < 3; m++ {
+			if n[m] == b && n[(m+1)%3] == a {
+				d = n[(m+2)%3]
+			}
+		}
+		if d == c {
+			continue
+		}
+		if _, ok := edges[[2]int{c, d}]; ok {
+			continue
+		}
+		if _, ok := edges[[2]int{d, c}]; ok {
+			continue
+		}
+		faces[i] = [3]int{c, a, d}
+		faces[j] = [3]int{b, c, d}
+		delete(edges, [2]int{a, b})
+		delete(edges, [2]int{b, a})
+		edges[[2]int{c, a}] = i
+		edges[[2]int{a, d}] = i
+		edges[[2]int{d, c}] = i
+		edges[[2]int{b, c}] = j
+		edges[[2]int{c, d}] = j
+		edges[[2]int{d, b}] = j
+		done[i], done[j] = true, true
+		changed = true
+	}
+	return changed
+}
@@ -556,6 +556,10 @@ func (p *Parser) parseAtom() (Expr, error) {
 			return p.parseEachExpr()
 		case "function":
 			return p.parseAnonFuncExpr()
+		case "shape":
+			if p.peek.Kind == TokLBrace {
+				return p.parseShapeExpr()
+			}
 		}
 		// true/false
 		if p.cur.Lexeme == "true" || p.cur.Lexeme == "false" {
@@ -645,6 +649,18 @@ func (p *Parser) parseAnonFuncExpr() (Expr, error) {
 	return &FuncLitExpr{Params: params, Body: body, P: pos}, nil
 }
 
+func (p *Parser) parseShapeExpr() (Expr, error) {
+	pos := p.cur.Pos
+	if err := p.expectIdent("shape"); err != nil {
+		return nil, err
+	}
+	blk, err := p.parseBlock()
+	if err != nil {
+		return nil, err
+	}
+	return &ShapeLitExpr{Body: blk.Stmts, P: pos}, nil
+}
+
 func (p *Parser) parseForExpr() (Expr, error) {
 	pos := p.cur.Pos
 	if err := p.expectIdent("for"); err != nil {
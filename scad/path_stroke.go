@@ -0,0 +1,203 @@
+package scad
+
+import (
+	"math"
+
+	"github.com/unixpickle/model3d/model2d"
+)
+
+// strokeArcSegments is the number of segments used per full turn for
+// round joins and caps of strokes.
+const strokeArcSegments = 32
+
+// strokeMesh2D outlines the area within width/2 of polylines, using round
+// joins and, for open polylines, round caps. A polyline with a single
+// point becomes a dot.
+//
+// Joins are approximated with strokeArcSegments segments per turn, so
+// turns smaller than one segment are mitered.
+//
+// Each polyline is closed if its first and last points coincide, up to
+// rounding error.
+func strokeMesh2D(polylines [][]model2d.Coord, width float64) *model2d.Mesh {
+	r := width / 2
+	mesh := model2d.NewMesh()
+	for _, points := range polylines {
+		points = dedupStrokePoints(points)
+		if len(points) == 0 {
+			continue
+		}
+		closed := len(points) > 2 && points[0].Dist(points[len(points)-1]) < r*1e-8
+		if closed {
+			points[len(points)-1] = points[0]
+		}
+		// Curves are often sampled much more finely than the stroke can
+		// resolve, and overlapping quads are expensive to union.
+		points = simplifyStrokePoints(points, r*(1-math.Cos(math.Pi/strokeArcSegments)))
+		if closed {
+			points = points[:len(points)-1]
+			if len(points) < 3 {
+				closed = false
+			}
+		}
+		if len(points) == 1 {
+			addStrokeLoop(mesh, strokeCircle(points[0], r))
+			continue
+		}
+
+		numSegs := len(points) - 1
+		if closed {
+			numSegs++
+		}
+		normals := make([]model2d.Coord, numSegs)
+		for i := range normals {
+			normals[i] = strokeNormal(points[i], points[(i+1)%len(points)]).Scale(r)
+		}
+
+		// Each segment is widened into a quad, whose corners are offset
+		// from the vertices along the left and right normals.
+		//
+		// Small turns are mitered on the outside, so that neighboring
+		// quads share an edge instead of leaving gaps too thin to fill
+		// reliably. The inside of every turn keeps square corners, since
+		// miters there would fold over on tight curves.
+		startLeft := append([]model2d.Coord{}, normals...)
+		startRight := append([]model2d.Coord{}, normals...)
+		endLeft := append([]model2d.Coord{}, normals...)
+		endRight := append([]model2d.Coord{}, normals...)
+		for i, p := range points {
+			if !closed && (i == 0 || i == len(points)-1) {
+				continue
+			}
+			prevSeg := (i + numSegs - 1) % numSegs
+			n0, n1 := normals[prevSeg], normals[i]
+			turn := math.Atan2(n0.X*n1.Y-n0.Y*n1.X, n0.Dot(n1))
+			if math.Abs(turn) < 2*math.Pi/strokeArcSegments {
+				miter := n0.Add(n1).Normalize().Scale(r / math.Cos(turn/2))
+				if turn < 0 {
+					endLeft[prevSeg], startLeft[i] = miter, miter
+				} else {
+					endRight[prevSeg], startRight[i] = miter, miter
+				}
+				continue
+			}
+
+			// The join fills the wedge on the outside of the turn.
+			//
+			// The apex of the wedge is moved from the vertex towards the
+			// inside of the turn, since the ends of the adjacent quads
+			// already cross at the vertex, and the clipper does not
+			// reliably merge three edges meeting at a computed crossing.
+			start, end := n0, n1
+			if turn > 0 {
+				start, end = start.Scale(-1), end.Scale(-1)
+			}
+			prev := points[(i+len(points)-1)%len(points)]
+			next := points[(i+1)%len(points)]
+			inward := p.Sub(prev).Normalize().Sub(next.Sub(p).Normalize()).Normalize()
+			apex := p.Sub(inward.Scale(r / 2))
+			addStrokeLoop(mesh, append([]model2d.Coord{apex}, strokeArc(p, start, end, turn)...))
+		}
+
+		for i := 0; i < numSegs; i++ {
+			p0, p1 := points[i], points[(i+1)%len(points)]
+			addStrokeLoop(mesh, []model2d.Coord{
+				p0.Add(startLeft[i]), p1.Add(endLeft[i]), p1.Sub(endRight[i]), p0.Sub(startRight[i]),
+			})
+		}
+		if !closed {
+			n0, n1 := normals[0], normals[numSegs-1]
+			addStrokeLoop(mesh, strokeArc(points[0], n0, n0.Scale(-1), math.Pi))
+			addStrokeLoop(mesh, strokeArc(points[len(points)-1], n1.Scale(-1), n1, math.Pi))
+		}
+	}
+	return fillMesh2D(mesh, fillRuleNonZero)
+}
+
+func dedupStrokePoints(points []model2d.Coord) []model2d.Coord {
+	var res []model2d.Coord
+	for _, p := range points {
+		if len(res) == 0 || res[len(res)-1] != p {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// simplifyStrokePoints removes points that are within tol of the polyline
+// without them, using the Douglas-Peucker algorithm. The endpoints are
+// always kept.
+func simplifyStrokePoints(points []model2d.Coord, tol float64) []model2d.Coord {
+	if len(points) < 3 {
+		return points
+	}
+	seg := model2d.Segment{points[0], points[len(points)-1]}
+	maxDist, maxIdx := -1.0, 0
+	for i := 1; i < len(points)-1; i++ {
+		var d float64
+		if seg[0] == seg[1] {
+			d = points[i].Dist(seg[0])
+		} else {
+			d = seg.Dist(points[i])
+		}
+		if d > maxDist {
+			maxDist, maxIdx = d, i
+		}
+	}
+	if maxDist <= tol {
+		return []model2d.Coord{points[0], points[len(points)-1]}
+	}
+	first := simplifyStrokePoints(points[:maxIdx+1], tol)
+	second := simplifyStrokePoints(points[maxIdx:], tol)
+	return append(first[:len(first)-1:len(first)-1], second...)
+}
+
+// strokeNormal returns the unit normal to the left of the segment.
+func strokeNormal(p0, p1 model2d.Coord) model2d.Coord {
+	d := p1.Sub(p0).Normalize()
+	return model2d.XY(-d.Y, d.X)
+}
+
+// strokeArc samples the arc from center+start to center+end, which sweeps
+// through the given angle. The endpoints are exact, so that the arc lines
+// up with the segments it joins.
+func strokeArc(center, start, end model2d.Coord, angle float64) []model2d.Coord {
+	n := int(math.Max(1, math.Ceil(math.Abs(angle)/(2*math.Pi)*strokeArcSegments)))
+	res := []model2d.Coord{center.Add(start)}
+	for i := 1; i < n; i++ {
+		theta := angle * float64(i) / float64(n)
+		cos, sin := math.Cos(theta), math.Sin(theta)
+		res = append(res, center.Add(model2d.XY(start.X*cos-start.Y*sin, start.X*sin+start.Y*cos)))
+	}
+	return append(res, center.Add(end))
+}
+
+func strokeCircle(center model2d.Coord, r float64) []model2d.Coord {
+	res := make([]model2d.Coord, strokeArcSegments)
+	for i := range res {
+		theta := 2 * math.Pi * float64(i) / strokeArcSegments
+		res[i] = center.Add(model2d.XY(math.Cos(theta), math.Sin(theta)).Scale(r))
+	}
+	return res
+}
+
+// addStrokeLoop adds a closed polygon to the mesh, orienting every loop the
+// same way so that overlapping pieces are unioned by the nonzero rule.
+func addStrokeLoop(mesh *model2d.Mesh, points []model2d.Coord) {
+	var area float64
+	for i, p := range points {
+		q := points[(i+1)%len(points)]
+		area += p.X*q.Y - p.Y*q.X
+	}
+	if area > 0 {
+		for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+			points[i], points[j] = points[j], points[i]
+		}
+	}
+	for i, p := range points {
+		q := points[(i+1)%len(points)]
+		if p != q {
+			mesh.Add(&model2d.Segment{p, q})
+		}
+	}
+}
@@ -0,0 +1,389 @@
+package scad
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/unixpickle/model3d/model2d"
+)
+
+// fillRule determines which winding numbers are inside a 2D outline.
+type fillRule int
+
+const (
+	fillRuleNonZero fillRule = iota
+	fillRuleEvenOdd
+)
+
+func parseFillRule(opName, s string) (fillRule, error) {
+	switch s {
+	case "nonzero":
+		return fillRuleNonZero, nil
+	case "evenodd":
+		return fillRuleEvenOdd, nil
+	default:
+		return 0, fmt.Errorf("%s(): fill_rule must be \"nonzero\" or \"evenodd\"", opName)
+	}
+}
+
+func (f fillRule) Inside(winding int) bool {
+	if f == fillRuleEvenOdd {
+		return winding%2 != 0
+	}
+	return winding != 0
+}
+
+// fillMesh2D resolves the outlines of m under the given fill rule and
+// returns a mesh without self-intersections, oriented so that the
+// interior lies opposite each segment's normal.
+//
+// The input does not need to be consistently oriented or free of
+// overlapping loops, but every loop should be closed.
+func fillMesh2D(m *model2d.Mesh, rule fillRule) *model2d.Mesh {
+	return clipMeshes2D([]*model2d.Mesh{m}, func(w []int) bool {
+		return rule.Inside(w[0])
+	})
+}
+
+// clipMeshes2D computes the arrangement of the outlines of all the
+// meshes, and keeps every piece of boundary that separates a region for
+// which inside() is true from one for which it is false.
+//
+// The inside() callback receives one winding number per input mesh.
+// Windings are counted so that loops following the model2d convention
+// (clockwise, normals facing outward) contribute +1.
+func clipMeshes2D(meshes []*model2d.Mesh, inside func(w []int) bool) *model2d.Mesh {
+	var segs []clipSegment
+	for i, m := range meshes {
+		m.Iterate(func(s *model2d.Segment) {
+			if s[0] != s[1] {
+				segs = append(segs, clipSegment{Seg: *s, Source: i})
+			}
+		})
+	}
+	pieces := mergeClipPieces(splitClipSegments(segs), len(meshes))
+	index := newClipIndex(pieces)
+
+	result := model2d.NewMesh()
+	plus := make([]int, len(meshes))
+	minus := make([]int, len(meshes))
+	for i, p := range pieces {
+		d := p.Seg[1].Sub(p.Seg[0])
+		horizontal := math.Abs(d.Y) >= math.Abs(d.X)
+		index.Winding(p.Seg.Mid(), horizontal, i, plus)
+
+		// The ray from the other side of this piece also crosses it.
+		sign := clipCrossingSign(d, horizontal)
+		for j, w := range plus {
+			minus[j] = w + sign*p.Weights[j]
+		}
+		insidePlus := inside(plus)
+		insideMinus := inside(minus)
+		if insidePlus == insideMinus {
+			continue
+		}
+
+		// Determine if the right side of the segment (the interior side
+		// in the model2d convention) is the "plus" side.
+		var rightIsPlus bool
+		if horizontal {
+			rightIsPlus = d.Y > 0
+		} else {
+			rightIsPlus = d.X < 0
+		}
+		if rightIsPlus == insidePlus {
+			result.Add(&model2d.Segment{p.Seg[0], p.Seg[1]})
+		} else {
+			result.Add(&model2d.Segment{p.Seg[1], p.Seg[0]})
+		}
+	}
+	return result
+}
+
+type clipSegment struct {
+	Seg    model2d.Segment
+	Source int
+}
+
+type clipPiece struct {
+	Seg     model2d.Segment
+	Weights []int
+}
+
+// clipCrossingSign computes the contribution of a segment with
+// direction d to the winding number at the origin of a ray that
+// crosses it, where the ray points in the +x direction if horizontal
+// is true, or in the +y direction otherwise.
+func clipCrossingSign(d model2d.Coord, horizontal bool) int {
+	var v float64
+	if horizontal {
+		v = -d.Y
+	} else {
+		v = d.X
+	}
+	if v > 0 {
+		return 1
+	} else if v < 0 {
+		return -1
+	}
+	return 0
+}
+
+// splitClipSegments splits segments at all of their mutual
+// intersections, including collinear overlaps, so that the resulting
+// pieces only meet at their endpoints.
+func splitClipSegments(segs []clipSegment) []clipSegment {
+	if len(segs) == 0 {
+		return nil
+	}
+	min, max := segs[0].Seg.Min(), segs[0].Seg.Max()
+	for _, s := range segs[1:] {
+		min = min.Min(s.Seg.Min())
+		max = max.Max(s.Seg.Max())
+	}
+	eps := max.Sub(min).MaxCoord() * 1e-10
+
+	splits := make([][]model2d.Coord, len(segs))
+	order := make([]int, len(segs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return segs[order[i]].Seg.Min().X < segs[order[j]].Seg.Min().X
+	})
+	for oi, i := range order {
+		s1 := segs[i].Seg
+		min1, max1 := s1.Min(), s1.Max()
+		for _, j := range order[oi+1:] {
+			s2 := segs[j].Seg
+			min2, max2 := s2.Min(), s2.Max()
+			if min2.X > max1.X+eps {
+				break
+			}
+			if min2.Y > max1.Y+eps || max2.Y < min1.Y-eps {
+				continue
+			}
+			for _, p := range clipIntersections(s1, s2, eps) {
+				splits[i] = append(splits[i], p)
+				splits[j] = append(splits[j], p)
+			}
+		}
+	}
+
+	var res []clipSegment
+	for i, s := range segs {
+		points := splits[i]
+		if len(points) == 0 {
+			res = append(res, s)
+			continue
+		}
+		d := s.Seg[1].Sub(s.Seg[0])
+		sort.Slice(points, func(a, b int) bool {
+			return points[a].Sub(s.Seg[0]).Dot(d) < points[b].Sub(s.Seg[0]).Dot(d)
+		})
+		prev := s.Seg[0]
+		for _, p := range points {
+			if p.Dist(prev) <= eps || p.Dist(s.Seg[1]) <= eps {
+				continue
+			}
+			res = append(res, clipSegment{Seg: model2d.Segment{prev, p}, Source: s.Source})
+			prev = p
+		}
+		res = append(res, clipSegment{Seg: model2d.Segment{prev, s.Seg[1]}, Source: s.Source})
+	}
+	return res
+}
+
+// clipIntersections finds the points where s1 and s2 touch, excluding
+// points where they share an endpoint.
+//
+// When an endpoint of one segment lies on the other, that exact
+// endpoint is returned so that split pieces line up exactly.
+func clipIntersections(s1, s2 model2d.Segment, eps float64) []model2d.Coord {
+	d1 := s1[1].Sub(s1[0])
+	d2 := s2[1].Sub(s2[0])
+	len1 := d1.Norm()
+	len2 := d2.Norm()
+
+	// Signed distances of each segment's endpoints from the other line.
+	a0 := clipCross(d1, s2[0].Sub(s1[0])) / len1
+	a1 := clipCross(d1, s2[1].Sub(s1[0])) / len1
+	b0 := clipCross(d2, s1[0].Sub(s2[0])) / len2
+	b1 := clipCross(d2, s1[1].Sub(s2[0])) / len2
+
+	var res []model2d.Coord
+	addIfInterior := func(p model2d.Coord, s model2d.Segment, d model2d.Coord, l float64) {
+		t := p.Sub(s[0]).Dot(d) / (l * l)
+		if t*l > eps && (1-t)*l > eps {
+			res = append(res, p)
+		}
+	}
+	onLine := func(x float64) bool {
+		return math.Abs(x) <= eps
+	}
+
+	if onLine(a0) && onLine(a1) {
+		// Collinear segments; each endpoint may split the other segment.
+		addIfInterior(s2[0], s1, d1, len1)
+		addIfInterior(s2[1], s1, d1, len1)
+		addIfInterior(s1[0], s2, d2, len2)
+		addIfInterior(s1[1], s2, d2, len2)
+		return res
+	}
+
+	// Endpoints lying on the other segment (T-junctions).
+	var touching bool
+	if onLine(a0) {
+		addIfInterior(s2[0], s1, d1, len1)
+		touching = true
+	}
+	if onLine(a1) {
+		addIfInterior(s2[1], s1, d1, len1)
+		touching = true
+	}
+	if onLine(b0) {
+		addIfInterior(s1[0], s2, d2, len2)
+		touching = true
+	}
+	if onLine(b1) {
+		addIfInterior(s1[1], s2, d2, len2)
+		touching = true
+	}
+	if touching {
+		return res
+	}
+
+	// Proper crossing.
+	if (a0 > 0) == (a1 > 0) || (b0 > 0) == (b1 > 0) {
+		return nil
+	}
+	t := b0 / (b0 - b1)
+	return []model2d.Coord{s1[0].Add(d1.Scale(t))}
+}
+
+func clipCross(a, b model2d.Coord) float64 {
+	return a.X*b.Y - a.Y*b.X
+}
+
+// mergeClipPieces combines coincident pieces, accumulating the signed
+// number of times each input traverses the piece in its canonical
+// direction.
+func mergeClipPieces(segs []clipSegment, numSources int) []clipPiece {
+	indices := map[model2d.Segment]int{}
+	var res []clipPiece
+	for _, s := range segs {
+		seg := s.Seg
+		sign := 1
+		if seg[1].X < seg[0].X || (seg[1].X == seg[0].X && seg[1].Y < seg[0].Y) {
+			seg = model2d.Segment{seg[1], seg[0]}
+			sign = -1
+		}
+		idx, ok := indices[seg]
+		if !ok {
+			idx = len(res)
+			indices[seg] = idx
+			res = append(res, clipPiece{Seg: seg, Weights: make([]int, numSources)})
+		}
+		res[idx].Weights[s.Source] += sign
+	}
+	return res
+}
+
+// clipIndex buckets pieces by their extent along each axis to
+// accelerate axis-aligned ray casting.
+type clipIndex struct {
+	Pieces []clipPiece
+
+	Min        model2d.Coord
+	BucketSize model2d.Coord
+	ByY        [][]int
+	ByX        [][]int
+}
+
+func newClipIndex(pieces []clipPiece) *clipIndex {
+	res := &clipIndex{Pieces: pieces}
+	if len(pieces) == 0 {
+		return res
+	}
+	min, max := pieces[0].Seg.Min(), pieces[0].Seg.Max()
+	for _, p := range pieces[1:] {
+		min = min.Min(p.Seg.Min())
+		max = max.Max(p.Seg.Max())
+	}
+	numBuckets := int(math.Ceil(math.Sqrt(float64(len(pieces))))) + 1
+	res.Min = min
+	res.BucketSize = max.Sub(min).Scale(1 / float64(numBuckets)).Max(model2d.XY(1e-12, 1e-12))
+	res.ByY = make([][]int, numBuckets)
+	res.ByX = make([][]int, numBuckets)
+	for i, p := range pieces {
+		pMin, pMax := p.Seg.Min(), p.Seg.Max()
+		for b := res.bucket(pMin.Y, true); b <= res.bucket(pMax.Y, true); b++ {
+			res.ByY[b] = append(res.ByY[b], i)
+		}
+		for b := res.bucket(pMin.X, false); b <= res.bucket(pMax.X, false); b++ {
+			res.ByX[b] = append(res.ByX[b], i)
+		}
+	}
+	return res
+}
+
+func (c *clipIndex) bucket(v float64, y bool) int {
+	var idx int
+	if y {
+		idx = int((v - c.Min.Y) / c.BucketSize.Y)
+	} else {
+		idx = int((v - c.Min.X) / c.BucketSize.X)
+	}
+	return clampInt(idx, 0, len(c.ByY)-1)
+}
+
+// Winding computes the per-source winding numbers at the point p by
+// casting a ray in the +x (horizontal) or +y direction, ignoring the
+// piece at index exclude.
+func (c *clipIndex) Winding(p model2d.Coord, horizontal bool, exclude int, out []int) {
+	for i := range out {
+		out[i] = 0
+	}
+	var candidates []int
+	if horizontal {
+		candidates = c.ByY[c.bucket(p.Y, true)]
+	} else {
+		candidates = c.ByX[c.bucket(p.X, false)]
+	}
+	ray := p
+	if !horizontal {
+		// Swap axes so that the ray always points along +x.
+		ray = model2d.XY(p.Y, p.X)
+	}
+	for _, i := range candidates {
+		if i == exclude {
+			continue
+		}
+		piece := &c.Pieces[i]
+		a, b := piece.Seg[0], piece.Seg[1]
+		if !horizontal {
+			a, b = model2d.XY(a.Y, a.X), model2d.XY(b.Y, b.X)
+		}
+		if (a.Y > ray.Y) == (b.Y > ray.Y) {
+			continue
+		}
+		x := a.X + (ray.Y-a.Y)/(b.Y-a.Y)*(b.X-a.X)
+		if x <= ray.X {
+			continue
+		}
+		sign := clipCrossingSign(piece.Seg[1].Sub(piece.Seg[0]), horizontal)
+		for j, w := range piece.Weights {
+			out[j] += sign * w
+		}
+	}
+}
+
+func clampInt(x, min, max int) int {
+	if x < min {
+		return min
+	} else if x > max {
+		return max
+	}
+	return x
+}
@@ -0,0 +1,224 @@
+package scad
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/unixpickle/model3d/model2d"
+	"github.com/unixpickle/model3d/model3d"
+)
+
+// Error bounds for the floating-point filters in orient2D and orient3D,
+// following Shewchuk's "Adaptive Precision Floating-Point Arithmetic
+// and Fast Robust Geometric Predicates".
+const (
+	orient2DErrBound = 3.3306690738754716e-16
+	orient3DErrBound = 7.771561172376103e-16
+)
+
+// orient2D returns the sign of the signed area of the triangle a, b, c,
+// which is positive when the points are in counter-clockwise order.
+//
+// The result is exact: when the floating-point estimate is too close to
+// zero, the determinant is recomputed with rational arithmetic.
+func orient2D(a, b, c model2d.Coord) int {
+	l := (a.X - c.X) * (b.Y - c.Y)
+	r := (a.Y - c.Y) * (b.X - c.X)
+	det := l - r
+	bound := orient2DErrBound * (math.Abs(l) + math.Abs(r))
+	if det > bound {
+		return 1
+	} else if det < -bound {
+		return -1
+	}
+	return orient2DExact(a, b, c)
+}
+
+func orient2DExact(a, b, c model2d.Coord) int {
+	acx, acy := ratSub(a.X, c.X), ratSub(a.Y, c.Y)
+	bcx, bcy := ratSub(b.X, c.X), ratSub(b.Y, c.Y)
+	l := new(big.Rat).Mul(acx, bcy)
+	r := new(big.Rat).Mul(acy, bcx)
+	return l.Cmp(r)
+}
+
+// orient3D returns the sign of the signed volume of the tetrahedron a,
+// b, c, d, which is positive when d lies below the plane through a, b
+// and c, where "above" is the side from which a, b and c appear in
+// counter-clockwise order.
+//
+// Like orient2D, the result is exact.
+func orient3D(a, b, c, d model3d.Coord3D) int {
+	det, permanent := orient3DEstimate(a, b, c, d)
+	bound := orient3DErrBound * permanent
+	if det > bound {
+		return 1
+	} else if det < -bound {
+		return -1
+	}
+	return orient3DExact(a, b, c, d)
+}
+
+func orient3DEstimate(a, b, c, d model3d.Coord3D) (float64, float64) {
+	ad, bd, cd := a.Sub(d), b.Sub(d), c.Sub(d)
+	bdxcdy := bd.X * cd.Y
+	cdxbdy := cd.X * bd.Y
+	cdxady := cd.X * ad.Y
+	adxcdy := ad.X * cd.Y
+	adxbdy := ad.X * bd.Y
+	bdxady := bd.X * ad.Y
+	det := ad.Z*(bdxcdy-cdxbdy) + bd.Z*(cdxady-adxcdy) + cd.Z*(adxbdy-bdxady)
+	permanent := (math.Abs(bdxcdy)+math.Abs(cdxbdy))*math.Abs(ad.Z) +
+		(math.Abs(cdxady)+math.Abs(adxcdy))*math.Abs(bd.Z) +
+		(math.Abs(adxbdy)+math.Abs(bdxady))*math.Abs(cd.Z)
+	return det, permanent
+}
+
+func orient3DExact(a, b, c, d model3d.Coord3D) int {
+	return orient3DDet(a, b, c, d).Sign()
+}
+
+// orient3DDet computes the exact determinant whose sign is returned by
+// orient3D.
+func orient3DDet(a, b, c, d model3d.Coord3D) *big.Rat {
+	ad := [3]*big.Rat{ratSub(a.X, d.X), ratSub(a.Y, d.Y), ratSub(a.Z, d.Z)}
+	bd := [3]*big.Rat{ratSub(b.X, d.X), ratSub(b.Y, d.Y), ratSub(b.Z, d.Z)}
+	cd := [3]*big.Rat{ratSub(c.X, d.X), ratSub(c.Y, d.Y), ratSub(c.Z, d.Z)}
+	det := new(big.Rat).Mul(ad[2], ratCross(bd[0], bd[1], cd[0], cd[1]))
+	det.Add(det, new(big.Rat).Mul(bd[2], ratCross(cd[0], cd[1], ad[0], ad[1])))
+	det.Add(det, new(big.Rat).Mul(cd[2], ratCross(ad[0], ad[1], bd[0], bd[1])))
+	return det
+}
+
+func ratSub(x, y float64) *big.Rat {
+	r := new(big.Rat).SetFloat64(x)
+	return r.Sub(r, new(big.Rat).SetFloat64(y))
+}
+
+// ratCross computes the 2D cross product ux*vy - uy*vx.
+func ratCross(ux, uy, vx, vy *big.Rat) *big.Rat {
+	l := new(big.Rat).Mul(ux, vy)
+	return l.Sub(l, new(big.Rat).Mul(uy, vx))
+}
+
+// An exactPoint is a point with rational coordinates, such as the
+// crossing of an edge and a plane, along with the nearest
+// floating-point coordinate.
+//
+// Points which are vertices of the input are stored without rational
+// coordinates, which are then computed on demand.
+type exactPoint struct {
+	Coord model3d.Coord3D
+
+	exact   [3]*big.Rat
+	rounded bool
+}
+
+func newExactVertex(c model3d.Coord3D) *exactPoint {
+	return &exactPoint{Coord: c}
+}
+
+func newExactPoint(x, y, z *big.Rat) *exactPoint {
+	p := &exactPoint{exact: [3]*big.Rat{x, y, z}}
+	var arr [3]float64
+	for i, r := range p.exact {
+		f, exact := r.Float64()
+		arr[i] = f
+		p.rounded = p.rounded || !exact
+	}
+	p.Coord = model3d.NewCoord3DArray(arr)
+	return p
+}
+
+// Rat returns the exact value of the given axis of the point.
+func (e *exactPoint) Rat(axis int) *big.Rat {
+	if e.exact[axis] == nil {
+		e.exact[axis] = new(big.Rat).SetFloat64(e.Coord.Array()[axis])
+	}
+	return e.exact[axis]
+}
+
+// Equal checks if two points are exactly the same.
+func (e *exactPoint) Equal(other *exactPoint) bool {
+	if e == other {
+		return true
+	} else if e.Coord != other.Coord {
+		return false
+	} else if !e.rounded && !other.rounded {
+		return true
+	}
+	for i := 0; i < 3; i++ {
+		if e.Rat(i).Cmp(other.Rat(i)) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Compare orders two points along one axis.
+func (e *exactPoint) Compare(other *exactPoint, axis int) int {
+	if !e.rounded && !other.rounded {
+		a, b := e.Coord.Array()[axis], other.Coord.Array()[axis]
+		if a < b {
+			return -1
+		} else if a > b {
+			return 1
+		}
+		return 0
+	}
+	return e.Rat(axis).Cmp(other.Rat(axis))
+}
+
+// exactPointSet interns exact points so that equal points are
+// represented by the same pointer.
+type exactPointSet map[model3d.Coord3D][]*exactPoint
+
+func (e exactPointSet) Vertex(c model3d.Coord3D) *exactPoint {
+	return e.Add(newExactVertex(c))
+}
+
+func (e exactPointSet) Add(p *exactPoint) *exactPoint {
+	for _, x := range e[p.Coord] {
+		if x.Equal(p) {
+			return x
+		}
+	}
+	e[p.Coord] = append(e[p.Coord], p)
+	return p
+}
+
+// orientProjected is like orient2D for exact points projected into a
+// 2D plane.
+func orientProjected(proj triProjection, a, b, c *exactPoint) int {
+	if !a.rounded && !b.rounded && !c.rounded {
+		return orient2D(proj.Project(a.Coord), proj.Project(b.Coord), proj.Project(c.Coord))
+	}
+
+	// Each rounded coordinate is within a relative error of 2^-53 of its
+	// exact value, which bounds the error of every difference below by
+	// a small multiple of the largest coordinate.
+	pa, pb, pc := proj.Project(a.Coord), proj.Project(b.Coord), proj.Project(c.Coord)
+	scale := math.Max(
+		math.Max(math.Max(math.Abs(pa.X), math.Abs(pa.Y)), math.Max(math.Abs(pb.X), math.Abs(pb.Y))),
+		math.Max(math.Abs(pc.X), math.Abs(pc.Y)),
+	)
+	acx, acy := pa.X-pc.X, pa.Y-pc.Y
+	bcx, bcy := pb.X-pc.X, pb.Y-pc.Y
+	l, r := acx*bcy, acy*bcx
+	det := l - r
+	sum := math.Abs(acx) + math.Abs(acy) + math.Abs(bcx) + math.Abs(bcy)
+	bound := 0x1p-50 * (scale*sum + math.Abs(l) + math.Abs(r) + scale*scale*0x1p-50)
+	if det > bound {
+		return 1
+	} else if det < -bound {
+		return -1
+	}
+
+	sub := func(p, q *exactPoint, axis int) *big.Rat {
+		return new(big.Rat).Sub(p.Rat(axis), q.Rat(axis))
+	}
+	return ratCross(
+		sub(a, c, proj.U), sub(a, c, proj.V),
+		sub(b, c, proj.U), sub(b, c, proj.V),
+	).Sign()
+}
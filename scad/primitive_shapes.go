@@ -0,0 +1,769 @@
+package scad
+
+import (
+	"math"
+
+	"github.com/unixpickle/model3d/model2d"
+	"github.com/unixpickle/model3d/model3d"
+	shapekernel "github.com/unixpickle/webgpu-meshes/shapekernel"
+)
+
+// solidSDF2D is the 2D equivalent of SolidSDF.
+type solidSDF2D interface {
+	model2d.SDF
+	model2d.Solid
+	model2d.Metaball
+}
+
+// A starPolygon is a star-shaped polygon centered at the origin, with N
+// outer vertices at radius R1 alternating with N inner vertices at
+// radius R2. The first outer vertex is on the positive x-axis.
+//
+// A regular polygon is a star whose inner vertices are the midpoints of
+// its edges.
+type starPolygon struct {
+	N  int
+	R1 float64
+	R2 float64
+}
+
+func newRegularPolygon(n int, r float64) *starPolygon {
+	return &starPolygon{N: n, R1: r, R2: r * math.Cos(math.Pi/float64(n))}
+}
+
+func (s *starPolygon) Vertices() []model2d.Coord {
+	res := make([]model2d.Coord, 0, s.N*2)
+	for i := 0; i < s.N*2; i++ {
+		r := s.R1
+		if i%2 == 1 {
+			r = s.R2
+		}
+		theta := float64(i) * math.Pi / float64(s.N)
+		res = append(res, model2d.XY(math.Cos(theta), math.Sin(theta)).Scale(r))
+	}
+	return res
+}
+
+func (s *starPolygon) Min() model2d.Coord {
+	res := model2d.Coord{}
+	for _, v := range s.Vertices() {
+		res = res.Min(v)
+	}
+	return res
+}
+
+func (s *starPolygon) Max() model2d.Coord {
+	res := model2d.Coord{}
+	for _, v := range s.Vertices() {
+		res = res.Max(v)
+	}
+	return res
+}
+
+func (s *starPolygon) Contains(c model2d.Coord) bool {
+	return s.SDF(c) >= 0
+}
+
+// SDF folds c into the sector between the first outer vertex and the
+// first inner vertex, where the boundary is a single edge.
+func (s *starPolygon) SDF(c model2d.Coord) float64 {
+	sector := math.Pi / float64(s.N)
+	theta := math.Mod(math.Atan2(c.Y, c.X), 2*sector)
+	if theta < 0 {
+		theta += 2 * sector
+	}
+	theta = sector - math.Abs(theta-sector)
+	p := model2d.XY(math.Cos(theta), math.Sin(theta)).Scale(c.Norm())
+
+	a := model2d.X(s.R1)
+	b := model2d.XY(math.Cos(sector), math.Sin(sector)).Scale(s.R2)
+	seg := model2d.Segment{a, b}
+	dist := seg.Dist(p)
+	if clipCross(b.Sub(a), p.Sub(a)) < 0 {
+		return -dist
+	}
+	return dist
+}
+
+func (s *starPolygon) MetaballField(c model2d.Coord) float64 {
+	return -s.SDF(c)
+}
+
+func (s *starPolygon) MetaballDistBound(d float64) float64 {
+	return d
+}
+
+func starPolygonSDFKernel(n shapekernel.Numerics, s *starPolygon) shapekernel.ShapeKernel {
+	sector := math.Pi / float64(s.N)
+	k := shapekernel.ShapeKernel{Kind: shapekernel.SDF2D}
+	fnName := kernelFnID(&k.IDs, "star_polygon_sdf")
+	shapekernel.AppendWGSL(
+		&k,
+		`
+			fn {{.Entrypoint}}(p: {{.N.Dtype2}}) -> {{.N.Dtype}} {
+				let c = {{.N.AsFloat2}}(p);
+				var theta = atan2(c.y, c.x);
+				theta = theta - floor(theta / {{.Sector2}}) * {{.Sector2}};
+				theta = {{.Sector}} - abs(theta - {{.Sector}});
+				let q = vec2f(cos(theta), sin(theta)) * length(c);
+				let a = vec2f({{.R1}}, 0.0);
+				let ba = vec2f({{.BX}}, {{.BY}}) - a;
+				let qa = q - a;
+				let h = clamp(dot(qa, ba) / dot(ba, ba), 0.0, 1.0);
+				let dist = length(qa - ba * h);
+				if (ba.x * qa.y - ba.y * qa.x < 0.0) {
+					return {{.N.FromFloat}}(-dist);
+				}
+				return {{.N.FromFloat}}(dist);
+			}
+		`,
+		"N", n.Symbols,
+		"Entrypoint", fnName,
+		"Sector", float32(sector),
+		"Sector2", float32(sector*2),
+		"R1", float32(s.R1),
+		"BX", float32(s.R2*math.Cos(sector)),
+		"BY", float32(s.R2*math.Sin(sector)),
+	)
+	k.EntrypointName = fnName
+	return k
+}
+
+// A triangle2D is a triangle with counter-clockwise vertices.
+type triangle2D [3]model2d.Coord
+
+func (t *triangle2D) Min() model2d.Coord {
+	return t[0].Min(t[1]).Min(t[2])
+}
+
+func (t *triangle2D) Max() model2d.Coord {
+	return t[0].Max(t[1]).Max(t[2])
+}
+
+func (t *triangle2D) Contains(c model2d.Coord) bool {
+	return t.SDF(c) >= 0
+}
+
+func (t *triangle2D) SDF(c model2d.Coord) float64 {
+	inside := true
+	dist := math.Inf(1)
+	for i, p1 := range t {
+		p2 := t[(i+1)%3]
+		if clipCross(p2.Sub(p1), c.Sub(p1)) < 0 {
+			inside = false
+		}
+		dist = math.Min(dist, model2d.Segment{p1, p2}.Dist(c))
+	}
+	if !inside {
+		return -dist
+	}
+	return dist
+}
+
+func (t *triangle2D) MetaballField(c model2d.Coord) float64 {
+	return -t.SDF(c)
+}
+
+func (t *triangle2D) MetaballDistBound(d float64) float64 {
+	return d
+}
+
+func triangle2DSDFKernel(n shapekernel.Numerics, t *triangle2D) shapekernel.ShapeKernel {
+	k := shapekernel.ShapeKernel{Kind: shapekernel.SDF2D}
+	segFn := kernelFnID(&k.IDs, "triangle_edge")
+	fnName := kernelFnID(&k.IDs, "triangle_sdf")
+	shapekernel.AppendWGSL(
+		&k,
+		`
+			// Returns the distance to an edge, negated if c is to its right.
+			fn {{.SegFn}}(c: vec2f, a: vec2f, b: vec2f) -> vec2f {
+				let ba = b - a;
+				let ca = c - a;
+				let h = clamp(dot(ca, ba) / dot(ba, ba), 0.0, 1.0);
+				return vec2f(length(ca - ba * h), ba.x * ca.y - ba.y * ca.x);
+			}
+
+			fn {{.Entrypoint}}(p: {{.N.Dtype2}}) -> {{.N.Dtype}} {
+				let c = {{.N.AsFloat2}}(p);
+				let e0 = {{.SegFn}}(c, {{.A}}, {{.B}});
+				let e1 = {{.SegFn}}(c, {{.B}}, {{.C}});
+				let e2 = {{.SegFn}}(c, {{.C}}, {{.A}});
+				let dist = min(e0.x, min(e1.x, e2.x));
+				if (min(e0.y, min(e1.y, e2.y)) < 0.0) {
+					return {{.N.FromFloat}}(-dist);
+				}
+				return {{.N.FromFloat}}(dist);
+			}
+		`,
+		"N", n.Symbols,
+		"SegFn", segFn,
+		"Entrypoint", fnName,
+		"A", wgslVec2f(t[0]),
+		"B", wgslVec2f(t[1]),
+		"C", wgslVec2f(t[2]),
+	)
+	k.EntrypointName = fnName
+	return k
+}
+
+// A prismPrimitive extrudes a 2D profile between two planes.
+//
+// For Axis 2, the profile is in the XY plane and extruded along Z. For
+// Axis 0, the profile's coordinates are Y and Z and it is extruded
+// along X.
+type prismPrimitive struct {
+	Profile solidSDF2D
+	Axis    int
+	Start   float64
+	End     float64
+}
+
+func (p *prismPrimitive) split(c model3d.Coord3D) (model2d.Coord, float64) {
+	if p.Axis == 0 {
+		return model2d.XY(c.Y, c.Z), c.X
+	}
+	return c.XY(), c.Z
+}
+
+func (p *prismPrimitive) join(c model2d.Coord, axis float64) model3d.Coord3D {
+	if p.Axis == 0 {
+		return model3d.XYZ(axis, c.X, c.Y)
+	}
+	return model3d.XYZ(c.X, c.Y, axis)
+}
+
+func (p *prismPrimitive) Min() model3d.Coord3D {
+	return p.join(p.Profile.Min(), p.Start)
+}
+
+func (p *prismPrimitive) Max() model3d.Coord3D {
+	return p.join(p.Profile.Max(), p.End)
+}
+
+func (p *prismPrimitive) Contains(c model3d.Coord3D) bool {
+	return p.SDF(c) >= 0
+}
+
+func (p *prismPrimitive) SDF(c model3d.Coord3D) float64 {
+	c2, axis := p.split(c)
+	outside := model2d.XY(-p.Profile.SDF(c2), math.Max(p.Start-axis, axis-p.End))
+	return -(math.Min(outside.MaxCoord(), 0) + outside.Max(model2d.Coord{}).Norm())
+}
+
+func (p *prismPrimitive) MetaballField(c model3d.Coord3D) float64 {
+	return -p.SDF(c)
+}
+
+func (p *prismPrimitive) MetaballDistBound(d float64) float64 {
+	return d
+}
+
+func prismSDFKernel(n shapekernel.Numerics, p *prismPrimitive, profile shapekernel.ShapeKernel) shapekernel.ShapeKernel {
+	k := profile
+	swizzle := "xyz"
+	if p.Axis == 0 {
+		swizzle = "yzx"
+	}
+	fnName := kernelFnID(&k.IDs, "prism_sdf")
+	shapekernel.AppendWGSL(
+		&k,
+		`
+			fn {{.Entrypoint}}(p: {{.N.Dtype3}}) -> {{.N.Dtype}} {
+				let c = {{.N.AsFloat3}}(p).{{.Swizzle}};
+				let d2 = {{.N.AsFloat}}({{.Profile}}({{.N.Make2}}({{.N.FromFloat}}(c.x), {{.N.FromFloat}}(c.y))));
+				let outside = vec2f(-d2, max({{.Start}} - c.z, c.z - {{.End}}));
+				let dist = min(max(outside.x, outside.y), 0.0) + length(max(outside, vec2f(0.0)));
+				return {{.N.FromFloat}}(-dist);
+			}
+		`,
+		"N", n.Symbols,
+		"Entrypoint", fnName,
+		"Swizzle", swizzle,
+		"Profile", k.EntrypointName,
+		"Start", float32(p.Start),
+		"End", float32(p.End),
+	)
+	k.Kind = shapekernel.SDF3D
+	k.EntrypointName = fnName
+	return k
+}
+
+// An ellipsoid is centered at the origin with the given semi-axes.
+//
+// Distances are computed exactly by finding the root of the closest
+// point equation with bisection, following "Distance from a Point to an
+// Ellipse, an Ellipsoid, or a Hyperellipsoid" by David Eberly.
+type ellipsoid struct {
+	Radii model3d.Coord3D
+}
+
+func (e *ellipsoid) Min() model3d.Coord3D {
+	return e.Radii.Scale(-1)
+}
+
+func (e *ellipsoid) Max() model3d.Coord3D {
+	return e.Radii
+}
+
+func (e *ellipsoid) Contains(c model3d.Coord3D) bool {
+	return c.Div(e.Radii).Norm() <= 1
+}
+
+func (e *ellipsoid) SDF(c model3d.Coord3D) float64 {
+	// Sort the axes from largest to smallest, since the distance
+	// computation assumes this order.
+	r := e.Radii.Array()
+	y := c.Abs().Array()
+	for _, swap := range [][2]int{{0, 1}, {1, 2}, {0, 1}} {
+		i, j := swap[0], swap[1]
+		if r[i] < r[j] {
+			r[i], r[j] = r[j], r[i]
+			y[i], y[j] = y[j], y[i]
+		}
+	}
+	dist := ellipsoidDist(r, y)
+	if e.Contains(c) {
+		return dist
+	}
+	return -dist
+}
+
+func (e *ellipsoid) MetaballField(c model3d.Coord3D) float64 {
+	return -e.SDF(c)
+}
+
+func (e *ellipsoid) MetaballDistBound(d float64) float64 {
+	return d
+}
+
+// ellipsoidDist computes the distance from a point in the first octant
+// to an ellipsoid with decreasing semi-axes.
+func ellipsoidDist(e, y [3]float64) float64 {
+	dist3 := func(x [3]float64) float64 {
+		return model3d.NewCoord3DArray(x).Dist(model3d.NewCoord3DArray(y))
+	}
+	if y[2] > 0 {
+		if y[1] > 0 {
+			if y[0] > 0 {
+				z := [3]float64{y[0] / e[0], y[1] / e[1], y[2] / e[2]}
+				g := z[0]*z[0] + z[1]*z[1] + z[2]*z[2] - 1
+				if g == 0 {
+					return 0
+				}
+				r0 := (e[0] / e[2]) * (e[0] / e[2])
+				r1 := (e[1] / e[2]) * (e[1] / e[2])
+				n0, n1 := r0*z[0], r1*z[1]
+				s := bisectEllipseRoot(z[2]-1, g, math.Sqrt(n0*n0+n1*n1+z[2]*z[2])-1, func(s float64) float64 {
+					a, b, c := n0/(s+r0), n1/(s+r1), z[2]/(s+1)
+					return a*a + b*b + c*c - 1
+				})
+				return dist3([3]float64{r0 * y[0] / (s + r0), r1 * y[1] / (s + r1), y[2] / (s + 1)})
+			}
+			return ellipseDist([2]float64{e[1], e[2]}, [2]float64{y[1], y[2]})
+		}
+		if y[0] > 0 {
+			return ellipseDist([2]float64{e[0], e[2]}, [2]float64{y[0], y[2]})
+		}
+		return math.Abs(y[2] - e[2])
+	}
+	denom0 := e[0]*e[0] - e[2]*e[2]
+	denom1 := e[1]*e[1] - e[2]*e[2]
+	numer0 := e[0] * y[0]
+	numer1 := e[1] * y[1]
+	if numer0 < denom0 && numer1 < denom1 {
+		xde0, xde1 := numer0/denom0, numer1/denom1
+		discr := 1 - xde0*xde0 - xde1*xde1
+		if discr > 0 {
+			return dist3([3]float64{e[0] * xde0, e[1] * xde1, e[2] * math.Sqrt(discr)})
+		}
+	}
+	return ellipseDist([2]float64{e[0], e[1]}, [2]float64{y[0], y[1]})
+}
+
+// ellipseDist computes the distance from a point in the first quadrant
+// to an ellipse with decreasing semi-axes.
+func ellipseDist(e, y [2]float64) float64 {
+	if y[1] > 0 {
+		if y[0] > 0 {
+			z0, z1 := y[0]/e[0], y[1]/e[1]
+			g := z0*z0 + z1*z1 - 1
+			if g == 0 {
+				return 0
+			}
+			r0 := (e[0] / e[1]) * (e[0] / e[1])
+			n0 := r0 * z0
+			s := bisectEllipseRoot(z1-1, g, math.Sqrt(n0*n0+z1*z1)-1, func(s float64) float64 {
+				a, b := n0/(s+r0), z1/(s+1)
+				return a*a + b*b - 1
+			})
+			return model2d.XY(r0*y[0]/(s+r0), y[1]/(s+1)).Dist(model2d.XY(y[0], y[1]))
+		}
+		return math.Abs(y[1] - e[1])
+	}
+	numer0 := e[0] * y[0]
+	denom0 := e[0]*e[0] - e[1]*e[1]
+	if numer0 < denom0 {
+		xde0 := numer0 / denom0
+		return model2d.XY(e[0]*xde0-y[0], e[1]*math.Sqrt(1-xde0*xde0)).Norm()
+	}
+	return math.Abs(y[0] - e[0])
+}
+
+// bisectEllipseRoot finds the root of the decreasing function f between
+// s0 and either 0 (for points inside the surface, where g < 0) or s1.
+func bisectEllipseRoot(s0, g, s1 float64, f func(s float64) float64) float64 {
+	if g < 0 {
+		s1 = 0
+	}
+	var s float64
+	for i := 0; i < 2200; i++ {
+		s = (s0 + s1) / 2
+		if s == s0 || s == s1 {
+			break
+		}
+		v := f(s)
+		if v > 0 {
+			s0 = s
+		} else if v < 0 {
+			s1 = s
+		} else {
+			break
+		}
+	}
+	return s
+}
+
+func ellipsoidSDFKernel(n shapekernel.Numerics, e *ellipsoid) shapekernel.ShapeKernel {
+	k := shapekernel.ShapeKernel{Kind: shapekernel.SDF3D}
+	rootFn := kernelFnID(&k.IDs, "ellipse_root")
+	dist2Fn := kernelFnID(&k.IDs, "ellipse_dist")
+	dist3Fn := kernelFnID(&k.IDs, "ellipsoid_dist")
+	fnName := kernelFnID(&k.IDs, "ellipsoid_sdf")
+	shapekernel.AppendWGSL(
+		&k,
+		`
+			// Bisects for the root of the closest point equation, where
+			// n.y is zero for ellipses.
+			fn {{.RootFn}}(r: vec2f, n: vec3f, g: f32) -> f32 {
+				var s0 = n.z - 1.0;
+				var s1 = 0.0;
+				if (g > 0.0) {
+					s1 = length(n * vec3f(r, 1.0)) - 1.0;
+				}
+				var s = 0.0;
+				for (var i = 0; i < 96; i++) {
+					s = 0.5 * (s0 + s1);
+					if (s == s0 || s == s1) {
+						break;
+					}
+					let ratio = n * vec3f(r, 1.0) / (vec3f(r, 1.0) + s);
+					let v = dot(ratio, ratio) - 1.0;
+					if (v > 0.0) {
+						s0 = s;
+					} else if (v < 0.0) {
+						s1 = s;
+					} else {
+						break;
+					}
+				}
+				return s;
+			}
+
+			fn {{.Dist2Fn}}(e: vec2f, y: vec2f) -> f32 {
+				if (y.y > 0.0) {
+					if (y.x > 0.0) {
+						let z = y / e;
+						let g = dot(z, z) - 1.0;
+						if (g == 0.0) {
+							return 0.0;
+						}
+						let r0 = (e.x / e.y) * (e.x / e.y);
+						let s = {{.RootFn}}(vec2f(r0, 1.0), vec3f(z.x, 0.0, z.y), g);
+						return distance(vec2f(r0 * y.x / (s + r0), y.y / (s + 1.0)), y);
+					}
+					return abs(y.y - e.y);
+				}
+				let numer0 = e.x * y.x;
+				let denom0 = e.x * e.x - e.y * e.y;
+				if (numer0 < denom0) {
+					let xde0 = numer0 / denom0;
+					return distance(vec2f(e.x * xde0, e.y * sqrt(1.0 - xde0 * xde0)), y);
+				}
+				return abs(y.x - e.x);
+			}
+
+			fn {{.Dist3Fn}}(e: vec3f, y: vec3f) -> f32 {
+				if (y.z > 0.0) {
+					if (y.y > 0.0) {
+						if (y.x > 0.0) {
+							let z = y / e;
+							let g = dot(z, z) - 1.0;
+							if (g == 0.0) {
+								return 0.0;
+							}
+							let r = (e.xy / e.z) * (e.xy / e.z);
+							let s = {{.RootFn}}(r, z, g);
+							let x = vec3f(r * y.xy / (s + r), y.z / (s + 1.0));
+							return distance(x, y);
+						}
+						return {{.Dist2Fn}}(e.yz, y.yz);
+					}
+					if (y.x > 0.0) {
+						return {{.Dist2Fn}}(e.xz, y.xz);
+					}
+					return abs(y.z - e.z);
+				}
+				let denom = e.xy * e.xy - e.z * e.z;
+				let numer = e.xy * y.xy;
+				if (numer.x < denom.x && numer.y < denom.y) {
+					let xde = numer / denom;
+					let discr = 1.0 - dot(xde, xde);
+					if (discr > 0.0) {
+						return distance(vec3f(e.xy * xde, e.z * sqrt(discr)), y);
+					}
+				}
+				return {{.Dist2Fn}}(e.xy, y.xy);
+			}
+
+			fn {{.Entrypoint}}(p: {{.N.Dtype3}}) -> {{.N.Dtype}} {
+				let c = {{.N.AsFloat3}}(p);
+				var e = {{.Radii}};
+				var y = abs(c);
+				if (e.x < e.y) {
+					e = e.yxz;
+					y = y.yxz;
+				}
+				if (e.y < e.z) {
+					e = e.xzy;
+					y = y.xzy;
+				}
+				if (e.x < e.y) {
+					e = e.yxz;
+					y = y.yxz;
+				}
+				let dist = {{.Dist3Fn}}(e, y);
+				if (length(c / {{.Radii}}) > 1.0) {
+					return {{.N.FromFloat}}(-dist);
+				}
+				return {{.N.FromFloat}}(dist);
+			}
+		`,
+		"N", n.Symbols,
+		"RootFn", rootFn,
+		"Dist2Fn", dist2Fn,
+		"Dist3Fn", dist3Fn,
+		"Entrypoint", fnName,
+		"Radii", wgslVec3f(e.Radii),
+	)
+	k.EntrypointName = fnName
+	return k
+}
+
+func torusSDFKernel(n shapekernel.Numerics, t *model3d.Torus) shapekernel.ShapeKernel {
+	k := shapekernel.ShapeKernel{Kind: shapekernel.SDF3D}
+	fnName := kernelFnID(&k.IDs, "torus_sdf")
+	shapekernel.AppendWGSL(
+		&k,
+		`
+			fn {{.Entrypoint}}(p: {{.N.Dtype3}}) -> {{.N.Dtype}} {
+				let c = {{.N.AsFloat3}}(p);
+				let q = vec2f(length(c.xy) - {{.Outer}}, c.z);
+				return {{.N.FromFloat}}({{.Inner}} - length(q));
+			}
+		`,
+		"N", n.Symbols,
+		"Entrypoint", fnName,
+		"Outer", float32(t.OuterRadius),
+		"Inner", float32(t.InnerRadius),
+	)
+	k.EntrypointName = fnName
+	return k
+}
+
+func wgslVec2f(c model2d.Coord) string {
+	return shapekernel.WGSL("vec2f({{.X}}, {{.Y}})", "X", float32(c.X), "Y", float32(c.Y))
+}
+
+func wgslVec3f(c model3d.Coord3D) string {
+	return shapekernel.WGSL(
+		"vec3f({{.X}}, {{.Y}}, {{.Z}})",
+		"X", float32(c.X),
+		"Y", float32(c.Y),
+		"Z", float32(c.Z),
+	)
+}
+
+// A roundedBox3D is a box with its edges and corners rounded to
+// Radius, keeping its outer dimensions.
+type roundedBox3D struct {
+	MinVal model3d.Coord3D
+	MaxVal model3d.Coord3D
+	Radius float64
+}
+
+func (r *roundedBox3D) Min() model3d.Coord3D {
+	return r.MinVal
+}
+
+func (r *roundedBox3D) Max() model3d.Coord3D {
+	return r.MaxVal
+}
+
+func (r *roundedBox3D) Contains(c model3d.Coord3D) bool {
+	return r.SDF(c) >= 0
+}
+
+func (r *roundedBox3D) SDF(c model3d.Coord3D) float64 {
+	center := r.MinVal.Mid(r.MaxVal)
+	inner := r.MaxVal.Sub(center).AddScalar(-r.Radius)
+	q := c.Sub(center).Abs().Sub(inner)
+	return r.Radius - (q.Max(model3d.Coord3D{}).Norm() + math.Min(q.MaxCoord(), 0))
+}
+
+func (r *roundedBox3D) MetaballField(c model3d.Coord3D) float64 {
+	return -r.SDF(c)
+}
+
+func (r *roundedBox3D) MetaballDistBound(d float64) float64 {
+	return d
+}
+
+func roundedBox3DSDFKernel(n shapekernel.Numerics, r *roundedBox3D) shapekernel.ShapeKernel {
+	center := r.MinVal.Mid(r.MaxVal)
+	k := shapekernel.ShapeKernel{Kind: shapekernel.SDF3D}
+	fnName := kernelFnID(&k.IDs, "rounded_box_sdf")
+	shapekernel.AppendWGSL(
+		&k,
+		`
+			fn {{.Entrypoint}}(p: {{.N.Dtype3}}) -> {{.N.Dtype}} {
+				let q = abs({{.N.AsFloat3}}(p) - {{.Center}}) - {{.Inner}};
+				let dist = length(max(q, vec3f(0.0))) + min(max(q.x, max(q.y, q.z)), 0.0);
+				return {{.N.FromFloat}}({{.Radius}} - dist);
+			}
+		`,
+		"N", n.Symbols,
+		"Entrypoint", fnName,
+		"Center", wgslVec3f(center),
+		"Inner", wgslVec3f(r.MaxVal.Sub(center).AddScalar(-r.Radius)),
+		"Radius", float32(r.Radius),
+	)
+	k.EntrypointName = fnName
+	return k
+}
+
+// A roundedBox2D is the 2D equivalent of roundedBox3D.
+type roundedBox2D struct {
+	MinVal model2d.Coord
+	MaxVal model2d.Coord
+	Radius float64
+}
+
+func (r *roundedBox2D) Min() model2d.Coord {
+	return r.MinVal
+}
+
+func (r *roundedBox2D) Max() model2d.Coord {
+	return r.MaxVal
+}
+
+func (r *roundedBox2D) Contains(c model2d.Coord) bool {
+	return r.SDF(c) >= 0
+}
+
+func (r *roundedBox2D) SDF(c model2d.Coord) float64 {
+	center := r.MinVal.Mid(r.MaxVal)
+	inner := r.MaxVal.Sub(center).AddScalar(-r.Radius)
+	q := c.Sub(center).Abs().Sub(inner)
+	return r.Radius - (q.Max(model2d.Coord{}).Norm() + math.Min(q.MaxCoord(), 0))
+}
+
+func (r *roundedBox2D) MetaballField(c model2d.Coord) float64 {
+	return -r.SDF(c)
+}
+
+func (r *roundedBox2D) MetaballDistBound(d float64) float64 {
+	return d
+}
+
+func roundedBox2DSDFKernel(n shapekernel.Numerics, r *roundedBox2D) shapekernel.ShapeKernel {
+	center := r.MinVal.Mid(r.MaxVal)
+	k := shapekernel.ShapeKernel{Kind: shapekernel.SDF2D}
+	fnName := kernelFnID(&k.IDs, "rounded_rect_sdf")
+	shapekernel.AppendWGSL(
+		&k,
+		`
+			fn {{.Entrypoint}}(p: {{.N.Dtype2}}) -> {{.N.Dtype}} {
+				let q = abs({{.N.AsFloat2}}(p) - {{.Center}}) - {{.Inner}};
+				let dist = length(max(q, vec2f(0.0))) + min(max(q.x, q.y), 0.0);
+				return {{.N.FromFloat}}({{.Radius}} - dist);
+			}
+		`,
+		"N", n.Symbols,
+		"Entrypoint", fnName,
+		"Center", wgslVec2f(center),
+		"Inner", wgslVec2f(r.MaxVal.Sub(center).AddScalar(-r.Radius)),
+		"Radius", float32(r.Radius),
+	)
+	k.EntrypointName = fnName
+	return k
+}
+
+// A roundedCylinder is a cylinder along the Z axis with its top and
+// bottom edges rounded to EdgeRadius, keeping its outer dimensions.
+type roundedCylinder struct {
+	Z0         float64
+	Z1         float64
+	Radius     float64
+	EdgeRadius float64
+}
+
+func (r *roundedCylinder) Min() model3d.Coord3D {
+	return model3d.XYZ(-r.Radius, -r.Radius, r.Z0)
+}
+
+func (r *roundedCylinder) Max() model3d.Coord3D {
+	return model3d.XYZ(r.Radius, r.Radius, r.Z1)
+}
+
+func (r *roundedCylinder) Contains(c model3d.Coord3D) bool {
+	return r.SDF(c) >= 0
+}
+
+func (r *roundedCylinder) SDF(c model3d.Coord3D) float64 {
+	q := model2d.XY(
+		c.XY().Norm()-(r.Radius-r.EdgeRadius),
+		math.Abs(c.Z-(r.Z0+r.Z1)/2)-((r.Z1-r.Z0)/2-r.EdgeRadius),
+	)
+	return r.EdgeRadius - (q.Max(model2d.Coord{}).Norm() + math.Min(q.MaxCoord(), 0))
+}
+
+func (r *roundedCylinder) MetaballField(c model3d.Coord3D) float64 {
+	return -r.SDF(c)
+}
+
+func (r *roundedCylinder) MetaballDistBound(d float64) float64 {
+	return d
+}
+
+func roundedCylinderSDFKernel(n shapekernel.Numerics, r *roundedCylinder) shapekernel.ShapeKernel {
+	k := shapekernel.ShapeKernel{Kind: shapekernel.SDF3D}
+	fnName := kernelFnID(&k.IDs, "rounded_cylinder_sdf")
+	shapekernel.AppendWGSL(
+		&k,
+		`
+			fn {{.Entrypoint}}(p: {{.N.Dtype3}}) -> {{.N.Dtype}} {
+				let c = {{.N.AsFloat3}}(p);
+				let q = vec2f(length(c.xy), abs(c.z - {{.MidZ}})) - {{.Inner}};
+				let dist = length(max(q, vec2f(0.0))) + min(max(q.x, q.y), 0.0);
+				return {{.N.FromFloat}}({{.EdgeRadius}} - dist);
+			}
+		`,
+		"N", n.Symbols,
+		"Entrypoint", fnName,
+		"MidZ", float32((r.Z0+r.Z1)/2),
+		"Inner", wgslVec2f(model2d.XY(r.Radius-r.EdgeRadius, (r.Z1-r.Z0)/2-r.EdgeRadius)),
+		"EdgeRadius", float32(r.EdgeRadius),
+	)
+	k.EntrypointName = fnName
+	return k
+}
@@ -1,6 +1,8 @@
 package scad
 
 import (
+	"strconv"
+
 	"github.com/unixpickle/model3d/model2d"
 	"github.com/unixpickle/model3d/model3d"
 	shapekernel "github.com/unixpickle/webgpu-meshes/shapekernel"
@@ -76,6 +78,12 @@ func primitiveSolidKernel3D(n shapekernel.Numerics, shape any) *shapekernel.Shap
 		return asPtr(shapekernel.ConeSolid(n, coordToVec3(s.Tip), coordToVec3(s.Base), s.Radius))
 	case *model3d.ConeSlice:
 		return asPtr(shapekernel.ConeSliceSolid(n, coordToVec3(s.P1), coordToVec3(s.P2), s.R1, s.R2))
+	case *model3d.Torus, *ellipsoid, *prismPrimitive, *roundedBox3D, *roundedCylinder:
+		k := primitiveSDFKernel3D(n, s)
+		if k == nil {
+			return nil
+		}
+		return asPtr(shapekernel.SDFToSolid(n, *k))
 	default:
 		return nil
 	}
@@ -95,6 +103,20 @@ func primitiveSDFKernel3D(n shapekernel.Numerics, shape any) *shapekernel.ShapeK
 		return asPtr(shapekernel.ConeSDF(n, coordToVec3(s.Tip), coordToVec3(s.Base), s.Radius))
 	case *model3d.ConeSlice:
 		return asPtr(shapekernel.ConeSliceSDF(n, coordToVec3(s.P1), coordToVec3(s.P2), s.R1, s.R2))
+	case *model3d.Torus:
+		return asPtr(torusSDFKernel(n, s))
+	case *ellipsoid:
+		return asPtr(ellipsoidSDFKernel(n, s))
+	case *prismPrimitive:
+		profile := primitiveSDFKernel2D(n, s.Profile)
+		if profile == nil {
+			return nil
+		}
+		return asPtr(prismSDFKernel(n, s, *profile))
+	case *roundedBox3D:
+		return asPtr(roundedBox3DSDFKernel(n, s))
+	case *roundedCylinder:
+		return asPtr(roundedCylinderSDFKernel(n, s))
 	default:
 		return nil
 	}
@@ -106,6 +128,8 @@ func primitiveSolidKernel2D(n shapekernel.Numerics, shape any) *shapekernel.Shap
 		return asPtr(shapekernel.CircleSolid(n, s.Radius))
 	case *model2d.Rect:
 		return rect2DSolidKernel(n, s)
+	case *starPolygon, *triangle2D, *roundedBox2D:
+		return asPtr(shapekernel.SDFToSolid(n, *primitiveSDFKernel2D(n, s)))
 	default:
 		return nil
 	}
@@ -117,6 +141,12 @@ func primitiveSDFKernel2D(n shapekernel.Numerics, shape any) *shapekernel.ShapeK
 		return asPtr(shapekernel.CircleSDF(n, s.Radius))
 	case *model2d.Rect:
 		return rect2DSDFKernel(n, s)
+	case *starPolygon:
+		return asPtr(starPolygonSDFKernel(n, s))
+	case *triangle2D:
+		return asPtr(triangle2DSDFKernel(n, s))
+	case *roundedBox2D:
+		return asPtr(roundedBox2DSDFKernel(n, s))
 	default:
 		return nil
 	}
@@ -171,3 +201,19 @@ func sliceToVec3(c []float64) shapekernel.Vec3 {
 func sliceToVec2(c []float64) shapekernel.Vec2 {
 	return shapekernel.Vec2{c[0], c[1]}
 }
+
+// kernelFnID allocates a function name within a kernel, following the
+// naming scheme that shapekernel.ShiftIDs rewrites when combining
+// kernels.
+func kernelFnID(ids *shapekernel.IDTracker, name string) string {
+	result := "sym_fn_" + strconv.Itoa(ids.NextFnID) + "_" + name
+	ids.NextFnID++
+	return result
+}
+
+// kernelBufferID allocates a buffer name within a kernel.
+func kernelBufferID(ids *shapekernel.IDTracker, name string) string {
+	result := "sym_buf_" + strconv.Itoa(ids.NextBufferID) + "_" + name
+	ids.NextBufferID++
+	return result
+}
@@ -0,0 +1,287 @@
+package scad
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/golang/freetype/truetype"
+	m3dfonts "github.com/unixpickle/m3dscad/fonts"
+	"github.com/unixpickle/textcurve"
+	"golang.org/x/image/math/fixed"
+)
+
+const defaultTextFamily = "Liberation Sans"
+
+// OpenType weight classes used when matching styles.
+const (
+	fontWeightThin       = 100
+	fontWeightExtraLight = 200
+	fontWeightLight      = 300
+	fontWeightNormal     = 400
+	fontWeightMedium     = 500
+	fontWeightSemibold   = 600
+	fontWeightBold       = 700
+	fontWeightExtraBold  = 800
+	fontWeightBlack      = 900
+)
+
+var (
+	textFontCacheLock sync.Mutex
+	textFontCache     = map[[sha256.Size]byte]*textFont{}
+)
+
+// A textFont is a font parsed by textcurve, along with the names and
+// metrics used to select and measure it.
+type textFont struct {
+	Parsed *textcurve.ParsedFont
+	Family string
+	Style  string
+	Weight float64
+	Italic bool
+
+	// Ascent is the distance from the baseline to the top of the font, in
+	// font units. Like textcurve, text is scaled so that this distance
+	// equals the size.
+	Ascent float64
+
+	// Descent and LineGap are the typographic descender depth and line gap,
+	// in font units.
+	Descent float64
+	LineGap float64
+
+	// MaxAscent and MaxDescent bound every glyph in the font, in font units.
+	MaxAscent  float64
+	MaxDescent float64
+}
+
+// Scale returns the factor mapping font units to model units.
+func (t *textFont) Scale(size float64) float64 {
+	return size / t.Ascent
+}
+
+// HasGlyph checks if the font can render r without a missing glyph.
+func (t *textFont) HasGlyph(r rune) bool {
+	return t.Parsed.TTFont.Index(r) != 0
+}
+
+// parseTextFont parses a TTF or OTF file with TrueType outlines.
+// Results are cached by file contents, since scripts tend to render many
+// strings with the same few fonts.
+func parseTextFont(data []byte) (*textFont, error) {
+	key := sha256.Sum256(data)
+	textFontCacheLock.Lock()
+	defer textFontCacheLock.Unlock()
+	if f, ok := textFontCache[key]; ok {
+		return f, nil
+	}
+	parsed, err := textcurve.ParseTTF(data)
+	if err != nil {
+		return nil, err
+	}
+	ttf := parsed.TTFont
+	f := &textFont{
+		Parsed: parsed,
+		Family: ttf.Name(truetype.NameIDFontFamily),
+		Style:  ttf.Name(truetype.NameIDFontSubfamily),
+		Weight: fontWeightNormal,
+	}
+	f.readMetrics(data)
+	textFontCache[key] = f
+	return f, nil
+}
+
+// readMetrics reads the weight, slant and vertical metrics from the OS/2
+// and head tables.
+// The ascent falls back to the font bounds and finally to the em size if
+// the typographic ascender is missing, matching textcurve's scaling.
+func (t *textFont) readMetrics(data []byte) {
+	readInt16 := func(data []byte, offset int) float64 {
+		return float64(int16(binary.BigEndian.Uint16(data[offset:])))
+	}
+	if raw := sfntTable(data, "head"); len(raw) >= 44 {
+		t.MaxDescent = -readInt16(raw, 38)
+		t.MaxAscent = readInt16(raw, 42)
+	}
+	if raw := sfntTable(data, "OS/2"); len(raw) >= 74 {
+		if weight := binary.BigEndian.Uint16(raw[4:]); weight > 0 {
+			t.Weight = float64(weight)
+		}
+		t.Italic = raw[63]&1 != 0
+		t.Ascent = readInt16(raw, 68)
+		t.Descent = -readInt16(raw, 70)
+		t.LineGap = readInt16(raw, 72)
+	}
+	ttf := t.Parsed.TTFont
+	if t.Ascent <= 0 {
+		t.Ascent = float64(ttf.Bounds(fixed.Int26_6(ttf.FUnitsPerEm())).Max.Y)
+	}
+	if t.Ascent <= 0 {
+		t.Ascent = float64(ttf.FUnitsPerEm())
+	}
+}
+
+// sfntTable finds a table in a TTF or OTF file, returning nil if it is
+// missing or truncated.
+func sfntTable(data []byte, tag string) []byte {
+	if len(data) < 12 {
+		return nil
+	}
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < numTables; i++ {
+		record := 12 + i*16
+		if record+16 > len(data) {
+			return nil
+		}
+		if string(data[record:record+4]) != tag {
+			continue
+		}
+		offset := int(binary.BigEndian.Uint32(data[record+8:]))
+		length := int(binary.BigEndian.Uint32(data[record+12:]))
+		if offset < 0 || length < 0 || offset+length > len(data) {
+			return nil
+		}
+		return data[offset : offset+length]
+	}
+	return nil
+}
+
+// availableTextFonts returns the fonts registered through Hooks.Fonts,
+// followed by the embedded Liberation Sans.
+func availableTextFonts(e *env, opName string) ([]*textFont, error) {
+	var res []*textFont
+	for i, data := range e.hooks.Fonts {
+		f, err := parseTextFont(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s(): parse registered font %d: %w", opName, i, err)
+		}
+		res = append(res, f)
+	}
+	liberation, err := parseTextFont(m3dfonts.LiberationSansRegularTTF)
+	if err != nil {
+		return nil, err
+	}
+	return append(res, liberation), nil
+}
+
+// selectTextFonts resolves an OpenSCAD-style font name such as
+// "Liberation Sans:style=Bold" to a primary font, followed by the fonts to
+// use for glyphs that the primary font is missing.
+//
+// If the family names a .ttf or .otf file, it is loaded with the ReadFile
+// hook instead of being looked up by name.
+func selectTextFonts(e *env, opName, name string) ([]*textFont, error) {
+	family, style := parseFontName(name)
+	weight, italic, err := parseFontStyle(opName, style)
+	if err != nil {
+		return nil, err
+	}
+	available, err := availableTextFonts(e, opName)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []*textFont
+	lowerFamily := strings.ToLower(family)
+	if strings.HasSuffix(lowerFamily, ".ttf") || strings.HasSuffix(lowerFamily, ".otf") {
+		data, err := e.hooks.ReadFile(family)
+		if err != nil {
+			return nil, fmt.Errorf("%s(): read font %q: %w", opName, family, err)
+		}
+		f, err := parseTextFont(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s(): parse font %q: %w", opName, family, err)
+		}
+		if style == "" {
+			return append([]*textFont{f}, available...), nil
+		}
+		candidates = []*textFont{f}
+	} else {
+		if family == "" {
+			family = defaultTextFamily
+		}
+		for _, f := range available {
+			if strings.EqualFold(f.Family, family) {
+				candidates = append(candidates, f)
+			}
+		}
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("%s(): unsupported font %q", opName, name)
+		}
+	}
+
+	var primary *textFont
+	bestDist := math.Inf(1)
+	for _, f := range candidates {
+		if f.Italic != italic {
+			continue
+		}
+		if dist := math.Abs(f.Weight - weight); dist < bestDist {
+			primary, bestDist = f, dist
+		}
+	}
+	// Weights within one step count as a match, since fonts are not always
+	// consistent about weight classes (e.g. bold faces marked 600).
+	if primary == nil || bestDist > 100 {
+		return nil, fmt.Errorf("%s(): font %q has no style %q", opName, family, style)
+	}
+
+	res := []*textFont{primary}
+	for _, f := range available {
+		if f != primary {
+			res = append(res, f)
+		}
+	}
+	return res, nil
+}
+
+// parseFontName splits a name like "Family:style=Bold:lang=en" into its
+// family and style. Other properties are ignored.
+func parseFontName(name string) (family, style string) {
+	parts := strings.Split(name, ":")
+	family = strings.TrimSpace(parts[0])
+	for _, part := range parts[1:] {
+		key, value, ok := strings.Cut(part, "=")
+		if ok && strings.EqualFold(strings.TrimSpace(key), "style") {
+			style = strings.TrimSpace(value)
+		}
+	}
+	return family, style
+}
+
+// parseFontStyle parses a style such as "Bold Italic" into a weight class
+// and slant.
+func parseFontStyle(opName, style string) (weight float64, italic bool, err error) {
+	weight = fontWeightNormal
+	for _, word := range strings.FieldsFunc(strings.ToLower(style), func(r rune) bool {
+		return r == ' ' || r == '-' || r == '_' || r == ','
+	}) {
+		switch word {
+		case "italic", "oblique":
+			italic = true
+		case "regular", "normal", "book", "roman":
+		case "thin", "hairline":
+			weight = fontWeightThin
+		case "extralight", "ultralight":
+			weight = fontWeightExtraLight
+		case "light":
+			weight = fontWeightLight
+		case "medium":
+			weight = fontWeightMedium
+		case "semibold", "demibold":
+			weight = fontWeightSemibold
+		case "bold":
+			weight = fontWeightBold
+		case "extrabold", "ultrabold":
+			weight = fontWeightExtraBold
+		case "black", "heavy":
+			weight = fontWeightBlack
+		default:
+			return 0, false, fmt.Errorf("%s(): unknown font style %q", opName, style)
+		}
+	}
+	return weight, italic, nil
+}
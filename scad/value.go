@@ -16,6 +16,7 @@ const (
 	ValEach
 	ValList
 	ValFunc
+	ValShape
 )
 
 type FuncClosure struct {
@@ -25,14 +26,15 @@ type FuncClosure struct {
 }
 
 type Value struct {
-	Kind ValueKind
-	Num  float64
-	Bool bool
-	Str  string
-	Rng  Range
-	Each *Value
-	List []Value
-	Func *FuncClosure
+	Kind  ValueKind
+	Num   float64
+	Bool  bool
+	Str   string
+	Rng   Range
+	Each  *Value
+	List  []Value
+	Func  *FuncClosure
+	Shape *ShapeRep
 }
 
 func Num(v float64) Value   { return Value{Kind: ValNum, Num: v} }
@@ -50,6 +52,10 @@ func FuncValue(v FuncClosure) Value {
 	vCopy := v
 	return Value{Kind: ValFunc, Func: &vCopy}
 }
+func ShapeValue(v ShapeRep) Value {
+	vCopy := v
+	return Value{Kind: ValShape, Shape: &vCopy}
+}
 
 type Range struct {
 	Start float64
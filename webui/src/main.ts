@@ -2,7 +2,7 @@ import "../style.css";
 import { createEditor, loadInitialSource } from "./editor";
 import { buildBinarySTL } from "./export/stl";
 import { MeshRenderer } from "./renderer/mesh_renderer";
-import type { AxisElements, MeshBackend } from "./types";
+import type { AxisElements, MeshBackend, UploadedFiles } from "./types";
 import { setupMobileToggle, setupResizer } from "./ui/layout";
 import { createOverlayController } from "./ui/overlay";
 import { isWebGPUSupported } from "./webgpu/meshing";
@@ -46,6 +46,7 @@ const resetViewBtn = requireElement<HTMLButtonElement>("resetView");
 const downloadBtn = requireElement<HTMLButtonElement>("download");
 const gridEl = requireElement<HTMLInputElement>("grid");
 const meshBackendEl = requireElement<HTMLSelectElement>("meshBackend");
+const filesEl = requireElement<HTMLInputElement>("files");
 const canvas = requireElement<HTMLCanvasElement>("preview");
 const resizer = requireElement("resizer");
 const appEl = requireElement("app");
@@ -86,6 +87,21 @@ meshBackendEl.addEventListener("change", () => {
   window.localStorage.setItem(MESH_BACKEND_STORAGE_KEY, meshBackendEl.value);
 });
 
+let uploadedFiles: UploadedFiles = {};
+
+filesEl.addEventListener("change", () => {
+  const list = Array.from(filesEl.files ?? []);
+  void Promise.all(
+    list.map(async (file) => {
+      const data = new Uint8Array(await file.arrayBuffer());
+      return [file.name, data] as const;
+    }),
+  ).then((entries) => {
+    uploadedFiles = Object.fromEntries(entries);
+    statusEl.textContent = `Loaded ${entries.length} file(s) for import().`;
+  });
+});
+
 function isMeshBackend(value: string | null): value is MeshBackend {
   return value === "cpu" || value === "gpu_f32" || value === "gpu_fixed64";
 }
@@ -156,16 +172,22 @@ async function compile(): Promise<void> {
       editor.getSource(),
       gridSize,
       isMeshBackend(meshBackendEl.value) ? meshBackendEl.value : "cpu",
+      uploadedFiles,
     );
     if (!result) {
       return;
     }
-    renderer.setMesh(result.positions, result.normals, result.bounds);
+    renderer.setMesh(
+      result.positions,
+      result.normals,
+      result.colors,
+      result.bounds,
+    );
     lastMesh = {
-      positions: result.positions,
-      normals: result.normals,
+      positions: result.exportPositions,
+      normals: result.exportNormals,
     };
-    downloadBtn.disabled = result.positions.length === 0;
+    downloadBtn.disabled = result.exportPositions.length === 0;
     statusEl.textContent = `Triangles: ${result.positions.length / 9}`;
     overlay.set("", { idle: true });
   } catch (err: unknown) {
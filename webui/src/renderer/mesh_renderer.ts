@@ -14,11 +14,13 @@ interface MeshRendererOptions {
 interface MeshBuffers {
   position: WebGLBuffer;
   normal: WebGLBuffer;
+  color: WebGLBuffer;
 }
 
 interface MeshAttribs {
   position: number;
   normal: number;
+  color: number;
 }
 
 interface MeshUniforms {
@@ -26,12 +28,12 @@ interface MeshUniforms {
   view: WebGLUniformLocation;
   proj: WebGLUniformLocation;
   lightDir: WebGLUniformLocation;
-  color: WebGLUniformLocation;
 }
 
 interface RenderMeshData {
   positions: Float32Array;
   normals: Float32Array;
+  colors: Float32Array;
 }
 
 function requiredBuffer(gl: WebGLRenderingContext, name: string): WebGLBuffer {
@@ -121,10 +123,11 @@ export class MeshRenderer {
   setMesh(
     positions: Float32Array,
     normals: Float32Array,
+    colors: Float32Array,
     bounds: Bounds | null,
   ): void {
     const hadMesh = this.vertexCount > 0;
-    this.meshData = { positions, normals };
+    this.meshData = { positions, normals, colors };
     this.uploadMesh();
     this.bounds = bounds;
     this.fitPending = !hadMesh;
@@ -212,7 +215,6 @@ export class MeshRenderer {
       eye[2] - this.camera.target[2],
     ]);
     gl.uniform3f(uniforms.lightDir, lightDir[0], lightDir[1], lightDir[2]);
-    gl.uniform3f(uniforms.color, 0.67, 0.75, 0.95);
 
     gl.bindBuffer(gl.ARRAY_BUFFER, buffers.position);
     gl.enableVertexAttribArray(attribs.position);
@@ -220,6 +222,9 @@ export class MeshRenderer {
     gl.bindBuffer(gl.ARRAY_BUFFER, buffers.normal);
     gl.enableVertexAttribArray(attribs.normal);
     gl.vertexAttribPointer(attribs.normal, 3, gl.FLOAT, false, 0, 0);
+    gl.bindBuffer(gl.ARRAY_BUFFER, buffers.color);
+    gl.enableVertexAttribArray(attribs.color);
+    gl.vertexAttribPointer(attribs.color, 3, gl.FLOAT, false, 0, 0);
     gl.drawArrays(gl.TRIANGLES, 0, this.vertexCount);
   }
 
@@ -232,17 +237,18 @@ export class MeshRenderer {
     this.buffers = {
       position: requiredBuffer(gl, "position"),
       normal: requiredBuffer(gl, "normal"),
+      color: requiredBuffer(gl, "color"),
     };
     this.attribs = {
       position: gl.getAttribLocation(this.program, "a_position"),
       normal: gl.getAttribLocation(this.program, "a_normal"),
+      color: gl.getAttribLocation(this.program, "a_color"),
     };
     this.uniforms = {
       model: requiredUniform(gl, this.program, "u_model"),
       view: requiredUniform(gl, this.program, "u_view"),
       proj: requiredUniform(gl, this.program, "u_proj"),
       lightDir: requiredUniform(gl, this.program, "u_lightDir"),
-      color: requiredUniform(gl, this.program, "u_color"),
     };
   }
 
@@ -251,12 +257,14 @@ export class MeshRenderer {
       this.vertexCount = this.meshData ? this.meshData.positions.length / 3 : 0;
       return;
     }
-    const { positions, normals } = this.meshData;
+    const { positions, normals, colors } = this.meshData;
     this.vertexCount = positions.length / 3;
     this.gl.bindBuffer(this.gl.ARRAY_BUFFER, this.buffers.position);
     this.gl.bufferData(this.gl.ARRAY_BUFFER, positions, this.gl.STATIC_DRAW);
     this.gl.bindBuffer(this.gl.ARRAY_BUFFER, this.buffers.normal);
     this.gl.bufferData(this.gl.ARRAY_BUFFER, normals, this.gl.STATIC_DRAW);
+    this.gl.bindBuffer(this.gl.ARRAY_BUFFER, this.buffers.color);
+    this.gl.bufferData(this.gl.ARRAY_BUFFER, colors, this.gl.STATIC_DRAW);
   }
 
   setupContextRecovery(): void {
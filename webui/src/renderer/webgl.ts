@@ -1,15 +1,18 @@
 export const vertexShader = `
   attribute vec3 a_position;
   attribute vec3 a_normal;
+  attribute vec3 a_color;
   uniform mat4 u_model;
   uniform mat4 u_view;
   uniform mat4 u_proj;
   varying vec3 v_normal;
   varying vec3 v_pos;
+  varying vec3 v_color;
   void main() {
     vec4 world = u_model * vec4(a_position, 1.0);
     v_pos = world.xyz;
     v_normal = mat3(u_model) * a_normal;
+    v_color = a_color;
     gl_Position = u_proj * u_view * world;
   }
 `;
@@ -17,14 +20,14 @@ export const vertexShader = `
 export const fragmentShader = `
   precision mediump float;
   uniform vec3 u_lightDir;
-  uniform vec3 u_color;
   varying vec3 v_normal;
   varying vec3 v_pos;
+  varying vec3 v_color;
   void main() {
     vec3 normal = normalize(v_normal);
     float diff = max(dot(normal, normalize(u_lightDir)), 0.0);
     float rim = pow(1.0 - max(dot(normal, vec3(0.0, 0.0, 1.0)), 0.0), 2.0);
-    vec3 color = u_color * (0.2 + diff * 0.8) + vec3(0.15, 0.2, 0.3) * rim;
+    vec3 color = v_color * (0.2 + diff * 0.8) + vec3(0.15, 0.2, 0.3) * rim;
     gl_FragColor = vec4(color, 1.0);
   }
 `;
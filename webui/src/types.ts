@@ -9,7 +9,11 @@ export type Mat4 = Float32Array<ArrayBuffer>;
 export interface MeshData {
   positions: Float32Array;
   normals: Float32Array;
+  colors: Float32Array;
   bounds: Bounds | null;
+  // Objects may overlap in the preview, so exports use their union.
+  exportPositions: Float32Array;
+  exportNormals: Float32Array;
 }
 
 export interface CameraState {
@@ -39,8 +43,11 @@ export interface CompileRequest {
   code: string;
   gridSize: number;
   meshBackend: MeshBackend;
+  files: UploadedFiles;
 }
 
+export type UploadedFiles = Record<string, Uint8Array>;
+
 export type MeshBackend = "cpu" | "gpu_f32" | "gpu_fixed64";
 
 export interface InitRequest {
@@ -55,7 +62,10 @@ export interface CompileSuccess {
   ok: true;
   positions: ArrayLike<number>;
   normals: ArrayLike<number>;
+  colors: ArrayLike<number>;
   bounds: Bounds | null;
+  exportPositions: ArrayLike<number>;
+  exportNormals: ArrayLike<number>;
 }
 
 export interface CompileError {
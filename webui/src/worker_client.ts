@@ -3,6 +3,7 @@ import type {
   InitRequest,
   MeshBackend,
   MeshData,
+  UploadedFiles,
   WorkerInboundMessage,
   WorkerRequest,
 } from "./types";
@@ -34,6 +35,7 @@ interface WorkerClient {
     code: string,
     gridSize: number,
     meshBackend?: MeshBackend,
+    files?: UploadedFiles,
   ): Promise<MeshData | null>;
   cancel(): boolean;
   isReady(): boolean;
@@ -47,7 +49,10 @@ function toMeshData(msg: CompileSuccess): MeshData {
   return {
     positions: new Float32Array(msg.positions),
     normals: new Float32Array(msg.normals),
+    colors: new Float32Array(msg.colors),
     bounds: msg.bounds,
+    exportPositions: new Float32Array(msg.exportPositions),
+    exportNormals: new Float32Array(msg.exportNormals),
   };
 }
 
@@ -60,6 +65,7 @@ function compileMessage(
   code: string,
   gridSize: number,
   meshBackend: MeshBackend,
+  files: UploadedFiles,
 ): WorkerRequest {
   return {
     type: "compile",
@@ -67,6 +73,7 @@ function compileMessage(
     code,
     gridSize,
     meshBackend,
+    files,
   };
 }
 
@@ -190,6 +197,7 @@ export function createWorkerClient({
     code: string,
     gridSize: number,
     meshBackend: MeshBackend = "cpu",
+    files: UploadedFiles = {},
   ): Promise<MeshData | null> {
     if (pendingRequestId != null || compilePreparing) {
       return null;
@@ -211,7 +219,7 @@ export function createWorkerClient({
         rejectCompile = reject;
       });
       worker.postMessage(
-        compileMessage(pendingRequestId, code, gridSize, meshBackend),
+        compileMessage(pendingRequestId, code, gridSize, meshBackend, files),
       );
       return await resultPromise;
     } catch (err) {
@@ -4,6 +4,8 @@ package main
 
 import (
 	"fmt"
+	"math"
+	pathpkg "path"
 	"syscall/js"
 
 	"github.com/unixpickle/model3d/model2d"
@@ -40,7 +42,9 @@ func compile(_ js.Value, args []js.Value) any {
 		})
 	}
 	backend := meshBackendCPU
+	files := map[string][]byte{}
 	if len(args) >= 3 && args[2].Type() == js.TypeObject {
+		files = uploadedFiles(args[2].Get("files"))
 		if opt := args[2].Get("meshBackend"); opt.Type() == js.TypeString {
 			backend = meshBackend(opt.String())
 		} else if opt := args[2].Get("useWebGPU"); opt.Type() == js.TypeBoolean && opt.Bool() {
@@ -64,20 +68,40 @@ func compile(_ js.Value, args []js.Value) any {
 		if err != nil {
 			return js.Null(), err
 		}
-		hooks := wasmHooks(backend)
-		shape, err := scad.Eval(prog, hooks)
+		hooks := wasmHooks(backend, files)
+		merged, objects, err := scad.EvalUnionAndObjects(prog, hooks)
 		if err != nil {
 			return js.Null(), err
 		}
 
-		mesh, err := shapeToMesh(shape, gridSize, hooks)
-		if err != nil {
-			return js.Null(), err
+		meshes := make([]*model3d.Mesh, len(objects))
+		colors := make([][3]float64, len(objects))
+		for i, shape := range objects {
+			meshes[i], err = shapeToMesh(shape, gridSize, hooks)
+			if err != nil {
+				return js.Null(), err
+			}
+			colors[i] = defaultPreviewColor
+			if shape.Tags.HasColor {
+				copy(colors[i][:], shape.Tags.Color[:3])
+			}
+		}
+		// Objects may overlap, so the STL export is meshed from their union
+		// instead of joining the preview meshes.
+		exportMesh := meshes[0]
+		if len(objects) > 1 {
+			exportMesh, err = shapeToMesh(merged, gridSize, hooks)
+			if err != nil {
+				return js.Null(), err
+			}
 		}
-		return meshResponse(mesh), nil
+		return meshResponse(meshes, colors, exportMesh), nil
 	})
 }
 
+// defaultPreviewColor is used for objects without a color() tag.
+var defaultPreviewColor = [3]float64{0.67, 0.75, 0.95}
+
 func wasmEchoHandler(msg string) {
 	echoMsg := js.Global().Get("Object").New()
 	echoMsg.Set("type", "echo")
@@ -107,8 +131,34 @@ func (b meshBackend) Numerics() shapekernel.Numerics {
 	return shapekernel.NativeFloat32Numerics
 }
 
-func wasmHooks(backend meshBackend) scad.Hooks {
+// uploadedFiles copies a JS object mapping file names to Uint8Arrays.
+func uploadedFiles(obj js.Value) map[string][]byte {
+	files := map[string][]byte{}
+	if obj.Type() != js.TypeObject {
+		return files
+	}
+	keys := js.Global().Get("Object").Call("keys", obj)
+	for i := 0; i < keys.Length(); i++ {
+		name := keys.Index(i).String()
+		data := obj.Get(name)
+		buf := make([]byte, data.Get("length").Int())
+		js.CopyBytesToGo(buf, data)
+		files[name] = buf
+	}
+	return files
+}
+
+func wasmHooks(backend meshBackend, files map[string][]byte) scad.Hooks {
 	return scad.Hooks{
+		ReadFile: func(path string) ([]byte, error) {
+			if data, ok := files[path]; ok {
+				return data, nil
+			}
+			if data, ok := files[pathpkg.Base(path)]; ok {
+				return data, nil
+			}
+			return nil, fmt.Errorf("file %q has not been uploaded", path)
+		},
 		Numerics: backend.Numerics(),
 		Echo:     wasmEchoHandler,
 		MarchingSquares: func(obj scad.ShapeRep, delta float64, iters int) (*model2d.Mesh, error) {
@@ -191,24 +241,45 @@ func newPromise(f func() (js.Value, error)) js.Value {
 	return js.Global().Get("Promise").New(executor)
 }
 
-func meshResponse(mesh *model3d.Mesh) js.Value {
-	tris := mesh.TriangleSlice()
-	positions := make([]float64, 0, len(tris)*9)
-	normals := make([]float64, 0, len(tris)*9)
-	for _, tri := range tris {
-		n := tri.Normal()
-		for i := 0; i < 3; i++ {
-			p := tri[i]
-			positions = append(positions, p.X, p.Y, p.Z)
-			normals = append(normals, n.X, n.Y, n.Z)
+// meshResponse creates the result of a compilation, with a colored
+// preview of each object and a separate mesh to export.
+func meshResponse(meshes []*model3d.Mesh, meshColors [][3]float64, exportMesh *model3d.Mesh) js.Value {
+	var positions, normals, colors []float64
+	min := model3d.XYZ(math.Inf(1), math.Inf(1), math.Inf(1))
+	max := min.Scale(-1)
+	for i, mesh := range meshes {
+		c := meshColors[i]
+		for _, tri := range mesh.TriangleSlice() {
+			n := tri.Normal()
+			for _, p := range tri {
+				positions = append(positions, p.X, p.Y, p.Z)
+				normals = append(normals, n.X, n.Y, n.Z)
+				colors = append(colors, c[0], c[1], c[2])
+			}
 		}
+		if mesh.NumTriangles() > 0 {
+			min = min.Min(mesh.Min())
+			max = max.Max(mesh.Max())
+		}
+	}
+	if len(positions) == 0 {
+		min, max = model3d.Coord3D{}, model3d.Coord3D{}
 	}
-	min := mesh.Min()
-	max := mesh.Max()
 	res := js.Global().Get("Object").New()
 	res.Set("ok", true)
 	res.Set("positions", jsFloat32Array(positions))
 	res.Set("normals", jsFloat32Array(normals))
+	res.Set("colors", jsFloat32Array(colors))
+	var exportPositions, exportNormals []float64
+	for _, tri := range exportMesh.TriangleSlice() {
+		n := tri.Normal()
+		for _, p := range tri {
+			exportPositions = append(exportPositions, p.X, p.Y, p.Z)
+			exportNormals = append(exportNormals, n.X, n.Y, n.Z)
+		}
+	}
+	res.Set("exportPositions", jsFloat32Array(exportPositions))
+	res.Set("exportNormals", jsFloat32Array(exportNormals))
 	bounds := js.Global().Get("Object").New()
 	bounds.Set("min", jsFloat64Array([]float64{min.X, min.Y, min.Z}))
 	bounds.Set("max", jsFloat64Array([]float64{max.X, max.Y, max.Z}))
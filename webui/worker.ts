@@ -2,6 +2,7 @@
 
 import type {
   MeshBackend,
+  UploadedFiles,
   WebGPUMeshRequestPayload,
   WorkerRequest,
   WorkerResponse,
@@ -21,6 +22,7 @@ declare const Go: {
 
 interface CompileOptions {
   meshBackend: MeshBackend;
+  files: UploadedFiles;
 }
 
 interface WorkerGlobalWithRuntime extends DedicatedWorkerGlobalScope {
@@ -171,6 +173,7 @@ workerScope.addEventListener(
         return Promise.resolve(
           workerScope.m3dscadCompile(msg.code, msg.gridSize, {
             meshBackend: msg.meshBackend || "cpu",
+            files: msg.files || {},
           }),
         )
           .then((result) => {